@@ -0,0 +1,19 @@
+//go:build embed_assets
+
+package assets
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// files holds the asset directories, compiled into the binary. New asset
+// directories must be listed here; the Go files are left out like cmd/pack
+// does
+//
+//go:embed i18n shaders
+var files embed.FS
+
+// Files holds the game assets in single binary builds, made with the
+// embed_assets build tag
+var Files fs.FS = files
@@ -0,0 +1,9 @@
+//go:build !embed_assets
+
+package assets
+
+import "io/fs"
+
+// Files holds the game assets in single binary builds, made with the
+// embed_assets build tag. It is nil otherwise
+var Files fs.FS
@@ -0,0 +1,22 @@
+#version 330 core
+// Separable 9 tap Gaussian blur, using linear filtering to read two taps
+// per sample
+
+in vec2 vTexCoord;
+
+uniform sampler2D uImage;
+uniform vec2 uDirection; // one texel along the blur axis
+
+out vec4 FragColor;
+
+const float weights[3] = float[](0.2270270270, 0.3162162162, 0.0702702703);
+const float offsets[3] = float[](0.0, 1.3846153846, 3.2307692308);
+
+void main() {
+	vec3 color = texture(uImage, vTexCoord).rgb * weights[0];
+	for (int i = 1; i < 3; i++) {
+		color += texture(uImage, vTexCoord + uDirection * offsets[i]).rgb * weights[i];
+		color += texture(uImage, vTexCoord - uDirection * offsets[i]).rgb * weights[i];
+	}
+	FragColor = vec4(color, 1.0);
+}
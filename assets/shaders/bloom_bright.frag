@@ -0,0 +1,17 @@
+#version 330 core
+// The bright pass keeps what exceeds the threshold. It renders at half
+// size, so the linear filter averages four pixels per sample
+
+in vec2 vTexCoord;
+
+uniform sampler2D uImage;
+uniform float uThreshold;
+
+out vec4 FragColor;
+
+void main() {
+	vec3 color = texture(uImage, vTexCoord).rgb;
+	float brightness = max(color.r, max(color.g, color.b));
+	float contribution = max(brightness - uThreshold, 0.0) / max(brightness, 0.0001);
+	FragColor = vec4(color * contribution, 1.0);
+}
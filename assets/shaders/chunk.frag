@@ -0,0 +1,35 @@
+#version 330 core
+#include "frame.glsl"
+#include "lighting.glsl"
+
+in vec3 vWorldPosition;
+in vec3 vNormal;
+in vec2 vTexCoord;
+flat in float vLayer;
+in float vShade;
+
+uniform sampler2DArray uBlocks;
+uniform vec4 uColor;
+// Water faces at the ocean surface are hidden where the water renderer
+// draws it: xy is the center of that area, z its half size, w the level
+uniform vec4 uOcean;
+uniform float uWaterLayer;
+
+out vec4 FragColor;
+
+void main() {
+	if (vLayer == uWaterLayer && vWorldPosition.y > uOcean.w - 0.05 &&
+		all(lessThan(abs(vWorldPosition.xz - uOcean.xy), vec2(uOcean.z)))) {
+		discard;
+	}
+
+	vec4 base = texture(uBlocks, vec3(vTexCoord, vLayer)) * uColor;
+	if (base.a < 0.1) {
+		discard;
+	}
+
+	// Transparent faces are seen from both sides
+	vec3 normal = gl_FrontFacing ? vNormal : -vNormal;
+	vec3 color = shade(base.rgb * vShade, normal, vWorldPosition, 0.0, 1.0);
+	FragColor = vec4(applyFog(color, vWorldPosition), base.a);
+}
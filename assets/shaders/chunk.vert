@@ -0,0 +1,27 @@
+#version 330 core
+#include "frame.glsl"
+
+layout (location = 0) in vec3 aPosition;
+layout (location = 1) in vec3 aNormal;
+layout (location = 2) in vec2 aTexCoord;
+layout (location = 5) in vec2 aBlock;
+
+uniform mat4 uModel;
+
+out vec3 vWorldPosition;
+out vec3 vNormal;
+out vec2 vTexCoord;
+flat out float vLayer;
+out float vShade;
+
+void main() {
+	// Chunks and tiles are only ever translated
+	vec4 world = uModel * vec4(aPosition, 1.0);
+	vWorldPosition = world.xyz;
+	vNormal = aNormal;
+	vTexCoord = aTexCoord;
+	vLayer = aBlock.x;
+	vShade = aBlock.y;
+	gl_ClipDistance[0] = dot(world, uClipPlane);
+	gl_Position = uViewProjection * world;
+}
@@ -0,0 +1,71 @@
+#version 330 core
+in vec2 vTexCoord;
+
+uniform sampler2D uScene;
+uniform sampler2D uBloom;
+uniform sampler2D uLUT;
+
+uniform bool uToneMapping;
+uniform float uExposure;
+uniform bool uBloomEnabled;
+uniform float uBloomIntensity;
+uniform bool uGrading;
+uniform float uLUTSize;
+uniform bool uVignetteEnabled;
+uniform float uVignette;
+uniform float uDamage;
+uniform float uTime;
+
+out vec4 FragColor;
+
+// aces is the fitted ACES filmic curve by Krzysztof Narkowicz
+vec3 aces(vec3 x) {
+	return clamp((x * (2.51 * x + 0.03)) / (x * (2.43 * x + 0.59) + 0.14), 0.0, 1.0);
+}
+
+// grade looks a color up in a LUT strip: the blue channel selects one of
+// uLUTSize square slices laid out left to right
+vec3 grade(vec3 color) {
+	float size = uLUTSize;
+	float slice = color.b * (size - 1.0);
+	float first = floor(slice);
+	float second = min(first + 1.0, size - 1.0);
+	vec2 uv = vec2((color.r * (size - 1.0) + 0.5) / (size * size), (color.g * (size - 1.0) + 0.5) / size);
+	vec3 a = texture(uLUT, uv + vec2(first / size, 0.0)).rgb;
+	vec3 b = texture(uLUT, uv + vec2(second / size, 0.0)).rgb;
+	return mix(a, b, slice - first);
+}
+
+void main() {
+	vec2 uv = vTexCoord;
+	vec3 color;
+	if (uDamage > 0.0) {
+		// Split the channels away from the center
+		vec2 shift = (uv - 0.5) * 0.012 * uDamage;
+		color = vec3(texture(uScene, uv + shift).r, texture(uScene, uv).g, texture(uScene, uv - shift).b);
+	} else {
+		color = texture(uScene, uv).rgb;
+	}
+
+	if (uBloomEnabled) {
+		color += texture(uBloom, uv).rgb * uBloomIntensity;
+	}
+	color = uToneMapping ? aces(color * uExposure) : clamp(color, 0.0, 1.0);
+	if (uGrading) {
+		color = grade(color);
+	}
+
+	// 0 at the center, 1 in the corners
+	float edge = length(uv - 0.5) * 1.41421356;
+	if (uVignetteEnabled) {
+		color *= 1.0 - uVignette * smoothstep(0.4, 1.0, edge);
+	}
+	if (uDamage > 0.0) {
+		float luma = dot(color, vec3(0.299, 0.587, 0.114));
+		color = mix(color, vec3(luma), 0.5 * uDamage);
+		float pulse = 0.75 + 0.25 * sin(uTime * 6.0);
+		color = mix(color, vec3(0.55, 0.0, 0.0), uDamage * pulse * smoothstep(0.2, 1.0, edge));
+	}
+
+	FragColor = vec4(color, 1.0);
+}
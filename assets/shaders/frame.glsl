@@ -0,0 +1,27 @@
+// The Frame uniform block filled by the 3D renderer. MAX_LIGHTS and
+// MAX_CASCADES are defined by the renderer
+
+struct Light {
+	vec4 position;  // xyz: position, w: type
+	vec4 direction; // xyz: direction, w: range
+	vec4 color;     // rgb: color times intensity
+	vec4 cone;      // x: cosine of the inner angle, y: of the outer angle,
+	                // z: shadow map index (-1 without shadow)
+};
+
+layout (std140) uniform Frame {
+	mat4 uView;
+	mat4 uProjection;
+	mat4 uViewProjection;
+	vec4 uCameraPosition; // w: time in seconds
+	vec4 uClipPlane;
+	vec4 uAmbient;
+	vec4 uFogColor;       // w: fog mode
+	vec4 uFogParams;      // x: start, y: end, z: density
+	vec4 uLightInfo;      // x: light count
+	mat4 uShadowMatrices[MAX_CASCADES];
+	vec4 uShadowSplits;   // view depth where each cascade ends
+	vec4 uShadowTexels;   // world size of a shadow texel of each cascade
+	vec4 uShadowParams;   // x: cascade count, y: filter radius, zw: atlas texel size
+	Light uLights[MAX_LIGHTS];
+};
@@ -0,0 +1,9 @@
+#version 330 core
+out vec2 vTexCoord;
+
+void main() {
+	// A single triangle covering the screen
+	vec2 position = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+	vTexCoord = position;
+	gl_Position = vec4(position * 2.0 - 1.0, 0.0, 1.0);
+}
@@ -0,0 +1,40 @@
+#version 330 core
+// FXAA after Timothy Lottes: blur along the edge direction estimated from
+// the luma of the diagonal neighbours
+
+in vec2 vTexCoord;
+
+uniform sampler2D uImage;
+uniform vec2 uTexel;
+
+out vec4 FragColor;
+
+#define FXAA_REDUCE_MIN (1.0 / 128.0)
+#define FXAA_REDUCE_MUL (1.0 / 8.0)
+#define FXAA_SPAN_MAX 8.0
+
+void main() {
+	const vec3 weights = vec3(0.299, 0.587, 0.114);
+	float lumaNW = dot(texture(uImage, vTexCoord + vec2(-1.0, -1.0) * uTexel).rgb, weights);
+	float lumaNE = dot(texture(uImage, vTexCoord + vec2(1.0, -1.0) * uTexel).rgb, weights);
+	float lumaSW = dot(texture(uImage, vTexCoord + vec2(-1.0, 1.0) * uTexel).rgb, weights);
+	float lumaSE = dot(texture(uImage, vTexCoord + vec2(1.0, 1.0) * uTexel).rgb, weights);
+	vec3 center = texture(uImage, vTexCoord).rgb;
+	float lumaM = dot(center, weights);
+
+	float lumaMin = min(lumaM, min(min(lumaNW, lumaNE), min(lumaSW, lumaSE)));
+	float lumaMax = max(lumaM, max(max(lumaNW, lumaNE), max(lumaSW, lumaSE)));
+
+	vec2 dir = vec2(-((lumaNW + lumaNE) - (lumaSW + lumaSE)), (lumaNW + lumaSW) - (lumaNE + lumaSE));
+	float reduce = max((lumaNW + lumaNE + lumaSW + lumaSE) * 0.25 * FXAA_REDUCE_MUL, FXAA_REDUCE_MIN);
+	float scale = 1.0 / (min(abs(dir.x), abs(dir.y)) + reduce);
+	dir = clamp(dir * scale, vec2(-FXAA_SPAN_MAX), vec2(FXAA_SPAN_MAX)) * uTexel;
+
+	vec3 a = 0.5 * (texture(uImage, vTexCoord + dir * (1.0 / 3.0 - 0.5)).rgb +
+		texture(uImage, vTexCoord + dir * (2.0 / 3.0 - 0.5)).rgb);
+	vec3 b = a * 0.5 + 0.25 * (texture(uImage, vTexCoord - dir * 0.5).rgb +
+		texture(uImage, vTexCoord + dir * 0.5).rgb);
+	float lumaB = dot(b, weights);
+
+	FragColor = vec4((lumaB < lumaMin || lumaB > lumaMax) ? a : b, 1.0);
+}
@@ -0,0 +1,147 @@
+// Lighting shared by the materials of the 3D renderer. Include it after
+// frame.glsl
+
+uniform sampler2DShadow uShadowMap;
+uniform samplerCubeShadow uPointShadowMap0;
+uniform samplerCubeShadow uPointShadowMap1;
+uniform samplerCubeShadow uPointShadowMap2;
+uniform samplerCubeShadow uPointShadowMap3;
+
+// Directions spreading the samples of filtered point shadows
+const vec3 pointShadowOffsets[8] = vec3[](
+	vec3(1, 1, 1), vec3(-1, 1, 1), vec3(1, -1, 1), vec3(-1, -1, 1),
+	vec3(1, 1, -1), vec3(-1, 1, -1), vec3(1, -1, -1), vec3(-1, -1, -1)
+);
+
+// cascadeShadow returns how much sunlight reaches a point, from 0 in full
+// shadow to 1
+float cascadeShadow(vec3 worldPosition, vec3 normal, float nDotL) {
+	int count = int(uShadowParams.x);
+	float depth = -(uView * vec4(worldPosition, 1.0)).z;
+	int cascade = -1;
+	for (int i = 0; i < count; i++) {
+		if (depth < uShadowSplits[i]) {
+			cascade = i;
+			break;
+		}
+	}
+	if (cascade < 0) {
+		return 1.0;
+	}
+
+	// Pushing the point off surfaces facing away from the sun avoids acne
+	vec3 offset = normal * uShadowTexels[cascade] * (1.5 - nDotL);
+	vec3 coord = (uShadowMatrices[cascade] * vec4(worldPosition + offset, 1.0)).xyz;
+	if (coord.z > 1.0) {
+		return 1.0;
+	}
+
+	int radius = int(uShadowParams.y);
+	float lit = 0.0;
+	for (int y = -radius; y <= radius; y++) {
+		for (int x = -radius; x <= radius; x++) {
+			lit += texture(uShadowMap, vec3(coord.xy + vec2(x, y) * uShadowParams.zw, coord.z));
+		}
+	}
+	float size = float(2 * radius + 1);
+	return lit / (size * size);
+}
+
+// pointShadowSample compares a depth with the shadow cube map of a slot
+float pointShadowSample(int slot, vec3 direction, float depth) {
+	vec4 coord = vec4(direction, depth);
+	if (slot == 0) {
+		return texture(uPointShadowMap0, coord);
+	} else if (slot == 1) {
+		return texture(uPointShadowMap1, coord);
+	} else if (slot == 2) {
+		return texture(uPointShadowMap2, coord);
+	}
+	return texture(uPointShadowMap3, coord);
+}
+
+// pointShadow returns how much of a point or spot light reaches a point
+float pointShadow(int slot, vec3 worldPosition, vec3 normal, vec3 lightPosition, float range) {
+	vec3 direction = worldPosition + normal * 0.05 - lightPosition;
+	float depth = length(direction) / range - 0.002;
+	if (uShadowParams.y < 1.0) {
+		return pointShadowSample(slot, direction, depth);
+	}
+
+	// Filtered shadows spread samples proportionally to the distance
+	float spread = length(direction) * 0.01 * uShadowParams.y;
+	float lit = pointShadowSample(slot, direction, depth);
+	for (int i = 0; i < 8; i++) {
+		lit += pointShadowSample(slot, direction + pointShadowOffsets[i] * spread, depth);
+	}
+	return lit / 9.0;
+}
+
+// shade returns the color of a surface lit by the ambient light and every
+// light of the frame
+vec3 shade(vec3 albedo, vec3 normal, vec3 worldPosition, float specular, float shininess) {
+	vec3 n = normalize(normal);
+	vec3 v = normalize(uCameraPosition.xyz - worldPosition);
+	vec3 color = uAmbient.rgb * albedo;
+
+	int count = int(uLightInfo.x);
+	for (int i = 0; i < count; i++) {
+		int type = int(uLights[i].position.w);
+		vec3 l;
+		float attenuation = 1.0;
+
+		if (type == 0) {
+			l = normalize(-uLights[i].direction.xyz);
+		} else {
+			vec3 toLight = uLights[i].position.xyz - worldPosition;
+			float dist = length(toLight);
+			l = toLight / max(dist, 0.0001);
+
+			// Inverse square falloff, smoothly reaching zero at the range
+			float ratio = dist / uLights[i].direction.w;
+			float window = clamp(1.0 - ratio * ratio * ratio * ratio, 0.0, 1.0);
+			attenuation = window * window / (1.0 + dist * dist);
+
+			if (type == 2) {
+				float angle = dot(-l, normalize(uLights[i].direction.xyz));
+				attenuation *= smoothstep(uLights[i].cone.y, uLights[i].cone.x, angle);
+			}
+		}
+
+		float diffuse = max(dot(n, l), 0.0);
+		int shadow = int(uLights[i].cone.z);
+		if (shadow >= 0 && diffuse > 0.0) {
+			if (type == 0) {
+				attenuation *= cascadeShadow(worldPosition, n, diffuse);
+			} else {
+				attenuation *= pointShadow(shadow, worldPosition, n, uLights[i].position.xyz, uLights[i].direction.w);
+			}
+		}
+		float highlight = 0.0;
+		if (diffuse > 0.0) {
+			vec3 h = normalize(l + v);
+			highlight = pow(max(dot(n, h), 0.0), shininess) * specular;
+		}
+		color += (albedo * diffuse + highlight) * uLights[i].color.rgb * attenuation;
+	}
+	return color;
+}
+
+// applyFog blends a color towards the fog color with the distance from
+// the camera
+vec3 applyFog(vec3 color, vec3 worldPosition) {
+	int mode = int(uFogColor.w);
+	if (mode == 0) {
+		return color;
+	}
+
+	float dist = length(worldPosition - uCameraPosition.xyz);
+	float amount;
+	if (mode == 1) {
+		amount = clamp((dist - uFogParams.x) / max(uFogParams.y - uFogParams.x, 0.0001), 0.0, 1.0);
+	} else {
+		float d = uFogParams.z * dist;
+		amount = 1.0 - exp(-d * d);
+	}
+	return mix(color, uFogColor.rgb, amount);
+}
@@ -0,0 +1,31 @@
+#version 330 core
+#include "frame.glsl"
+#include "lighting.glsl"
+
+in vec3 vWorldPosition;
+in vec3 vNormal;
+in vec2 vTexCoord;
+
+uniform vec4 uColor;
+uniform sampler2D uTexture;
+uniform bool uHasTexture;
+uniform float uSpecular;
+uniform float uShininess;
+uniform vec3 uEmissive;
+uniform bool uUnlit;
+
+out vec4 FragColor;
+
+void main() {
+	vec4 base = uColor;
+	if (uHasTexture) {
+		base *= texture(uTexture, vTexCoord);
+	}
+
+	// Double-sided surfaces are lit from the side they are seen from
+	vec3 normal = gl_FrontFacing ? vNormal : -vNormal;
+	vec3 color = uUnlit ? base.rgb : shade(base.rgb, normal, vWorldPosition, uSpecular, uShininess);
+	color += uEmissive;
+
+	FragColor = vec4(applyFog(color, vWorldPosition), base.a);
+}
@@ -0,0 +1,22 @@
+#version 330 core
+#include "frame.glsl"
+
+layout (location = 0) in vec3 aPosition;
+layout (location = 1) in vec3 aNormal;
+layout (location = 2) in vec2 aTexCoord;
+
+uniform mat4 uModel;
+uniform mat3 uNormalMatrix;
+
+out vec3 vWorldPosition;
+out vec3 vNormal;
+out vec2 vTexCoord;
+
+void main() {
+	vec4 world = uModel * vec4(aPosition, 1.0);
+	vWorldPosition = world.xyz;
+	vNormal = uNormalMatrix * aNormal;
+	vTexCoord = aTexCoord;
+	gl_ClipDistance[0] = dot(world, uClipPlane);
+	gl_Position = uViewProjection * world;
+}
@@ -0,0 +1,12 @@
+#version 330 core
+// Point shadows store the distance to the light divided by its range, so
+// that the six faces of the cube share one depth scale
+
+in vec3 vWorldPosition;
+
+uniform vec3 uLightPosition;
+uniform float uLightRange;
+
+void main() {
+	gl_FragDepth = clamp(length(vWorldPosition - uLightPosition) / uLightRange, 0.0, 1.0);
+}
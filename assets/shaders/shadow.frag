@@ -0,0 +1,4 @@
+#version 330 core
+// The sun shadow keeps the rasterized depth
+
+void main() {}
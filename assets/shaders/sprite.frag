@@ -0,0 +1,11 @@
+#version 330 core
+in vec2 vTexCoord;
+in vec4 vColor;
+
+uniform sampler2D uTexture;
+
+out vec4 FragColor;
+
+void main() {
+	FragColor = texture(uTexture, vTexCoord) * vColor;
+}
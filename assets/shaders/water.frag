@@ -0,0 +1,55 @@
+#version 330 core
+in vec3 vWorld;
+in vec3 vNormal;
+in vec4 vClip;
+in float vCrest;
+
+uniform sampler2D uReflection;
+uniform sampler2D uRefraction;
+uniform sampler2D uRefractionDepth;
+uniform vec3 uEye;
+uniform vec3 uShallowColor;
+uniform vec3 uDeepColor;
+uniform vec3 uSunDirection;
+uniform vec3 uSunColor;
+uniform float uClarityDepth;
+uniform float uFoamDepth;
+uniform float uTime;
+// Elements (2, 2) and (2, 3) of the projection matrix
+uniform vec2 uDepthParams;
+
+out vec4 FragColor;
+
+// linearDepth returns the view distance of a depth buffer value
+float linearDepth(float depth) {
+	return uDepthParams.y / (depth * 2.0 - 1.0 + uDepthParams.x);
+}
+
+void main() {
+	vec2 screen = vClip.xy / vClip.w * 0.5 + 0.5;
+	vec3 n = normalize(vNormal);
+
+	// Distance the view ray travels through the water
+	float depth = max(linearDepth(texture(uRefractionDepth, screen).r) - linearDepth(gl_FragCoord.z), 0.0);
+
+	// Ripples bend the reflected and refracted images, less in the shallows
+	vec2 distorted = clamp(screen + n.xz * 0.03 * clamp(depth / 2.0, 0.0, 1.0), 0.001, 0.999);
+	vec3 reflection = texture(uReflection, distorted).rgb;
+	vec3 refraction = texture(uRefraction, distorted).rgb;
+	refraction = mix(refraction * uShallowColor, uDeepColor, clamp(depth / uClarityDepth, 0.0, 1.0));
+
+	vec3 v = normalize(uEye - vWorld);
+	float fresnel = 0.02 + 0.98 * pow(1.0 - max(dot(v, n), 0.0), 5.0);
+	vec3 color = mix(refraction, reflection, fresnel);
+
+	vec3 h = normalize(v + uSunDirection);
+	color += uSunColor * pow(max(dot(n, h), 0.0), 256.0);
+
+	// Foam along shores and on the highest crests
+	float shore = 1.0 - clamp(depth / uFoamDepth, 0.0, 1.0);
+	float pattern = 0.5 + 0.5 * sin(vWorld.x * 3.1 + uTime * 1.7) * sin(vWorld.z * 2.7 - uTime * 1.3);
+	float foam = clamp(shore * (0.6 + 0.4 * pattern) + max(vCrest - 0.7, 0.0) * 2.0, 0.0, 1.0);
+	color = mix(color, vec3(0.95), foam);
+
+	FragColor = vec4(color, clamp(depth * 4.0, 0.0, 1.0));
+}
@@ -0,0 +1,39 @@
+#version 330 core
+layout (location = 0) in vec3 aPosition;
+
+uniform mat4 uModel;
+uniform mat4 uViewProjection;
+uniform float uTime;
+// Direction, amplitude and wavelength of each wave
+uniform vec4 uWaves[MAX_WAVES];
+// Speed and steepness of each wave
+uniform vec2 uWaveMotion[MAX_WAVES];
+uniform int uWaveCount;
+uniform float uWaveHeight;
+
+out vec3 vWorld;
+out vec3 vNormal;
+out vec4 vClip;
+out float vCrest;
+
+void main() {
+	vec3 p = (uModel * vec4(aPosition, 1.0)).xyz;
+	vec3 offset = vec3(0.0);
+	vec3 normal = vec3(0.0, 1.0, 0.0);
+
+	for (int i = 0; i < uWaveCount; i++) {
+		vec2 d = normalize(uWaves[i].xy);
+		float a = uWaves[i].z;
+		float k = 6.28318530 / uWaves[i].w;
+		float q = uWaveMotion[i].y / (k * a * float(uWaveCount));
+		float f = k * dot(d, p.xz) - uWaveMotion[i].x * k * uTime;
+		offset += vec3(q * a * d.x * cos(f), a * sin(f), q * a * d.y * cos(f));
+		normal -= vec3(d.x * k * a * cos(f), q * k * a * sin(f), d.y * k * a * cos(f));
+	}
+
+	vWorld = p + offset;
+	vNormal = normalize(normal);
+	vCrest = uWaveHeight > 0.0 ? offset.y / uWaveHeight : 0.0;
+	vClip = uViewProjection * vec4(vWorld, 1.0);
+	gl_Position = vClip;
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"runtime"
 
+	bundled "github.com/luidsonl/magic-and-blades/assets"
 	"github.com/luidsonl/magic-and-blades/internal/engine"
+	"github.com/luidsonl/magic-and-blades/internal/engine/assets"
 	"github.com/luidsonl/magic-and-blades/internal/game"
-	"github.com/veandco/go-sdl2/sdl"
+	"github.com/luidsonl/magic-and-blades/internal/scenes/gameplay"
+	"github.com/luidsonl/magic-and-blades/internal/scenes/menu"
 )
 
 func init() {
@@ -18,19 +22,77 @@ func init() {
 func main() {
 	fmt.Println("Starting Magic and Blades...")
 
-	// Initialize SDL
-	if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
-		log.Fatalf("Failed to initialize SDL: %v", err)
+	// Command-line flags override the saved settings for this run
+	settingsPath := flag.String("settings", "", "path to the settings file (default: per-user config directory)")
+	width := flag.Int("width", 0, "window width")
+	height := flag.Int("height", 0, "window height")
+	fullscreen := flag.Bool("fullscreen", false, "start in fullscreen mode")
+	lang := flag.String("lang", "", "interface language (e.g. en, pt)")
+	headless := flag.Bool("headless", false, "run without a window")
+	dummyVideo := flag.Bool("dummy-video", false, "use the SDL dummy video driver, so that headless runs need no display")
+	ticks := flag.Uint64("ticks", 0, "stop after this many simulation ticks (0 = no limit)")
+	hotReload := flag.Bool("hot-reload", false, "rebuild shaders when their source files change")
+	assetRoot := flag.String("assets", "", "asset directory or .pak archive (default: embedded, else found next to the executable or working directory)")
+	flag.Parse()
+
+	// Assets given on the command line win over embedded ones
+	switch {
+	case *assetRoot != "":
+		assets.SetRoot(*assetRoot)
+		log.Printf("Loading assets from %s", assets.Root())
+	case bundled.Files != nil:
+		assets.SetFiles(bundled.Files)
+		log.Printf("Loading embedded assets")
+	default:
+		log.Printf("Loading assets from %s", assets.Root())
 	}
-	defer sdl.Quit()
 
 	// Game configuration
-	config := game.Config{
-		WindowTitle:  "Magic and Blades",
-		WindowWidth:  800,
-		WindowHeight: 600,
-		Fullscreen:   false,
-		Language:     "", // Auto-detect language
+	if *settingsPath == "" {
+		path, err := game.SettingsPath()
+		if err != nil {
+			log.Printf("Warning: settings will not be saved: %v", err)
+		}
+		*settingsPath = path
+	}
+
+	config := game.DefaultConfig()
+	if *settingsPath != "" {
+		loaded, err := game.LoadConfig(*settingsPath)
+		if err != nil {
+			log.Printf("Warning: using default settings: %v", err)
+		}
+		config = loaded
+	}
+
+	// Overrides apply to this run only and are never saved
+	var overrides game.Overrides
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "width":
+			overrides.WindowWidth = int32(*width)
+		case "height":
+			overrides.WindowHeight = int32(*height)
+		case "fullscreen":
+			if *fullscreen {
+				overrides.WindowMode = game.WindowModeFullscreen
+			} else {
+				overrides.WindowMode = game.WindowModeWindowed
+			}
+		case "lang":
+			overrides.Language = *lang
+		case "headless":
+			config.Headless = *headless
+		case "dummy-video":
+			config.DummyVideo = *dummyVideo
+		case "ticks":
+			config.MaxTicks = *ticks
+		case "hot-reload":
+			config.HotReload = *hotReload
+		}
+	})
+	if err := config.Override(overrides); err != nil {
+		log.Fatalf("Invalid command-line settings: %v", err)
 	}
 
 	// Initialize game engine
@@ -39,6 +101,7 @@ func main() {
 		log.Fatalf("Failed to create engine: %v", err)
 	}
 	defer gameEngine.Destroy()
+	gameEngine.GetSceneManager().Register(menu.SceneGameplay, gameplay.Factory(gameEngine))
 
 	// Main game loop
 	gameEngine.Run()
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/assets/pack"
+)
+
+func main() {
+	dir := flag.String("dir", "assets", "asset directory to pack")
+	output := flag.String("o", "assets"+pack.Extension, "archive to write")
+	flag.Parse()
+
+	info, err := os.Stat(*dir)
+	if err != nil || !info.IsDir() {
+		log.Fatalf("Asset directory not found: %s", *dir)
+	}
+
+	// Write next to the final file so a failed run keeps the previous one
+	tmp := *output + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		log.Fatalf("Failed to create archive: %v", err)
+	}
+
+	count := 0
+	w := bufio.NewWriter(file)
+	err = pack.Write(w, os.DirFS(*dir), func(name string) bool {
+		// Go sources of the directory only select how it is embedded
+		if strings.EqualFold(path.Ext(name), ".go") {
+			return false
+		}
+		count++
+		return true
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		log.Fatalf("Failed to write archive: %v", err)
+	}
+	if err := os.Rename(tmp, *output); err != nil {
+		os.Remove(tmp)
+		log.Fatalf("Failed to write archive: %v", err)
+	}
+
+	size := int64(0)
+	if info, err := os.Stat(*output); err == nil {
+		size = info.Size()
+	}
+	fmt.Printf("Packed %d files from %s into %s (%d bytes)\n", count, *dir, *output, size)
+}
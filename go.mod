@@ -6,5 +6,6 @@ require (
 	github.com/go-gl/gl v0.0.0-20231021071112-07e5d0ea2e71
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20250301202403-da16c1255728
 	github.com/veandco/go-sdl2 v0.4.40
+	golang.org/x/image v0.25.0
 	golang.org/x/text v0.28.0
 )
@@ -0,0 +1,53 @@
+package assets
+
+import (
+	"fmt"
+	"image"
+	"io/fs"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/font"
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
+)
+
+// Bytes loads the raw contents of files
+var Bytes = &Kind[[]byte]{
+	Name: "bytes",
+	Decode: func(fsys fs.FS, name string) (any, error) {
+		return fs.ReadFile(fsys, name)
+	},
+}
+
+// Image loads PNG and JPEG images into memory
+var Image = &Kind[image.Image]{
+	Name: "image",
+	Decode: func(fsys fs.FS, name string) (any, error) {
+		return graphics.LoadImage(fsys, name)
+	},
+}
+
+// Font loads TTF and OTF fonts
+var Font = &Kind[*font.Font]{
+	Name: "font",
+	Decode: func(fsys fs.FS, name string) (any, error) {
+		return font.LoadFS(fsys, name)
+	},
+}
+
+// Texture loads textures with linear filtering and mipmaps
+var Texture = TextureKind(graphics.TextureOptions{Filter: graphics.FilterLinear, Mipmaps: true})
+
+// TextureKind returns the kind loading textures in any format
+// graphics.LoadTexture accepts with the given options. Kinds with the same
+// options share their textures
+func TextureKind(opts graphics.TextureOptions) *Kind[*graphics.Texture] {
+	return &Kind[*graphics.Texture]{
+		Name: fmt.Sprintf("texture(%d,%d,%t,%g)", opts.Filter, opts.Wrap, opts.Mipmaps, opts.Anisotropy),
+		Decode: func(fsys fs.FS, name string) (any, error) {
+			return graphics.ReadTexture(fsys, name)
+		},
+		Upload: func(decoded any) (*graphics.Texture, error) {
+			return decoded.(*graphics.TextureFile).Upload(opts)
+		},
+		Free: (*graphics.Texture).Destroy,
+	}
+}
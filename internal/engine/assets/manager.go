@@ -0,0 +1,458 @@
+package assets
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is the loading state of an asset
+type State int
+
+const (
+	// StateLoading assets are being decoded or wait for their upload
+	StateLoading State = iota
+	// StateReady assets can be used
+	StateReady
+	// StateFailed assets could not be loaded; Err tells why
+	StateFailed
+)
+
+// String returns the name of the state
+func (s State) String() string {
+	switch s {
+	case StateLoading:
+		return "loading"
+	case StateReady:
+		return "ready"
+	case StateFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// Kind describes how to load one type of asset
+type Kind[T any] struct {
+	// Name identifies the kind. Assets are shared by kind and name
+	Name string
+	// Decode reads the file name of fsys into memory. It runs on a worker
+	// goroutine, so it must not use the GL context
+	Decode func(fsys fs.FS, name string) (any, error)
+	// Upload turns the decoded data into the asset on the thread calling
+	// Update, e.g. creating GL objects. When nil the decoded data must be
+	// a T and is used as is
+	Upload func(decoded any) (T, error)
+	// Free releases the asset when its last reference is dropped. Optional
+	Free func(T)
+}
+
+// entry is a loaded or loading asset
+type entry struct {
+	key  string
+	path string
+	refs int
+	deps []*entry
+
+	decode func(fsys fs.FS, name string) (any, error)
+	upload func(decoded any) (any, error)
+	free   func(value any)
+
+	// Set by the worker before handing the entry back
+	decoded any
+	err     error
+
+	// Owned by the thread calling Update
+	state State
+	value any
+	// cancelled entries were released before they finished loading
+	cancelled bool
+}
+
+// Dependency is an asset that others can depend on, i.e. any Handle
+type Dependency interface {
+	entry() *entry
+}
+
+// Handle is a reference to an asset of type T. The zero value refers to
+// nothing
+type Handle[T any] struct {
+	e *entry
+	m *Manager
+}
+
+// Get returns the asset once it is ready
+func (h Handle[T]) Get() (T, bool) {
+	var zero T
+	if h.e == nil || h.e.state != StateReady {
+		return zero, false
+	}
+	value, _ := h.e.value.(T)
+	return value, true
+}
+
+// State returns the loading state of the asset
+func (h Handle[T]) State() State {
+	if h.e == nil {
+		return StateFailed
+	}
+	return h.e.state
+}
+
+// Ready reports whether the asset can be used
+func (h Handle[T]) Ready() bool {
+	return h.State() == StateReady
+}
+
+// Err returns why the asset failed to load
+func (h Handle[T]) Err() error {
+	if h.e == nil {
+		return fmt.Errorf("invalid asset handle")
+	}
+	if h.e.state != StateFailed {
+		return nil
+	}
+	return h.e.err
+}
+
+// Name returns the name the asset was loaded with
+func (h Handle[T]) Name() string {
+	if h.e == nil {
+		return ""
+	}
+	return h.e.path
+}
+
+// Valid reports whether the handle refers to an asset
+func (h Handle[T]) Valid() bool {
+	return h.e != nil
+}
+
+// Release drops the reference held by the handle. The asset is freed with
+// its last reference. Releasing the zero handle does nothing
+func (h Handle[T]) Release() {
+	if h.e != nil {
+		h.m.release(h.e)
+	}
+}
+
+func (h Handle[T]) entry() *entry {
+	return h.e
+}
+
+// Progress counts the assets requested since the manager was last idle,
+// for loading screens
+type Progress struct {
+	Loaded int
+	Failed int
+	Total  int
+}
+
+// Fraction returns the part of the assets that finished loading, from 0
+// to 1
+func (p Progress) Fraction() float32 {
+	if p.Total == 0 {
+		return 1
+	}
+	return float32(p.Loaded+p.Failed) / float32(p.Total)
+}
+
+// Done reports whether every requested asset finished loading
+func (p Progress) Done() bool {
+	return p.Loaded+p.Failed >= p.Total
+}
+
+// Manager loads assets from a file system and shares them by kind and
+// name. Files are decoded on worker goroutines; Update finishes loading on
+// the calling thread, which must own the GL context. The manager and its
+// handles must only be used from that thread
+type Manager struct {
+	files fs.FS
+
+	// mu guards the work shared with the workers
+	mu       sync.Mutex
+	work     *sync.Cond
+	done     *sync.Cond
+	queue    []*entry
+	decoded  []*entry
+	inFlight int
+	closed   bool
+	workers  sync.WaitGroup
+
+	entries map[string]*entry
+	// pending entries are decoded and wait for their dependencies or an
+	// upload slot
+	pending []*entry
+	loading int
+	batch   Progress
+}
+
+// NewManager creates a manager loading from files with the given number
+// of worker goroutines (one less than the CPUs when not positive)
+func NewManager(files fs.FS, workers int) *Manager {
+	if workers <= 0 {
+		workers = max(1, runtime.NumCPU()-1)
+	}
+	m := &Manager{
+		files:   files,
+		entries: make(map[string]*entry),
+	}
+	m.work = sync.NewCond(&m.mu)
+	m.done = sync.NewCond(&m.mu)
+
+	m.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go m.worker()
+	}
+	return m
+}
+
+// Files returns the file system assets are loaded from
+func (m *Manager) Files() fs.FS {
+	return m.files
+}
+
+// Load returns a handle to the asset of kind named name, a slash separated
+// path in the files of the manager. The asset starts loading unless it is
+// already loaded or loading, in which case it gains a reference. It only
+// becomes ready after deps, which it keeps referenced until it is freed;
+// deps are ignored when the asset is already known. Every handle must be
+// released
+func Load[T any](m *Manager, kind *Kind[T], name string, deps ...Dependency) Handle[T] {
+	name = path.Clean(filepath.ToSlash(name))
+	name = strings.TrimPrefix(name, "/")
+	key := kind.Name + ":" + name
+	if e, ok := m.entries[key]; ok {
+		e.refs++
+		return Handle[T]{e: e, m: m}
+	}
+
+	e := &entry{
+		key:    key,
+		path:   name,
+		refs:   1,
+		decode: kind.Decode,
+		upload: func(decoded any) (any, error) {
+			if kind.Upload == nil {
+				value, ok := decoded.(T)
+				if !ok {
+					return nil, fmt.Errorf("decoded %T instead of %T", decoded, value)
+				}
+				return value, nil
+			}
+			return kind.Upload(decoded)
+		},
+	}
+	if kind.Free != nil {
+		e.free = func(value any) { kind.Free(value.(T)) }
+	}
+	for _, dep := range deps {
+		if d := dep.entry(); d != nil {
+			d.refs++
+			e.deps = append(e.deps, d)
+		}
+	}
+	m.entries[key] = e
+
+	// A new batch starts when the previous one is complete
+	if m.loading == 0 {
+		m.batch = Progress{}
+	}
+	m.loading++
+	m.batch.Total++
+
+	m.mu.Lock()
+	m.queue = append(m.queue, e)
+	m.inFlight++
+	m.mu.Unlock()
+	m.work.Signal()
+
+	return Handle[T]{e: e, m: m}
+}
+
+// worker decodes queued entries until the manager is destroyed
+func (m *Manager) worker() {
+	defer m.workers.Done()
+	for {
+		m.mu.Lock()
+		for len(m.queue) == 0 && !m.closed {
+			m.work.Wait()
+		}
+		if m.closed {
+			m.mu.Unlock()
+			return
+		}
+		e := m.queue[0]
+		m.queue = m.queue[1:]
+		m.mu.Unlock()
+
+		var decoded any
+		var err error
+		if e.decode != nil {
+			decoded, err = e.decode(m.files, e.path)
+		}
+
+		m.mu.Lock()
+		e.decoded, e.err = decoded, err
+		m.decoded = append(m.decoded, e)
+		m.inFlight--
+		m.mu.Unlock()
+		m.done.Broadcast()
+	}
+}
+
+// Update finishes loading decoded assets whose dependencies are done,
+// uploading them until budget is spent (all of them when not positive)
+func (m *Manager) Update(budget time.Duration) {
+	m.mu.Lock()
+	m.pending = append(m.pending, m.decoded...)
+	m.decoded = m.decoded[:0]
+	m.mu.Unlock()
+
+	start := time.Now()
+	remaining := m.pending[:0]
+	for i, e := range m.pending {
+		if budget > 0 && time.Since(start) >= budget {
+			remaining = append(remaining, m.pending[i:]...)
+			break
+		}
+		if e.cancelled {
+			continue
+		}
+		if !m.finish(e) {
+			remaining = append(remaining, e)
+		}
+	}
+	clear(m.pending[len(remaining):])
+	m.pending = remaining
+}
+
+// finish uploads an entry, or fails it, once its dependencies are done.
+// It returns false while they are still loading
+func (m *Manager) finish(e *entry) bool {
+	for _, d := range e.deps {
+		switch d.state {
+		case StateLoading:
+			return false
+		case StateFailed:
+			m.fail(e, fmt.Errorf("failed to load dependency %s: %v", d.key, d.err))
+			return true
+		}
+	}
+
+	decoded := e.decoded
+	e.decoded = nil
+	if e.err != nil {
+		m.fail(e, e.err)
+		return true
+	}
+
+	value, err := e.upload(decoded)
+	if err != nil {
+		m.fail(e, err)
+		return true
+	}
+	e.value = value
+	e.state = StateReady
+	m.loading--
+	m.batch.Loaded++
+	return true
+}
+
+// fail marks an entry as failed
+func (m *Manager) fail(e *entry, err error) {
+	e.err = err
+	e.state = StateFailed
+	m.loading--
+	m.batch.Failed++
+}
+
+// release drops a reference to an entry, freeing it with the last one
+func (m *Manager) release(e *entry) {
+	if e.refs <= 0 {
+		return
+	}
+	e.refs--
+	if e.refs > 0 {
+		return
+	}
+
+	delete(m.entries, e.key)
+	switch e.state {
+	case StateLoading:
+		// The worker or Update drops it when it comes by
+		e.cancelled = true
+		m.loading--
+		m.batch.Total--
+	case StateReady:
+		if e.free != nil {
+			e.free(e.value)
+		}
+		e.value = nil
+	}
+	for _, d := range e.deps {
+		m.release(d)
+	}
+	e.deps = nil
+}
+
+// Finish blocks until every requested asset is loaded, e.g. at startup
+// before anything can be shown
+func (m *Manager) Finish() {
+	for {
+		m.Update(0)
+		if m.loading == 0 {
+			return
+		}
+
+		m.mu.Lock()
+		for len(m.decoded) == 0 && m.inFlight > 0 {
+			m.done.Wait()
+		}
+		stalled := len(m.decoded) == 0 && m.inFlight == 0
+		m.mu.Unlock()
+
+		// Only cancelled entries can be left once everything is decoded
+		if stalled && len(m.pending) == 0 {
+			return
+		}
+	}
+}
+
+// Progress returns the loading progress of the current batch of assets
+func (m *Manager) Progress() Progress {
+	return m.batch
+}
+
+// Len returns the number of loaded and loading assets
+func (m *Manager) Len() int {
+	return len(m.entries)
+}
+
+// Destroy stops the workers and frees every loaded asset, whatever its
+// references
+func (m *Manager) Destroy() {
+	m.mu.Lock()
+	m.closed = true
+	m.queue = nil
+	m.mu.Unlock()
+	m.work.Broadcast()
+	m.workers.Wait()
+
+	for key, e := range m.entries {
+		if e.state == StateReady && e.free != nil {
+			e.free(e.value)
+		}
+		e.value = nil
+		e.refs = 0
+		delete(m.entries, key)
+	}
+	m.pending = nil
+	m.decoded = nil
+	m.loading = 0
+}
@@ -0,0 +1,301 @@
+package assets
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// managerTimeout bounds the wait for background decoding
+const managerTimeout = 5 * time.Second
+
+// encodePNG returns a PNG image of the given size
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// newTestManager creates a manager over files that is destroyed with the
+// test
+func newTestManager(t *testing.T, files fstest.MapFS) *Manager {
+	t.Helper()
+	m := NewManager(files, 2)
+	t.Cleanup(m.Destroy)
+	return m
+}
+
+// countingKind loads files like Bytes, counting uploads and frees by
+// contents
+type countingKind struct {
+	Kind[[]byte]
+	uploads int
+	frees   map[string]int
+}
+
+// newCountingKind returns a counting kind that only decodes once gate is
+// closed, or right away when gate is nil
+func newCountingKind(name string, gate <-chan struct{}) *countingKind {
+	k := &countingKind{frees: make(map[string]int)}
+	k.Name = name
+	k.Decode = func(fsys fs.FS, name string) (any, error) {
+		if gate != nil {
+			<-gate
+		}
+		return Bytes.Decode(fsys, name)
+	}
+	k.Upload = func(decoded any) ([]byte, error) {
+		k.uploads++
+		return decoded.([]byte), nil
+	}
+	k.Free = func(data []byte) {
+		k.frees[string(data)]++
+	}
+	return k
+}
+
+// newGate returns a gate for newCountingKind and the function opening it.
+// The gate is opened at the end of the test so the workers can stop
+func newGate(t *testing.T) (gate chan struct{}, open func()) {
+	gate = make(chan struct{})
+	var once sync.Once
+	open = func() { once.Do(func() { close(gate) }) }
+	t.Cleanup(open)
+	return gate, open
+}
+
+// waitPending updates m until the asset dep is decoded and waits for its
+// upload
+func waitPending(t *testing.T, m *Manager, dep Dependency) {
+	t.Helper()
+	deadline := time.Now().Add(managerTimeout)
+	for {
+		m.Update(0)
+		for _, e := range m.pending {
+			if e == dep.entry() {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("asset not decoded after %v", managerTimeout)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManagerLoad(t *testing.T) {
+	m := newTestManager(t, fstest.MapFS{
+		"data/notes.txt":     {Data: []byte("hello")},
+		"textures/stone.png": {Data: encodePNG(t, 4, 2)},
+		"textures/bad.png":   {Data: []byte("not an image")},
+	})
+
+	notes := Load(m, Bytes, "data/notes.txt")
+	defer notes.Release()
+	stone := Load(m, Image, "textures/stone.png")
+	defer stone.Release()
+	bad := Load(m, Image, "textures/bad.png")
+	defer bad.Release()
+	missing := Load(m, Bytes, "data/missing.txt")
+	defer missing.Release()
+
+	if notes.State() != StateLoading {
+		t.Errorf("state before Update = %s, want loading", notes.State())
+	}
+	if _, ok := notes.Get(); ok {
+		t.Errorf("Get returned an asset before it was loaded")
+	}
+	if got, want := m.Progress(), (Progress{Total: 4}); got != want {
+		t.Errorf("progress = %+v, want %+v", got, want)
+	}
+
+	m.Finish()
+	if data, ok := notes.Get(); !ok || string(data) != "hello" {
+		t.Errorf("notes = %q, %v, want hello", data, ok)
+	}
+	if img, ok := stone.Get(); !ok || img.Bounds().Dx() != 4 || img.Bounds().Dy() != 2 {
+		t.Errorf("image = %v, %v, want 4x2", img, ok)
+	}
+	if bad.State() != StateFailed || bad.Err() == nil {
+		t.Errorf("bad image: state = %s, err = %v, want a failure", bad.State(), bad.Err())
+	}
+	if missing.State() != StateFailed || missing.Err() == nil {
+		t.Errorf("missing file: state = %s, err = %v, want a failure", missing.State(), missing.Err())
+	}
+
+	progress := m.Progress()
+	if want := (Progress{Loaded: 2, Failed: 2, Total: 4}); progress != want {
+		t.Errorf("progress = %+v, want %+v", progress, want)
+	}
+	if !progress.Done() || progress.Fraction() != 1 {
+		t.Errorf("finished progress: done = %v, fraction = %v", progress.Done(), progress.Fraction())
+	}
+
+	// Loading after the batch completed starts a new one
+	more := Load(m, Bytes, "textures/stone.png")
+	defer more.Release()
+	if got, want := m.Progress(), (Progress{Total: 1}); got != want {
+		t.Errorf("progress of a new batch = %+v, want %+v", got, want)
+	}
+	if m.Progress().Fraction() != 0 {
+		t.Errorf("fraction of a new batch = %v, want 0", m.Progress().Fraction())
+	}
+}
+
+func TestManagerRefCounting(t *testing.T) {
+	m := newTestManager(t, fstest.MapFS{"a.txt": {Data: []byte("a")}})
+	kind := newCountingKind("counting", nil)
+
+	first := Load(m, &kind.Kind, "a.txt")
+	second := Load(m, &kind.Kind, "./data/../a.txt")
+	raw := Load(m, Bytes, "a.txt")
+	defer raw.Release()
+	m.Finish()
+
+	if m.Len() != 2 {
+		t.Errorf("%d assets loaded, want one per kind", m.Len())
+	}
+	if kind.uploads != 1 {
+		t.Errorf("shared asset uploaded %d times", kind.uploads)
+	}
+	if first.e != second.e {
+		t.Errorf("the same file was loaded twice")
+	}
+
+	first.Release()
+	if kind.frees["a"] != 0 || !second.Ready() {
+		t.Errorf("asset freed while still referenced")
+	}
+	second.Release()
+	if kind.frees["a"] != 1 {
+		t.Errorf("asset freed %d times, want once", kind.frees["a"])
+	}
+	if m.Len() != 1 {
+		t.Errorf("%d assets loaded after release, want 1", m.Len())
+	}
+
+	// Extra releases and the zero handle do nothing
+	second.Release()
+	Handle[[]byte]{}.Release()
+	if kind.frees["a"] != 1 {
+		t.Errorf("asset freed %d times, want once", kind.frees["a"])
+	}
+
+	// Loading again after the last release reloads it
+	again := Load(m, &kind.Kind, "a.txt")
+	defer again.Release()
+	m.Finish()
+	if !again.Ready() || kind.uploads != 2 {
+		t.Errorf("asset not reloaded: ready = %v, uploads = %d", again.Ready(), kind.uploads)
+	}
+}
+
+func TestManagerDependencies(t *testing.T) {
+	m := newTestManager(t, fstest.MapFS{
+		"atlas.png":  {Data: encodePNG(t, 2, 2)},
+		"atlas.json": {Data: []byte("{}")},
+	})
+	gate, open := newGate(t)
+	images := newCountingKind("gated", gate)
+	kind := newCountingKind("counting", nil)
+
+	atlas := Load(m, &images.Kind, "atlas.png")
+	layout := Load(m, &kind.Kind, "atlas.json", atlas)
+	missing := Load(m, Bytes, "missing.png")
+	broken := Load(m, &kind.Kind, "broken.json", missing)
+	defer broken.Release()
+
+	// The layout is decoded first but waits for the atlas
+	waitPending(t, m, layout)
+	m.Update(0)
+	if layout.State() != StateLoading || kind.uploads != 0 {
+		t.Errorf("asset uploaded before its dependency: state = %s", layout.State())
+	}
+
+	open()
+	m.Finish()
+	if !atlas.Ready() || !layout.Ready() {
+		t.Fatalf("atlas = %s, layout = %s, want both ready", atlas.State(), layout.State())
+	}
+	if broken.State() != StateFailed || !strings.Contains(broken.Err().Error(), "dependency") {
+		t.Errorf("asset with a failed dependency: state = %s, err = %v", broken.State(), broken.Err())
+	}
+
+	// Dependencies stay loaded while their dependents are
+	atlas.Release()
+	missing.Release()
+	if len(images.frees) != 0 {
+		t.Errorf("dependency freed while its dependent is loaded")
+	}
+	if m.Len() != 4 {
+		t.Errorf("%d assets loaded, want 4", m.Len())
+	}
+	layout.Release()
+	if m.Len() != 2 {
+		t.Errorf("%d assets loaded after releasing the dependent, want 2", m.Len())
+	}
+	if kind.frees["{}"] != 1 || len(images.frees) != 1 {
+		t.Errorf("dependent freed %d times and dependency %d times, want once each", kind.frees["{}"], len(images.frees))
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	m := newTestManager(t, fstest.MapFS{
+		"a.txt": {Data: []byte("a")},
+		"b.txt": {Data: []byte("b")},
+	})
+	gate, open := newGate(t)
+	kind := newCountingKind("gated", gate)
+
+	a := Load(m, &kind.Kind, "a.txt")
+	b := Load(m, &kind.Kind, "b.txt")
+	defer b.Release()
+	a.Release()
+
+	if m.Len() != 1 {
+		t.Errorf("%d assets loaded after cancelling one, want 1", m.Len())
+	}
+	if got, want := m.Progress(), (Progress{Total: 1}); got != want {
+		t.Errorf("progress = %+v, want %+v", got, want)
+	}
+
+	open()
+	m.Finish()
+	if !b.Ready() {
+		t.Errorf("b = %s, want ready", b.State())
+	}
+	if kind.uploads != 1 {
+		t.Errorf("%d assets uploaded, want only b", kind.uploads)
+	}
+	if kind.frees["a"] != 0 {
+		t.Errorf("cancelled asset was freed")
+	}
+	if got, want := m.Progress(), (Progress{Loaded: 1, Total: 1}); got != want {
+		t.Errorf("progress = %+v, want %+v", got, want)
+	}
+}
+
+func TestManagerDestroyFreesAssets(t *testing.T) {
+	m := NewManager(fstest.MapFS{"a.txt": {Data: []byte("a")}}, 1)
+	kind := newCountingKind("counting", nil)
+	Load(m, &kind.Kind, "a.txt")
+	Load(m, &kind.Kind, "a.txt")
+	m.Finish()
+
+	m.Destroy()
+	if kind.frees["a"] != 1 || m.Len() != 0 {
+		t.Errorf("Destroy freed %d times and kept %d assets", kind.frees["a"], m.Len())
+	}
+}
@@ -0,0 +1,168 @@
+package assets
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// Overlay stacks file systems: files are read from the last layer
+// providing them, so later layers override earlier ones, and directory
+// listings merge every layer. It implements fs.ReadFileFS, fs.ReadDirFS and
+// fs.StatFS
+type Overlay struct {
+	layers []fs.FS
+}
+
+// NewOverlay stacks layers from the lowest priority to the highest
+func NewOverlay(layers ...fs.FS) *Overlay {
+	return &Overlay{layers: append([]fs.FS(nil), layers...)}
+}
+
+// Layers returns the stacked file systems from the lowest priority to the
+// highest, e.g. to merge files instead of overriding them
+func (o *Overlay) Layers() []fs.FS {
+	return append([]fs.FS(nil), o.layers...)
+}
+
+// Providers returns the indexes of the layers that have the file name, in
+// layer order
+func (o *Overlay) Providers(name string) []int {
+	var providers []int
+	for i, layer := range o.layers {
+		if _, err := fs.Stat(layer, name); err == nil {
+			providers = append(providers, i)
+		}
+	}
+	return providers
+}
+
+// Open opens the file of the highest layer providing name, or the merged
+// directory when the highest is a directory
+func (o *Overlay) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for i := len(o.layers) - 1; i >= 0; i-- {
+		f, err := o.layers[i].Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if !info.IsDir() {
+			return f, nil
+		}
+		f.Close()
+
+		entries, err := o.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &overlayDir{info: info, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadFile reads the file of the highest layer providing name
+func (o *Overlay) ReadFile(name string) ([]byte, error) {
+	for i := len(o.layers) - 1; i >= 0; i-- {
+		data, err := fs.ReadFile(o.layers[i], name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return data, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Stat describes the file or directory of the highest layer providing name
+func (o *Overlay) Stat(name string) (fs.FileInfo, error) {
+	for i := len(o.layers) - 1; i >= 0; i-- {
+		info, err := fs.Stat(o.layers[i], name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return info, err
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir lists the entries of a directory in every layer, sorted by name.
+// Entries of higher layers replace those of lower layers with the same name,
+// and a file shadows the directories of the layers below it like Open does
+func (o *Overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	merged := make(map[string]fs.DirEntry)
+	found := false
+	for i := len(o.layers) - 1; i >= 0; i-- {
+		info, err := fs.Stat(o.layers[i], name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if !found {
+				return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+			}
+			break
+		}
+
+		entries, err := fs.ReadDir(o.layers[i], name)
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, entry := range entries {
+			if _, ok := merged[entry.Name()]; !ok {
+				merged[entry.Name()] = entry
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// overlayDir is an open merged directory
+type overlayDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *overlayDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *overlayDir) Close() error               { return nil }
+
+func (d *overlayDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile
+func (d *overlayDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(remaining))
+	d.offset += n
+	return remaining[:n], nil
+}
@@ -0,0 +1,165 @@
+package assets
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// entryNames returns the names of directory entries, marking directories
+// with a trailing slash
+func entryNames(entries []fs.DirEntry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+		if entry.IsDir() {
+			names[i] += "/"
+		}
+	}
+	return names
+}
+
+func TestOverlayShadowing(t *testing.T) {
+	base := fstest.MapFS{
+		"i18n/en.json":   {Data: []byte("base en")},
+		"i18n/pt.json":   {Data: []byte("base pt")},
+		"shaders/a.vert": {Data: []byte("base a")},
+		"textures/b.png": {Data: []byte("base b")},
+	}
+	mod := fstest.MapFS{
+		"i18n/en.json":   {Data: []byte("mod en")},
+		"i18n/fr.json":   {Data: []byte("mod fr")},
+		"textures/c.png": {Data: []byte("mod c")},
+	}
+	top := fstest.MapFS{
+		"i18n/en.json": {Data: []byte("top en")},
+	}
+	o := NewOverlay(base, mod, top)
+
+	files := map[string]string{
+		"i18n/en.json":   "top en",
+		"i18n/pt.json":   "base pt",
+		"i18n/fr.json":   "mod fr",
+		"shaders/a.vert": "base a",
+		"textures/b.png": "base b",
+		"textures/c.png": "mod c",
+	}
+	for name, want := range files {
+		data, err := o.ReadFile(name)
+		if err != nil {
+			t.Errorf("ReadFile(%q) failed: %v", name, err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("ReadFile(%q) = %q, want %q", name, data, want)
+		}
+		data, err = fs.ReadFile(fsOnly{o}, name)
+		if err != nil || string(data) != want {
+			t.Errorf("Open(%q) read %q, %v, want %q", name, data, err, want)
+		}
+	}
+
+	if got := o.Providers("i18n/en.json"); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Errorf("Providers(i18n/en.json) = %v, want [0 1 2]", got)
+	}
+
+	entries, err := o.ReadDir("i18n")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if got, want := entryNames(entries), []string{"en.json", "fr.json", "pt.json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDir(i18n) = %v, want %v", got, want)
+	}
+
+	if err := fstest.TestFS(o, "i18n/en.json", "i18n/fr.json", "i18n/pt.json", "shaders/a.vert", "textures/b.png", "textures/c.png"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOverlayFileDirectoryConflict(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers []fs.FS
+		// entries of ReadDir("data"), or nil when it must fail
+		entries []string
+		// file is the contents read from "data", or "" when it is a
+		// directory
+		file string
+	}{
+		{
+			name: "directory over file",
+			layers: []fs.FS{
+				fstest.MapFS{"data": {Data: []byte("base file")}},
+				fstest.MapFS{"data/a.json": {Data: []byte("a")}},
+			},
+			entries: []string{"a.json"},
+		},
+		{
+			name: "file over directory",
+			layers: []fs.FS{
+				fstest.MapFS{"data/a.json": {Data: []byte("a")}},
+				fstest.MapFS{"data": {Data: []byte("mod file")}},
+			},
+			file: "mod file",
+		},
+		{
+			name: "directories around a file",
+			layers: []fs.FS{
+				fstest.MapFS{"data/a.json": {Data: []byte("a")}},
+				fstest.MapFS{"data": {Data: []byte("mod file")}},
+				fstest.MapFS{"data/b.json": {Data: []byte("b")}},
+			},
+			entries: []string{"b.json"},
+		},
+		{
+			name: "file entry over directory entry",
+			layers: []fs.FS{
+				fstest.MapFS{"data/x/a.json": {Data: []byte("a")}},
+				fstest.MapFS{"data/x": {Data: []byte("x")}},
+			},
+			entries: []string{"x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewOverlay(tt.layers...)
+
+			entries, err := o.ReadDir("data")
+			if tt.entries == nil {
+				if err == nil {
+					t.Errorf("ReadDir of a file returned %v", entryNames(entries))
+				}
+			} else if err != nil {
+				t.Errorf("ReadDir failed: %v", err)
+			} else if got := entryNames(entries); !reflect.DeepEqual(got, tt.entries) {
+				t.Errorf("ReadDir = %v, want %v", got, tt.entries)
+			}
+
+			info, err := o.Stat("data")
+			if err != nil {
+				t.Fatalf("Stat failed: %v", err)
+			}
+			if info.IsDir() != (tt.file == "") {
+				t.Errorf("Stat reports IsDir = %v, want %v", info.IsDir(), tt.file == "")
+			}
+
+			f, err := o.Open("data")
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			f.Close()
+			if tt.file != "" {
+				data, err := o.ReadFile("data")
+				if err != nil || string(data) != tt.file {
+					t.Errorf("ReadFile = %q, %v, want %q", data, err, tt.file)
+				}
+			}
+		})
+	}
+}
+
+// fsOnly hides every method of an fs.FS but Open
+type fsOnly struct {
+	fs.FS
+}
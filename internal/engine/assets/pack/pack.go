@@ -0,0 +1,365 @@
+package pack
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Extension is the file extension of pack archives
+const Extension = ".pak"
+
+// magic starts every pack archive
+var magic = [4]byte{'M', 'B', 'P', 'K'}
+
+// version is the version of the archive layout written by Write
+const version = 1
+
+// Compression methods of archived files
+const (
+	methodStore   = 0
+	methodDeflate = 1
+)
+
+// header starts the archive. File data follows it, then the index at
+// IndexOffset
+type header struct {
+	Magic       [4]byte
+	Version     uint32
+	IndexOffset uint64
+	Files       uint32
+	// ModTime is when the archive was written, in Unix seconds. Archived
+	// files report it as their modification time
+	ModTime int64
+}
+
+// indexEntry describes an archived file. In the index it is followed by
+// the slash separated file name of NameLength bytes
+type indexEntry struct {
+	Offset     uint64
+	Size       uint64
+	Length     uint64
+	CRC        uint32
+	Method     uint8
+	NameLength uint16
+}
+
+// file is an archived file
+type file struct {
+	name string
+	indexEntry
+}
+
+// Archive reads files from a pack archive. It implements fs.FS,
+// fs.ReadFileFS, fs.ReadDirFS and fs.StatFS, and is safe for concurrent
+// use
+type Archive struct {
+	r       io.ReaderAt
+	closer  io.Closer
+	files   map[string]*file
+	dirs    map[string][]fs.DirEntry
+	modTime time.Time
+}
+
+// Open opens the pack archive at path
+func Open(path string) (*Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	a, err := NewArchive(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open pack %s: %v", path, err)
+	}
+	a.closer = f
+	return a, nil
+}
+
+// NewArchive reads the index of an archive from r
+func NewArchive(r io.ReaderAt) (*Archive, error) {
+	var h header
+	if err := binary.Read(io.NewSectionReader(r, 0, int64(binary.Size(h))), binary.LittleEndian, &h); err != nil {
+		return nil, fmt.Errorf("truncated pack header")
+	}
+	if h.Magic != magic {
+		return nil, fmt.Errorf("not a pack archive")
+	}
+	if h.Version != version {
+		return nil, fmt.Errorf("unsupported pack version %d", h.Version)
+	}
+
+	a := &Archive{
+		r:       r,
+		files:   make(map[string]*file, h.Files),
+		modTime: time.Unix(h.ModTime, 0),
+	}
+	index := bufio.NewReader(io.NewSectionReader(r, int64(h.IndexOffset), math.MaxInt64-int64(h.IndexOffset)))
+	for i := uint32(0); i < h.Files; i++ {
+		f := &file{}
+		if err := binary.Read(index, binary.LittleEndian, &f.indexEntry); err != nil {
+			return nil, fmt.Errorf("truncated pack index")
+		}
+		name := make([]byte, f.NameLength)
+		if _, err := io.ReadFull(index, name); err != nil {
+			return nil, fmt.Errorf("truncated pack index")
+		}
+		f.name = string(name)
+		if !fs.ValidPath(f.name) || f.name == "." {
+			return nil, fmt.Errorf("invalid file name %q in pack index", f.name)
+		}
+		a.files[f.name] = f
+	}
+	a.buildDirs()
+	return a, nil
+}
+
+// buildDirs lists the entries of every directory, which the archive only
+// stores implicitly in file names
+func (a *Archive) buildDirs() {
+	a.dirs = map[string][]fs.DirEntry{".": nil}
+	seen := make(map[string]bool)
+	for name, f := range a.files {
+		a.dirs[path.Dir(name)] = append(a.dirs[path.Dir(name)], fs.FileInfoToDirEntry(a.fileInfo(f)))
+		for dir := path.Dir(name); dir != "." && !seen[dir]; dir = path.Dir(dir) {
+			seen[dir] = true
+			parent := path.Dir(dir)
+			a.dirs[parent] = append(a.dirs[parent], fs.FileInfoToDirEntry(a.dirInfo(dir)))
+			if _, ok := a.dirs[dir]; !ok {
+				a.dirs[dir] = nil
+			}
+		}
+	}
+	for _, entries := range a.dirs {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	}
+}
+
+// Close closes the archive file when opened with Open
+func (a *Archive) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// Names returns the names of every archived file, sorted
+func (a *Archive) Names() []string {
+	names := make([]string, 0, len(a.files))
+	for name := range a.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open opens an archived file or directory
+func (a *Archive) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if entries, ok := a.dirs[name]; ok {
+		return &dirHandle{info: a.dirInfo(name), entries: entries}, nil
+	}
+	data, err := a.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileHandle{info: a.fileInfo(a.files[name]), Reader: bytes.NewReader(data)}, nil
+}
+
+// ReadFile returns the contents of an archived file
+func (a *Archive) ReadFile(name string) ([]byte, error) {
+	f, ok := a.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	var r io.Reader = io.NewSectionReader(a.r, int64(f.Offset), int64(f.Size))
+	if f.Method == methodDeflate {
+		inflater := flate.NewReader(r)
+		defer inflater.Close()
+		r = inflater
+	}
+	data := make([]byte, f.Length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	if crc32.ChecksumIEEE(data) != f.CRC {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fmt.Errorf("checksum mismatch")}
+	}
+	return data, nil
+}
+
+// ReadDir lists an archived directory
+func (a *Archive) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := a.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+// Stat describes an archived file or directory
+func (a *Archive) Stat(name string) (fs.FileInfo, error) {
+	if _, ok := a.dirs[name]; ok {
+		return a.dirInfo(name), nil
+	}
+	if f, ok := a.files[name]; ok {
+		return a.fileInfo(f), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (a *Archive) fileInfo(f *file) fileInfo {
+	return fileInfo{name: path.Base(f.name), size: int64(f.Length), modTime: a.modTime}
+}
+
+func (a *Archive) dirInfo(name string) fileInfo {
+	return fileInfo{name: path.Base(name), dir: true, modTime: a.modTime}
+}
+
+// fileInfo describes an archived file or directory
+type fileInfo struct {
+	name    string
+	size    int64
+	dir     bool
+	modTime time.Time
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return i.dir }
+func (i fileInfo) Sys() any           { return nil }
+
+func (i fileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// fileHandle is an open archived file, decompressed in memory
+type fileHandle struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *fileHandle) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *fileHandle) Close() error               { return nil }
+
+// dirHandle is an open archived directory
+type dirHandle struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dirHandle) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirHandle) Close() error               { return nil }
+
+func (d *dirHandle) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fmt.Errorf("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile
+func (d *dirHandle) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return append([]fs.DirEntry(nil), remaining...), nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(remaining))
+	d.offset += n
+	return append([]fs.DirEntry(nil), remaining[:n]...), nil
+}
+
+// Write archives every file of src whose name keep accepts (every file
+// when keep is nil) to w. Files are compressed unless that does not make
+// them smaller
+func Write(w io.Writer, src fs.FS, keep func(name string) bool) error {
+	var names []string
+	err := fs.WalkDir(src, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (keep != nil && !keep(name)) {
+			return nil
+		}
+		if strings.HasPrefix(path.Base(name), ".") {
+			return nil
+		}
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list files: %v", err)
+	}
+	sort.Strings(names)
+
+	h := header{Magic: magic, Version: version, Files: uint32(len(names)), ModTime: time.Now().Unix()}
+	offset := uint64(binary.Size(h))
+
+	// The index offset is only known once the data is written, so the
+	// archive is built in memory
+	var data bytes.Buffer
+	var index bytes.Buffer
+	var compressed bytes.Buffer
+	for _, name := range names {
+		if len(name) > math.MaxUint16 {
+			return fmt.Errorf("file name too long: %s", name)
+		}
+		contents, err := fs.ReadFile(src, name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", name, err)
+		}
+
+		compressed.Reset()
+		deflater, _ := flate.NewWriter(&compressed, flate.BestCompression)
+		deflater.Write(contents)
+		deflater.Close()
+
+		entry := indexEntry{
+			Offset:     offset + uint64(data.Len()),
+			Length:     uint64(len(contents)),
+			CRC:        crc32.ChecksumIEEE(contents),
+			NameLength: uint16(len(name)),
+		}
+		if compressed.Len() < len(contents) {
+			entry.Method = methodDeflate
+			entry.Size = uint64(compressed.Len())
+			data.Write(compressed.Bytes())
+		} else {
+			entry.Method = methodStore
+			entry.Size = uint64(len(contents))
+			data.Write(contents)
+		}
+		binary.Write(&index, binary.LittleEndian, entry)
+		index.WriteString(name)
+	}
+	h.IndexOffset = offset + uint64(data.Len())
+
+	if err := binary.Write(w, binary.LittleEndian, h); err != nil {
+		return err
+	}
+	if _, err := w.Write(data.Bytes()); err != nil {
+		return err
+	}
+	_, err = w.Write(index.Bytes())
+	return err
+}
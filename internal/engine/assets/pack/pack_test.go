@@ -0,0 +1,154 @@
+package pack
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// testFiles returns files to archive: compressible text, random bytes that
+// are stored as is, nested directories and a hidden file
+func testFiles() fstest.MapFS {
+	noise := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(noise)
+	return fstest.MapFS{
+		"i18n/en.json":              {Data: []byte(`{"title.main_menu": "Main Menu"}`)},
+		"i18n/pt.json":              {Data: []byte(`{"title.main_menu": "Menu Principal"}`)},
+		"shaders/sprite.vert":       {Data: bytes.Repeat([]byte("void main() {}\n"), 100)},
+		"textures/blocks/noise.png": {Data: noise},
+		"textures/empty.txt":        {Data: []byte{}},
+		"readme.md":                 {Data: []byte("# Assets\n")},
+		"i18n/.hidden":              {Data: []byte("skipped")},
+	}
+}
+
+// archive packs src with keep and reads the archive back
+func archive(t *testing.T, src fstest.MapFS, keep func(name string) bool) *Archive {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, src, keep); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	a, err := NewArchive(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewArchive failed: %v", err)
+	}
+	return a
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := testFiles()
+	a := archive(t, src, nil)
+
+	want := []string{"i18n/en.json", "i18n/pt.json", "readme.md", "shaders/sprite.vert", "textures/blocks/noise.png", "textures/empty.txt"}
+	if err := fstest.TestFS(a, want...); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range want {
+		data, err := a.ReadFile(name)
+		if err != nil {
+			t.Errorf("ReadFile(%q) failed: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(data, src[name].Data) {
+			t.Errorf("ReadFile(%q) returned %d bytes differing from the original", name, len(data))
+		}
+	}
+	if _, err := a.ReadFile("i18n/.hidden"); err == nil {
+		t.Errorf("hidden file was archived")
+	}
+
+	methods := map[string]uint8{"shaders/sprite.vert": methodDeflate, "textures/blocks/noise.png": methodStore}
+	for name, method := range methods {
+		if got := a.files[name].Method; got != method {
+			t.Errorf("method of %s = %d, want %d", name, got, method)
+		}
+	}
+}
+
+func TestWriteKeep(t *testing.T) {
+	a := archive(t, testFiles(), func(name string) bool {
+		return filepath.Ext(name) == ".json"
+	})
+	if err := fstest.TestFS(a, "i18n/en.json", "i18n/pt.json"); err != nil {
+		t.Fatal(err)
+	}
+	if names := a.Names(); len(names) != 2 {
+		t.Errorf("archived %v, want only the JSON files", names)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "assets"+Extension)
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	if err := Write(out, testFiles(), nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	a, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := fstest.TestFS(a, "i18n/en.json", "textures/blocks/noise.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestNewArchiveErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testFiles(), nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	valid := buf.Bytes()
+
+	wrongVersion := append([]byte(nil), valid...)
+	wrongVersion[4] = 99
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not a pack", []byte("PK\x03\x04 this is a zip file, not a pack archive")},
+		{"wrong version", wrongVersion},
+		{"truncated index", valid[:len(valid)-5]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewArchive(bytes.NewReader(tt.data)); err == nil {
+				t.Errorf("NewArchive accepted an invalid archive")
+			}
+		})
+	}
+}
+
+func TestReadFileChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	src := fstest.MapFS{"data.bin": {Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}}}
+	if err := Write(&buf, src, nil); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	data := buf.Bytes()
+	a, err := NewArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewArchive failed: %v", err)
+	}
+
+	// Corrupt the stored contents after the index was read
+	data[a.files["data.bin"].Offset] ^= 0xff
+	if _, err := a.ReadFile("data.bin"); err == nil {
+		t.Errorf("ReadFile returned corrupted contents")
+	}
+}
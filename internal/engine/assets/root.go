@@ -0,0 +1,152 @@
+package assets
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/assets/pack"
+)
+
+// RootEnv names the environment variable that overrides the asset root
+const RootEnv = "MAGIC_AND_BLADES_ASSETS"
+
+// rootDirName is the name of the asset directory used in development
+const rootDirName = "assets"
+
+// rootPackName is the name of the asset archive shipped with releases
+const rootPackName = rootDirName + pack.Extension
+
+// rootMarker is a directory every asset root contains, so that unrelated
+// directories named assets are skipped
+const rootMarker = "i18n"
+
+// maxRootDepth is how many parent directories are searched for the asset
+// root, e.g. when running a test binary or go run from a subdirectory
+const maxRootDepth = 4
+
+var root struct {
+	sync.Mutex
+	path  string
+	files fs.FS
+}
+
+// SetRoot sets the asset root, a directory or pack archive, e.g. from a
+// command-line flag. It must be called before the first call to Root or
+// Files to take effect everywhere
+func SetRoot(path string) {
+	root.Lock()
+	defer root.Unlock()
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	root.path = path
+	root.files = nil
+}
+
+// SetFiles makes assets read from fsys, e.g. files embedded in the binary,
+// instead of the root
+func SetFiles(fsys fs.FS) {
+	root.Lock()
+	defer root.Unlock()
+	root.files = fsys
+}
+
+// Root returns the directory or pack archive holding the game assets.
+// Unless set with SetRoot or the RootEnv environment variable, it is the
+// assets.pak archive or assets directory next to the executable or in one
+// of its parents, then the same relative to the working directory, so the
+// game runs from any directory
+func Root() string {
+	root.Lock()
+	defer root.Unlock()
+	return rootPath()
+}
+
+// rootPath returns the root, searching it the first time. root must be
+// locked
+func rootPath() string {
+	if root.path == "" {
+		root.path = findRoot()
+	}
+	return root.path
+}
+
+// Files returns the file system every asset is read from, with slash
+// separated names relative to the root such as "i18n/en.json". A root that
+// cannot be opened yields an empty file system, so that loaders fall back
+// to their defaults
+func Files() fs.FS {
+	root.Lock()
+	defer root.Unlock()
+	if root.files == nil {
+		files, err := openRoot(rootPath())
+		if err != nil {
+			log.Printf("Warning: failed to open assets: %v", err)
+			files = emptyFS{}
+		}
+		root.files = files
+	}
+	return root.files
+}
+
+// emptyFS is a file system without files
+type emptyFS struct{}
+
+func (emptyFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// openRoot opens a pack archive or directory
+func openRoot(path string) (fs.FS, error) {
+	if strings.EqualFold(filepath.Ext(path), pack.Extension) {
+		return pack.Open(path)
+	}
+	return os.DirFS(path), nil
+}
+
+// findRoot searches the asset root
+func findRoot() string {
+	if dir := os.Getenv(RootEnv); dir != "" {
+		if abs, err := filepath.Abs(dir); err == nil {
+			return abs
+		}
+		return dir
+	}
+
+	var starts []string
+	if exe, err := os.Executable(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		starts = append(starts, filepath.Dir(exe))
+	}
+	if wd, err := os.Getwd(); err == nil {
+		starts = append(starts, wd)
+	}
+
+	for _, start := range starts {
+		dir := start
+		for i := 0; i <= maxRootDepth; i++ {
+			archive := filepath.Join(dir, rootPackName)
+			if info, err := os.Stat(archive); err == nil && !info.IsDir() {
+				return archive
+			}
+			candidate := filepath.Join(dir, rootDirName)
+			if info, err := os.Stat(filepath.Join(candidate, rootMarker)); err == nil && info.IsDir() {
+				return candidate
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+
+	// Nothing found: keep the historical relative path
+	return rootDirName
+}
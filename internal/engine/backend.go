@@ -0,0 +1,316 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/luidsonl/magic-and-blades/internal/game"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// backend presents frames produced by the engine loop
+type backend interface {
+	// beginFrame prepares a new frame and reports whether scenes should
+	// be drawn into it
+	beginFrame() bool
+	// endFrame presents the finished frame
+	endFrame()
+	// resize adapts the output to a new window size
+	resize(width, height int32)
+	// drawableSize returns the size in pixels of the area drawn to, or 0
+	// when there is none
+	drawableSize() (width, height int32)
+	// applyDisplay applies the window mode, size, display and vsync from
+	// config and returns the resulting drawable size
+	applyDisplay(config game.Config) (width, height int32, err error)
+	// destroy releases the backend resources
+	destroy()
+}
+
+// sdlSubsystems returns the SDL subsystems required by the configuration
+func sdlSubsystems(config game.Config) uint32 {
+	if !config.Headless {
+		return sdl.INIT_EVERYTHING
+	}
+
+	// Headless runs only need the event queue, plus video when the dummy
+	// driver is requested
+	flags := uint32(sdl.INIT_EVENTS | sdl.INIT_TIMER)
+	if config.DummyVideo {
+		flags |= sdl.INIT_VIDEO
+	}
+	return flags
+}
+
+// initSDL initializes the SDL subsystems required by the configuration,
+// selecting the dummy video driver when requested, and returns them
+func initSDL(config game.Config) (uint32, error) {
+	subsystems := sdlSubsystems(config)
+	if config.Headless && config.DummyVideo {
+		// Read by SDL when video is initialized. A hint, unlike the
+		// environment variable, leaves the process environment alone
+		sdl.SetHint(sdl.HINT_VIDEODRIVER, "dummy")
+	}
+	if err := sdl.InitSubSystem(subsystems); err != nil {
+		return 0, fmt.Errorf("failed to initialize SDL: %v", err)
+	}
+	return subsystems, nil
+}
+
+// quitSDL shuts down subsystems initialized by initSDL, and SDL itself
+// once no subsystem is left, e.g. by another engine
+func quitSDL(subsystems uint32) {
+	sdl.QuitSubSystem(subsystems)
+	if sdl.WasInit(0) == 0 {
+		sdl.Quit()
+	}
+}
+
+// glBackend renders into an SDL window through an OpenGL 3.3 core context
+type glBackend struct {
+	window  *sdl.Window
+	context sdl.GLContext
+}
+
+// newGLBackend creates the game window and its OpenGL context
+func newGLBackend(config game.Config) (*glBackend, error) {
+	// Configure OpenGL attributes before creating the window
+	if err := sdl.GLSetAttribute(sdl.GL_CONTEXT_MAJOR_VERSION, 3); err != nil {
+		return nil, fmt.Errorf("failed to set OpenGL major version: %v", err)
+	}
+	if err := sdl.GLSetAttribute(sdl.GL_CONTEXT_MINOR_VERSION, 3); err != nil {
+		return nil, fmt.Errorf("failed to set OpenGL minor version: %v", err)
+	}
+	if err := sdl.GLSetAttribute(sdl.GL_CONTEXT_PROFILE_MASK, sdl.GL_CONTEXT_PROFILE_CORE); err != nil {
+		return nil, fmt.Errorf("failed to set OpenGL profile: %v", err)
+	}
+	if err := sdl.GLSetAttribute(sdl.GL_DOUBLEBUFFER, 1); err != nil {
+		return nil, fmt.Errorf("failed to enable double buffering: %v", err)
+	}
+
+	// Create window. It starts windowed; the configured mode is applied
+	// once the context exists
+	window, err := sdl.CreateWindow(
+		config.WindowTitle,
+		centeredOn(config.Display), centeredOn(config.Display),
+		config.WindowWidth, config.WindowHeight,
+		sdl.WINDOW_OPENGL|sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create window: %v", err)
+	}
+
+	// Create OpenGL context
+	context, err := window.GLCreateContext()
+	if err != nil {
+		window.Destroy()
+		return nil, fmt.Errorf("failed to create OpenGL context: %v", err)
+	}
+
+	b := &glBackend{
+		window:  window,
+		context: context,
+	}
+
+	// Initialize OpenGL
+	if err := b.initOpenGL(config); err != nil {
+		b.destroy()
+		return nil, err
+	}
+
+	log.Printf("OpenGL context created")
+
+	if _, _, err := b.applyDisplay(config); err != nil {
+		log.Printf("Warning: failed to apply display settings: %v", err)
+	}
+
+	return b, nil
+}
+
+// initOpenGL initializes OpenGL
+func (b *glBackend) initOpenGL(config game.Config) error {
+	// Initialize OpenGL
+	if err := gl.Init(); err != nil {
+		return fmt.Errorf("failed to initialize OpenGL: %v", err)
+	}
+
+	// Display OpenGL version information
+	version := gl.GoStr(gl.GetString(gl.VERSION))
+	vendor := gl.GoStr(gl.GetString(gl.VENDOR))
+	renderer := gl.GoStr(gl.GetString(gl.RENDERER))
+
+	log.Printf("OpenGL version: %s", version)
+	log.Printf("OpenGL vendor: %s", vendor)
+	log.Printf("OpenGL renderer: %s", renderer)
+
+	// Set basic OpenGL settings
+	gl.Enable(gl.DEPTH_TEST)
+	gl.DepthFunc(gl.LESS)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+
+	// Set viewport size
+	gl.Viewport(0, 0, config.WindowWidth, config.WindowHeight)
+
+	// Set default clear color
+	gl.ClearColor(0.2, 0.3, 0.3, 1.0)
+
+	return nil
+}
+
+func (b *glBackend) beginFrame() bool {
+	// Clear color and depth buffers
+	gl.ClearColor(0.2, 0.3, 0.3, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	return true
+}
+
+func (b *glBackend) endFrame() {
+	// Swap buffers
+	b.window.GLSwap()
+}
+
+func (b *glBackend) resize(width, height int32) {
+	// Update viewport size
+	gl.Viewport(0, 0, width, height)
+}
+
+func (b *glBackend) drawableSize() (int32, int32) {
+	return b.window.GLGetDrawableSize()
+}
+
+func (b *glBackend) applyDisplay(config game.Config) (int32, int32, error) {
+	display := config.Display
+	if count, err := sdl.GetNumVideoDisplays(); err == nil && (display < 0 || display >= count) {
+		log.Printf("Warning: display %d not available, using display 0", display)
+		display = 0
+	}
+
+	// The window is only resized, moved or switched between modes, so the
+	// OpenGL context and every GPU resource survive the change
+	switch config.WindowMode {
+	case game.WindowModeFullscreen:
+		if err := b.setFullscreenMode(display, config.WindowWidth, config.WindowHeight); err != nil {
+			return 0, 0, err
+		}
+	case game.WindowModeBorderless:
+		if err := b.moveToDisplay(display); err != nil {
+			return 0, 0, err
+		}
+		if err := b.window.SetFullscreen(uint32(sdl.WINDOW_FULLSCREEN_DESKTOP)); err != nil {
+			return 0, 0, fmt.Errorf("failed to enter borderless mode: %v", err)
+		}
+	default:
+		if err := b.window.SetFullscreen(0); err != nil {
+			return 0, 0, fmt.Errorf("failed to leave fullscreen mode: %v", err)
+		}
+		b.window.SetBordered(true)
+		b.window.SetSize(config.WindowWidth, config.WindowHeight)
+		b.window.SetPosition(centeredOn(display), centeredOn(display))
+	}
+
+	b.setVSync(config.VSync)
+
+	width, height := b.window.GLGetDrawableSize()
+	b.resize(width, height)
+
+	log.Printf("Display set to %s %dx%d on display %d (vsync %v)",
+		config.WindowMode, width, height, display, config.VSync)
+
+	return width, height, nil
+}
+
+// setFullscreenMode switches to exclusive fullscreen using the display mode
+// closest to the requested resolution
+func (b *glBackend) setFullscreenMode(display int, width, height int32) error {
+	// Leave fullscreen first so the window can move between displays
+	b.window.SetFullscreen(0)
+	if err := b.moveToDisplay(display); err != nil {
+		return err
+	}
+
+	var closest sdl.DisplayMode
+	wanted := sdl.DisplayMode{W: width, H: height}
+	if _, err := sdl.GetClosestDisplayMode(display, &wanted, &closest); err != nil {
+		return fmt.Errorf("no display mode close to %dx%d: %v", width, height, err)
+	}
+	if err := b.window.SetDisplayMode(&closest); err != nil {
+		return fmt.Errorf("failed to set display mode: %v", err)
+	}
+
+	if err := b.window.SetFullscreen(uint32(sdl.WINDOW_FULLSCREEN)); err != nil {
+		return fmt.Errorf("failed to enter fullscreen mode: %v", err)
+	}
+	return nil
+}
+
+// moveToDisplay centers the window on the given display
+func (b *glBackend) moveToDisplay(display int) error {
+	current, err := b.window.GetDisplayIndex()
+	if err != nil {
+		return fmt.Errorf("failed to query window display: %v", err)
+	}
+	if current != display {
+		b.window.SetPosition(centeredOn(display), centeredOn(display))
+	}
+	return nil
+}
+
+// setVSync enables or disables vertical sync, preferring adaptive vsync
+// when the driver supports it
+func (b *glBackend) setVSync(enabled bool) {
+	if !enabled {
+		if err := sdl.GLSetSwapInterval(0); err != nil {
+			log.Printf("Warning: failed to disable vsync: %v", err)
+		}
+		return
+	}
+
+	if err := sdl.GLSetSwapInterval(-1); err == nil {
+		return
+	}
+	if err := sdl.GLSetSwapInterval(1); err != nil {
+		log.Printf("Warning: failed to enable vsync: %v", err)
+	}
+}
+
+func (b *glBackend) destroy() {
+	if b.context != nil {
+		sdl.GLDeleteContext(b.context)
+		b.context = nil
+		log.Printf("OpenGL context destroyed")
+	}
+
+	if b.window != nil {
+		b.window.Destroy()
+		b.window = nil
+		log.Printf("Window destroyed")
+	}
+}
+
+// nullBackend is used in headless mode: there is no window and nothing is
+// drawn, but the loop, scenes and update logic run as usual
+type nullBackend struct{}
+
+func (nullBackend) beginFrame() bool { return false }
+
+func (nullBackend) endFrame() {}
+
+func (nullBackend) resize(width, height int32) {}
+
+func (nullBackend) drawableSize() (int32, int32) { return 0, 0 }
+
+func (nullBackend) applyDisplay(config game.Config) (int32, int32, error) {
+	return config.WindowWidth, config.WindowHeight, nil
+}
+
+func (nullBackend) destroy() {}
+
+// centeredOn returns the window coordinate that centers a window on the
+// given display
+func centeredOn(display int) int32 {
+	return int32(sdl.WINDOWPOS_CENTERED_MASK | display)
+}
@@ -1 +1,153 @@
 package engine
+
+import (
+	"math"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// Projection selects how a camera projects the world onto the screen
+type Projection int
+
+const (
+	// ProjectionPerspective makes distant objects smaller
+	ProjectionPerspective Projection = iota
+	// ProjectionOrthographic keeps sizes constant, e.g. for editor views
+	ProjectionOrthographic
+)
+
+// Camera defaults
+const (
+	DefaultFOV  = 70 * math.Pi / 180
+	DefaultNear = 0.1
+	DefaultFar  = 1000
+)
+
+// worldUp is the up direction of the world
+var worldUp = vecmath.Vec3{0, 1, 0}
+
+// Camera is a viewpoint into the world. Like OpenGL it looks along its
+// local -Z axis with +Y up. The aspect ratio comes from the viewport size,
+// updated with SetViewport when the window is resized
+type Camera struct {
+	Position    vecmath.Vec3
+	Orientation vecmath.Quat
+	Projection  Projection
+	// FOV is the vertical field of view in radians (perspective only)
+	FOV float32
+	// OrthoHeight is the height of the visible area in world units
+	// (orthographic only)
+	OrthoHeight float32
+	Near, Far   float32
+
+	width, height int32
+}
+
+// NewCamera creates a perspective camera at the origin looking along -Z
+func NewCamera(width, height int32) *Camera {
+	return &Camera{
+		Orientation: vecmath.QuatIdent(),
+		Projection:  ProjectionPerspective,
+		FOV:         DefaultFOV,
+		OrthoHeight: 10,
+		Near:        DefaultNear,
+		Far:         DefaultFar,
+		width:       width,
+		height:      height,
+	}
+}
+
+// SetViewport sets the size in pixels of the area the camera renders to
+func (c *Camera) SetViewport(width, height int32) {
+	c.width, c.height = width, height
+}
+
+// Viewport returns the size in pixels of the area the camera renders to
+func (c *Camera) Viewport() (width, height int32) {
+	return c.width, c.height
+}
+
+// Aspect returns the viewport width divided by its height
+func (c *Camera) Aspect() float32 {
+	if c.width <= 0 || c.height <= 0 {
+		return 1
+	}
+	return float32(c.width) / float32(c.height)
+}
+
+// Forward returns the direction the camera looks at
+func (c *Camera) Forward() vecmath.Vec3 {
+	return c.Orientation.Rotate(vecmath.Vec3{0, 0, -1})
+}
+
+// Right returns the direction to the right of the view
+func (c *Camera) Right() vecmath.Vec3 {
+	return c.Orientation.Rotate(vecmath.Vec3{1, 0, 0})
+}
+
+// Up returns the direction to the top of the view
+func (c *Camera) Up() vecmath.Vec3 {
+	return c.Orientation.Rotate(vecmath.Vec3{0, 1, 0})
+}
+
+// LookAt turns the camera towards target, keeping the horizon level
+func (c *Camera) LookAt(target vecmath.Vec3) {
+	if dir := target.Sub(c.Position); dir.LenSqr() > 0 {
+		c.Orientation = vecmath.QuatLookAt(dir, worldUp)
+	}
+}
+
+// ViewMatrix returns the world to camera transform
+func (c *Camera) ViewMatrix() vecmath.Mat4 {
+	return c.Orientation.Conjugate().Mat4().Mul(vecmath.Translate(c.Position.Neg()))
+}
+
+// ProjectionMatrix returns the camera to clip space transform
+func (c *Camera) ProjectionMatrix() vecmath.Mat4 {
+	aspect := c.Aspect()
+	if c.Projection == ProjectionOrthographic {
+		h := c.OrthoHeight / 2
+		return vecmath.Ortho(-h*aspect, h*aspect, -h, h, c.Near, c.Far)
+	}
+	return vecmath.Perspective(c.FOV, aspect, c.Near, c.Far)
+}
+
+// ViewProjection returns the world to clip space transform
+func (c *Camera) ViewProjection() vecmath.Mat4 {
+	return c.ProjectionMatrix().Mul(c.ViewMatrix())
+}
+
+// Frustum returns the world space planes bounding what the camera sees,
+// for culling
+func (c *Camera) Frustum() vecmath.Frustum {
+	return vecmath.FrustumFromMatrix(c.ViewProjection())
+}
+
+// ScreenRay returns the world space ray through a pixel, with (0, 0) at
+// the top-left corner of the viewport, for picking. The ray starts on the
+// near plane and its direction has unit length
+func (c *Camera) ScreenRay(x, y float32) vecmath.Ray {
+	ndcX := 2*x/float32(max(c.width, 1)) - 1
+	ndcY := 1 - 2*y/float32(max(c.height, 1))
+
+	inverse := c.ViewProjection().Inverse()
+	near := inverse.MulPoint(vecmath.Vec3{ndcX, ndcY, -1})
+	far := inverse.MulPoint(vecmath.Vec3{ndcX, ndcY, 1})
+
+	return vecmath.Ray{Origin: near, Direction: far.Sub(near).Normalize()}
+}
+
+// WorldToScreen returns the pixel a world space point projects to, with
+// (0, 0) at the top-left corner of the viewport. ok is false for points
+// behind the camera
+func (c *Camera) WorldToScreen(p vecmath.Vec3) (x, y float32, ok bool) {
+	clip := c.ViewProjection().MulVec4(vecmath.Vec4{p[0], p[1], p[2], 1})
+	if clip[3] <= 0 {
+		return 0, 0, false
+	}
+
+	ndcX, ndcY := clip[0]/clip[3], clip[1]/clip[3]
+	x = (ndcX + 1) / 2 * float32(c.width)
+	y = (1 - ndcY) / 2 * float32(c.height)
+	return x, y, true
+}
@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+	"github.com/luidsonl/magic-and-blades/internal/game"
+)
+
+// CameraInput is the camera movement requested by the player since the
+// last update. Scenes fill it from keyboard, mouse or gamepad state
+type CameraInput struct {
+	// Move is the movement along the camera's right, up and forward axes,
+	// each in [-1, 1]
+	Move vecmath.Vec3
+	// LookX and LookY are the mouse motion in pixels
+	LookX, LookY float32
+	// Zoom is the mouse wheel motion, positive towards the target
+	Zoom float32
+}
+
+// CameraController moves a camera every tick
+type CameraController interface {
+	Update(camera *Camera, input CameraInput, t game.Time)
+}
+
+// maxPitch keeps controllers from looking straight up or down, where yaw
+// becomes ambiguous
+const maxPitch = 89 * math.Pi / 180
+
+// yawPitch returns the orientation for a yaw around the world Y axis and a
+// pitch around the camera's X axis
+func yawPitch(yaw, pitch float32) vecmath.Quat {
+	return vecmath.QuatAxisAngle(worldUp, yaw).Mul(vecmath.QuatAxisAngle(vecmath.Vec3{1, 0, 0}, pitch))
+}
+
+// clamp limits v to [lo, hi]
+func clamp(v, lo, hi float32) float32 {
+	return max(lo, min(v, hi))
+}
+
+// FreeFlyController moves the camera like a first-person spectator: the
+// mouse looks around and movement follows the view direction
+type FreeFlyController struct {
+	// Speed is the movement speed in units per second
+	Speed float32
+	// Sensitivity is the rotation in radians per pixel of mouse motion
+	Sensitivity float32
+	Yaw, Pitch  float32
+}
+
+// NewFreeFlyController creates a free-fly controller with default speed
+// and sensitivity
+func NewFreeFlyController() *FreeFlyController {
+	return &FreeFlyController{Speed: 10, Sensitivity: 0.0025}
+}
+
+// Update implements CameraController
+func (f *FreeFlyController) Update(camera *Camera, input CameraInput, t game.Time) {
+	f.Yaw -= input.LookX * f.Sensitivity
+	f.Pitch = clamp(f.Pitch-input.LookY*f.Sensitivity, -maxPitch, maxPitch)
+	camera.Orientation = yawPitch(f.Yaw, f.Pitch)
+
+	move := camera.Right().Mul(input.Move[0]).
+		Add(worldUp.Mul(input.Move[1])).
+		Add(camera.Forward().Mul(input.Move[2]))
+	if move.LenSqr() > 1 {
+		move = move.Normalize()
+	}
+	camera.Position = camera.Position.Add(move.Mul(f.Speed * float32(t.DeltaSeconds())))
+}
+
+// ThirdPersonController follows a target from behind on a spring arm. The
+// arm shortens immediately when an obstacle gets between the target and
+// the camera and extends back smoothly once it is clear
+type ThirdPersonController struct {
+	// Target is the followed point, updated by the game every tick
+	Target vecmath.Vec3
+	// Offset moves the pivot the arm rotates around from the target, e.g.
+	// to shoulder height
+	Offset vecmath.Vec3
+	// ArmLength is the desired distance from the pivot to the camera
+	ArmLength, MinArmLength, MaxArmLength float32
+	Yaw, Pitch                            float32
+	Sensitivity                           float32
+	// ZoomSpeed is the arm length change in units per wheel step
+	ZoomSpeed float32
+	// FollowStiffness controls how fast the pivot catches up with the
+	// target (per second); zero follows rigidly
+	FollowStiffness float32
+	// ArmStiffness controls how fast the arm extends back after a
+	// collision (per second)
+	ArmStiffness float32
+	// ProbeRadius keeps the camera this far from obstacles
+	ProbeRadius float32
+	// Collide returns the fraction of the way from one point to another
+	// at which the first obstacle is hit. Nil disables collision avoidance
+	Collide func(from, to vecmath.Vec3) (fraction float32, hit bool)
+
+	pivot    vecmath.Vec3
+	length   float32
+	attached bool
+}
+
+// NewThirdPersonController creates a third-person controller with default
+// arm and smoothing settings
+func NewThirdPersonController() *ThirdPersonController {
+	return &ThirdPersonController{
+		Offset:          vecmath.Vec3{0, 1.6, 0},
+		ArmLength:       5,
+		MinArmLength:    1.5,
+		MaxArmLength:    12,
+		Pitch:           -0.3,
+		Sensitivity:     0.0025,
+		ZoomSpeed:       0.5,
+		FollowStiffness: 12,
+		ArmStiffness:    4,
+		ProbeRadius:     0.2,
+	}
+}
+
+// Update implements CameraController
+func (c *ThirdPersonController) Update(camera *Camera, input CameraInput, t game.Time) {
+	dt := float32(t.DeltaSeconds())
+
+	c.Yaw -= input.LookX * c.Sensitivity
+	c.Pitch = clamp(c.Pitch-input.LookY*c.Sensitivity, -maxPitch, maxPitch)
+	c.ArmLength = clamp(c.ArmLength-input.Zoom*c.ZoomSpeed, c.MinArmLength, c.MaxArmLength)
+
+	goal := c.Target.Add(c.Offset)
+	if !c.attached || c.FollowStiffness <= 0 {
+		c.pivot = goal
+		c.length = c.ArmLength
+		c.attached = true
+	} else {
+		c.pivot = c.pivot.Lerp(goal, min(1, c.FollowStiffness*dt))
+	}
+
+	camera.Orientation = yawPitch(c.Yaw, c.Pitch)
+	back := camera.Forward().Neg()
+
+	// Pull the camera in front of the closest obstacle
+	desired := c.ArmLength
+	if c.Collide != nil {
+		reach := c.ArmLength + c.ProbeRadius
+		if fraction, hit := c.Collide(c.pivot, c.pivot.Add(back.Mul(reach))); hit {
+			desired = max(0, fraction*reach-c.ProbeRadius)
+		}
+	}
+
+	if desired < c.length {
+		c.length = desired
+	} else {
+		c.length += (desired - c.length) * min(1, c.ArmStiffness*dt)
+	}
+
+	camera.Position = c.pivot.Add(back.Mul(c.length))
+}
+
+// OrbitController rotates the camera around a target, as in editors. Look
+// orbits, Zoom changes the distance and Move pans the target across the
+// view
+type OrbitController struct {
+	Target                             vecmath.Vec3
+	Distance, MinDistance, MaxDistance float32
+	Yaw, Pitch                         float32
+	Sensitivity                        float32
+	// ZoomSpeed is the fraction of the distance covered per wheel step
+	ZoomSpeed float32
+	// PanSpeed is the pan speed in distances per second
+	PanSpeed float32
+}
+
+// NewOrbitController creates an orbit controller around target
+func NewOrbitController(target vecmath.Vec3, distance float32) *OrbitController {
+	return &OrbitController{
+		Target:      target,
+		Distance:    distance,
+		MinDistance: 0.5,
+		MaxDistance: 500,
+		Pitch:       -0.5,
+		Sensitivity: 0.005,
+		ZoomSpeed:   0.1,
+		PanSpeed:    1,
+	}
+}
+
+// Update implements CameraController
+func (o *OrbitController) Update(camera *Camera, input CameraInput, t game.Time) {
+	o.Yaw -= input.LookX * o.Sensitivity
+	o.Pitch = clamp(o.Pitch-input.LookY*o.Sensitivity, -maxPitch, maxPitch)
+	o.Distance = clamp(o.Distance*(1-input.Zoom*o.ZoomSpeed), o.MinDistance, o.MaxDistance)
+
+	camera.Orientation = yawPitch(o.Yaw, o.Pitch)
+
+	pan := camera.Right().Mul(input.Move[0]).Add(camera.Up().Mul(input.Move[1]))
+	o.Target = o.Target.Add(pan.Mul(o.PanSpeed * o.Distance * float32(t.DeltaSeconds())))
+
+	camera.Position = o.Target.Sub(camera.Forward().Mul(o.Distance))
+}
@@ -1,57 +1,78 @@
 package engine
 
 import (
-	"fmt"
+	"io/fs"
 	"log"
+	"time"
 
+	"github.com/luidsonl/magic-and-blades/internal/engine/assets"
+	"github.com/luidsonl/magic-and-blades/internal/engine/font"
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
+	"github.com/luidsonl/magic-and-blades/internal/engine/shader"
 	"github.com/luidsonl/magic-and-blades/internal/game"
 	"github.com/luidsonl/magic-and-blades/internal/i18n"
+	"github.com/luidsonl/magic-and-blades/internal/mods"
+	"github.com/luidsonl/magic-and-blades/internal/scenes/menu"
 
-	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 // Engine represents the main game engine
 type Engine struct {
-	window     *sdl.Window
-	context    sdl.GLContext
+	// subsystems are the SDL subsystems initialized for the engine
+	subsystems uint32
+	backend    backend
 	config     game.Config
 	state      *game.GameState
 	translator i18n.Translator
+	scenes     *menu.SceneManager
+	uiFont     *font.Font
+	// files are the base assets with the loaded mods layered over them
+	files fs.FS
+	mods  *mods.Set
+	// ui draws the 2D graphics of menus, HUD and overlays (nil in
+	// headless mode)
+	ui *graphics.Renderer2D
+	// post renders frames offscreen and applies the effects enabled in the
+	// settings (nil in headless mode or when unsupported)
+	post *graphics.PostProcess
+	// assets loads files in the background and uploads them between
+	// frames (nil in headless mode)
+	assets *assets.Manager
+	// lut is the color grading table replacing the built-in one of post
+	// once loaded
+	lut        assets.Handle[*graphics.Texture]
+	lutApplied bool
+	// shaders rebuilds watched shaders when their files change (nil unless
+	// hot reload is enabled)
+	shaders *shader.Watcher
+	// resizeHandlers are notified when the drawable size changes
+	resizeHandlers []func(width, height int32)
 }
 
-// NewEngine creates a new instance of the game engine
+// NewEngine creates a new instance of the game engine, initializing the
+// SDL subsystems it needs
+//
+// In headless mode no window or OpenGL context is created: the loop, scene
+// manager and update logic run against a null backend that draws nothing.
 func NewEngine(config game.Config) (*Engine, error) {
-	// Configure OpenGL attributes before creating the window
-	if err := sdl.GLSetAttribute(sdl.GL_CONTEXT_MAJOR_VERSION, 3); err != nil {
-		return nil, fmt.Errorf("failed to set OpenGL major version: %v", err)
-	}
-	if err := sdl.GLSetAttribute(sdl.GL_CONTEXT_MINOR_VERSION, 3); err != nil {
-		return nil, fmt.Errorf("failed to set OpenGL minor version: %v", err)
-	}
-	if err := sdl.GLSetAttribute(sdl.GL_CONTEXT_PROFILE_MASK, sdl.GL_CONTEXT_PROFILE_CORE); err != nil {
-		return nil, fmt.Errorf("failed to set OpenGL profile: %v", err)
-	}
-	if err := sdl.GLSetAttribute(sdl.GL_DOUBLEBUFFER, 1); err != nil {
-		return nil, fmt.Errorf("failed to enable double buffering: %v", err)
-	}
-
-	// Create window
-	window, err := sdl.CreateWindow(
-		config.WindowTitle,
-		sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		config.WindowWidth, config.WindowHeight,
-		sdl.WINDOW_OPENGL|sdl.WINDOW_SHOWN,
-	)
+	subsystems, err := initSDL(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create window: %v", err)
+		return nil, err
 	}
 
-	// Create OpenGL context
-	context, err := window.GLCreateContext()
-	if err != nil {
-		window.Destroy()
-		return nil, fmt.Errorf("failed to create OpenGL context: %v", err)
+	// Create the presentation backend
+	var output backend
+	if config.Headless {
+		output = nullBackend{}
+		log.Printf("Running in headless mode")
+	} else {
+		glOutput, err := newGLBackend(config)
+		if err != nil {
+			quitSDL(subsystems)
+			return nil, err
+		}
+		output = glOutput
 	}
 
 	// Initialize game state
@@ -61,12 +82,24 @@ func NewEngine(config game.Config) (*Engine, error) {
 	var translator i18n.Translator
 	var initErr error
 
+	// Mods layer their files over the base assets, leaving the global asset
+	// root untouched for other engines; translations merge the keys of
+	// every layer instead
+	modSet := mods.Load(mods.DefaultDir(config.SettingsPath), assets.Files(), config.Mods)
+	files := modSet.Files()
+
+	var languages []fs.FS
+	for _, layer := range modSet.Layers() {
+		if sub, err := fs.Sub(layer, "i18n"); err == nil {
+			languages = append(languages, sub)
+		}
+	}
 	if config.Language != "" {
 		// Use specified language
-		translator, initErr = i18n.NewWithLanguage(config.Language)
+		translator, initErr = i18n.NewWithLanguage(config.Language, languages...)
 	} else {
 		// Auto-detect system language
-		translator, initErr = i18n.New()
+		translator, initErr = i18n.New(languages...)
 	}
 
 	if initErr != nil {
@@ -76,212 +109,404 @@ func NewEngine(config game.Config) (*Engine, error) {
 	}
 
 	engine := &Engine{
-		window:     window,
-		context:    context,
+		subsystems: subsystems,
+		backend:    output,
 		config:     config,
 		state:      state,
 		translator: translator,
+		files:      files,
+		mods:       modSet,
 	}
 
-	// Initialize OpenGL
-	if err := engine.initOpenGL(); err != nil {
-		engine.Destroy()
-		return nil, err
+	// Load the UI font; extra fonts in assets/fonts cover scripts the
+	// built-in font lacks, such as CJK
+	engine.uiFont = font.Default()
+	if err := engine.uiFont.LoadFallbackFS(files, "fonts"); err != nil {
+		log.Printf("Warning: failed to load fallback fonts: %v", err)
 	}
 
+	if !config.Headless {
+		if config.HotReload {
+			engine.shaders = shader.NewWatcher(shader.DefaultPollInterval)
+			log.Printf("Shader hot reload enabled")
+		}
+
+		ui, err := graphics.NewRenderer2D(files)
+		if err != nil {
+			engine.Destroy()
+			return nil, err
+		}
+		engine.ui = ui
+		engine.WatchShader(ui.Shader())
+		engine.assets = assets.NewManager(files, 0)
+
+		width, height := engine.DrawableSize()
+		post, err := newPostProcess(files, width, height)
+		if err != nil {
+			log.Printf("Warning: post-processing disabled: %v", err)
+		} else {
+			engine.post = post
+			engine.lut = assets.Load(engine.assets, lutKind, gradingLUTPath)
+			for _, s := range post.Shaders() {
+				engine.WatchShader(s)
+			}
+		}
+	}
+
+	// Initialize scene stack with the main menu. Game scenes depend on the
+	// engine, so the game registers them on the scene manager
+	engine.scenes = menu.NewSceneManager(translator, &engine.config, engine.ui, engine.uiFont, modSet)
+	engine.scenes.SetCommandHandler(engine.handleSceneCommand)
+	engine.scenes.SwitchTo(menu.SceneMainMenu)
+
 	// Log successful initialization
 	log.Printf("Engine initialized successfully")
-	log.Printf("OpenGL context created")
 	log.Printf("Language set to: %s", translator.GetLanguage())
-	log.Printf("Initial scene: %s", state.CurrentScene)
+	log.Printf("Initial scene: %s", engine.scenes.GetSceneType())
 
 	return engine, nil
 }
 
+// assetUploadBudget is the time spent each frame finishing assets loaded
+// in the background, so that loading does not stall rendering
+const assetUploadBudget = 4 * time.Millisecond
+
+// maxFrameTime caps the real time consumed by a single frame so that a long
+// stall (debugger, window drag) does not make the simulation try to catch up
+// forever
+const maxFrameTime = 250 * time.Millisecond
+
 // Run starts the main game loop
+//
+// The simulation advances in fixed steps of 1/TickRate seconds while
+// rendering happens once per loop iteration, receiving the fraction of the
+// next tick that has already elapsed so it can interpolate between states.
 func (e *Engine) Run() {
 	defer e.Destroy()
 
 	// Log game start
 	log.Printf("Starting game loop")
 
+	dt := game.TickDuration(e.config.TickRate)
+	e.state.Time.DT = dt
+
+	var accumulator time.Duration
+	var fps fpsCounter
+	previous := time.Now()
+
 	// Main game loop
 	for e.state.Running {
+		now := time.Now()
+		frameTime := now.Sub(previous)
+		previous = now
+
+		// Avoid the spiral of death
+		if frameTime > maxFrameTime {
+			frameTime = maxFrameTime
+		}
+		accumulator += frameTime
+
 		// Process events
 		e.processEvents()
 
-		// Update game logic based on current scene
-		e.update()
+		// Run as many fixed updates as the elapsed time requires
+		for accumulator >= dt && e.state.Running {
+			e.tick()
+			accumulator -= dt
+		}
 
-		// Render scene
-		e.render()
+		// Render scene interpolated between the last two ticks
+		alpha := float64(accumulator) / float64(dt)
+		e.render(alpha)
 
-		// Swap buffers
-		e.window.GLSwap()
+		e.state.Time.FPS = fps.frame(now)
 
-		// Cap frame rate
-		sdl.Delay(16) // ~60 FPS
+		// Yield the CPU between frames
+		sdl.Delay(1)
 	}
 
 	log.Printf("Game loop ended")
 }
 
+// RunTicks runs exactly n simulation ticks back to back, without waiting
+// for real time, and returns. Events are processed and a frame is rendered
+// after every tick. Intended for automated tests and tools
+func (e *Engine) RunTicks(n int) {
+	e.state.Time.DT = game.TickDuration(e.config.TickRate)
+
+	for i := 0; i < n && e.state.Running; i++ {
+		e.processEvents()
+		if !e.state.Running {
+			break
+		}
+		e.tick()
+		e.render(0)
+	}
+}
+
+// tick runs one fixed update and advances the simulation clock
+func (e *Engine) tick() {
+	e.update()
+	e.state.Time.Tick++
+	e.state.Time.Elapsed += e.state.Time.DT
+
+	if e.config.MaxTicks > 0 && e.state.Time.Tick >= e.config.MaxTicks {
+		e.state.Running = false
+		log.Printf("Reached tick limit (%d)", e.config.MaxTicks)
+	}
+}
+
 // processEvents processes all pending events
 func (e *Engine) processEvents() {
+	// Headless runs may not initialize SDL at all
+	if sdl.WasInit(sdl.INIT_EVENTS) == 0 {
+		return
+	}
+
 	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 		switch evt := event.(type) {
 		case *sdl.QuitEvent:
 			e.state.Running = false
 			log.Printf("Quit event received")
-		case *sdl.KeyboardEvent:
-			if evt.State == sdl.PRESSED {
-				switch evt.Keysym.Sym {
-				case sdl.K_ESCAPE:
-					// Handle ESC based on current scene
-					if e.state.CurrentScene == "menu" {
-						e.state.Running = false
-						log.Printf("Escape key pressed, exiting game")
-					} else {
-						// Return to menu from other scenes
-						e.state.CurrentScene = "menu"
-						log.Printf("Returning to menu")
-					}
-				}
-			}
+			continue
 		case *sdl.WindowEvent:
-			if evt.Event == sdl.WINDOWEVENT_RESIZED {
+			// Sent for user resizes as well as applied display changes
+			if evt.Event == sdl.WINDOWEVENT_SIZE_CHANGED {
 				log.Printf("Window resized: %dx%d", evt.Data1, evt.Data2)
-				// Update viewport size
-				gl.Viewport(0, 0, evt.Data1, evt.Data2)
+				e.handleResize(evt.Data1, evt.Data2)
 			}
 		}
+
+		// Let the active scene handle the event
+		e.scenes.ProcessEvent(event)
 	}
 }
 
-// update handles game logic updates based on current scene
+// update advances the active scene by one fixed tick
 func (e *Engine) update() {
-	// Scene-specific update logic would go here
-	// For now, we just log the current scene
-	switch e.state.CurrentScene {
-	case "menu":
-		// Menu scene logic
-	case "gameplay":
-		// Gameplay logic
-	case "pause":
-		// Pause menu logic
+	e.scenes.Update(e.state.Time)
+
+	if e.scenes.QuitRequested() {
+		e.state.Running = false
+		log.Printf("Quit requested by scene")
 	}
 }
 
-// render renders the game scene based on current state
-func (e *Engine) render() {
-	// Clear color and depth buffers
-	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
-
-	// Scene-specific rendering
-	switch e.state.CurrentScene {
-	case "menu":
-		e.renderMenu()
-	case "gameplay":
-		e.renderGameplay()
-	case "pause":
-		e.renderPauseMenu()
-	default:
-		// Default background
-		gl.ClearColor(0.2, 0.3, 0.3, 1.0)
-		gl.Clear(gl.COLOR_BUFFER_BIT)
+// handleSceneCommand handles commands scenes send to the engine
+func (e *Engine) handleSceneCommand(cmd menu.Command) {
+	switch cmd.Type {
+	case menu.CommandApplyDisplay:
+		if err := e.ApplyDisplaySettings(); err != nil {
+			log.Printf("Warning: failed to apply display settings: %v", err)
+		}
 	}
 }
 
-// renderMenu renders the main menu scene
-func (e *Engine) renderMenu() {
-	gl.ClearColor(0.1, 0.1, 0.2, 1.0) // Dark blue background
-	gl.Clear(gl.COLOR_BUFFER_BIT)
+// ApplyDisplaySettings applies the window size, mode, display and vsync
+// from the current config without recreating the window or GL context
+func (e *Engine) ApplyDisplaySettings() error {
+	width, height, err := e.backend.applyDisplay(e.config)
+	if err != nil {
+		return err
+	}
 
-	// Menu rendering logic will be implemented here
-	// For now, just clear with a different color
+	e.handleResize(width, height)
+	return nil
 }
 
-// renderGameplay renders the gameplay scene
-func (e *Engine) renderGameplay() {
-	gl.ClearColor(0.3, 0.5, 0.3, 1.0) // Green background for gameplay
-	gl.Clear(gl.COLOR_BUFFER_BIT)
-
-	// Gameplay rendering logic will be implemented here
+// OnResize registers a function called with the new drawable size whenever
+// it changes, e.g. to update camera projections
+func (e *Engine) OnResize(handler func(width, height int32)) {
+	e.resizeHandlers = append(e.resizeHandlers, handler)
 }
 
-// renderPauseMenu renders the pause menu
-func (e *Engine) renderPauseMenu() {
-	gl.ClearColor(0.2, 0.2, 0.2, 0.8) // Semi-transparent dark background
-	gl.Clear(gl.COLOR_BUFFER_BIT)
+// handleResize updates the viewport and notifies resize handlers
+func (e *Engine) handleResize(width, height int32) {
+	e.backend.resize(width, height)
 
-	// Pause menu rendering logic will be implemented here
-}
-
-// initOpenGL initializes OpenGL
-func (e *Engine) initOpenGL() error {
-	// Initialize OpenGL
-	if err := gl.Init(); err != nil {
-		return fmt.Errorf("failed to initialize OpenGL: %v", err)
+	if e.post != nil {
+		if err := e.post.Resize(width, height); err != nil {
+			log.Printf("Warning: failed to resize post-processing targets: %v", err)
+		}
 	}
 
-	// Display OpenGL version information
-	version := gl.GoStr(gl.GetString(gl.VERSION))
-	vendor := gl.GoStr(gl.GetString(gl.VENDOR))
-	renderer := gl.GoStr(gl.GetString(gl.RENDERER))
+	for _, handler := range e.resizeHandlers {
+		handler(width, height)
+	}
+}
 
-	log.Printf("OpenGL version: %s", version)
-	log.Printf("OpenGL vendor: %s", vendor)
-	log.Printf("OpenGL renderer: %s", renderer)
+// render renders the scene stack. alpha is the interpolation factor in
+// [0, 1) between the previous and the current tick
+func (e *Engine) render(alpha float64) {
+	if !e.backend.beginFrame() {
+		return
+	}
 
-	// Set basic OpenGL settings
-	gl.Enable(gl.DEPTH_TEST)
-	gl.DepthFunc(gl.LESS)
-	gl.Enable(gl.BLEND)
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	// Pick up edited shaders before anything is drawn with them
+	if e.shaders != nil {
+		e.shaders.Poll(time.Now())
+	}
 
-	// Set viewport size
-	gl.Viewport(0, 0, e.config.WindowWidth, e.config.WindowHeight)
+	// Finish loading the assets decoded in the background
+	if e.assets != nil {
+		e.assets.Update(assetUploadBudget)
+		e.updateLUT()
+	}
 
-	// Set default clear color
-	gl.ClearColor(0.2, 0.3, 0.3, 1.0)
+	// Scenes draw themselves through the post-processing chain, overlays
+	// last directly on the screen
+	width, height := e.DrawableSize()
+	if e.post != nil {
+		e.post.Effects = postEffects(e.config.PostEffects)
+		e.post.Begin(width, height)
+	}
+	e.ui.Begin(width, height)
+	e.scenes.Render(alpha)
+	e.ui.Flush()
+	if e.post != nil {
+		e.post.End(float32(e.state.Time.Elapsed.Seconds()))
+	}
+	e.drawShaderErrors()
+	e.drawLoading()
+	e.ui.End()
 
-	return nil
+	e.backend.endFrame()
 }
 
 // Destroy releases engine resources
 func (e *Engine) Destroy() {
 	log.Printf("Destroying engine resources")
 
-	if e.context != nil {
-		sdl.GLDeleteContext(e.context)
-		log.Printf("OpenGL context destroyed")
+	if e.scenes != nil {
+		e.scenes.Destroy()
+		e.scenes = nil
+	}
+
+	if e.post != nil {
+		// A loaded table belongs to the asset manager
+		if !(e.lutApplied && e.lut.Ready()) && e.post.LUT != nil {
+			e.post.LUT.Destroy()
+		}
+		e.post.Destroy()
+		e.post = nil
+	}
+	e.lut.Release()
+
+	if e.assets != nil {
+		e.assets.Destroy()
+		e.assets = nil
+	}
+
+	// Nothing reads asset files any more
+	if e.mods != nil {
+		if err := e.mods.Close(); err != nil {
+			log.Printf("Warning: failed to close mods: %v", err)
+		}
+		e.mods = nil
+	}
+
+	if e.ui != nil {
+		e.ui.Destroy()
+		e.ui = nil
 	}
 
-	if e.window != nil {
-		e.window.Destroy()
-		log.Printf("Window destroyed")
+	if e.backend != nil {
+		e.backend.destroy()
+		e.backend = nil
+	}
+
+	if e.subsystems != 0 {
+		quitSDL(e.subsystems)
+		e.subsystems = 0
 	}
 
 	log.Printf("Engine cleanup completed")
 }
 
+// IsHeadless reports whether the engine runs without a window
+func (e *Engine) IsHeadless() bool {
+	return e.config.Headless
+}
+
 // GetTranslator returns the translator instance
 func (e *Engine) GetTranslator() i18n.Translator {
 	return e.translator
 }
 
+// GetRenderer2D returns the renderer drawing 2D graphics and text (nil in
+// headless mode)
+func (e *Engine) GetRenderer2D() *graphics.Renderer2D {
+	return e.ui
+}
+
+// GetPostProcess returns the post-processing chain, e.g. to show damage
+// (nil in headless mode or when post-processing is unavailable)
+func (e *Engine) GetPostProcess() *graphics.PostProcess {
+	return e.post
+}
+
+// GetAssets returns the asset manager (nil in headless mode)
+func (e *Engine) GetAssets() *assets.Manager {
+	return e.assets
+}
+
+// Files returns the asset files of the game, including those of mods
+func (e *Engine) Files() fs.FS {
+	return e.files
+}
+
+// GetFont returns the UI font
+func (e *Engine) GetFont() *font.Font {
+	return e.uiFont
+}
+
+// WatchShader rebuilds s whenever its source files change while hot
+// reload is enabled; otherwise it does nothing
+func (e *Engine) WatchShader(s *shader.Shader) {
+	if e.shaders != nil {
+		e.shaders.Add(s)
+	}
+}
+
+// UnwatchShader stops watching s, e.g. before destroying it
+func (e *Engine) UnwatchShader(s *shader.Shader) {
+	if e.shaders != nil {
+		e.shaders.Remove(s)
+	}
+}
+
+// DrawableSize returns the size in pixels of the area drawn to. Headless
+// engines report the configured window size
+func (e *Engine) DrawableSize() (width, height int32) {
+	width, height = e.backend.drawableSize()
+	if width == 0 || height == 0 {
+		return e.config.WindowWidth, e.config.WindowHeight
+	}
+	return width, height
+}
+
 // GetConfig returns the game configuration
 func (e *Engine) GetConfig() game.Config {
 	return e.config
 }
 
+// GetTime returns timing information for the current tick
+func (e *Engine) GetTime() game.Time {
+	return e.state.Time
+}
+
 // GetState returns the game state
 func (e *Engine) GetState() *game.GameState {
 	return e.state
 }
 
-// SetScene changes the current scene
-func (e *Engine) SetScene(sceneName string) {
-	e.state.CurrentScene = sceneName
-	log.Printf("Scene changed to: %s", sceneName)
+// GetSceneManager returns the scene stack
+func (e *Engine) GetSceneManager() *menu.SceneManager {
+	return e.scenes
+}
+
+// SetScene replaces the scene stack with a scene of the given type
+func (e *Engine) SetScene(sceneType menu.SceneType) {
+	e.scenes.SwitchTo(sceneType)
+	log.Printf("Scene changed to: %s", sceneType)
 }
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/luidsonl/magic-and-blades/internal/game"
+)
+
+// newHeadlessEngine creates a headless engine for config, which is switched
+// to headless mode. Settings are not saved
+func newHeadlessEngine(t *testing.T, config game.Config) *Engine {
+	t.Helper()
+	config.Headless = true
+
+	e, err := NewEngine(config)
+	if err != nil {
+		t.Fatalf("failed to create headless engine: %v", err)
+	}
+	t.Cleanup(e.Destroy)
+	return e
+}
+
+func TestHeadlessMaxTicks(t *testing.T) {
+	e := newHeadlessEngine(t, game.Config{Language: "en", MaxTicks: 5})
+
+	e.RunTicks(100)
+	if got := e.GetTime().Tick; got != 5 {
+		t.Errorf("ran %d ticks, want 5", got)
+	}
+	if e.GetState().Running {
+		t.Errorf("engine still running after the tick limit")
+	}
+}
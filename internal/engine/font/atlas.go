@@ -0,0 +1,167 @@
+package font
+
+import (
+	"image"
+	"image/draw"
+	"log"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// PageSize is the width and height in pixels of an atlas page
+const PageSize = 1024
+
+// glyphPadding keeps neighbouring glyphs from bleeding into each other when
+// sampled with linear filtering
+const glyphPadding = 1
+
+// Page is an atlas texture holding rasterized glyph coverage
+type Page struct {
+	Image *image.Alpha
+	// Version increases every time glyphs are added to the page
+	Version int
+}
+
+// glyph describes a rasterized glyph
+type glyph struct {
+	page    int
+	rect    image.Rectangle
+	offset  image.Point
+	advance float32
+	face    int
+}
+
+// atlas caches the glyphs of a font at one pixel size
+type atlas struct {
+	font       *Font
+	faces      []font.Face
+	pages      []*Page
+	glyphs     map[rune]glyph
+	kerning    map[[2]rune]float32
+	ascent     float32
+	lineHeight float32
+
+	// Shelf packer state for the last page
+	penX, penY, shelfHeight int
+}
+
+// newAtlas creates an atlas for a pixel size
+func newAtlas(f *Font, size int) *atlas {
+	a := &atlas{
+		font:    f,
+		glyphs:  make(map[rune]glyph),
+		kerning: make(map[[2]rune]float32),
+	}
+
+	for i, sf := range f.faces {
+		face, err := opentype.NewFace(sf, &opentype.FaceOptions{
+			Size:    float64(size),
+			DPI:     72,
+			Hinting: font.HintingFull,
+		})
+		if err != nil {
+			log.Printf("Warning: failed to create face for %s: %v", f.names[i], err)
+			face = nil
+		}
+		a.faces = append(a.faces, face)
+	}
+
+	if primary := a.faces[0]; primary != nil {
+		metrics := primary.Metrics()
+		a.ascent = fixedToFloat(metrics.Ascent)
+		a.lineHeight = fixedToFloat(metrics.Height)
+	}
+
+	return a
+}
+
+// glyph returns the glyph for r, rasterizing it on first use
+func (a *atlas) glyph(r rune) glyph {
+	if g, ok := a.glyphs[r]; ok {
+		return g
+	}
+
+	g := glyph{face: a.font.faceFor(r)}
+	face := a.faces[g.face]
+	if face == nil {
+		face = a.faces[0]
+	}
+	if face == nil {
+		a.glyphs[r] = g
+		return g
+	}
+
+	bounds, mask, maskPoint, advance, ok := face.Glyph(fixed.Point26_6{}, r)
+	g.advance = fixedToFloat(advance)
+	if ok && !bounds.Empty() {
+		g.page, g.rect = a.allocate(bounds.Dx(), bounds.Dy())
+		g.offset = bounds.Min
+
+		page := a.pages[g.page]
+		draw.Draw(page.Image, g.rect, mask, maskPoint, draw.Src)
+		page.Version++
+	}
+
+	a.glyphs[r] = g
+	return g
+}
+
+// allocate reserves a w x h region, starting a new shelf or page when the
+// current one is full
+func (a *atlas) allocate(w, h int) (int, image.Rectangle) {
+	w += glyphPadding
+	h += glyphPadding
+
+	if len(a.pages) == 0 {
+		a.addPage()
+	}
+
+	if a.penX+w > PageSize {
+		// Start a new shelf below the current one
+		a.penX = 0
+		a.penY += a.shelfHeight
+		a.shelfHeight = 0
+	}
+	if a.penY+h > PageSize {
+		a.addPage()
+	}
+
+	rect := image.Rect(a.penX, a.penY, a.penX+w-glyphPadding, a.penY+h-glyphPadding)
+	a.penX += w
+	if h > a.shelfHeight {
+		a.shelfHeight = h
+	}
+
+	return len(a.pages) - 1, rect
+}
+
+// addPage starts a new empty page
+func (a *atlas) addPage() {
+	a.pages = append(a.pages, &Page{Image: image.NewAlpha(image.Rect(0, 0, PageSize, PageSize))})
+	a.penX, a.penY, a.shelfHeight = 0, 0, 0
+}
+
+// kern returns the kerning adjustment between two runes. Kerning only
+// applies when both runes come from the same font
+func (a *atlas) kern(left, right rune) float32 {
+	pair := [2]rune{left, right}
+	if k, ok := a.kerning[pair]; ok {
+		return k
+	}
+
+	var k float32
+	lg, rg := a.glyph(left), a.glyph(right)
+	if lg.face == rg.face && a.faces[lg.face] != nil {
+		k = fixedToFloat(a.faces[lg.face].Kern(left, right))
+	}
+
+	a.kerning[pair] = k
+	return k
+}
+
+// fixedToFloat converts a 26.6 fixed point value to float32
+func fixedToFloat(v fixed.Int26_6) float32 {
+	return float32(v) / 64
+}
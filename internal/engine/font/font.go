@@ -0,0 +1,173 @@
+package font
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Font is a TrueType/OpenType font with an optional chain of fallback fonts
+// used for characters the primary font does not provide (e.g. CJK).
+// Glyphs are rasterized on demand into one atlas per pixel size
+type Font struct {
+	mu      sync.Mutex
+	faces   []*sfnt.Font
+	names   []string
+	buf     sfnt.Buffer
+	atlases map[int]*atlas
+}
+
+// Parse creates a font from TTF or OTF data
+func Parse(name string, data []byte) (*Font, error) {
+	f := &Font{atlases: make(map[int]*atlas)}
+	if err := f.AddFallback(name, data); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Load reads a font from a TTF or OTF file
+func Load(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font %s: %v", path, err)
+	}
+	return Parse(filepath.Base(path), data)
+}
+
+// LoadFS reads a font from a TTF or OTF file of fsys
+func LoadFS(fsys fs.FS, name string) (*Font, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font %s: %v", name, err)
+	}
+	return Parse(path.Base(name), data)
+}
+
+// Default returns the built-in Go Regular font, which covers Latin scripts
+// including Portuguese accents
+func Default() *Font {
+	f, err := Parse("Go Regular", goregular.TTF)
+	if err != nil {
+		// The embedded font is known to be valid
+		panic(fmt.Sprintf("font: invalid built-in font: %v", err))
+	}
+	return f
+}
+
+// AddFallback appends a font to the fallback chain. Fonts are searched in
+// the order they were added
+func (f *Font) AddFallback(name string, data []byte) error {
+	face, err := opentype.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse font %s: %v", name, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.faces = append(f.faces, face)
+	f.names = append(f.names, name)
+
+	// Glyphs may now resolve to a different font
+	f.atlases = make(map[int]*atlas)
+	return nil
+}
+
+// LoadFallback appends the font at path to the fallback chain
+func (f *Font) LoadFallback(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read font %s: %v", path, err)
+	}
+	return f.AddFallback(filepath.Base(path), data)
+}
+
+// LoadFallbackDir appends every .ttf and .otf file in dir to the
+// fallback chain in name order. A missing directory is not an error
+func (f *Font) LoadFallbackDir(dir string) error {
+	return f.LoadFallbackFS(os.DirFS(dir), ".")
+}
+
+// LoadFallbackFS is LoadFallbackDir for a directory of fsys
+func (f *Font) LoadFallbackFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		ext := strings.ToLower(path.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".ttf" && ext != ".otf") {
+			continue
+		}
+
+		name := path.Join(dir, entry.Name())
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			err = fmt.Errorf("failed to read font %s: %v", name, err)
+		} else {
+			err = f.AddFallback(entry.Name(), data)
+		}
+		if err != nil {
+			log.Printf("Warning: skipping fallback font: %v", err)
+			continue
+		}
+		log.Printf("Loaded fallback font: %s", entry.Name())
+	}
+	return nil
+}
+
+// faceFor returns the index of the first font in the chain providing r, or
+// 0 so that the primary font draws its missing-glyph box
+func (f *Font) faceFor(r rune) int {
+	for i, face := range f.faces {
+		if index, err := face.GlyphIndex(&f.buf, r); err == nil && index != 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// atlas returns the glyph atlas for a pixel size, creating it if needed.
+// Callers must hold f.mu
+func (f *Font) atlas(size int) *atlas {
+	if size <= 0 {
+		size = 16
+	}
+
+	a, ok := f.atlases[size]
+	if !ok {
+		a = newAtlas(f, size)
+		f.atlases[size] = a
+	}
+	return a
+}
+
+// Pages returns the atlas pages for a pixel size. Backends upload a page
+// again whenever its Version changes
+func (f *Font) Pages(size int) []*Page {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.atlas(size).pages
+}
+
+// LineHeight returns the distance between two baselines at a pixel size
+func (f *Font) LineHeight(size int) float32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.atlas(size).lineHeight
+}
@@ -0,0 +1,215 @@
+package font
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Align controls the horizontal alignment of text lines
+type Align int
+
+const (
+	// AlignLeft places lines to the right of x
+	AlignLeft Align = iota
+	// AlignCenter centers lines on x
+	AlignCenter
+	// AlignRight places lines to the left of x
+	AlignRight
+)
+
+// Options controls how text is laid out and drawn
+type Options struct {
+	// Size is the font size in pixels
+	Size  int
+	Align Align
+	// MaxWidth wraps lines longer than this many pixels (0 disables
+	// wrapping)
+	MaxWidth float32
+	// LineSpacing scales the distance between lines (1 when zero)
+	LineSpacing float32
+	Color       color.RGBA
+	// Outline draws an outline of this many pixels around the glyphs
+	Outline      int
+	OutlineColor color.RGBA
+}
+
+// Quad is a glyph placed on screen
+type Quad struct {
+	// Page is the atlas page holding the glyph
+	Page int
+	// Src is the glyph region in the atlas page
+	Src image.Rectangle
+	// X, Y, W and H are the destination rectangle in pixels
+	X, Y, W, H float32
+}
+
+// Layout positions the glyphs of text with its top-left corner at (x, y),
+// or its top-center / top-right corner depending on the alignment
+func (f *Font) Layout(text string, x, y float32, opts Options) []Quad {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a := f.atlas(opts.Size)
+	quads := make([]Quad, 0, utf8.RuneCountInString(text))
+
+	for i, line := range a.wrap(text, opts.MaxWidth) {
+		pen := x
+		switch opts.Align {
+		case AlignCenter:
+			pen -= a.width(line) / 2
+		case AlignRight:
+			pen -= a.width(line)
+		}
+		baseline := y + a.ascent + float32(i)*a.lineHeight*lineSpacing(opts)
+
+		var prev rune
+		for _, r := range line {
+			g := a.glyph(r)
+			if prev != 0 {
+				pen += a.kern(prev, r)
+			}
+
+			if !g.rect.Empty() {
+				quads = append(quads, Quad{
+					Page: g.page,
+					Src:  g.rect,
+					X:    pen + float32(g.offset.X),
+					Y:    baseline + float32(g.offset.Y),
+					W:    float32(g.rect.Dx()),
+					H:    float32(g.rect.Dy()),
+				})
+			}
+
+			pen += g.advance
+			prev = r
+		}
+	}
+
+	return quads
+}
+
+// Measure returns the size of the text block produced by Layout
+func (f *Font) Measure(text string, opts Options) (width, height float32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a := f.atlas(opts.Size)
+	lines := a.wrap(text, opts.MaxWidth)
+	for _, line := range lines {
+		if w := a.width(line); w > width {
+			width = w
+		}
+	}
+
+	if len(lines) > 0 {
+		height = a.lineHeight + float32(len(lines)-1)*a.lineHeight*lineSpacing(opts)
+	}
+	return width, height
+}
+
+// lineSpacing returns the effective line spacing multiplier
+func lineSpacing(opts Options) float32 {
+	if opts.LineSpacing <= 0 {
+		return 1
+	}
+	return opts.LineSpacing
+}
+
+// width returns the advance width of a single line including kerning
+func (a *atlas) width(line string) float32 {
+	var w float32
+	var prev rune
+	for _, r := range line {
+		if prev != 0 {
+			w += a.kern(prev, r)
+		}
+		w += a.glyph(r).advance
+		prev = r
+	}
+	return w
+}
+
+// wrap splits text into lines at newlines and, when maxWidth is positive,
+// at spaces or between ideographs so that no line exceeds maxWidth. Words
+// longer than maxWidth are broken between characters
+func (a *atlas) wrap(text string, maxWidth float32) []string {
+	paragraphs := strings.Split(text, "\n")
+	if maxWidth <= 0 {
+		return paragraphs
+	}
+
+	var lines []string
+	for _, paragraph := range paragraphs {
+		lines = append(lines, a.wrapParagraph([]rune(paragraph), maxWidth)...)
+	}
+	return lines
+}
+
+// wrapParagraph wraps a paragraph without newlines
+func (a *atlas) wrapParagraph(runes []rune, maxWidth float32) []string {
+	var lines []string
+
+	for len(runes) > 0 {
+		// Find how many runes fit and the last place the line may break
+		var w float32
+		var prev rune
+		fit, lastBreak := 0, 0
+		for i, r := range runes {
+			if prev != 0 {
+				w += a.kern(prev, r)
+			}
+			w += a.glyph(r).advance
+			if w > maxWidth && i > 0 && !unicode.IsSpace(r) {
+				break
+			}
+			fit = i + 1
+			if unicode.IsSpace(r) || isIdeograph(r) {
+				lastBreak = i + 1
+			} else if i+1 < len(runes) && isIdeograph(runes[i+1]) {
+				lastBreak = i + 1
+			}
+			prev = r
+		}
+
+		end := fit
+		if fit < len(runes) && lastBreak > 0 {
+			end = lastBreak
+		}
+
+		lines = append(lines, strings.TrimRightFunc(string(runes[:end]), unicode.IsSpace))
+
+		// Drop the spaces the line was broken at
+		runes = runes[end:]
+		for len(runes) > 0 && unicode.IsSpace(runes[0]) {
+			runes = runes[1:]
+		}
+	}
+
+	if len(lines) == 0 {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// isIdeograph reports whether lines may break before or after r even
+// without spaces, as in Chinese and Japanese text
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// OutlineOffsets returns the offsets at which glyphs are drawn in the
+// outline color to produce an outline of the given thickness
+func OutlineOffsets(thickness int) [][2]float32 {
+	var offsets [][2]float32
+	for dy := -thickness; dy <= thickness; dy++ {
+		for dx := -thickness; dx <= thickness; dx++ {
+			if (dx != 0 || dy != 0) && dx*dx+dy*dy <= thickness*thickness+1 {
+				offsets = append(offsets, [2]float32{float32(dx), float32(dy)})
+			}
+		}
+	}
+	return offsets
+}
@@ -1 +1,628 @@
 package graphics
+
+import (
+	"image"
+	"image/color"
+	"io/fs"
+	"math"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/luidsonl/magic-and-blades/internal/engine/font"
+	"github.com/luidsonl/magic-and-blades/internal/engine/shader"
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// floatsPerVertex2D is position (2) + texture coordinates (2) + color (4)
+const floatsPerVertex2D = 8
+
+// BlendMode selects how 2D draws are combined with what is below them
+type BlendMode int
+
+const (
+	// BlendAlpha mixes by the alpha of the drawn color
+	BlendAlpha BlendMode = iota
+	// BlendAdditive adds the drawn color, e.g. for glows and particles
+	BlendAdditive
+	// BlendMultiply darkens by the drawn color, e.g. for shadows
+	BlendMultiply
+	// BlendOpaque replaces what is below
+	BlendOpaque
+)
+
+// apply sets the GL blend state of the mode
+func (m BlendMode) apply() {
+	switch m {
+	case BlendOpaque:
+		gl.Disable(gl.BLEND)
+		return
+	case BlendAdditive:
+		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE)
+	case BlendMultiply:
+		gl.BlendFunc(gl.DST_COLOR, gl.ONE_MINUS_SRC_ALPHA)
+	default:
+		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	}
+	gl.Enable(gl.BLEND)
+}
+
+// White draws regions with their own colors
+var White = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+// Region returns a region covering the whole texture
+func (t *Texture) Region() Region {
+	return Region{Texture: t, W: float32(t.width), H: float32(t.height)}
+}
+
+// Rect is a rectangle in pixels
+type Rect struct {
+	X, Y, W, H float32
+}
+
+// Region is a rectangle of a texture in pixels, such as a sprite in an
+// atlas
+type Region struct {
+	Texture    *Texture
+	X, Y, W, H float32
+}
+
+// uv returns the texture coordinates of the region corners
+func (r Region) uv() (u0, v0, u1, v1 float32) {
+	w, h := float32(r.Texture.width), float32(r.Texture.height)
+	u0, v0 = r.X/w, r.Y/h
+	u1, v1 = (r.X+r.W)/w, (r.Y+r.H)/h
+	if r.Texture.flipped {
+		v0, v1 = 1-v0, 1-v1
+	}
+	return u0, v0, u1, v1
+}
+
+// sub returns a part of the region, relative to its top-left corner
+func (r Region) sub(x, y, w, h float32) Region {
+	return Region{Texture: r.Texture, X: r.X + x, Y: r.Y + y, W: w, H: h}
+}
+
+// Atlas names the regions of a texture holding many sprites
+type Atlas struct {
+	texture *Texture
+	regions map[string]Region
+}
+
+// NewAtlas creates an atlas over texture without regions
+func NewAtlas(texture *Texture) *Atlas {
+	return &Atlas{texture: texture, regions: make(map[string]Region)}
+}
+
+// NewGridAtlas creates an atlas whose regions are the cells of a grid,
+// named in row-major order
+func NewGridAtlas(texture *Texture, cellWidth, cellHeight int, names []string) *Atlas {
+	a := NewAtlas(texture)
+	columns := max(1, int(texture.width)/cellWidth)
+	for i, name := range names {
+		x, y := i%columns*cellWidth, i/columns*cellHeight
+		a.Add(name, x, y, cellWidth, cellHeight)
+	}
+	return a
+}
+
+// Add names a rectangle of the texture
+func (a *Atlas) Add(name string, x, y, width, height int) {
+	a.regions[name] = Region{Texture: a.texture, X: float32(x), Y: float32(y), W: float32(width), H: float32(height)}
+}
+
+// Region returns a named region
+func (a *Atlas) Region(name string) (Region, bool) {
+	r, ok := a.regions[name]
+	return r, ok
+}
+
+// Texture returns the atlas texture
+func (a *Atlas) Texture() *Texture {
+	return a.texture
+}
+
+// NineSlice is a region drawn as a resizable panel: the corners keep their
+// size, the edges stretch along one axis and the center along both
+type NineSlice struct {
+	Region Region
+	// Left, Top, Right and Bottom are the border widths in pixels
+	Left, Top, Right, Bottom float32
+}
+
+// SpriteOptions controls how a sprite is placed and colored
+type SpriteOptions struct {
+	// Origin is the point of the sprite, in its own pixels, placed at the
+	// draw position; rotation and scale happen around it
+	Origin vecmath.Vec2
+	// Rotation is the clockwise rotation in radians
+	Rotation float32
+	// Scale multiplies the sprite size (1 when zero)
+	Scale vecmath.Vec2
+	// Tint multiplies the texture colors (white when zero)
+	Tint         color.RGBA
+	FlipX, FlipY bool
+	// Blend is the blend mode of the sprite
+	Blend BlendMode
+}
+
+// Camera2D maps world coordinates to the screen. The zero camera draws in
+// pixels with the origin at the top-left corner of the viewport
+type Camera2D struct {
+	// Position is the world point at the top-left corner before zooming
+	// and rotating
+	Position vecmath.Vec2
+	// Zoom scales the world around the viewport center (1 when zero)
+	Zoom float32
+	// Rotation rotates the world around the viewport center, in radians
+	Rotation float32
+}
+
+// Matrix returns the projection of the camera for a viewport size
+func (c Camera2D) Matrix(width, height float32) vecmath.Mat4 {
+	projection := vecmath.Ortho(0, width, height, 0, -1, 1)
+	zoom := c.Zoom
+	if zoom == 0 {
+		zoom = 1
+	}
+	if zoom == 1 && c.Rotation == 0 {
+		return projection.Mul(vecmath.Translate(vecmath.Vec3{-c.Position[0], -c.Position[1], 0}))
+	}
+
+	center := vecmath.Vec3{width / 2, height / 2, 0}
+	sin, cos := math.Sincos(float64(c.Rotation))
+	rotation := vecmath.Ident4()
+	rotation[0], rotation[1] = float32(cos), float32(sin)
+	rotation[4], rotation[5] = -float32(sin), float32(cos)
+
+	return projection.
+		Mul(vecmath.Translate(center)).
+		Mul(rotation).
+		Mul(vecmath.Scale(vecmath.Vec3{zoom, zoom, 1})).
+		Mul(vecmath.Translate(center.Mul(-1))).
+		Mul(vecmath.Translate(vecmath.Vec3{-c.Position[0], -c.Position[1], 0}))
+}
+
+// batch is a run of indices drawn with one call
+type batch struct {
+	texture    uint32
+	blend      BlendMode
+	projection vecmath.Mat4
+	start      int
+	count      int
+}
+
+// Stats counts the work of the last flushed frame
+type Stats struct {
+	DrawCalls int
+	Vertices  int
+}
+
+// Renderer2D batches textured quads, text and shapes and draws them with
+// as few calls as possible: consecutive draws sharing a texture and blend
+// mode become a single draw call. Everything drawn between Begin and End is
+// drawn in order over what is already on screen. It must only be used on
+// the thread owning the GL context
+type Renderer2D struct {
+	shader *shader.Shader
+	vao    uint32
+	vbo    uint32
+	ebo    uint32
+	white  *Texture
+	glyphs map[*font.Page]*glyphTexture
+
+	width      int32
+	height     int32
+	camera     Camera2D
+	projection vecmath.Mat4
+
+	vertices []float32
+	indices  []uint32
+	batches  []batch
+	stats    Stats
+	frame    Stats
+}
+
+// glyphTexture is a font atlas page uploaded to OpenGL
+type glyphTexture struct {
+	texture *Texture
+	version int
+}
+
+// NewRenderer2D creates a 2D renderer with the shaders read from files
+func NewRenderer2D(files fs.FS) (*Renderer2D, error) {
+	program, err := shader.Load("sprite", shaderFiles("sprite.vert", "sprite.frag"), shader.Options{FS: files})
+	if err != nil {
+		return nil, err
+	}
+
+	white := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	white.Pix = []byte{255, 255, 255, 255}
+
+	r := &Renderer2D{
+		shader: program,
+		white:  NewTexture(white, false),
+		glyphs: make(map[*font.Page]*glyphTexture),
+	}
+
+	gl.GenVertexArrays(1, &r.vao)
+	gl.GenBuffers(1, &r.vbo)
+	gl.GenBuffers(1, &r.ebo)
+
+	gl.BindVertexArray(r.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.vbo)
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, r.ebo)
+	stride := int32(floatsPerVertex2D * 4)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointerWithOffset(0, 2, gl.FLOAT, false, stride, 0)
+	gl.EnableVertexAttribArray(1)
+	gl.VertexAttribPointerWithOffset(1, 2, gl.FLOAT, false, stride, 2*4)
+	gl.EnableVertexAttribArray(2)
+	gl.VertexAttribPointerWithOffset(2, 4, gl.FLOAT, false, stride, 4*4)
+	gl.BindVertexArray(0)
+
+	return r, nil
+}
+
+// Shader returns the sprite shader, e.g. to watch it for hot reload
+func (r *Renderer2D) Shader() *shader.Shader {
+	return r.shader
+}
+
+// Begin starts a frame drawn to a viewport of the given size with the
+// screen camera
+func (r *Renderer2D) Begin(width, height int32) {
+	r.width, r.height = width, height
+	r.vertices, r.indices, r.batches = r.vertices[:0], r.indices[:0], r.batches[:0]
+	r.frame = Stats{}
+	r.SetCamera(Camera2D{})
+}
+
+// End draws everything still pending and finishes the frame
+func (r *Renderer2D) End() {
+	r.Flush()
+	r.stats = r.frame
+}
+
+// Size returns the viewport size given to Begin
+func (r *Renderer2D) Size() (width, height int32) {
+	return r.width, r.height
+}
+
+// Stats returns the draw calls and vertices of the last finished frame
+func (r *Renderer2D) Stats() Stats {
+	return r.stats
+}
+
+// Camera returns the current camera
+func (r *Renderer2D) Camera() Camera2D {
+	return r.camera
+}
+
+// SetCamera changes the camera of the following draws
+func (r *Renderer2D) SetCamera(camera Camera2D) {
+	r.camera = camera
+	r.projection = camera.Matrix(float32(r.width), float32(r.height))
+}
+
+// WhiteRegion returns a region of a single white pixel, which draws solid
+// colors in the same batch as shapes
+func (r *Renderer2D) WhiteRegion() Region {
+	return r.white.Region()
+}
+
+// DrawRegion stretches a region over dst, multiplying its colors by tint
+func (r *Renderer2D) DrawRegion(region Region, dst Rect, tint color.RGBA) {
+	u0, v0, u1, v1 := region.uv()
+	r.quad(region.Texture, BlendAlpha, [4]vecmath.Vec2{
+		{dst.X, dst.Y}, {dst.X + dst.W, dst.Y}, {dst.X + dst.W, dst.Y + dst.H}, {dst.X, dst.Y + dst.H},
+	}, u0, v0, u1, v1, tintOf(tint))
+}
+
+// DrawSprite draws a region at its own size, with its origin at (x, y)
+func (r *Renderer2D) DrawSprite(region Region, x, y float32, opts SpriteOptions) {
+	scale := opts.Scale
+	if scale == (vecmath.Vec2{}) {
+		scale = vecmath.Vec2{1, 1}
+	}
+
+	corners := [4]vecmath.Vec2{{0, 0}, {region.W, 0}, {region.W, region.H}, {0, region.H}}
+	sin, cos := math.Sincos(float64(opts.Rotation))
+	for i, c := range corners {
+		c = c.Sub(opts.Origin).MulVec(scale)
+		corners[i] = vecmath.Vec2{
+			x + c[0]*float32(cos) - c[1]*float32(sin),
+			y + c[0]*float32(sin) + c[1]*float32(cos),
+		}
+	}
+
+	tint := opts.Tint
+	if tint == (color.RGBA{}) {
+		tint = White
+	}
+
+	u0, v0, u1, v1 := region.uv()
+	if opts.FlipX {
+		u0, u1 = u1, u0
+	}
+	if opts.FlipY {
+		v0, v1 = v1, v0
+	}
+	r.quad(region.Texture, opts.Blend, corners, u0, v0, u1, v1, tintOf(tint))
+}
+
+// DrawNineSlice draws a panel over dst. Borders shrink when dst is smaller
+// than them
+func (r *Renderer2D) DrawNineSlice(n NineSlice, dst Rect, tint color.RGBA) {
+	left, right := fitBorders(n.Left, n.Right, dst.W)
+	top, bottom := fitBorders(n.Top, n.Bottom, dst.H)
+
+	// Source and destination columns and rows
+	srcX := [4]float32{0, n.Left, n.Region.W - n.Right, n.Region.W}
+	srcY := [4]float32{0, n.Top, n.Region.H - n.Bottom, n.Region.H}
+	dstX := [4]float32{dst.X, dst.X + left, dst.X + dst.W - right, dst.X + dst.W}
+	dstY := [4]float32{dst.Y, dst.Y + top, dst.Y + dst.H - bottom, dst.Y + dst.H}
+
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			w, h := dstX[col+1]-dstX[col], dstY[row+1]-dstY[row]
+			if w <= 0 || h <= 0 {
+				continue
+			}
+			part := n.Region.sub(srcX[col], srcY[row], srcX[col+1]-srcX[col], srcY[row+1]-srcY[row])
+			r.DrawRegion(part, Rect{dstX[col], dstY[row], w, h}, tint)
+		}
+	}
+}
+
+// fitBorders scales two opposite borders down to fit in size
+func fitBorders(a, b, size float32) (float32, float32) {
+	if a+b <= size || a+b == 0 {
+		return a, b
+	}
+	scale := size / (a + b)
+	return a * scale, b * scale
+}
+
+// FillRect fills a rectangle with a color
+func (r *Renderer2D) FillRect(dst Rect, c color.RGBA) {
+	r.DrawRegion(r.WhiteRegion(), dst, c)
+}
+
+// DrawRect outlines a rectangle with lines of the given thickness drawn
+// inside it
+func (r *Renderer2D) DrawRect(dst Rect, thickness float32, c color.RGBA) {
+	thickness = min(thickness, dst.W/2, dst.H/2)
+	r.FillRect(Rect{dst.X, dst.Y, dst.W, thickness}, c)
+	r.FillRect(Rect{dst.X, dst.Y + dst.H - thickness, dst.W, thickness}, c)
+	r.FillRect(Rect{dst.X, dst.Y + thickness, thickness, dst.H - 2*thickness}, c)
+	r.FillRect(Rect{dst.X + dst.W - thickness, dst.Y + thickness, thickness, dst.H - 2*thickness}, c)
+}
+
+// DrawLine draws a line segment of the given thickness
+func (r *Renderer2D) DrawLine(x0, y0, x1, y1, thickness float32, c color.RGBA) {
+	direction := vecmath.Vec2{x1 - x0, y1 - y0}
+	if direction.Len() == 0 {
+		return
+	}
+	normal := vecmath.Vec2{-direction[1], direction[0]}.Normalize().Mul(thickness / 2)
+
+	a, b := vecmath.Vec2{x0, y0}, vecmath.Vec2{x1, y1}
+	r.quad(r.white, BlendAlpha, [4]vecmath.Vec2{
+		a.Add(normal), b.Add(normal), b.Sub(normal), a.Sub(normal),
+	}, 0, 0, 1, 1, tintOf(c))
+}
+
+// circleSegments returns the number of segments approximating a circle
+// closely enough at a radius in pixels
+func circleSegments(radius float32) int {
+	return min(128, max(12, int(radius)))
+}
+
+// FillCircle fills a circle with a color
+func (r *Renderer2D) FillCircle(cx, cy, radius float32, c color.RGBA) {
+	segments := circleSegments(radius)
+	base := r.begin(r.white, BlendAlpha, segments+1, segments*3)
+	tint := tintOf(c)
+
+	r.vertex(cx, cy, 0.5, 0.5, tint)
+	for i := 0; i < segments; i++ {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(segments))
+		r.vertex(cx+radius*float32(cos), cy+radius*float32(sin), 0.5, 0.5, tint)
+		next := uint32((i+1)%segments) + 1
+		r.indices = append(r.indices, base, base+uint32(i)+1, base+next)
+	}
+}
+
+// DrawCircle outlines a circle with a line of the given thickness drawn
+// inside it
+func (r *Renderer2D) DrawCircle(cx, cy, radius, thickness float32, c color.RGBA) {
+	segments := circleSegments(radius)
+	base := r.begin(r.white, BlendAlpha, segments*2, segments*6)
+	tint := tintOf(c)
+	inner := max(0, radius-thickness)
+
+	for i := 0; i < segments; i++ {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(segments))
+		r.vertex(cx+radius*float32(cos), cy+radius*float32(sin), 0.5, 0.5, tint)
+		r.vertex(cx+inner*float32(cos), cy+inner*float32(sin), 0.5, 0.5, tint)
+
+		outer0, inner0 := base+uint32(i*2), base+uint32(i*2)+1
+		next := uint32((i+1)%segments) * 2
+		outer1, inner1 := base+next, base+next+1
+		r.indices = append(r.indices, outer0, outer1, inner1, outer0, inner1, inner0)
+	}
+}
+
+// DrawText draws text with f at (x, y); see font.Font.Layout for the
+// meaning of x
+func (r *Renderer2D) DrawText(f *font.Font, text string, x, y float32, opts font.Options) {
+	quads := f.Layout(text, x, y, opts)
+	if len(quads) == 0 {
+		return
+	}
+	pages := f.Pages(opts.Size)
+
+	if opts.Outline > 0 {
+		for _, offset := range font.OutlineOffsets(opts.Outline) {
+			r.drawGlyphs(quads, pages, offset, opts.OutlineColor)
+		}
+	}
+	r.drawGlyphs(quads, pages, [2]float32{}, opts.Color)
+}
+
+// drawGlyphs draws glyph quads shifted by offset
+func (r *Renderer2D) drawGlyphs(quads []font.Quad, pages []*font.Page, offset [2]float32, c color.RGBA) {
+	for _, q := range quads {
+		region := Region{
+			Texture: r.glyphTexture(pages[q.Page]),
+			X:       float32(q.Src.Min.X),
+			Y:       float32(q.Src.Min.Y),
+			W:       float32(q.Src.Dx()),
+			H:       float32(q.Src.Dy()),
+		}
+		r.DrawRegion(region, Rect{q.X + offset[0], q.Y + offset[1], q.W, q.H}, c)
+	}
+}
+
+// glyphTexture returns the texture of a font page, uploading it when it
+// changed. Pages hold coverage only, which the texture swizzle turns into
+// white with coverage as alpha so glyphs are tinted like any sprite
+func (r *Renderer2D) glyphTexture(page *font.Page) *Texture {
+	g, ok := r.glyphs[page]
+	if !ok {
+		g = &glyphTexture{
+			texture: &Texture{width: font.PageSize, height: font.PageSize, owned: true},
+			version: -1,
+		}
+		gl.GenTextures(1, &g.texture.id)
+		gl.BindTexture(gl.TEXTURE_2D, g.texture.id)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+		swizzle := [4]int32{gl.ONE, gl.ONE, gl.ONE, gl.RED}
+		gl.TexParameteriv(gl.TEXTURE_2D, gl.TEXTURE_SWIZZLE_RGBA, &swizzle[0])
+		r.glyphs[page] = g
+	}
+
+	// Glyphs are only ever added to a page, so draws still pending from
+	// the previous version stay valid after the upload
+	if g.version != page.Version {
+		// Single channel coverage; rows are tightly packed
+		gl.BindTexture(gl.TEXTURE_2D, g.texture.id)
+		gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.R8, font.PageSize, font.PageSize, 0, gl.RED, gl.UNSIGNED_BYTE, gl.Ptr(page.Image.Pix))
+		gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+		gl.BindTexture(gl.TEXTURE_2D, 0)
+		g.version = page.Version
+	}
+
+	return g.texture
+}
+
+// tintOf converts a color to floats
+func tintOf(c color.RGBA) [4]float32 {
+	return [4]float32{float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255, float32(c.A) / 255}
+}
+
+// quad appends a textured quad with corners in clockwise order from the
+// top-left
+func (r *Renderer2D) quad(texture *Texture, blend BlendMode, corners [4]vecmath.Vec2, u0, v0, u1, v1 float32, tint [4]float32) {
+	base := r.begin(texture, blend, 4, 6)
+	r.vertex(corners[0][0], corners[0][1], u0, v0, tint)
+	r.vertex(corners[1][0], corners[1][1], u1, v0, tint)
+	r.vertex(corners[2][0], corners[2][1], u1, v1, tint)
+	r.vertex(corners[3][0], corners[3][1], u0, v1, tint)
+	r.indices = append(r.indices, base, base+1, base+2, base, base+2, base+3)
+}
+
+// vertex appends a vertex
+func (r *Renderer2D) vertex(x, y, u, v float32, tint [4]float32) {
+	r.vertices = append(r.vertices, x, y, u, v, tint[0], tint[1], tint[2], tint[3])
+}
+
+// begin prepares room for a draw and returns the index of its first
+// vertex. The draw joins the last batch when it shares its state
+func (r *Renderer2D) begin(texture *Texture, blend BlendMode, vertices, indices int) uint32 {
+	if n := len(r.batches); n > 0 {
+		last := &r.batches[n-1]
+		if last.texture == texture.id && last.blend == blend && last.projection == r.projection {
+			last.count += indices
+			return uint32(len(r.vertices) / floatsPerVertex2D)
+		}
+	}
+
+	r.batches = append(r.batches, batch{
+		texture:    texture.id,
+		blend:      blend,
+		projection: r.projection,
+		start:      len(r.indices),
+		count:      indices,
+	})
+	return uint32(len(r.vertices) / floatsPerVertex2D)
+}
+
+// Flush draws everything pending. Call it before drawing with other
+// renderers so that their output is layered correctly
+func (r *Renderer2D) Flush() {
+	if len(r.batches) == 0 {
+		return
+	}
+
+	// 2D is drawn on top of everything else
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	cullFace := gl.IsEnabled(gl.CULL_FACE)
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Disable(gl.CULL_FACE)
+
+	r.shader.Use()
+	r.shader.SetSampler("uTexture", 0)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindVertexArray(r.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(r.vertices)*4, gl.Ptr(r.vertices), gl.STREAM_DRAW)
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, r.ebo)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(r.indices)*4, gl.Ptr(r.indices), gl.STREAM_DRAW)
+
+	var projection vecmath.Mat4
+	blend := BlendMode(-1)
+	for i, b := range r.batches {
+		if i == 0 || b.projection != projection {
+			projection = b.projection
+			r.shader.SetMat4("uProjection", projection)
+		}
+		if b.blend != blend {
+			blend = b.blend
+			blend.apply()
+		}
+		gl.BindTexture(gl.TEXTURE_2D, b.texture)
+		gl.DrawElementsWithOffset(gl.TRIANGLES, int32(b.count), gl.UNSIGNED_INT, uintptr(b.start*4))
+	}
+
+	r.frame.DrawCalls += len(r.batches)
+	r.frame.Vertices += len(r.vertices) / floatsPerVertex2D
+	r.vertices, r.indices, r.batches = r.vertices[:0], r.indices[:0], r.batches[:0]
+
+	// Restore the state the rest of the engine expects
+	gl.BindVertexArray(0)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+	if cullFace {
+		gl.Enable(gl.CULL_FACE)
+	}
+}
+
+// Destroy releases the GL resources
+func (r *Renderer2D) Destroy() {
+	for page, g := range r.glyphs {
+		g.texture.Destroy()
+		delete(r.glyphs, page)
+	}
+	r.white.Destroy()
+	gl.DeleteBuffers(1, &r.vbo)
+	gl.DeleteBuffers(1, &r.ebo)
+	gl.DeleteVertexArrays(1, &r.vao)
+	r.shader.Destroy()
+}
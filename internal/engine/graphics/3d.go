@@ -1 +1,530 @@
 package graphics
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/luidsonl/magic-and-blades/internal/engine/mesh"
+	"github.com/luidsonl/magic-and-blades/internal/engine/shader"
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// MaxLights is the number of lights that shade a frame. The renderer keeps
+// directional lights and the point and spot lights closest to the view
+const MaxLights = 16
+
+// FrameBinding is the uniform buffer binding point of the per-frame data
+const FrameBinding = 0
+
+// ShaderDir is the asset directory holding the shaders of the renderers
+const ShaderDir = "shaders"
+
+// Shader includes provided by the renderer, in ShaderDir. Shaders drawing
+// materials include them to read the frame data and to share the lighting
+// code
+const (
+	// FrameInclude declares the Frame uniform block: camera, clip plane,
+	// ambient light, fog and lights
+	FrameInclude = "frame.glsl"
+	// LightingInclude declares shade, which lights a surface with
+	// Blinn-Phong, and applyFog
+	LightingInclude = "lighting.glsl"
+)
+
+// Offsets in floats of the Frame uniform block members
+const (
+	frameShadowOffset = 3*16 + 6*4
+	frameLightsOffset = frameShadowOffset + MaxCascades*16 + 3*4
+	frameFloats       = frameLightsOffset + MaxLights*16
+)
+
+// ShaderInclude resolves the includes provided by the renderer and reads
+// any other include from disk. Pass it as shader.Options.Include
+func ShaderInclude(file string) (string, error) {
+	return ShaderIncludeFS(nil)(file)
+}
+
+// ShaderIncludeFS is ShaderInclude reading every include from fsys, or
+// from disk when nil. The includes provided by the renderer are found in
+// ShaderDir wherever the including shader is
+func ShaderIncludeFS(fsys fs.FS) func(file string) (string, error) {
+	return func(file string) (string, error) {
+		switch name := path.Base(filepath.ToSlash(file)); name {
+		case FrameInclude, LightingInclude:
+			file = path.Join(ShaderDir, name)
+		}
+		var data []byte
+		var err error
+		if fsys != nil {
+			data, err = fs.ReadFile(fsys, filepath.ToSlash(file))
+		} else {
+			data, err = os.ReadFile(filepath.FromSlash(file))
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+// NewMaterialShader loads a shader for materials drawn by the Renderer3D
+// from files read from opts.FS. It can include FrameInclude and
+// LightingInclude, which use the MAX_LIGHTS and MAX_CASCADES defines it
+// adds
+func NewMaterialShader(name string, files shader.Files, opts shader.Options) (*shader.Shader, error) {
+	if opts.Include == nil {
+		opts.Include = ShaderIncludeFS(opts.FS)
+	}
+	defines := map[string]string{
+		"MAX_LIGHTS":   strconv.Itoa(MaxLights),
+		"MAX_CASCADES": strconv.Itoa(MaxCascades),
+	}
+	for k, v := range opts.Defines {
+		defines[k] = v
+	}
+	opts.Defines = defines
+	return shader.Load(name, files, opts)
+}
+
+// shaderFiles returns the paths in ShaderDir of a vertex and a fragment
+// shader
+func shaderFiles(vertex, fragment string) shader.Files {
+	return shader.Files{Vertex: path.Join(ShaderDir, vertex), Fragment: path.Join(ShaderDir, fragment)}
+}
+
+// LightType selects how a light shines
+type LightType int
+
+const (
+	// LightDirectional lights everything from one direction, like the sun
+	LightDirectional LightType = iota
+	// LightPoint shines in every direction from a position, like a torch
+	LightPoint
+	// LightSpot shines in a cone from a position, like a lantern
+	LightSpot
+)
+
+// Light is a light shading the scene
+type Light struct {
+	Type LightType
+	// Position is where point and spot lights are
+	Position vecmath.Vec3
+	// Direction is where directional and spot lights shine towards
+	Direction vecmath.Vec3
+	Color     vecmath.Vec3
+	Intensity float32
+	// Range is the distance at which point and spot lights fade out
+	Range float32
+	// InnerAngle and OuterAngle are the half angles in radians of a spot
+	// light cone. Light fades out between them
+	InnerAngle, OuterAngle float32
+	// CastShadows lights are blocked by opaque items. Only the first
+	// directional light and the closest point and spot lights get shadow
+	// maps, as configured by Renderer3D.SetShadows
+	CastShadows bool
+
+	// shadow is the shadow map slot plus one assigned by RenderShadows
+	shadow int
+}
+
+// FogMode selects how fog thickens with distance
+type FogMode int
+
+const (
+	FogNone FogMode = iota
+	// FogLinear goes from clear at Start to opaque at End
+	FogLinear
+	// FogExponential thickens with the squared distance times Density
+	FogExponential
+)
+
+// Fog hides distant geometry in a color
+type Fog struct {
+	Mode       FogMode
+	Color      vecmath.Vec3
+	Start, End float32
+	Density    float32
+}
+
+// Material describes how a mesh is shaded
+type Material struct {
+	// Shader draws the material (the built-in Blinn-Phong shader when
+	// nil). Custom shaders should be built with NewMaterialShader
+	Shader *shader.Shader
+	// Color multiplies the texture color
+	Color vecmath.Vec4
+	// Texture is a 2D texture sampled with the mesh UVs (none when zero)
+	Texture uint32
+	// Specular and Shininess are the strength and exponent of highlights
+	Specular  float32
+	Shininess float32
+	// Emissive is added to the lit color
+	Emissive vecmath.Vec3
+	// Transparent materials are blended and drawn after opaque ones
+	Transparent bool
+	// DoubleSided materials are drawn without back face culling
+	DoubleSided bool
+	// Unlit materials ignore lights
+	Unlit bool
+	// Bind sets the extra uniforms and textures of custom shaders. Texture
+	// units from 1 up to ShadowUnit are free
+	Bind func(s *shader.Shader)
+}
+
+// Item is a mesh submitted for drawing
+type Item struct {
+	Mesh      *mesh.Mesh
+	Material  *Material
+	Transform vecmath.Mat4
+}
+
+// View is the viewpoint a frame is rendered from
+type View struct {
+	View       vecmath.Mat4
+	Projection vecmath.Mat4
+	// Position is the eye position in world space
+	Position vecmath.Vec3
+	// Clip is the plane shaders write to gl_ClipDistance[0], used while
+	// GL_CLIP_DISTANCE0 is enabled
+	Clip vecmath.Vec4
+	// Time is in seconds, for animated materials
+	Time float32
+}
+
+// RenderStats counts the work of the last Render call
+type RenderStats struct {
+	Items     int
+	Culled    int
+	DrawCalls int
+	// ShadowDrawCalls counts the draws of the last RenderShadows call
+	ShadowDrawCalls int
+}
+
+// drawItem is a visible item with its squared distance from the eye
+type drawItem struct {
+	item     *Item
+	distance float32
+}
+
+// Renderer3D is a forward renderer. Items and lights are submitted between
+// Begin and Render; Render culls the items against the view, draws opaque
+// ones front to back and then transparent ones back to front. Render may
+// be called several times per frame for different views, e.g. for water
+// reflections. It must only be used on the thread owning the GL context
+type Renderer3D struct {
+	// Ambient is the light reaching every surface
+	Ambient vecmath.Vec3
+	Fog     Fog
+
+	shader  *shader.Shader
+	shadows *shadows
+	ubo     uint32
+	// bound holds the version of the shaders whose Frame block was bound,
+	// which changes when a shader is reloaded
+	bound map[*shader.Shader]int
+
+	items       []Item
+	lights      []Light
+	frame       []float32
+	opaque      []drawItem
+	transparent []drawItem
+	stats       RenderStats
+}
+
+// NewRenderer3D creates a 3D renderer with the shaders read from files
+func NewRenderer3D(files fs.FS) (*Renderer3D, error) {
+	program, err := NewMaterialShader("lit", shaderFiles("lit.vert", "lit.frag"), shader.Options{FS: files})
+	if err != nil {
+		return nil, err
+	}
+	shadows, err := newShadows(files)
+	if err != nil {
+		program.Destroy()
+		return nil, err
+	}
+
+	r := &Renderer3D{
+		Ambient: vecmath.Vec3{0.25, 0.25, 0.3},
+		shader:  program,
+		shadows: shadows,
+		bound:   make(map[*shader.Shader]int),
+		frame:   make([]float32, frameFloats),
+	}
+
+	gl.GenBuffers(1, &r.ubo)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, r.ubo)
+	gl.BufferData(gl.UNIFORM_BUFFER, frameFloats*4, nil, gl.DYNAMIC_DRAW)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+
+	return r, nil
+}
+
+// Shaders returns the built-in material and shadow shaders, e.g. to watch
+// them for hot reload
+func (r *Renderer3D) Shaders() []*shader.Shader {
+	return []*shader.Shader{r.shader, r.shadows.cascade, r.shadows.point}
+}
+
+// Begin starts a frame, dropping the items, lights and shadows of the last
+// one
+func (r *Renderer3D) Begin() {
+	clear(r.items)
+	r.items = r.items[:0]
+	r.lights = r.lights[:0]
+	r.shadows.rendered = false
+	r.shadows.cascades = 0
+	r.stats.ShadowDrawCalls = 0
+}
+
+// Submit queues an item for drawing
+func (r *Renderer3D) Submit(item Item) {
+	if item.Mesh == nil || item.Material == nil {
+		return
+	}
+	r.items = append(r.items, item)
+}
+
+// AddLight adds a light to the frame
+func (r *Renderer3D) AddLight(light Light) {
+	r.lights = append(r.lights, light)
+}
+
+// Lights returns the lights of the frame
+func (r *Renderer3D) Lights() []Light {
+	return r.lights
+}
+
+// Stats returns the work done by the last Render call
+func (r *Renderer3D) Stats() RenderStats {
+	return r.stats
+}
+
+// Render draws the submitted items from a view into the bound framebuffer
+func (r *Renderer3D) Render(view View) {
+	viewProjection := view.Projection.Mul(view.View)
+	frustum := vecmath.FrustumFromMatrix(viewProjection)
+	r.stats = RenderStats{Items: len(r.items), ShadowDrawCalls: r.stats.ShadowDrawCalls}
+
+	r.opaque, r.transparent = r.opaque[:0], r.transparent[:0]
+	for i := range r.items {
+		item := &r.items[i]
+		sphere := worldSphere(item.Mesh.BoundingSphere(), item.Transform)
+		if !frustum.IntersectsSphere(sphere) {
+			r.stats.Culled++
+			continue
+		}
+
+		d := drawItem{item: item, distance: sphere.Center.Sub(view.Position).LenSqr()}
+		if item.Material.Transparent {
+			r.transparent = append(r.transparent, d)
+		} else {
+			r.opaque = append(r.opaque, d)
+		}
+	}
+
+	// Opaque items front to back so that hidden fragments fail the depth
+	// test early, transparent ones back to front so they blend correctly
+	sort.Slice(r.opaque, func(i, j int) bool { return r.opaque[i].distance < r.opaque[j].distance })
+	sort.Slice(r.transparent, func(i, j int) bool { return r.transparent[i].distance > r.transparent[j].distance })
+
+	r.uploadFrame(view, viewProjection)
+	r.bindShadowMaps()
+
+	gl.Enable(gl.DEPTH_TEST)
+	gl.DepthFunc(gl.LESS)
+	gl.Enable(gl.CULL_FACE)
+	gl.CullFace(gl.BACK)
+
+	var current *Material
+	gl.Disable(gl.BLEND)
+	for _, d := range r.opaque {
+		current = r.draw(d.item, current)
+	}
+
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.DepthMask(false)
+	for _, d := range r.transparent {
+		current = r.draw(d.item, current)
+	}
+	gl.DepthMask(true)
+
+	gl.Enable(gl.CULL_FACE)
+	gl.BindVertexArray(0)
+}
+
+// worldSphere transforms a bounding sphere, growing it by the largest
+// scale of the transform
+func worldSphere(sphere vecmath.Sphere, transform vecmath.Mat4) vecmath.Sphere {
+	scale := max(
+		vecmath.Vec3{transform[0], transform[1], transform[2]}.Len(),
+		vecmath.Vec3{transform[4], transform[5], transform[6]}.Len(),
+		vecmath.Vec3{transform[8], transform[9], transform[10]}.Len(),
+	)
+	return vecmath.Sphere{Center: transform.MulPoint(sphere.Center), Radius: sphere.Radius * scale}
+}
+
+// uploadFrame fills the Frame uniform block
+func (r *Renderer3D) uploadFrame(view View, viewProjection vecmath.Mat4) {
+	f := r.frame
+	clear(f)
+	copy(f[0:], view.View[:])
+	copy(f[16:], view.Projection[:])
+	copy(f[32:], viewProjection[:])
+	copy(f[48:], []float32{view.Position[0], view.Position[1], view.Position[2], view.Time})
+	copy(f[52:], view.Clip[:])
+	copy(f[56:], r.Ambient[:])
+	copy(f[60:], []float32{r.Fog.Color[0], r.Fog.Color[1], r.Fog.Color[2], float32(r.Fog.Mode)})
+	copy(f[64:], []float32{r.Fog.Start, r.Fog.End, r.Fog.Density, 0})
+
+	lights := r.frameLights(view.Position)
+	f[68] = float32(len(lights))
+
+	s := r.shadows
+	shadow := f[frameShadowOffset:]
+	for i := 0; i < s.cascades; i++ {
+		copy(shadow[i*16:], s.matrices[i][:])
+	}
+	shadow = shadow[MaxCascades*16:]
+	copy(shadow[0:], s.splits[:])
+	copy(shadow[4:], s.texels[:])
+	shadow[8] = float32(s.cascades)
+	shadow[9] = float32(s.settings.Filter)
+	if s.atlas != nil {
+		width, height := s.atlas.Size()
+		shadow[10], shadow[11] = 1/float32(width), 1/float32(height)
+	}
+
+	for i, light := range lights {
+		l := f[frameLightsOffset+i*16:]
+		color := light.Color.Mul(light.Intensity)
+		slot := -1
+		if s.rendered {
+			slot = light.shadow - 1
+		}
+		copy(l[0:], []float32{light.Position[0], light.Position[1], light.Position[2], float32(light.Type)})
+		copy(l[4:], []float32{light.Direction[0], light.Direction[1], light.Direction[2], max(light.Range, 0.001)})
+		copy(l[8:], color[:])
+		copy(l[12:], []float32{float32(math.Cos(float64(light.InnerAngle))), float32(math.Cos(float64(light.OuterAngle))), float32(slot)})
+	}
+
+	gl.BindBuffer(gl.UNIFORM_BUFFER, r.ubo)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, len(f)*4, gl.Ptr(f))
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, FrameBinding, r.ubo)
+}
+
+// frameLights returns at most MaxLights lights: directional lights first,
+// then the closest positioned lights
+func (r *Renderer3D) frameLights(eye vecmath.Vec3) []Light {
+	if len(r.lights) <= MaxLights {
+		return r.lights
+	}
+
+	sort.SliceStable(r.lights, func(i, j int) bool {
+		a, b := r.lights[i], r.lights[j]
+		if (a.Type == LightDirectional) != (b.Type == LightDirectional) {
+			return a.Type == LightDirectional
+		}
+		return a.Position.Sub(eye).LenSqr() < b.Position.Sub(eye).LenSqr()
+	})
+	return r.lights[:MaxLights]
+}
+
+// draw draws an item, switching from the material of the previous item
+func (r *Renderer3D) draw(item *Item, previous *Material) *Material {
+	m := item.Material
+	s := m.Shader
+	if s == nil {
+		s = r.shader
+	}
+
+	if m != previous {
+		if previous == nil || previous.Shader != m.Shader {
+			s.Use()
+			r.bindFrame(s)
+		}
+		r.applyMaterial(s, m)
+		if m.DoubleSided {
+			gl.Disable(gl.CULL_FACE)
+		} else {
+			gl.Enable(gl.CULL_FACE)
+		}
+	}
+
+	s.SetMat4("uModel", item.Transform)
+	s.SetMat3("uNormalMatrix", item.Transform.NormalMatrix())
+	item.Mesh.Draw()
+	r.stats.DrawCalls++
+	return m
+}
+
+// bindFrame connects the Frame block of a shader to the frame buffer and
+// its shadow samplers to the shadow map units
+func (r *Renderer3D) bindFrame(s *shader.Shader) {
+	if r.bound[s] == s.Version() {
+		return
+	}
+	program := s.Program()
+	name := gl.Str("Frame\x00")
+	if index := gl.GetUniformBlockIndex(program, name); index != gl.INVALID_INDEX {
+		gl.UniformBlockBinding(program, index, FrameBinding)
+	}
+	s.SetSampler("uShadowMap", ShadowUnit)
+	for i := 0; i < MaxPointShadows; i++ {
+		s.SetSampler(fmt.Sprintf("uPointShadowMap%d", i), int32(PointShadowUnit+i))
+	}
+	r.bound[s] = s.Version()
+}
+
+// bindShadowMaps binds the shadow maps to their texture units
+func (r *Renderer3D) bindShadowMaps() {
+	s := r.shadows
+	var atlas uint32
+	if s.atlas != nil {
+		atlas = s.atlas.DepthTexture()
+	}
+	gl.ActiveTexture(gl.TEXTURE0 + ShadowUnit)
+	gl.BindTexture(gl.TEXTURE_2D, atlas)
+	for i := 0; i < MaxPointShadows; i++ {
+		var cube uint32
+		if i < len(s.cubes) {
+			cube = s.cubes[i].texture
+		}
+		gl.ActiveTexture(gl.TEXTURE0 + PointShadowUnit + uint32(i))
+		gl.BindTexture(gl.TEXTURE_CUBE_MAP, cube)
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+// applyMaterial sets the standard uniforms of a material
+func (r *Renderer3D) applyMaterial(s *shader.Shader, m *Material) {
+	s.SetVec4("uColor", m.Color)
+	s.SetFloat("uSpecular", m.Specular)
+	s.SetFloat("uShininess", max(m.Shininess, 1))
+	s.SetVec3("uEmissive", m.Emissive)
+	s.SetBool("uUnlit", m.Unlit)
+	s.SetBool("uHasTexture", m.Texture != 0)
+	s.SetSampler("uTexture", 0)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, m.Texture)
+
+	if m.Bind != nil {
+		m.Bind(s)
+		gl.ActiveTexture(gl.TEXTURE0)
+	}
+}
+
+// Destroy releases the GL resources
+func (r *Renderer3D) Destroy() {
+	gl.DeleteBuffers(1, &r.ubo)
+	r.shadows.destroy()
+	r.shader.Destroy()
+}
@@ -0,0 +1,333 @@
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"math"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/luidsonl/magic-and-blades/internal/engine/shader"
+)
+
+// Effect is a set of post-processing effects
+type Effect uint32
+
+const (
+	// EffectToneMapping maps HDR colors to the screen range with the ACES
+	// curve instead of clipping them
+	EffectToneMapping Effect = 1 << iota
+	// EffectBloom makes bright areas such as spells glow
+	EffectBloom
+	// EffectFXAA smooths jagged edges
+	EffectFXAA
+	// EffectColorGrading remaps colors through the LUT
+	EffectColorGrading
+	// EffectVignette darkens the corners of the screen
+	EffectVignette
+	// EffectDamage tints and distorts the screen by the Damage amount, e.g.
+	// when hit or at low health
+	EffectDamage
+
+	// EffectAll enables every effect
+	EffectAll = EffectToneMapping | EffectBloom | EffectFXAA | EffectColorGrading | EffectVignette | EffectDamage
+)
+
+// bloomBlurPasses is the number of horizontal and vertical blur pairs
+// spreading the bloom
+const bloomBlurPasses = 2
+
+// PostProcess renders frames offscreen in HDR and applies full screen
+// effects when presenting them. Rendering between Begin and End goes to
+// the offscreen target; End draws the result to the framebuffer bound
+// before Begin. It must only be used on the thread owning the GL context
+type PostProcess struct {
+	// Effects are the enabled effects. Without any, frames are drawn
+	// directly to the screen
+	Effects Effect
+	// Exposure scales HDR colors before tone mapping
+	Exposure float32
+	// BloomThreshold is the brightness above which colors bloom
+	BloomThreshold float32
+	// BloomIntensity scales the glow added to the frame
+	BloomIntensity float32
+	// Vignette is how dark the corners get, from 0 to 1
+	Vignette float32
+	// Damage is the strength of the damage effect, from 0 to 1
+	Damage float32
+	// LUT is the color grading lookup table, see NewLUT. Color grading is
+	// skipped without one
+	LUT *Texture
+
+	scene   *RenderTarget
+	bloom   [2]*RenderTarget
+	output  *RenderTarget
+	vao     uint32
+	width   int32
+	height  int32
+	active  bool
+	restore uint32
+
+	bright    *shader.Shader
+	blur      *shader.Shader
+	composite *shader.Shader
+	fxaa      *shader.Shader
+}
+
+// NewPostProcess creates the post-processing chain for frames of the given
+// size with every effect enabled. The shaders are read from files
+func NewPostProcess(files fs.FS, width, height int32) (*PostProcess, error) {
+	p := &PostProcess{
+		Effects:        EffectAll,
+		Exposure:       1,
+		BloomThreshold: 1,
+		BloomIntensity: 0.6,
+		Vignette:       0.35,
+	}
+
+	var err error
+	build := func(name, fragment string) *shader.Shader {
+		if err != nil {
+			return nil
+		}
+		var s *shader.Shader
+		s, err = shader.Load(name, shaderFiles("fullscreen.vert", fragment), shader.Options{FS: files})
+		return s
+	}
+	p.bright = build("bloom bright pass", "bloom_bright.frag")
+	p.blur = build("bloom blur", "bloom_blur.frag")
+	p.composite = build("post composite", "composite.frag")
+	p.fxaa = build("fxaa", "fxaa.frag")
+	if err != nil {
+		p.Destroy()
+		return nil, err
+	}
+
+	gl.GenVertexArrays(1, &p.vao)
+
+	if err := p.Resize(width, height); err != nil {
+		p.Destroy()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Shaders returns the shaders of the effects, e.g. to watch them for hot
+// reload
+func (p *PostProcess) Shaders() []*shader.Shader {
+	return []*shader.Shader{p.bright, p.blur, p.composite, p.fxaa}
+}
+
+// Resize adapts the render targets to a new frame size
+func (p *PostProcess) Resize(width, height int32) error {
+	width, height = max(1, width), max(1, height)
+	if p.scene != nil && p.width == width && p.height == height {
+		return nil
+	}
+	p.width, p.height = width, height
+
+	resize := func(target **RenderTarget, w, h int32, format ColorFormat) error {
+		if *target == nil {
+			t, err := NewRenderTarget(w, h, format)
+			if err != nil {
+				return fmt.Errorf("failed to create post-processing target: %v", err)
+			}
+			*target = t
+			return nil
+		}
+		return (*target).Resize(w, h)
+	}
+
+	if err := resize(&p.scene, width, height, ColorRGBA16F); err != nil {
+		return err
+	}
+	for i := range p.bloom {
+		if err := resize(&p.bloom[i], max(1, width/2), max(1, height/2), ColorRGBA16F); err != nil {
+			return err
+		}
+	}
+	return resize(&p.output, width, height, ColorRGBA8)
+}
+
+// Size returns the frame size
+func (p *PostProcess) Size() (width, height int32) {
+	return p.width, p.height
+}
+
+// Begin directs rendering to the HDR target, resized to width by height,
+// and clears it. Without effects rendering stays on the bound framebuffer
+func (p *PostProcess) Begin(width, height int32) {
+	p.active = p.Effects != 0
+	if !p.active {
+		return
+	}
+	if err := p.Resize(width, height); err != nil {
+		p.active = false
+		return
+	}
+
+	p.restore = CurrentFramebuffer()
+	p.scene.Bind()
+	gl.ClearColor(0, 0, 0, 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+}
+
+// End applies the effects to the frame rendered since Begin and draws it to
+// the framebuffer bound before Begin. time drives animated effects
+func (p *PostProcess) End(time float32) {
+	if !p.active {
+		return
+	}
+	p.active = false
+
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Disable(gl.BLEND)
+	gl.Disable(gl.CULL_FACE)
+	gl.BindVertexArray(p.vao)
+
+	bloom := p.Effects&EffectBloom != 0
+	if bloom {
+		p.renderBloom()
+	}
+
+	fxaa := p.Effects&EffectFXAA != 0
+	if fxaa {
+		p.output.Bind()
+	} else {
+		BindFramebuffer(p.restore, p.width, p.height)
+	}
+	p.renderComposite(bloom, time)
+
+	if fxaa {
+		BindFramebuffer(p.restore, p.width, p.height)
+		p.fxaa.Use()
+		p.fxaa.SetSampler("uImage", 0)
+		p.fxaa.SetVec2("uTexel", [2]float32{1 / float32(p.width), 1 / float32(p.height)})
+		bindTexture(0, p.output.ColorTexture())
+		gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	}
+
+	gl.BindVertexArray(0)
+	gl.Enable(gl.DEPTH_TEST)
+	gl.Enable(gl.CULL_FACE)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+}
+
+// renderBloom extracts the bright parts of the scene into the first bloom
+// target at half size and blurs them back and forth between both targets
+func (p *PostProcess) renderBloom() {
+	p.bloom[0].Bind()
+	p.bright.Use()
+	p.bright.SetSampler("uImage", 0)
+	p.bright.SetFloat("uThreshold", p.BloomThreshold)
+	bindTexture(0, p.scene.ColorTexture())
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+
+	width, height := p.bloom[0].Size()
+	p.blur.Use()
+	p.blur.SetSampler("uImage", 0)
+	for i := 0; i < bloomBlurPasses; i++ {
+		p.bloom[1].Bind()
+		p.blur.SetVec2("uDirection", [2]float32{1 / float32(width), 0})
+		bindTexture(0, p.bloom[0].ColorTexture())
+		gl.DrawArrays(gl.TRIANGLES, 0, 3)
+
+		p.bloom[0].Bind()
+		p.blur.SetVec2("uDirection", [2]float32{0, 1 / float32(height)})
+		bindTexture(0, p.bloom[1].ColorTexture())
+		gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	}
+}
+
+// renderComposite combines the scene and the bloom and applies the color
+// effects into the bound framebuffer
+func (p *PostProcess) renderComposite(bloom bool, time float32) {
+	s := p.composite
+	s.Use()
+	s.SetSampler("uScene", 0)
+	s.SetSampler("uBloom", 1)
+	s.SetSampler("uLUT", 2)
+	bindTexture(0, p.scene.ColorTexture())
+	bindTexture(1, p.bloom[0].ColorTexture())
+
+	s.SetBool("uToneMapping", p.Effects&EffectToneMapping != 0)
+	s.SetFloat("uExposure", p.Exposure)
+	s.SetBool("uBloomEnabled", bloom)
+	s.SetFloat("uBloomIntensity", p.BloomIntensity)
+
+	grading := p.Effects&EffectColorGrading != 0 && p.LUT != nil
+	s.SetBool("uGrading", grading)
+	if grading {
+		_, size := p.LUT.Size()
+		s.SetFloat("uLUTSize", float32(size))
+		bindTexture(2, p.LUT.ID())
+	}
+
+	s.SetBool("uVignetteEnabled", p.Effects&EffectVignette != 0)
+	s.SetFloat("uVignette", p.Vignette)
+	damage := float32(0)
+	if p.Effects&EffectDamage != 0 {
+		damage = min(max(p.Damage, 0), 1)
+	}
+	s.SetFloat("uDamage", damage)
+	s.SetFloat("uTime", time)
+
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+// bindTexture binds a 2D texture to a texture unit
+func bindTexture(unit uint32, texture uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + unit)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+}
+
+// Destroy releases the GL resources. The LUT is left alive
+func (p *PostProcess) Destroy() {
+	for _, t := range []*RenderTarget{p.scene, p.bloom[0], p.bloom[1], p.output} {
+		if t != nil {
+			t.Destroy()
+		}
+	}
+	for _, s := range []*shader.Shader{p.bright, p.blur, p.composite, p.fxaa} {
+		if s != nil {
+			s.Destroy()
+		}
+	}
+	if p.vao != 0 {
+		gl.DeleteVertexArrays(1, &p.vao)
+	}
+}
+
+// NewLUT creates a color grading lookup table from a strip of size square
+// slices of size by size pixels, laid out left to right by increasing blue.
+// Red grows to the right within a slice and green downwards
+func NewLUT(img image.Image) (*Texture, error) {
+	bounds := img.Bounds()
+	size := bounds.Dy()
+	if size < 2 || bounds.Dx() != size*size {
+		return nil, fmt.Errorf("invalid LUT size %dx%d: expected a strip of square slices", bounds.Dx(), size)
+	}
+	return NewTexture(img, true), nil
+}
+
+// GenerateLUT builds a LUT strip of the given size for NewLUT, mapping
+// every color through grade. Channels are in [0, 1]
+func GenerateLUT(size int, grade func(r, g, b float64) (float64, float64, float64)) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size*size, size))
+	scale := float64(size - 1)
+	channel := func(v float64) uint8 {
+		return uint8(math.Round(min(max(v, 0), 1) * 255))
+	}
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				gr, gg, gb := grade(float64(r)/scale, float64(g)/scale, float64(b)/scale)
+				img.SetRGBA(b*size+r, g, color.RGBA{R: channel(gr), G: channel(gg), B: channel(gb), A: 255})
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,413 @@
+package graphics
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/luidsonl/magic-and-blades/internal/engine/shader"
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// Shadow limits
+const (
+	// MaxCascades is the number of slices the sun shadow can be split in
+	MaxCascades = 4
+	// MaxPointShadows is the number of point and spot lights that can cast
+	// shadows in a frame
+	MaxPointShadows = 4
+)
+
+// Texture units used by the shadow maps. Materials must not bind textures
+// to them
+const (
+	// ShadowUnit holds the cascaded shadow map of the sun
+	ShadowUnit = 8
+	// PointShadowUnit holds the first cube shadow map, the others follow
+	PointShadowUnit = ShadowUnit + 1
+)
+
+// shadowCasterDistance is how far beyond a cascade towards the sun casters
+// are still rendered, so that mountains shade the valleys behind them
+const shadowCasterDistance = 256
+
+// cascadeSplitBlend mixes logarithmic (1) and uniform (0) cascade splits
+const cascadeSplitBlend = 0.75
+
+// cubeFaces are the view direction and up vector of each face of a cube
+// map, in the order of GL_TEXTURE_CUBE_MAP_POSITIVE_X and following
+var cubeFaces = [6][2]vecmath.Vec3{
+	{{1, 0, 0}, {0, -1, 0}},
+	{{-1, 0, 0}, {0, -1, 0}},
+	{{0, 1, 0}, {0, 0, 1}},
+	{{0, -1, 0}, {0, 0, -1}},
+	{{0, 0, 1}, {0, -1, 0}},
+	{{0, 0, -1}, {0, -1, 0}},
+}
+
+// ShadowSettings configures shadow rendering. The zero value disables
+// shadows
+type ShadowSettings struct {
+	// Resolution is the size in pixels of each sun cascade (no sun shadow
+	// when zero)
+	Resolution int32
+	// Cascades is the number of slices the view is split in for the sun,
+	// from 1 to MaxCascades. Nearby slices get sharper shadows
+	Cascades int
+	// Distance is how far from the camera the sun casts shadows
+	Distance float32
+	// Filter is the radius in texels of the PCF kernel softening shadow
+	// edges (a single hardware filtered sample when zero)
+	Filter int
+	// PointResolution is the size in pixels of the cube map faces of point
+	// and spot lights
+	PointResolution int32
+	// PointLights is the number of point and spot lights casting shadows,
+	// at most MaxPointShadows. The closest lights are picked
+	PointLights int
+}
+
+// cubeShadow is the shadow cube map of a point or spot light
+type cubeShadow struct {
+	fbo     uint32
+	texture uint32
+}
+
+// newCubeShadow creates a cube depth texture with a framebuffer to render
+// its faces
+func newCubeShadow(size int32) (*cubeShadow, error) {
+	c := &cubeShadow{}
+	gl.GenTextures(1, &c.texture)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, c.texture)
+	for face := uint32(0); face < 6; face++ {
+		gl.TexImage2D(gl.TEXTURE_CUBE_MAP_POSITIVE_X+face, 0, gl.DEPTH_COMPONENT24, size, size, 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+	}
+	setShadowSampling(gl.TEXTURE_CUBE_MAP)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, 0)
+
+	gl.GenFramebuffers(1, &c.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, c.fbo)
+	defer gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.DrawBuffer(gl.NONE)
+	gl.ReadBuffer(gl.NONE)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_CUBE_MAP_POSITIVE_X, c.texture, 0)
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		c.destroy()
+		return nil, fmt.Errorf("failed to create point shadow map: framebuffer status 0x%x", status)
+	}
+	return c, nil
+}
+
+// destroy deletes the framebuffer and the texture
+func (c *cubeShadow) destroy() {
+	gl.DeleteFramebuffers(1, &c.fbo)
+	gl.DeleteTextures(1, &c.texture)
+}
+
+// setShadowSampling makes the bound depth texture compare against the
+// reference depth when sampled, with hardware filtering of the results
+func setShadowSampling(target uint32) {
+	gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(target, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(target, gl.TEXTURE_COMPARE_MODE, gl.COMPARE_REF_TO_TEXTURE)
+	gl.TexParameteri(target, gl.TEXTURE_COMPARE_FUNC, gl.LEQUAL)
+}
+
+// shadows holds the shadow maps of a Renderer3D and what the last
+// RenderShadows call rendered into them
+type shadows struct {
+	settings ShadowSettings
+	cascade  *shader.Shader
+	point    *shader.Shader
+
+	// atlas holds the sun cascades side by side
+	atlas *RenderTarget
+	cubes []*cubeShadow
+
+	// Per frame results, uploaded with the Frame block
+	cascades int
+	matrices [MaxCascades]vecmath.Mat4
+	splits   [MaxCascades]float32
+	texels   [MaxCascades]float32
+	rendered bool
+}
+
+// newShadows loads the depth shaders from files. Maps are created by
+// SetShadows
+func newShadows(files fs.FS) (*shadows, error) {
+	cascade, err := shader.Load("shadow", shaderFiles("depth.vert", "shadow.frag"), shader.Options{FS: files})
+	if err != nil {
+		return nil, err
+	}
+	point, err := shader.Load("point shadow", shaderFiles("depth.vert", "point_shadow.frag"), shader.Options{FS: files})
+	if err != nil {
+		cascade.Destroy()
+		return nil, err
+	}
+	return &shadows{cascade: cascade, point: point}, nil
+}
+
+// release deletes the shadow maps
+func (s *shadows) release() {
+	if s.atlas != nil {
+		s.atlas.Destroy()
+		s.atlas = nil
+	}
+	for _, c := range s.cubes {
+		c.destroy()
+	}
+	s.cubes = nil
+}
+
+// destroy deletes the shadow maps and shaders
+func (s *shadows) destroy() {
+	s.release()
+	s.cascade.Destroy()
+	s.point.Destroy()
+}
+
+// SetShadows changes the shadow quality, recreating the shadow maps when
+// their size changes
+func (r *Renderer3D) SetShadows(settings ShadowSettings) error {
+	settings.Cascades = min(max(settings.Cascades, 1), MaxCascades)
+	settings.PointLights = min(max(settings.PointLights, 0), MaxPointShadows)
+	settings.Filter = max(settings.Filter, 0)
+	if settings.PointResolution <= 0 {
+		settings.PointLights = 0
+	}
+	if settings == r.shadows.settings {
+		return nil
+	}
+
+	s := r.shadows
+	s.release()
+	s.settings = ShadowSettings{}
+	s.rendered = false
+
+	if settings.Resolution > 0 {
+		atlas, err := NewRenderTarget(settings.Resolution*int32(settings.Cascades), settings.Resolution, ColorNone)
+		if err != nil {
+			return err
+		}
+		gl.BindTexture(gl.TEXTURE_2D, atlas.DepthTexture())
+		setShadowSampling(gl.TEXTURE_2D)
+		gl.BindTexture(gl.TEXTURE_2D, 0)
+		s.atlas = atlas
+	}
+
+	for i := 0; i < settings.PointLights; i++ {
+		c, err := newCubeShadow(settings.PointResolution)
+		if err != nil {
+			s.release()
+			return err
+		}
+		s.cubes = append(s.cubes, c)
+	}
+
+	s.settings = settings
+	return nil
+}
+
+// ShadowSettings returns the current shadow settings
+func (r *Renderer3D) ShadowSettings() ShadowSettings {
+	return r.shadows.settings
+}
+
+// RenderShadows renders the shadow maps of the submitted lights from the
+// items submitted so far. Call it once per frame after submitting, with the
+// main view: sun cascades are fitted to its frustum and the point lights
+// closest to it cast shadows. Later Render calls of the frame sample the
+// maps, also for other views such as water reflections
+func (r *Renderer3D) RenderShadows(view View) {
+	s := r.shadows
+	s.rendered = true
+	s.cascades = 0
+	for i := range r.lights {
+		r.lights[i].shadow = 0
+	}
+	if s.atlas == nil && len(s.cubes) == 0 {
+		return
+	}
+
+	var framebuffer int32
+	var viewport [4]int32
+	gl.GetIntegerv(gl.FRAMEBUFFER_BINDING, &framebuffer)
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+
+	// Casters are drawn without culling so that thin geometry such as
+	// leaves shades both ways, with an offset against shadow acne
+	gl.Enable(gl.DEPTH_TEST)
+	gl.DepthFunc(gl.LESS)
+	gl.DepthMask(true)
+	gl.Disable(gl.BLEND)
+	gl.Disable(gl.CULL_FACE)
+	gl.Enable(gl.POLYGON_OFFSET_FILL)
+	gl.PolygonOffset(1.5, 2)
+
+	lights := r.frameLights(view.Position)
+	if s.atlas != nil {
+		for i := range lights {
+			if lights[i].Type == LightDirectional && lights[i].CastShadows {
+				r.renderCascades(view, lights[i].Direction)
+				lights[i].shadow = 1
+				break
+			}
+		}
+	}
+
+	slot := 0
+	for i := range lights {
+		if slot == len(s.cubes) {
+			break
+		}
+		if lights[i].Type != LightDirectional && lights[i].CastShadows {
+			r.renderCube(s.cubes[slot], lights[i])
+			slot++
+			lights[i].shadow = slot
+		}
+	}
+
+	gl.Disable(gl.POLYGON_OFFSET_FILL)
+	gl.Enable(gl.CULL_FACE)
+	gl.BindVertexArray(0)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(framebuffer))
+	gl.Viewport(viewport[0], viewport[1], viewport[2], viewport[3])
+}
+
+// renderCascades splits the view frustum along its depth and renders the
+// sun shadow of each slice into its tile of the atlas
+func (r *Renderer3D) renderCascades(view View, direction vecmath.Vec3) {
+	s := r.shadows
+	settings := s.settings
+	count := settings.Cascades
+
+	// The projection stores its near and far planes
+	p := view.Projection
+	near, viewFar := p[14]/(p[10]-1), p[14]/(p[10]+1)
+	far := viewFar
+	if settings.Distance > 0 {
+		far = min(far, settings.Distance)
+	}
+	corners := frustumCorners(view)
+
+	s.atlas.Bind()
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+	s.cascade.Use()
+
+	start := near
+	for i := 0; i < count; i++ {
+		// Practical split scheme: logarithmic splits keep the nearest
+		// slices small, uniform ones keep the farthest from growing huge
+		f := float32(i+1) / float32(count)
+		logSplit := near * float32(math.Pow(float64(far/near), float64(f)))
+		uniformSplit := near + (far-near)*f
+		end := cascadeSplitBlend*logSplit + (1-cascadeSplitBlend)*uniformSplit
+
+		lightViewProjection, texel := fitCascade(view.Position, corners, viewFar, start, end, direction, settings.Resolution)
+		s.splits[i] = end
+		s.texels[i] = texel
+
+		// Map the cascade into its tile: x to [i/count, (i+1)/count],
+		// y and depth to [0, 1]
+		tile := vecmath.Translate(vecmath.Vec3{(0.5 + float32(i)) / float32(count), 0.5, 0.5}).
+			Mul(vecmath.Scale(vecmath.Vec3{0.5 / float32(count), 0.5, 0.5}))
+		s.matrices[i] = tile.Mul(lightViewProjection)
+
+		gl.Viewport(int32(i)*settings.Resolution, 0, settings.Resolution, settings.Resolution)
+		s.cascade.SetMat4("uViewProjection", lightViewProjection)
+		r.drawCasters(s.cascade, vecmath.FrustumFromMatrix(lightViewProjection))
+		start = end
+	}
+	s.cascades = count
+}
+
+// frustumCorners returns the world space corners of the far plane of a
+// view, whose rays from the eye bound the frustum
+func frustumCorners(view View) [4]vecmath.Vec3 {
+	inverse := view.Projection.Mul(view.View).Inverse()
+	var corners [4]vecmath.Vec3
+	for i, ndc := range [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+		corners[i] = inverse.MulPoint(vecmath.Vec3{ndc[0], ndc[1], 1})
+	}
+	return corners
+}
+
+// fitCascade returns the sun view projection covering the frustum slice
+// between the view depths start and end, given the far plane corners at
+// depth far, and the size of a shadow texel in
+// world units. The projection bounds a sphere around the slice, so it does
+// not change as the camera turns, and moves in whole texels, so that shadow
+// edges do not shimmer as the camera moves
+func fitCascade(eye vecmath.Vec3, corners [4]vecmath.Vec3, far, start, end float32, direction vecmath.Vec3, resolution int32) (vecmath.Mat4, float32) {
+	// Points along the rays through the far corners are linear in depth
+	var points [8]vecmath.Vec3
+	var center vecmath.Vec3
+	for i, corner := range corners {
+		ray := corner.Sub(eye)
+		points[i] = eye.Add(ray.Mul(start / far))
+		points[i+4] = eye.Add(ray.Mul(end / far))
+		center = center.Add(points[i]).Add(points[i+4])
+	}
+	center = center.Mul(1.0 / 8)
+
+	var radius float32
+	for _, p := range points {
+		radius = max(radius, p.Sub(center).Len())
+	}
+	// Rounding keeps the size steady despite float noise
+	radius = float32(math.Ceil(float64(radius)*16)) / 16
+	texel := 2 * radius / float32(resolution)
+
+	orientation := vecmath.QuatLookAt(direction, vecmath.Vec3{0, 1, 0})
+	local := orientation.Conjugate().Rotate(center)
+	local[0] = float32(math.Floor(float64(local[0]/texel))) * texel
+	local[1] = float32(math.Floor(float64(local[1]/texel))) * texel
+	center = orientation.Rotate(local)
+
+	lightView := orientation.Conjugate().Mat4().Mul(vecmath.Translate(center.Neg()))
+	projection := vecmath.Ortho(-radius, radius, -radius, radius, -radius-shadowCasterDistance, radius)
+	return projection.Mul(lightView), texel
+}
+
+// renderCube renders the six faces of the shadow of a point or spot light
+func (r *Renderer3D) renderCube(c *cubeShadow, light Light) {
+	size := r.shadows.settings.PointResolution
+	radius := max(light.Range, 0.001)
+	projection := vecmath.Perspective(math.Pi/2, 1, 0.05, radius)
+
+	s := r.shadows.point
+	s.Use()
+	s.SetVec3("uLightPosition", light.Position)
+	s.SetFloat("uLightRange", radius)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, c.fbo)
+	gl.Viewport(0, 0, size, size)
+	for face, axes := range cubeFaces {
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_CUBE_MAP_POSITIVE_X+uint32(face), c.texture, 0)
+		gl.Clear(gl.DEPTH_BUFFER_BIT)
+
+		viewProjection := projection.Mul(vecmath.LookAt(light.Position, light.Position.Add(axes[0]), axes[1]))
+		s.SetMat4("uViewProjection", viewProjection)
+		r.drawCasters(s, vecmath.FrustumFromMatrix(viewProjection))
+	}
+}
+
+// drawCasters draws the opaque items inside frustum with a depth shader
+func (r *Renderer3D) drawCasters(s *shader.Shader, frustum vecmath.Frustum) {
+	for i := range r.items {
+		item := &r.items[i]
+		if item.Material.Transparent {
+			continue
+		}
+		if !frustum.IntersectsSphere(worldSphere(item.Mesh.BoundingSphere(), item.Transform)) {
+			continue
+		}
+		s.SetMat4("uModel", item.Transform)
+		item.Mesh.Draw()
+		r.stats.ShadowDrawCalls++
+	}
+}
@@ -0,0 +1,167 @@
+package graphics
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// ColorFormat selects the color buffer of a render target
+type ColorFormat int
+
+const (
+	// ColorNone creates no color buffer, e.g. for shadow maps
+	ColorNone ColorFormat = iota
+	// ColorRGBA8 stores 8 bits per channel
+	ColorRGBA8
+	// ColorRGBA16F stores half floats per channel, for HDR rendering
+	ColorRGBA16F
+)
+
+// RenderTarget is an offscreen framebuffer whose color and depth buffers
+// are textures that later passes can sample. It must only be used on the
+// thread owning the GL context
+type RenderTarget struct {
+	fbo    uint32
+	color  uint32
+	depth  uint32
+	format ColorFormat
+	width  int32
+	height int32
+}
+
+// NewRenderTarget creates a render target of the given size with a color
+// buffer in format and a depth texture
+func NewRenderTarget(width, height int32, format ColorFormat) (*RenderTarget, error) {
+	t := &RenderTarget{format: format}
+	gl.GenFramebuffers(1, &t.fbo)
+	if err := t.Resize(width, height); err != nil {
+		t.Destroy()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Resize reallocates the buffers for a new size. Contents are lost
+func (t *RenderTarget) Resize(width, height int32) error {
+	width, height = max(1, width), max(1, height)
+	if t.width == width && t.height == height {
+		return nil
+	}
+	t.width, t.height = width, height
+	t.deleteTextures()
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, t.fbo)
+	defer gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	if t.format != ColorNone {
+		internal, kind := int32(gl.RGBA8), uint32(gl.UNSIGNED_BYTE)
+		if t.format == ColorRGBA16F {
+			internal, kind = gl.RGBA16F, gl.HALF_FLOAT
+		}
+		t.color = newTargetTexture(internal, width, height, gl.RGBA, kind)
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, t.color, 0)
+	} else {
+		gl.DrawBuffer(gl.NONE)
+		gl.ReadBuffer(gl.NONE)
+	}
+
+	t.depth = newTargetTexture(gl.DEPTH_COMPONENT24, width, height, gl.DEPTH_COMPONENT, gl.FLOAT)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, t.depth, 0)
+
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		return fmt.Errorf("failed to create render target: framebuffer status 0x%x", status)
+	}
+	return nil
+}
+
+// newTargetTexture allocates a texture for a framebuffer attachment
+func newTargetTexture(internal int32, width, height int32, format, kind uint32) uint32 {
+	var id uint32
+	gl.GenTextures(1, &id)
+	gl.BindTexture(gl.TEXTURE_2D, id)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, internal, width, height, 0, format, kind, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return id
+}
+
+// Bind directs rendering to the target and sets the viewport to its size
+func (t *RenderTarget) Bind() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, t.fbo)
+	gl.Viewport(0, 0, t.width, t.height)
+}
+
+// BindDefault directs rendering back to the window with a viewport of the
+// given size
+func BindDefault(width, height int32) {
+	BindFramebuffer(0, width, height)
+}
+
+// BindFramebuffer directs rendering to a framebuffer object with a viewport
+// of the given size
+func BindFramebuffer(fbo uint32, width, height int32) {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+	gl.Viewport(0, 0, width, height)
+}
+
+// CurrentFramebuffer returns the framebuffer object rendering is directed
+// to, e.g. to restore it after drawing into a render target
+func CurrentFramebuffer() uint32 {
+	var fbo int32
+	gl.GetIntegerv(gl.FRAMEBUFFER_BINDING, &fbo)
+	return uint32(fbo)
+}
+
+// Size returns the size of the target in pixels
+func (t *RenderTarget) Size() (width, height int32) {
+	return t.width, t.height
+}
+
+// ColorTexture returns the color texture, or 0 without a color buffer
+func (t *RenderTarget) ColorTexture() uint32 {
+	return t.color
+}
+
+// DepthTexture returns the depth texture
+func (t *RenderTarget) DepthTexture() uint32 {
+	return t.depth
+}
+
+// Texture returns the color buffer for drawing it with the Renderer2D, or
+// nil without one. It must be fetched again after Resize
+func (t *RenderTarget) Texture() *Texture {
+	if t.color == 0 {
+		return nil
+	}
+	return &Texture{id: t.color, width: t.width, height: t.height, flipped: true}
+}
+
+// Framebuffer returns the GL framebuffer object
+func (t *RenderTarget) Framebuffer() uint32 {
+	return t.fbo
+}
+
+// deleteTextures deletes the attachments
+func (t *RenderTarget) deleteTextures() {
+	if t.color != 0 {
+		gl.DeleteTextures(1, &t.color)
+		t.color = 0
+	}
+	if t.depth != 0 {
+		gl.DeleteTextures(1, &t.depth)
+		t.depth = 0
+	}
+}
+
+// Destroy deletes the framebuffer and its textures
+func (t *RenderTarget) Destroy() {
+	t.deleteTextures()
+	if t.fbo != 0 {
+		gl.DeleteFramebuffers(1, &t.fbo)
+		t.fbo = 0
+	}
+}
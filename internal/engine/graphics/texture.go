@@ -0,0 +1,391 @@
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg" // decoded by LoadImage
+	_ "image/png"  // decoded by LoadImage
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// Anisotropic filtering comes from GL_EXT_texture_filter_anisotropic,
+// which the core 3.3 bindings do not define
+const (
+	textureMaxAnisotropy    = 0x84FE
+	maxTextureMaxAnisotropy = 0x84FF
+)
+
+// TextureFilter selects how a texture is sampled between texels
+type TextureFilter int
+
+const (
+	// FilterNearest picks the closest texel, keeping pixel art sharp
+	FilterNearest TextureFilter = iota
+	// FilterLinear blends the closest texels
+	FilterLinear
+)
+
+// TextureWrap selects how a texture repeats outside [0, 1]
+type TextureWrap int
+
+const (
+	// WrapClamp stretches the edge texels
+	WrapClamp TextureWrap = iota
+	// WrapRepeat tiles the texture
+	WrapRepeat
+	// WrapMirror tiles the texture, mirroring every other copy
+	WrapMirror
+)
+
+// TextureOptions configures how a texture is uploaded and sampled
+type TextureOptions struct {
+	Filter TextureFilter
+	Wrap   TextureWrap
+	// Mipmaps generates smaller versions of the texture for distant
+	// surfaces, unless the file provides them
+	Mipmaps bool
+	// Anisotropy is the maximum anisotropic filtering, which sharpens
+	// mipmapped textures seen at grazing angles. It is clamped to what the
+	// driver supports; 0 and 1 disable it
+	Anisotropy float32
+}
+
+// Texture is a 2D texture that can be drawn by the Renderer2D or sampled
+// by materials
+type Texture struct {
+	id     uint32
+	width  int32
+	height int32
+	// owned textures are deleted by Destroy
+	owned bool
+	// flipped textures store their rows bottom-up, as rendered by GL
+	flipped bool
+}
+
+// NewTexture uploads an image without mipmaps. smooth selects linear
+// filtering instead of nearest, which keeps pixel art sharp
+func NewTexture(img image.Image, smooth bool) *Texture {
+	opts := TextureOptions{Filter: FilterNearest}
+	if smooth {
+		opts.Filter = FilterLinear
+	}
+	return UploadTexture(img, opts)
+}
+
+// UploadTexture uploads an image with the given options. The first row of
+// the image is at texture coordinate v = 0
+func UploadTexture(img image.Image, opts TextureOptions) *Texture {
+	rgba := toRGBA(img)
+	t := &Texture{width: int32(rgba.Rect.Dx()), height: int32(rgba.Rect.Dy()), owned: true}
+	gl.GenTextures(1, &t.id)
+	gl.BindTexture(gl.TEXTURE_2D, t.id)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, t.width, t.height, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+	if opts.Mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+	applyTextureOptions(gl.TEXTURE_2D, opts)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return t
+}
+
+// LoadTexture reads and uploads a texture file of fsys: PNG or JPEG, or
+// KTX and DDS files holding uncompressed or S3TC compressed images with
+// their mipmaps
+func LoadTexture(fsys fs.FS, name string, opts TextureOptions) (*Texture, error) {
+	file, err := ReadTexture(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return file.Upload(opts)
+}
+
+// TextureFile is a decoded texture file waiting to be uploaded. Reading
+// does not need the GL context, so it can happen on any goroutine
+type TextureFile struct {
+	path  string
+	image image.Image
+	data  *textureData
+}
+
+// ReadTexture reads and decodes a texture file of fsys in any format
+// LoadTexture accepts
+func ReadTexture(fsys fs.FS, name string) (*TextureFile, error) {
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case ".ktx", ".dds":
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var data *textureData
+		if ext == ".ktx" {
+			data, err = parseKTX(raw)
+		} else {
+			data, err = parseDDS(raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read texture %s: %v", name, err)
+		}
+		return &TextureFile{path: name, data: data}, nil
+	default:
+		img, err := LoadImage(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		return &TextureFile{path: name, image: img}, nil
+	}
+}
+
+// Upload creates a texture from the file with the given options
+func (f *TextureFile) Upload(opts TextureOptions) (*Texture, error) {
+	if f.image != nil {
+		return UploadTexture(f.image, opts), nil
+	}
+	t, err := uploadTextureData(f.data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload texture %s: %v", f.path, err)
+	}
+	return t, nil
+}
+
+// LoadImage decodes a PNG or JPEG file of fsys
+func LoadImage(fsys fs.FS, name string) (image.Image, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return decodeImage(name, file)
+}
+
+// decodeImage decodes an image read from the file at path
+func decodeImage(path string, r io.Reader) (image.Image, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image %s: %v", path, err)
+	}
+	return img, nil
+}
+
+// uploadTextureData uploads the mipmap levels of a texture file
+func uploadTextureData(data *textureData, opts TextureOptions) (*Texture, error) {
+	if data.compressed() {
+		switch data.internalFormat {
+		case compressedRGBDXT1, compressedRGBADXT1, compressedRGBADXT3, compressedRGBADXT5:
+		default:
+			return nil, fmt.Errorf("unsupported compressed format 0x%x", data.internalFormat)
+		}
+		if !s3tcSupported() {
+			return nil, fmt.Errorf("S3TC compressed textures are not supported by the driver")
+		}
+	}
+
+	t := &Texture{width: data.width, height: data.height, owned: true}
+	gl.GenTextures(1, &t.id)
+	gl.BindTexture(gl.TEXTURE_2D, t.id)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	for level, pixels := range data.levels {
+		w, h := max(1, data.width>>level), max(1, data.height>>level)
+		if data.compressed() {
+			gl.CompressedTexImage2D(gl.TEXTURE_2D, int32(level), data.internalFormat, w, h, 0, int32(len(pixels)), gl.Ptr(pixels))
+		} else {
+			gl.TexImage2D(gl.TEXTURE_2D, int32(level), int32(data.internalFormat), w, h, 0, data.format, data.kind, gl.Ptr(pixels))
+		}
+	}
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+
+	// Use the mipmaps of the file, generating them only when it has none
+	levels := len(data.levels)
+	if opts.Mipmaps && levels == 1 && !data.compressed() {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	} else {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, int32(levels-1))
+		opts.Mipmaps = opts.Mipmaps && levels > 1
+	}
+	applyTextureOptions(gl.TEXTURE_2D, opts)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return t, nil
+}
+
+// WrapTexture wraps a texture owned elsewhere so it can be drawn. Destroy
+// leaves it alive
+func WrapTexture(id uint32, width, height int32) *Texture {
+	return &Texture{id: id, width: width, height: height}
+}
+
+// ID returns the GL texture name
+func (t *Texture) ID() uint32 {
+	return t.id
+}
+
+// Size returns the size of the texture in pixels
+func (t *Texture) Size() (width, height int32) {
+	return t.width, t.height
+}
+
+// Destroy deletes the texture if it is owned by this value
+func (t *Texture) Destroy() {
+	if t.owned && t.id != 0 {
+		gl.DeleteTextures(1, &t.id)
+	}
+	t.id = 0
+}
+
+// TextureArray is a stack of 2D textures of the same size sampled with a
+// layer index, such as the faces of every block type
+type TextureArray struct {
+	id     uint32
+	width  int32
+	height int32
+	layers int32
+}
+
+// NewTextureArray uploads images as the layers of a texture array. Layers
+// whose size differs from the first one are scaled to it
+func NewTextureArray(layers []image.Image, opts TextureOptions) (*TextureArray, error) {
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("failed to create texture array: no layers")
+	}
+
+	size := layers[0].Bounds()
+	a := &TextureArray{width: int32(size.Dx()), height: int32(size.Dy()), layers: int32(len(layers))}
+	gl.GenTextures(1, &a.id)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, a.id)
+	gl.TexImage3D(gl.TEXTURE_2D_ARRAY, 0, gl.RGBA8, a.width, a.height, a.layers, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	for i, img := range layers {
+		rgba := toRGBA(img)
+		if rgba.Rect.Dx() != size.Dx() || rgba.Rect.Dy() != size.Dy() {
+			rgba = scaleNearest(rgba, size.Dx(), size.Dy())
+		}
+		gl.TexSubImage3D(gl.TEXTURE_2D_ARRAY, 0, 0, 0, int32(i), a.width, a.height, 1, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+	}
+	if opts.Mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D_ARRAY)
+	}
+	applyTextureOptions(gl.TEXTURE_2D_ARRAY, opts)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, 0)
+	return a, nil
+}
+
+// ID returns the GL texture name
+func (a *TextureArray) ID() uint32 {
+	return a.id
+}
+
+// Size returns the size of a layer in pixels
+func (a *TextureArray) Size() (width, height int32) {
+	return a.width, a.height
+}
+
+// Layers returns the number of layers
+func (a *TextureArray) Layers() int {
+	return int(a.layers)
+}
+
+// Destroy deletes the texture array
+func (a *TextureArray) Destroy() {
+	if a.id != 0 {
+		gl.DeleteTextures(1, &a.id)
+		a.id = 0
+	}
+}
+
+// toRGBA returns the pixels of an image as tightly packed RGBA
+func toRGBA(img image.Image) *image.RGBA {
+	rgba, ok := img.(*image.RGBA)
+	if ok && rgba.Stride == rgba.Rect.Dx()*4 && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	rgba = image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Rect, img, img.Bounds().Min, draw.Src)
+	return rgba
+}
+
+// scaleNearest resizes an image without blending pixels
+func scaleNearest(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	for y := 0; y < height; y++ {
+		sy := y * sh / height
+		for x := 0; x < width; x++ {
+			sx := x * sw / width
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// applyTextureOptions sets the sampling parameters of the bound texture
+func applyTextureOptions(target uint32, opts TextureOptions) {
+	magFilter := int32(gl.NEAREST)
+	minFilter := int32(gl.NEAREST)
+	switch {
+	case opts.Filter == FilterLinear && opts.Mipmaps:
+		magFilter, minFilter = gl.LINEAR, gl.LINEAR_MIPMAP_LINEAR
+	case opts.Filter == FilterLinear:
+		magFilter, minFilter = gl.LINEAR, gl.LINEAR
+	case opts.Mipmaps:
+		// Blending between mipmaps hides the transition while texels
+		// stay sharp
+		minFilter = gl.NEAREST_MIPMAP_LINEAR
+	}
+	gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, minFilter)
+	gl.TexParameteri(target, gl.TEXTURE_MAG_FILTER, magFilter)
+
+	wrap := int32(gl.CLAMP_TO_EDGE)
+	switch opts.Wrap {
+	case WrapRepeat:
+		wrap = gl.REPEAT
+	case WrapMirror:
+		wrap = gl.MIRRORED_REPEAT
+	}
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_S, wrap)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_T, wrap)
+
+	if opts.Anisotropy > 1 && opts.Mipmaps {
+		if limit := supportedAnisotropy(); limit > 1 {
+			gl.TexParameterf(target, textureMaxAnisotropy, min(opts.Anisotropy, limit))
+		}
+	}
+}
+
+// Driver capabilities, queried on first use
+var (
+	extensions    map[string]bool
+	maxAnisotropy float32
+)
+
+// hasExtension reports whether the driver supports a GL extension
+func hasExtension(name string) bool {
+	if extensions == nil {
+		extensions = make(map[string]bool)
+		var count int32
+		gl.GetIntegerv(gl.NUM_EXTENSIONS, &count)
+		for i := uint32(0); i < uint32(count); i++ {
+			extensions[gl.GoStr(gl.GetStringi(gl.EXTENSIONS, i))] = true
+		}
+	}
+	return extensions[name]
+}
+
+// supportedAnisotropy returns the maximum anisotropy of the driver, or 1
+// without anisotropic filtering
+func supportedAnisotropy() float32 {
+	if maxAnisotropy == 0 {
+		maxAnisotropy = 1
+		if hasExtension("GL_EXT_texture_filter_anisotropic") || hasExtension("GL_ARB_texture_filter_anisotropic") {
+			gl.GetFloatv(maxTextureMaxAnisotropy, &maxAnisotropy)
+		}
+	}
+	return maxAnisotropy
+}
+
+// s3tcSupported reports whether DXT compressed textures can be uploaded
+func s3tcSupported() bool {
+	return hasExtension("GL_EXT_texture_compression_s3tc")
+}
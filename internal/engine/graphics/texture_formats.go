@@ -0,0 +1,196 @@
+package graphics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// S3TC formats from GL_EXT_texture_compression_s3tc, which the core 3.3
+// bindings do not define
+const (
+	compressedRGBDXT1  = 0x83F0
+	compressedRGBADXT1 = 0x83F1
+	compressedRGBADXT3 = 0x83F2
+	compressedRGBADXT5 = 0x83F3
+)
+
+// textureData is a texture read from a KTX or DDS file, with its mipmap
+// levels from the largest
+type textureData struct {
+	width, height  int32
+	internalFormat uint32
+	// format and kind describe uncompressed pixels; kind is zero for
+	// compressed ones
+	format, kind uint32
+	levels       [][]byte
+}
+
+// compressed reports whether the levels hold compressed blocks
+func (d *textureData) compressed() bool {
+	return d.kind == 0
+}
+
+// ktxIdentifier starts every KTX 1 file
+var ktxIdentifier = []byte{0xAB, 'K', 'T', 'X', ' ', '1', '1', 0xBB, '\r', '\n', 0x1A, '\n'}
+
+// ktxHeader follows the identifier of KTX 1 files
+type ktxHeader struct {
+	Endianness            uint32
+	GLType                uint32
+	GLTypeSize            uint32
+	GLFormat              uint32
+	GLInternalFormat      uint32
+	GLBaseInternalFormat  uint32
+	PixelWidth            uint32
+	PixelHeight           uint32
+	PixelDepth            uint32
+	NumberOfArrayElements uint32
+	NumberOfFaces         uint32
+	NumberOfMipmapLevels  uint32
+	BytesOfKeyValueData   uint32
+}
+
+// parseKTX reads a 2D texture from a little endian KTX 1 file
+func parseKTX(data []byte) (*textureData, error) {
+	if !bytes.HasPrefix(data, ktxIdentifier) {
+		return nil, fmt.Errorf("not a KTX 1 file")
+	}
+	r := bytes.NewReader(data[len(ktxIdentifier):])
+
+	var h ktxHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return nil, fmt.Errorf("truncated KTX header")
+	}
+	if h.Endianness != 0x04030201 {
+		return nil, fmt.Errorf("big endian KTX files are not supported")
+	}
+	if h.PixelHeight == 0 || h.PixelDepth > 1 || h.NumberOfArrayElements > 0 || h.NumberOfFaces != 1 {
+		return nil, fmt.Errorf("only 2D KTX textures are supported")
+	}
+
+	t := &textureData{
+		width:          int32(h.PixelWidth),
+		height:         int32(h.PixelHeight),
+		internalFormat: h.GLInternalFormat,
+		format:         h.GLFormat,
+		kind:           h.GLType,
+	}
+	offset := len(data) - r.Len() + int(h.BytesOfKeyValueData)
+	for level := 0; level < max(1, int(h.NumberOfMipmapLevels)); level++ {
+		if offset+4 > len(data) {
+			return nil, fmt.Errorf("truncated KTX level %d", level)
+		}
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		offset += 4
+		if offset+size > len(data) {
+			return nil, fmt.Errorf("truncated KTX level %d", level)
+		}
+		t.levels = append(t.levels, data[offset:offset+size])
+		// Levels are padded to 4 bytes
+		offset += (size + 3) &^ 3
+	}
+	return t, nil
+}
+
+// DDS header flags
+const (
+	ddsMipmapCount = 0x20000
+	ddsFourCC      = 0x4
+	ddsRGB         = 0x40
+	ddsAlphaPixels = 0x1
+)
+
+// ddsHeader follows the "DDS " magic of DDS files
+type ddsHeader struct {
+	Size              uint32
+	Flags             uint32
+	Height            uint32
+	Width             uint32
+	PitchOrLinearSize uint32
+	Depth             uint32
+	MipMapCount       uint32
+	Reserved1         [11]uint32
+	PixelFormat       struct {
+		Size        uint32
+		Flags       uint32
+		FourCC      [4]byte
+		RGBBitCount uint32
+		RBitMask    uint32
+		GBitMask    uint32
+		BBitMask    uint32
+		ABitMask    uint32
+	}
+	Caps      [4]uint32
+	Reserved2 uint32
+}
+
+// parseDDS reads a 2D texture from a DDS file holding DXT1, DXT3 or DXT5
+// blocks or 32 bit RGBA or BGRA pixels
+func parseDDS(data []byte) (*textureData, error) {
+	if !bytes.HasPrefix(data, []byte("DDS ")) {
+		return nil, fmt.Errorf("not a DDS file")
+	}
+	r := bytes.NewReader(data[4:])
+
+	var h ddsHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil || h.Size != 124 {
+		return nil, fmt.Errorf("invalid DDS header")
+	}
+	if h.Width == 0 || h.Height == 0 {
+		return nil, fmt.Errorf("invalid DDS size %dx%d", h.Width, h.Height)
+	}
+
+	t := &textureData{width: int32(h.Width), height: int32(h.Height)}
+	pf := h.PixelFormat
+	blockSize := 0
+	switch {
+	case pf.Flags&ddsFourCC != 0:
+		switch string(pf.FourCC[:]) {
+		case "DXT1":
+			t.internalFormat, blockSize = compressedRGBDXT1, 8
+			if pf.Flags&ddsAlphaPixels != 0 {
+				t.internalFormat = compressedRGBADXT1
+			}
+		case "DXT3":
+			t.internalFormat, blockSize = compressedRGBADXT3, 16
+		case "DXT5":
+			t.internalFormat, blockSize = compressedRGBADXT5, 16
+		default:
+			return nil, fmt.Errorf("unsupported DDS format %q", pf.FourCC[:])
+		}
+	case pf.Flags&ddsRGB != 0 && pf.RGBBitCount == 32:
+		t.internalFormat, t.kind = gl.RGBA8, gl.UNSIGNED_BYTE
+		switch pf.RBitMask {
+		case 0x000000ff:
+			t.format = gl.RGBA
+		case 0x00ff0000:
+			t.format = gl.BGRA
+		default:
+			return nil, fmt.Errorf("unsupported DDS channel layout")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported DDS pixel format")
+	}
+
+	levels := 1
+	if h.Flags&ddsMipmapCount != 0 {
+		levels = max(1, int(h.MipMapCount))
+	}
+	offset := len(data) - r.Len()
+	for level := 0; level < levels; level++ {
+		w, h := max(1, int(t.width)>>level), max(1, int(t.height)>>level)
+		size := w * h * 4
+		if blockSize != 0 {
+			size = ((w + 3) / 4) * ((h + 3) / 4) * blockSize
+		}
+		if offset+size > len(data) {
+			return nil, fmt.Errorf("truncated DDS level %d", level)
+		}
+		t.levels = append(t.levels, data[offset:offset+size])
+		offset += size
+	}
+	return t, nil
+}
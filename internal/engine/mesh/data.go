@@ -0,0 +1,67 @@
+package mesh
+
+import (
+	"math"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// Primitive is the kind of primitive vertices are assembled into
+type Primitive int
+
+const (
+	Triangles Primitive = iota
+	Lines
+	Points
+)
+
+// Data is mesh geometry in main memory. It can be built on any goroutine
+// and uploaded later with Upload on the GL thread
+type Data struct {
+	Layout    Layout
+	Primitive Primitive
+	Vertices  []float32
+	// Indices are optional; without them vertices are drawn in order
+	Indices []uint32
+}
+
+// VertexCount returns the number of vertices
+func (d *Data) VertexCount() int {
+	if d.Layout.Stride() == 0 {
+		return 0
+	}
+	return len(d.Vertices) / int(d.Layout.Stride())
+}
+
+// Bounds returns the bounding box and sphere of the vertex positions
+func (d *Data) Bounds() (vecmath.AABB, vecmath.Sphere) {
+	return bounds(d.Layout, d.Vertices)
+}
+
+// bounds computes the bounding box and sphere of the positions in
+// interleaved vertex data
+func bounds(layout Layout, vertices []float32) (vecmath.AABB, vecmath.Sphere) {
+	box := vecmath.EmptyAABB()
+	offset, ok := layout.Offset(SemanticPosition)
+	stride := int(layout.Stride())
+	if !ok || stride == 0 {
+		return box, vecmath.Sphere{}
+	}
+
+	for i := int(offset); i+2 < len(vertices); i += stride {
+		box = box.Extend(vecmath.Vec3{vertices[i], vertices[i+1], vertices[i+2]})
+	}
+	if box.IsEmpty() {
+		return box, vecmath.Sphere{}
+	}
+
+	// The box center gives a slightly loose but cheap sphere
+	center := box.Center()
+	var radiusSqr float32
+	for i := int(offset); i+2 < len(vertices); i += stride {
+		p := vecmath.Vec3{vertices[i], vertices[i+1], vertices[i+2]}
+		radiusSqr = max(radiusSqr, p.Sub(center).LenSqr())
+	}
+
+	return box, vecmath.Sphere{Center: center, Radius: float32(math.Sqrt(float64(radiusSqr)))}
+}
@@ -0,0 +1,75 @@
+package mesh
+
+// Semantic identifies what a vertex attribute holds
+type Semantic int
+
+const (
+	SemanticPosition Semantic = iota
+	SemanticNormal
+	SemanticUV
+	SemanticColor
+	SemanticTangent
+	// SemanticCustom is any other per-vertex data, e.g. ambient occlusion
+	SemanticCustom
+)
+
+// Attribute describes one vertex attribute. Vertex data is stored as
+// float32, so an attribute is Components consecutive floats
+type Attribute struct {
+	Semantic Semantic
+	// Location is the shader input location (layout (location = N))
+	Location uint32
+	// Components is the number of floats, from 1 to 4
+	Components int32
+}
+
+// Standard attributes and the locations shaders are expected to use
+var (
+	Position = Attribute{Semantic: SemanticPosition, Location: 0, Components: 3}
+	Normal   = Attribute{Semantic: SemanticNormal, Location: 1, Components: 3}
+	UV       = Attribute{Semantic: SemanticUV, Location: 2, Components: 2}
+	Color    = Attribute{Semantic: SemanticColor, Location: 3, Components: 4}
+	Tangent  = Attribute{Semantic: SemanticTangent, Location: 4, Components: 4}
+)
+
+// Layout describes interleaved vertex data
+type Layout struct {
+	Attributes []Attribute
+	// offsets holds the offset in floats of every attribute
+	offsets []int32
+	stride  int32
+}
+
+// NewLayout creates a layout storing the attributes interleaved in the
+// given order
+func NewLayout(attributes ...Attribute) Layout {
+	l := Layout{
+		Attributes: attributes,
+		offsets:    make([]int32, len(attributes)),
+	}
+	for i, attribute := range attributes {
+		l.offsets[i] = l.stride
+		l.stride += attribute.Components
+	}
+	return l
+}
+
+// StandardLayout is position, normal and UV, as produced by the primitive
+// builders
+var StandardLayout = NewLayout(Position, Normal, UV)
+
+// Stride returns the number of floats per vertex
+func (l Layout) Stride() int32 {
+	return l.stride
+}
+
+// Offset returns the offset in floats of the first attribute with the
+// given semantic
+func (l Layout) Offset(semantic Semantic) (int32, bool) {
+	for i, attribute := range l.Attributes {
+		if attribute.Semantic == semantic {
+			return l.offsets[i], true
+		}
+	}
+	return 0, false
+}
@@ -1 +1,245 @@
 package mesh
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// Usage hints how often the buffers of a mesh are rewritten
+type Usage uint32
+
+const (
+	// Static meshes are written once, e.g. props and primitives
+	Static Usage = gl.STATIC_DRAW
+	// Dynamic meshes are rewritten occasionally, e.g. remeshed chunks
+	Dynamic Usage = gl.DYNAMIC_DRAW
+	// Stream meshes are rewritten every frame, e.g. particles
+	Stream Usage = gl.STREAM_DRAW
+)
+
+// Mesh is geometry uploaded to the GPU as a vertex array object with a
+// vertex buffer and an optional index buffer. It must only be used on the
+// thread owning the GL context and released with Release
+type Mesh struct {
+	layout    Layout
+	usage     Usage
+	primitive Primitive
+
+	vao, vbo, ebo uint32
+
+	// Number of vertices and indices drawn and the number the buffers can
+	// hold without being reallocated
+	vertexCount, vertexCapacity int
+	indexCount, indexCapacity   int
+
+	bounds vecmath.AABB
+	sphere vecmath.Sphere
+}
+
+// New creates an empty mesh with the given vertex layout
+func New(layout Layout, primitive Primitive, usage Usage) *Mesh {
+	m := &Mesh{
+		layout:    layout,
+		usage:     usage,
+		primitive: primitive,
+		bounds:    vecmath.EmptyAABB(),
+	}
+
+	gl.GenVertexArrays(1, &m.vao)
+	gl.GenBuffers(1, &m.vbo)
+
+	gl.BindVertexArray(m.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
+	stride := layout.Stride() * 4
+	for i, attribute := range layout.Attributes {
+		gl.EnableVertexAttribArray(attribute.Location)
+		gl.VertexAttribPointerWithOffset(attribute.Location, attribute.Components, gl.FLOAT, false, stride, uintptr(layout.offsets[i]*4))
+	}
+	gl.BindVertexArray(0)
+
+	return m
+}
+
+// Upload creates a mesh from geometry built in main memory
+func Upload(data *Data, usage Usage) *Mesh {
+	m := New(data.Layout, data.Primitive, usage)
+	m.SetVertices(data.Vertices)
+	if len(data.Indices) > 0 {
+		m.SetIndices(data.Indices)
+	}
+	return m
+}
+
+// Layout returns the vertex layout
+func (m *Mesh) Layout() Layout {
+	return m.layout
+}
+
+// VertexCount returns the number of vertices
+func (m *Mesh) VertexCount() int {
+	return m.vertexCount
+}
+
+// IndexCount returns the number of indices, 0 for non-indexed meshes
+func (m *Mesh) IndexCount() int {
+	return m.indexCount
+}
+
+// Bounds returns the bounding box of the vertex positions
+func (m *Mesh) Bounds() vecmath.AABB {
+	return m.bounds
+}
+
+// BoundingSphere returns the bounding sphere of the vertex positions
+func (m *Mesh) BoundingSphere() vecmath.Sphere {
+	return m.sphere
+}
+
+// SetVertices replaces the vertex data. The buffer is only reallocated
+// when it is too small or the mesh is static
+func (m *Mesh) SetVertices(vertices []float32) {
+	count := len(vertices) / int(m.layout.Stride())
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
+	m.vertexCapacity = writeBuffer(gl.ARRAY_BUFFER, m.usage, m.vertexCapacity, count, int(m.layout.Stride())*4, vertices)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+
+	m.vertexCount = count
+	m.bounds, m.sphere = bounds(m.layout, vertices)
+}
+
+// UpdateVertices overwrites vertices starting at vertex index first
+// without reallocating the buffer. The bounds only ever grow on partial
+// updates; call SetVertices to shrink them
+func (m *Mesh) UpdateVertices(first int, vertices []float32) error {
+	stride := int(m.layout.Stride())
+	count := len(vertices) / stride
+	if first < 0 || first+count > m.vertexCount {
+		return fmt.Errorf("vertex update [%d, %d) out of range [0, %d)", first, first+count, m.vertexCount)
+	}
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
+	gl.BufferSubData(gl.ARRAY_BUFFER, first*stride*4, len(vertices)*4, gl.Ptr(vertices))
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+
+	box, _ := bounds(m.layout, vertices)
+	if !box.IsEmpty() {
+		m.bounds = m.bounds.Union(box)
+		center := m.bounds.Center()
+		m.sphere = vecmath.Sphere{Center: center, Radius: m.bounds.Max.Sub(center).Len()}
+	}
+	return nil
+}
+
+// SetIndices replaces the index data, making the mesh indexed. An empty
+// slice makes it non-indexed again
+func (m *Mesh) SetIndices(indices []uint32) {
+	if m.ebo == 0 {
+		gl.GenBuffers(1, &m.ebo)
+		gl.BindVertexArray(m.vao)
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, m.ebo)
+		gl.BindVertexArray(0)
+	}
+
+	// The element buffer binding is part of the vertex array state
+	gl.BindVertexArray(m.vao)
+	m.indexCapacity = writeBuffer(gl.ELEMENT_ARRAY_BUFFER, m.usage, m.indexCapacity, len(indices), 4, indices)
+	gl.BindVertexArray(0)
+
+	m.indexCount = len(indices)
+}
+
+// UpdateIndices overwrites indices starting at index first without
+// reallocating the buffer
+func (m *Mesh) UpdateIndices(first int, indices []uint32) error {
+	if first < 0 || first+len(indices) > m.indexCount {
+		return fmt.Errorf("index update [%d, %d) out of range [0, %d)", first, first+len(indices), m.indexCount)
+	}
+
+	gl.BindVertexArray(m.vao)
+	gl.BufferSubData(gl.ELEMENT_ARRAY_BUFFER, first*4, len(indices)*4, gl.Ptr(indices))
+	gl.BindVertexArray(0)
+	return nil
+}
+
+// Draw draws the whole mesh
+func (m *Mesh) Draw() {
+	if m.indexCount > 0 {
+		m.DrawRange(0, m.indexCount)
+	} else {
+		m.DrawRange(0, m.vertexCount)
+	}
+}
+
+// DrawRange draws count indices (or vertices for non-indexed meshes)
+// starting at first
+func (m *Mesh) DrawRange(first, count int) {
+	if count <= 0 {
+		return
+	}
+
+	gl.BindVertexArray(m.vao)
+	if m.indexCount > 0 {
+		gl.DrawElementsWithOffset(glPrimitive(m.primitive), int32(count), gl.UNSIGNED_INT, uintptr(first*4))
+	} else {
+		gl.DrawArrays(glPrimitive(m.primitive), int32(first), int32(count))
+	}
+	gl.BindVertexArray(0)
+}
+
+// Release deletes the GPU buffers. The mesh must not be drawn afterwards
+func (m *Mesh) Release() {
+	if m.ebo != 0 {
+		gl.DeleteBuffers(1, &m.ebo)
+		m.ebo = 0
+	}
+	if m.vbo != 0 {
+		gl.DeleteBuffers(1, &m.vbo)
+		m.vbo = 0
+	}
+	if m.vao != 0 {
+		gl.DeleteVertexArrays(1, &m.vao)
+		m.vao = 0
+	}
+	m.vertexCount, m.vertexCapacity = 0, 0
+	m.indexCount, m.indexCapacity = 0, 0
+}
+
+// writeBuffer uploads count elements of elementSize bytes to the buffer
+// bound to target and returns its new capacity in elements. Static buffers
+// and buffers that are too small are reallocated; streamed buffers are
+// orphaned first so the driver does not wait for draws still using them
+func writeBuffer[T float32 | uint32](target uint32, usage Usage, capacity, count, elementSize int, data []T) int {
+	var ptr unsafe.Pointer
+	if len(data) > 0 {
+		ptr = gl.Ptr(data)
+	}
+
+	if usage == Static || count > capacity {
+		gl.BufferData(target, count*elementSize, ptr, uint32(usage))
+		return count
+	}
+
+	if usage == Stream {
+		gl.BufferData(target, capacity*elementSize, nil, uint32(usage))
+	}
+	if count > 0 {
+		gl.BufferSubData(target, 0, count*elementSize, ptr)
+	}
+	return capacity
+}
+
+// glPrimitive returns the GL primitive mode
+func glPrimitive(p Primitive) uint32 {
+	switch p {
+	case Lines:
+		return gl.LINES
+	case Points:
+		return gl.POINTS
+	default:
+		return gl.TRIANGLES
+	}
+}
@@ -0,0 +1,132 @@
+package mesh
+
+import "math"
+
+// cubeFaces lists, for every face of a unit cube, its normal and the two
+// axes spanning it, chosen so that triangles wind counter-clockwise when
+// seen from outside
+var cubeFaces = [6]struct {
+	normal, u, v [3]float32
+}{
+	{normal: [3]float32{1, 0, 0}, u: [3]float32{0, 0, -1}, v: [3]float32{0, 1, 0}},
+	{normal: [3]float32{-1, 0, 0}, u: [3]float32{0, 0, 1}, v: [3]float32{0, 1, 0}},
+	{normal: [3]float32{0, 1, 0}, u: [3]float32{1, 0, 0}, v: [3]float32{0, 0, -1}},
+	{normal: [3]float32{0, -1, 0}, u: [3]float32{1, 0, 0}, v: [3]float32{0, 0, 1}},
+	{normal: [3]float32{0, 0, 1}, u: [3]float32{1, 0, 0}, v: [3]float32{0, 1, 0}},
+	{normal: [3]float32{0, 0, -1}, u: [3]float32{-1, 0, 0}, v: [3]float32{0, 1, 0}},
+}
+
+// Cube builds a cube with the given edge length centered on the origin.
+// Every face has its own vertices so normals and UVs are per face
+func Cube(size float32) *Data {
+	d := &Data{Layout: StandardLayout, Primitive: Triangles}
+	h := size / 2
+
+	for _, face := range cubeFaces {
+		base := uint32(d.VertexCount())
+		for _, corner := range [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+			var p [3]float32
+			for axis := 0; axis < 3; axis++ {
+				p[axis] = (face.normal[axis] + corner[0]*face.u[axis] + corner[1]*face.v[axis]) * h
+			}
+			d.Vertices = append(d.Vertices,
+				p[0], p[1], p[2],
+				face.normal[0], face.normal[1], face.normal[2],
+				(corner[0]+1)/2, (corner[1]+1)/2,
+			)
+		}
+		d.Indices = append(d.Indices, base, base+1, base+2, base, base+2, base+3)
+	}
+
+	return d
+}
+
+// Plane builds a horizontal plane facing +Y centered on the origin, split
+// into subdivisions x subdivisions quads
+func Plane(width, depth float32, subdivisions int) *Data {
+	subdivisions = max(subdivisions, 1)
+	d := &Data{Layout: StandardLayout, Primitive: Triangles}
+
+	for z := 0; z <= subdivisions; z++ {
+		for x := 0; x <= subdivisions; x++ {
+			u := float32(x) / float32(subdivisions)
+			v := float32(z) / float32(subdivisions)
+			d.Vertices = append(d.Vertices,
+				(u-0.5)*width, 0, (v-0.5)*depth,
+				0, 1, 0,
+				u, v,
+			)
+		}
+	}
+
+	row := uint32(subdivisions + 1)
+	for z := uint32(0); z < uint32(subdivisions); z++ {
+		for x := uint32(0); x < uint32(subdivisions); x++ {
+			i := z*row + x
+			d.Indices = append(d.Indices, i, i+row, i+row+1, i, i+row+1, i+1)
+		}
+	}
+
+	return d
+}
+
+// Sphere builds a UV sphere centered on the origin with the given number
+// of segments around the Y axis and rings from pole to pole
+func Sphere(radius float32, segments, rings int) *Data {
+	segments = max(segments, 3)
+	rings = max(rings, 2)
+	d := &Data{Layout: StandardLayout, Primitive: Triangles}
+
+	for ring := 0; ring <= rings; ring++ {
+		v := float64(ring) / float64(rings)
+		phi := v * math.Pi
+		for segment := 0; segment <= segments; segment++ {
+			u := float64(segment) / float64(segments)
+			theta := u * 2 * math.Pi
+
+			nx := float32(math.Sin(phi) * math.Cos(theta))
+			ny := float32(math.Cos(phi))
+			nz := float32(-math.Sin(phi) * math.Sin(theta))
+			d.Vertices = append(d.Vertices,
+				nx*radius, ny*radius, nz*radius,
+				nx, ny, nz,
+				float32(u), float32(v),
+			)
+		}
+	}
+
+	row := uint32(segments + 1)
+	for ring := uint32(0); ring < uint32(rings); ring++ {
+		for segment := uint32(0); segment < uint32(segments); segment++ {
+			i := ring*row + segment
+			// The triangles touching the poles would be degenerate
+			if ring != 0 {
+				d.Indices = append(d.Indices, i, i+row, i+1)
+			}
+			if ring != uint32(rings)-1 {
+				d.Indices = append(d.Indices, i+1, i+row, i+row+1)
+			}
+		}
+	}
+
+	return d
+}
+
+// Grid builds a horizontal grid of lines centered on the origin, with
+// divisions cells along each axis, e.g. for editor and debug views. It only
+// has positions
+func Grid(size float32, divisions int) *Data {
+	divisions = max(divisions, 1)
+	d := &Data{Layout: NewLayout(Position), Primitive: Lines}
+	h := size / 2
+
+	for i := 0; i <= divisions; i++ {
+		t := float32(i)/float32(divisions)*size - h
+		d.Vertices = append(d.Vertices,
+			t, 0, -h, t, 0, h,
+			-h, 0, t, h, 0, t,
+		)
+	}
+
+	return d
+}
@@ -1 +1,385 @@
 package objects
+
+import (
+	"fmt"
+	"sync"
+)
+
+// BlockID identifies a block type in a BlockRegistry
+type BlockID uint16
+
+// Air is the empty block, always registered with ID 0
+const Air BlockID = 0
+
+// MaxLight is the strongest light level a block can emit
+const MaxLight = 15
+
+// Face is one of the six faces of a block
+type Face int
+
+const (
+	FaceEast  Face = iota // +X
+	FaceWest              // -X
+	FaceUp                // +Y
+	FaceDown              // -Y
+	FaceSouth             // +Z
+	FaceNorth             // -Z
+)
+
+// Faces lists every face in order
+var Faces = [6]Face{FaceEast, FaceWest, FaceUp, FaceDown, FaceSouth, FaceNorth}
+
+// faceDirections holds the unit offset towards the neighbor of each face
+var faceDirections = [6][3]int{
+	{1, 0, 0}, {-1, 0, 0},
+	{0, 1, 0}, {0, -1, 0},
+	{0, 0, 1}, {0, 0, -1},
+}
+
+// Direction returns the offset from a block to its neighbor across f
+func (f Face) Direction() [3]int {
+	return faceDirections[f]
+}
+
+// Opposite returns the face pointing the other way
+func (f Face) Opposite() Face {
+	return f ^ 1
+}
+
+// Block describes a block type
+type Block struct {
+	ID   BlockID
+	Name string
+	// Solid blocks collide with entities
+	Solid bool
+	// Transparent blocks let light through and do not hide the faces of
+	// their neighbors, e.g. glass, leaves and water
+	Transparent bool
+	// Textures holds the texture name of each face, indexed by Face
+	Textures [6]string
+	// Light is the light level emitted, from 0 to MaxLight
+	Light uint8
+}
+
+// AllFaces returns face textures using the same texture on every face
+func AllFaces(texture string) [6]string {
+	return [6]string{texture, texture, texture, texture, texture, texture}
+}
+
+// TopSideBottom returns face textures with different top and bottom
+// textures, e.g. for grass and logs
+func TopSideBottom(top, side, bottom string) [6]string {
+	return [6]string{side, side, top, bottom, side, side}
+}
+
+// BlockRegistry holds the block types known to the game. Blocks must be
+// registered before the world is generated or loaded, since chunks refer
+// to them by ID
+type BlockRegistry struct {
+	blocks []Block
+	byName map[string]BlockID
+}
+
+// NewBlockRegistry creates a registry containing only air
+func NewBlockRegistry() *BlockRegistry {
+	r := &BlockRegistry{byName: make(map[string]BlockID)}
+	r.blocks = append(r.blocks, Block{ID: Air, Name: "air", Transparent: true})
+	r.byName["air"] = Air
+	return r
+}
+
+// Register adds a block type and returns its ID. The ID field of b is
+// ignored
+func (r *BlockRegistry) Register(b Block) (BlockID, error) {
+	if b.Name == "" {
+		return 0, fmt.Errorf("block has no name")
+	}
+	if _, exists := r.byName[b.Name]; exists {
+		return 0, fmt.Errorf("block %q is already registered", b.Name)
+	}
+	if len(r.blocks) > int(^BlockID(0)) {
+		return 0, fmt.Errorf("too many block types")
+	}
+	if b.Light > MaxLight {
+		b.Light = MaxLight
+	}
+
+	b.ID = BlockID(len(r.blocks))
+	r.blocks = append(r.blocks, b)
+	r.byName[b.Name] = b.ID
+	return b.ID, nil
+}
+
+// Get returns the block type with an ID. Unknown IDs return air
+func (r *BlockRegistry) Get(id BlockID) *Block {
+	if int(id) >= len(r.blocks) {
+		return &r.blocks[Air]
+	}
+	return &r.blocks[id]
+}
+
+// Lookup returns the ID of a block type by name
+func (r *BlockRegistry) Lookup(name string) (BlockID, bool) {
+	id, ok := r.byName[name]
+	return id, ok
+}
+
+// Len returns the number of registered block types, including air
+func (r *BlockRegistry) Len() int {
+	return len(r.blocks)
+}
+
+// TextureNames returns the distinct face textures of every block in
+// registration order. The position of a name is its layer in the block
+// texture array
+func (r *BlockRegistry) TextureNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, b := range r.blocks {
+		for _, name := range b.Textures {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// textureLayers returns the texture layer of every face of every block,
+// indexed by BlockID, matching TextureNames
+func (r *BlockRegistry) textureLayers() [][6]int {
+	index := make(map[string]int)
+	for i, name := range r.TextureNames() {
+		index[name] = i
+	}
+
+	layers := make([][6]int, len(r.blocks))
+	for id, b := range r.blocks {
+		for face, name := range b.Textures {
+			layers[id][face] = index[name]
+		}
+	}
+	return layers
+}
+
+// DefaultBlocks are the block types of the base game
+var DefaultBlocks = []Block{
+	{Name: "stone", Solid: true, Textures: AllFaces("stone")},
+	{Name: "dirt", Solid: true, Textures: AllFaces("dirt")},
+	{Name: "grass", Solid: true, Textures: TopSideBottom("grass_top", "grass_side", "dirt")},
+	{Name: "sand", Solid: true, Textures: AllFaces("sand")},
+	{Name: "gravel", Solid: true, Textures: AllFaces("gravel")},
+	{Name: "snow", Solid: true, Textures: AllFaces("snow")},
+	{Name: "log", Solid: true, Textures: TopSideBottom("log_top", "log_side", "log_top")},
+	{Name: "leaves", Solid: true, Transparent: true, Textures: AllFaces("leaves")},
+	{Name: "planks", Solid: true, Textures: AllFaces("planks")},
+	{Name: "cobblestone", Solid: true, Textures: AllFaces("cobblestone")},
+	{Name: "glass", Solid: true, Transparent: true, Textures: AllFaces("glass")},
+	{Name: "water", Transparent: true, Textures: AllFaces("water")},
+	{Name: "torch", Transparent: true, Textures: AllFaces("torch"), Light: 14},
+	{Name: "glowstone", Solid: true, Textures: AllFaces("glowstone"), Light: MaxLight},
+}
+
+// RegisterDefaultBlocks registers DefaultBlocks
+func (r *BlockRegistry) RegisterDefaultBlocks() error {
+	for _, b := range DefaultBlocks {
+		if _, err := r.Register(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// World is a voxel world made of chunks, addressed by world block
+// coordinates. Missing chunks read as air. Chunks whose blocks (or
+// neighbors' border blocks) changed are tracked as dirty until taken by
+// the mesher. It is safe for concurrent use
+type World struct {
+	registry *BlockRegistry
+
+	mu     sync.RWMutex
+	chunks map[ChunkPos]*Chunk
+	dirty  map[ChunkPos]struct{}
+}
+
+// NewWorld creates an empty world of blocks from registry
+func NewWorld(registry *BlockRegistry) *World {
+	return &World{
+		registry: registry,
+		chunks:   make(map[ChunkPos]*Chunk),
+		dirty:    make(map[ChunkPos]struct{}),
+	}
+}
+
+// Registry returns the block registry of the world
+func (w *World) Registry() *BlockRegistry {
+	return w.registry
+}
+
+// Chunk returns the chunk at pos, or nil when it is not loaded
+func (w *World) Chunk(pos ChunkPos) *Chunk {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.chunks[pos]
+}
+
+// AddChunk adds a generated or loaded chunk, replacing any chunk at the
+// same position. The chunk and its neighbors are marked dirty
+func (w *World) AddChunk(c *Chunk) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.chunks[c.Pos] = c
+	w.markDirtyAround(c.Pos)
+}
+
+// RemoveChunk removes the chunk at pos and returns it, or nil when it was
+// not loaded. Its neighbors are marked dirty since their border faces
+// become visible
+func (w *World) RemoveChunk(pos ChunkPos) *Chunk {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	c, ok := w.chunks[pos]
+	if !ok {
+		return nil
+	}
+	delete(w.chunks, pos)
+	w.markDirtyAround(pos)
+	delete(w.dirty, pos)
+	return c
+}
+
+// Chunks returns the positions of every loaded chunk
+func (w *World) Chunks() []ChunkPos {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	positions := make([]ChunkPos, 0, len(w.chunks))
+	for pos := range w.chunks {
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+// Block returns the block at world coordinates
+func (w *World) Block(x, y, z int) BlockID {
+	pos, lx, ly, lz := ChunkPosOf(x, y, z)
+	c := w.Chunk(pos)
+	if c == nil {
+		return Air
+	}
+	return c.Get(lx, ly, lz)
+}
+
+// Neighbor returns the block next to the block at world coordinates
+// across a face, which may lie in another chunk
+func (w *World) Neighbor(x, y, z int, face Face) BlockID {
+	d := face.Direction()
+	return w.Block(x+d[0], y+d[1], z+d[2])
+}
+
+// SetBlock replaces the block at world coordinates, creating its chunk if
+// needed. The chunk is marked modified and dirty, and the chunks the
+// block touches are marked dirty when it lies on a chunk border
+func (w *World) SetBlock(x, y, z int, id BlockID) {
+	pos, lx, ly, lz := ChunkPosOf(x, y, z)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	c, ok := w.chunks[pos]
+	if !ok {
+		if id == Air {
+			return
+		}
+		c = NewChunk(pos)
+		w.chunks[pos] = c
+	}
+
+	if !c.Set(lx, ly, lz, id) {
+		return
+	}
+	c.setModified(true)
+
+	// Border blocks also affect the faces and ambient occlusion of the
+	// chunks they touch, including diagonal ones
+	var offsets [3][]int32
+	for axis, l := range [3]int{lx, ly, lz} {
+		offsets[axis] = []int32{0}
+		if l == 0 {
+			offsets[axis] = append(offsets[axis], -1)
+		} else if l == ChunkSize-1 {
+			offsets[axis] = append(offsets[axis], 1)
+		}
+	}
+	for _, dx := range offsets[0] {
+		for _, dy := range offsets[1] {
+			for _, dz := range offsets[2] {
+				w.markDirty(ChunkPos{pos.X + dx, pos.Y + dy, pos.Z + dz})
+			}
+		}
+	}
+}
+
+// WaterLevel returns the amount of flowing water in the block at world
+// coordinates, or false when it holds no flowing water
+func (w *World) WaterLevel(x, y, z int) (uint8, bool) {
+	pos, lx, ly, lz := ChunkPosOf(x, y, z)
+	c := w.Chunk(pos)
+	if c == nil {
+		return 0, false
+	}
+	return c.WaterLevel(lx, ly, lz)
+}
+
+// SetWaterLevel sets the amount of flowing water in the block at world
+// coordinates, zero making it a source. The chunk is marked modified so
+// that the level is saved with it
+func (w *World) SetWaterLevel(x, y, z int, level uint8) {
+	pos, lx, ly, lz := ChunkPosOf(x, y, z)
+	c := w.Chunk(pos)
+	if c != nil && c.SetWaterLevel(lx, ly, lz, level) {
+		c.setModified(true)
+	}
+}
+
+// MarkDirty flags a chunk for remeshing
+func (w *World) MarkDirty(pos ChunkPos) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.markDirty(pos)
+}
+
+// TakeDirty returns the dirty chunks and clears the dirty set
+func (w *World) TakeDirty() []ChunkPos {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	positions := make([]ChunkPos, 0, len(w.dirty))
+	for pos := range w.dirty {
+		positions = append(positions, pos)
+	}
+	clear(w.dirty)
+	return positions
+}
+
+// markDirty flags a loaded chunk for remeshing. Callers must hold w.mu
+func (w *World) markDirty(pos ChunkPos) {
+	if _, ok := w.chunks[pos]; ok {
+		w.dirty[pos] = struct{}{}
+	}
+}
+
+// markDirtyAround flags a chunk and the 26 chunks around it. Callers must
+// hold w.mu
+func (w *World) markDirtyAround(pos ChunkPos) {
+	for dx := int32(-1); dx <= 1; dx++ {
+		for dy := int32(-1); dy <= 1; dy++ {
+			for dz := int32(-1); dz <= 1; dz++ {
+				w.markDirty(ChunkPos{pos.X + dx, pos.Y + dy, pos.Z + dz})
+			}
+		}
+	}
+}
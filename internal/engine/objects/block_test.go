@@ -0,0 +1,150 @@
+package objects
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	testStone BlockID = 1
+	testDirt  BlockID = 2
+)
+
+// newLoadedWorld creates a world with empty chunks loaded in the 3x3x3
+// region around the origin chunk and no chunk dirty
+func newLoadedWorld() *World {
+	w := NewWorld(NewBlockRegistry())
+	for x := int32(-1); x <= 1; x++ {
+		for y := int32(-1); y <= 1; y++ {
+			for z := int32(-1); z <= 1; z++ {
+				w.AddChunk(NewChunk(ChunkPos{x, y, z}))
+			}
+		}
+	}
+	w.TakeDirty()
+	return w
+}
+
+// takeDirty returns the dirty chunks of w as a set
+func takeDirty(w *World) map[ChunkPos]bool {
+	dirty := make(map[ChunkPos]bool)
+	for _, pos := range w.TakeDirty() {
+		dirty[pos] = true
+	}
+	return dirty
+}
+
+func TestWorldNeighbor(t *testing.T) {
+	w := NewWorld(NewBlockRegistry())
+	w.SetBlock(15, 0, 0, testStone)
+	w.SetBlock(16, 0, 0, testDirt)
+	w.SetBlock(0, -1, 0, testDirt)
+	w.SetBlock(-1, 5, -17, testStone)
+
+	tests := []struct {
+		name    string
+		x, y, z int
+		face    Face
+		want    BlockID
+	}{
+		{"east across border", 15, 0, 0, FaceEast, testDirt},
+		{"west across border", 16, 0, 0, FaceWest, testStone},
+		{"down across border", 0, 0, 0, FaceDown, testDirt},
+		{"up across border", 0, -1, 0, FaceUp, Air},
+		{"north across negative border", -1, 5, -16, FaceNorth, testStone},
+		{"inside a chunk", 14, 0, 0, FaceEast, testStone},
+		{"missing chunk", 0, 0, 15, FaceSouth, Air},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.Neighbor(tt.x, tt.y, tt.z, tt.face); got != tt.want {
+				t.Errorf("Neighbor(%d, %d, %d, %d) = %d, want %d", tt.x, tt.y, tt.z, tt.face, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorldSetBlockMarksDirty(t *testing.T) {
+	origin := ChunkPos{0, 0, 0}
+	tests := []struct {
+		name    string
+		x, y, z int
+		want    []ChunkPos
+	}{
+		{"inside", 5, 5, 5, []ChunkPos{origin}},
+		{"west face", 0, 5, 5, []ChunkPos{origin, {-1, 0, 0}}},
+		{"top face", 5, 15, 5, []ChunkPos{origin, {0, 1, 0}}},
+		{"edge", 15, 5, 0, []ChunkPos{origin, {1, 0, 0}, {0, 0, -1}, {1, 0, -1}}},
+		{"corner", 0, 0, 0, []ChunkPos{
+			origin, {-1, 0, 0}, {0, -1, 0}, {0, 0, -1},
+			{-1, -1, 0}, {-1, 0, -1}, {0, -1, -1}, {-1, -1, -1},
+		}},
+		{"corner of a negative chunk", -1, -1, -1, []ChunkPos{
+			{-1, -1, -1}, {0, -1, -1}, {-1, 0, -1}, {-1, -1, 0},
+			{0, 0, -1}, {0, -1, 0}, {-1, 0, 0}, origin,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newLoadedWorld()
+			w.SetBlock(tt.x, tt.y, tt.z, testStone)
+
+			want := make(map[ChunkPos]bool)
+			for _, pos := range tt.want {
+				want[pos] = true
+			}
+			if got := takeDirty(w); !reflect.DeepEqual(got, want) {
+				t.Errorf("dirty = %v, want %v", got, want)
+			}
+
+			// Setting the same block again changes nothing
+			w.SetBlock(tt.x, tt.y, tt.z, testStone)
+			if got := takeDirty(w); len(got) != 0 {
+				t.Errorf("unchanged block marked %v dirty", got)
+			}
+		})
+	}
+}
+
+func TestWorldSetBlockSkipsMissingChunks(t *testing.T) {
+	w := NewWorld(NewBlockRegistry())
+
+	// Air in a missing chunk does not create it
+	w.SetBlock(0, 0, 0, Air)
+	if got := len(w.Chunks()); got != 0 {
+		t.Fatalf("air created %d chunks", got)
+	}
+
+	// Neighbors that are not loaded are not marked
+	w.SetBlock(0, 0, 0, testStone)
+	want := map[ChunkPos]bool{{0, 0, 0}: true}
+	if got := takeDirty(w); !reflect.DeepEqual(got, want) {
+		t.Errorf("dirty = %v, want %v", got, want)
+	}
+}
+
+func TestWorldTakeDirty(t *testing.T) {
+	w := newLoadedWorld()
+	pos := ChunkPos{1, 0, 0}
+
+	w.MarkDirty(pos)
+	w.MarkDirty(pos)
+	w.MarkDirty(ChunkPos{5, 5, 5}) // not loaded
+	if got, want := takeDirty(w), map[ChunkPos]bool{pos: true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dirty = %v, want %v", got, want)
+	}
+	if got := w.TakeDirty(); len(got) != 0 {
+		t.Errorf("second TakeDirty = %v, want none", got)
+	}
+
+	// Removing a chunk drops it from the dirty set and marks its neighbors
+	w.MarkDirty(pos)
+	w.RemoveChunk(pos)
+	got := takeDirty(w)
+	if got[pos] {
+		t.Errorf("removed chunk is still dirty")
+	}
+	if !got[ChunkPos{0, 0, 0}] {
+		t.Errorf("neighbor of the removed chunk is not dirty")
+	}
+}
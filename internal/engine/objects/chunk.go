@@ -0,0 +1,313 @@
+package objects
+
+import (
+	"maps"
+	"sync"
+)
+
+// ChunkSize is the edge length of a cubic chunk in blocks
+const ChunkSize = 16
+
+// ChunkVolume is the number of blocks in a chunk
+const ChunkVolume = ChunkSize * ChunkSize * ChunkSize
+
+// ChunkPos is the position of a chunk in chunk units
+type ChunkPos struct {
+	X, Y, Z int32
+}
+
+// Origin returns the world coordinates of the chunk's minimum corner
+func (p ChunkPos) Origin() (x, y, z int) {
+	return int(p.X) * ChunkSize, int(p.Y) * ChunkSize, int(p.Z) * ChunkSize
+}
+
+// Offset returns the neighboring chunk position across a face
+func (p ChunkPos) Offset(face Face) ChunkPos {
+	d := face.Direction()
+	return ChunkPos{p.X + int32(d[0]), p.Y + int32(d[1]), p.Z + int32(d[2])}
+}
+
+// ChunkPosOf returns the chunk containing the block at world coordinates
+// and the block's coordinates inside it
+func ChunkPosOf(x, y, z int) (ChunkPos, int, int, int) {
+	cx, lx := floorDiv(x)
+	cy, ly := floorDiv(y)
+	cz, lz := floorDiv(z)
+	return ChunkPos{int32(cx), int32(cy), int32(cz)}, lx, ly, lz
+}
+
+// floorDiv splits a world coordinate into chunk and local coordinates,
+// rounding towards negative infinity
+func floorDiv(v int) (int, int) {
+	q, r := v/ChunkSize, v%ChunkSize
+	if r < 0 {
+		q--
+		r += ChunkSize
+	}
+	return q, r
+}
+
+// Chunk stores the blocks of a cubic region. Blocks are stored as indices
+// into a palette of the block IDs present in the chunk, packed with as few
+// bits per block as the palette needs: a chunk of a single block type
+// (e.g. all air) stores no indices at all. It is safe for concurrent use
+type Chunk struct {
+	Pos ChunkPos
+
+	mu sync.RWMutex
+	// palette maps indices to block IDs; counts holds how many blocks use
+	// each entry so that unused entries can be reused
+	palette []BlockID
+	counts  []int
+	// bits is the width of a packed index: 0, 1, 2, 4, 8 or 16
+	bits int
+	data []uint64
+	// nonAir counts the blocks that are not air
+	nonAir int
+	// modified is set when the player changed the chunk since it was
+	// generated or loaded, so it must be saved when unloaded
+	modified bool
+	// levels holds the amount of water of the flowing water blocks, by
+	// storage index. Water blocks without a level are sources
+	levels map[int]uint8
+}
+
+// NewChunk creates a chunk filled with air
+func NewChunk(pos ChunkPos) *Chunk {
+	return &Chunk{
+		Pos:     pos,
+		palette: []BlockID{Air},
+		counts:  []int{ChunkVolume},
+	}
+}
+
+// chunkIndex returns the storage index of local coordinates
+func chunkIndex(x, y, z int) int {
+	return (y*ChunkSize+z)*ChunkSize + x
+}
+
+// Get returns the block at local coordinates in [0, ChunkSize)
+func (c *Chunk) Get(x, y, z int) BlockID {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.palette[c.index(chunkIndex(x, y, z))]
+}
+
+// Set replaces the block at local coordinates and reports whether it
+// changed
+func (c *Chunk) Set(x, y, z int, id BlockID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := chunkIndex(x, y, z)
+	old := c.index(i)
+	previous := c.palette[old]
+	if previous == id {
+		return false
+	}
+
+	entry := c.paletteEntry(id)
+	c.counts[old]--
+	c.counts[entry]++
+	c.setIndex(i, entry)
+
+	if previous == Air {
+		c.nonAir++
+	} else if id == Air {
+		c.nonAir--
+	}
+	delete(c.levels, i)
+	return true
+}
+
+// Fill sets every block of the chunk to id
+func (c *Chunk) Fill(id BlockID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.palette = []BlockID{id}
+	c.counts = []int{ChunkVolume}
+	c.bits = 0
+	c.data = nil
+	c.nonAir = 0
+	if id != Air {
+		c.nonAir = ChunkVolume
+	}
+	c.levels = nil
+}
+
+// WaterLevel returns the amount of flowing water in the block at local
+// coordinates, or false when the block holds no flowing water
+func (c *Chunk) WaterLevel(x, y, z int) (uint8, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	level, ok := c.levels[chunkIndex(x, y, z)]
+	return level, ok
+}
+
+// SetWaterLevel sets the amount of flowing water in the block at local
+// coordinates, which is forgotten when the block is replaced. Zero makes
+// the block a source again. It reports whether the level changed
+func (c *Chunk) SetWaterLevel(x, y, z int, level uint8) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := chunkIndex(x, y, z)
+	if previous, ok := c.levels[i]; ok && previous == level || !ok && level == 0 {
+		return false
+	}
+	if level == 0 {
+		delete(c.levels, i)
+		return true
+	}
+	if c.levels == nil {
+		c.levels = make(map[int]uint8)
+	}
+	c.levels[i] = level
+	return true
+}
+
+// waterLevels copies the levels of the flowing water blocks, by storage
+// index
+func (c *Chunk) waterLevels() map[int]uint8 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.levels)
+}
+
+// IsEmpty reports whether the chunk only contains air
+func (c *Chunk) IsEmpty() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nonAir == 0
+}
+
+// Modified reports whether the chunk was edited through World.SetBlock
+// since it was generated, loaded or last saved
+func (c *Chunk) Modified() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.modified
+}
+
+// setModified sets the modified flag
+func (c *Chunk) setModified(modified bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.modified = modified
+}
+
+// Blocks copies every block into dst, indexed by (y*ChunkSize+z)*ChunkSize+x.
+// Meshing works on such a copy so it does not hold the chunk lock
+func (c *Chunk) Blocks(dst *[ChunkVolume]BlockID) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.bits == 0 {
+		for i := range dst {
+			dst[i] = c.palette[0]
+		}
+		return
+	}
+	for i := range dst {
+		dst[i] = c.palette[c.index(i)]
+	}
+}
+
+// Compact drops unused palette entries, shrinking the indices when
+// possible. It is worth calling after many blocks were replaced
+func (c *Chunk) Compact() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var blocks [ChunkVolume]BlockID
+	for i := range blocks {
+		blocks[i] = c.palette[c.index(i)]
+	}
+
+	c.palette = c.palette[:0]
+	c.counts = c.counts[:0]
+	c.bits = 0
+	c.data = nil
+	for i, id := range blocks {
+		if i == 0 {
+			c.palette = append(c.palette, id)
+			c.counts = append(c.counts, 0)
+		}
+		entry := c.paletteEntry(id)
+		c.counts[entry]++
+		if c.bits > 0 {
+			c.setIndex(i, entry)
+		}
+	}
+}
+
+// paletteEntry returns the palette index of id, adding it and widening
+// the packed indices if needed
+func (c *Chunk) paletteEntry(id BlockID) int {
+	free := -1
+	for i, entry := range c.palette {
+		if entry == id {
+			return i
+		}
+		if free < 0 && c.counts[i] == 0 {
+			free = i
+		}
+	}
+
+	if free >= 0 {
+		c.palette[free] = id
+		return free
+	}
+
+	c.palette = append(c.palette, id)
+	c.counts = append(c.counts, 0)
+	if len(c.palette) > 1<<c.bits {
+		c.resize(bitsFor(len(c.palette)))
+	}
+	return len(c.palette) - 1
+}
+
+// resize repacks the indices with a new width
+func (c *Chunk) resize(bits int) {
+	oldData, oldBits := c.data, c.bits
+	c.bits = bits
+	c.data = make([]uint64, ChunkVolume*bits/64)
+	for i := 0; i < ChunkVolume; i++ {
+		c.setIndex(i, packedIndex(oldData, oldBits, i))
+	}
+}
+
+// index returns the palette index stored for a block
+func (c *Chunk) index(i int) int {
+	return packedIndex(c.data, c.bits, i)
+}
+
+// setIndex stores the palette index of a block
+func (c *Chunk) setIndex(i, entry int) {
+	bit := i * c.bits
+	word, shift := bit/64, bit%64
+	mask := uint64(1<<c.bits-1) << shift
+	c.data[word] = c.data[word]&^mask | uint64(entry)<<shift
+}
+
+// packedIndex reads the i-th index of the given width from data
+func packedIndex(data []uint64, bits, i int) int {
+	if bits == 0 {
+		return 0
+	}
+	bit := i * bits
+	word, shift := bit/64, bit%64
+	return int(data[word]>>shift) & (1<<bits - 1)
+}
+
+// bitsFor returns the smallest supported index width for a palette size.
+// Widths divide 64 so indices never straddle two words
+func bitsFor(size int) int {
+	for _, bits := range []int{1, 2, 4, 8} {
+		if size <= 1<<bits {
+			return bits
+		}
+	}
+	return 16
+}
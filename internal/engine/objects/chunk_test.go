@@ -0,0 +1,170 @@
+package objects
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// checkChunk compares every block of c with the reference array and checks
+// the palette bookkeeping
+func checkChunk(t *testing.T, c *Chunk, want *[ChunkVolume]BlockID) {
+	t.Helper()
+	nonAir := 0
+	for y := 0; y < ChunkSize; y++ {
+		for z := 0; z < ChunkSize; z++ {
+			for x := 0; x < ChunkSize; x++ {
+				id := want[chunkIndex(x, y, z)]
+				if got := c.Get(x, y, z); got != id {
+					t.Fatalf("block (%d, %d, %d) = %d, want %d", x, y, z, got, id)
+				}
+				if id != Air {
+					nonAir++
+				}
+			}
+		}
+	}
+
+	var blocks [ChunkVolume]BlockID
+	c.Blocks(&blocks)
+	if blocks != *want {
+		t.Fatalf("Blocks differs from the reference")
+	}
+	if got := c.IsEmpty(); got != (nonAir == 0) {
+		t.Errorf("IsEmpty = %v with %d non-air blocks", got, nonAir)
+	}
+
+	counts := make(map[BlockID]int)
+	for _, id := range want {
+		counts[id]++
+	}
+	total := 0
+	for i, id := range c.palette {
+		if c.counts[i] == 0 {
+			continue
+		}
+		if c.counts[i] != counts[id] {
+			t.Errorf("count of block %d = %d, want %d", id, c.counts[i], counts[id])
+		}
+		total += c.counts[i]
+	}
+	if total != ChunkVolume {
+		t.Errorf("palette counts add up to %d, want %d", total, ChunkVolume)
+	}
+	if len(c.palette) > 1<<c.bits {
+		t.Errorf("palette of %d entries does not fit %d bits", len(c.palette), c.bits)
+	}
+}
+
+func TestChunkSetAndCompact(t *testing.T) {
+	tests := []struct {
+		name string
+		// kinds is the number of block types set
+		kinds int
+		sets  int
+	}{
+		{"two kinds", 2, 500},
+		{"few kinds", 3, 2000},
+		{"byte wide", 200, 5000},
+		{"two bytes wide", 1000, 20000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(int64(tt.kinds)))
+			c := NewChunk(ChunkPos{1, -2, 3})
+			var want [ChunkVolume]BlockID
+
+			for round := 0; round < 3; round++ {
+				for i := 0; i < tt.sets; i++ {
+					x, y, z := rng.Intn(ChunkSize), rng.Intn(ChunkSize), rng.Intn(ChunkSize)
+					id := BlockID(rng.Intn(tt.kinds))
+					changed := c.Set(x, y, z, id)
+					if wantChanged := want[chunkIndex(x, y, z)] != id; changed != wantChanged {
+						t.Fatalf("Set(%d, %d, %d, %d) = %v, want %v", x, y, z, id, changed, wantChanged)
+					}
+					want[chunkIndex(x, y, z)] = id
+				}
+				checkChunk(t, c, &want)
+
+				c.Compact()
+				checkChunk(t, c, &want)
+
+				// Clear most blocks so that the next compaction shrinks
+				// the indices
+				for i := range want {
+					if rng.Intn(8) != 0 {
+						x, z, y := i%ChunkSize, i/ChunkSize%ChunkSize, i/(ChunkSize*ChunkSize)
+						c.Set(x, y, z, Air)
+						want[i] = Air
+					}
+				}
+				checkChunk(t, c, &want)
+			}
+
+			c.Compact()
+			checkChunk(t, c, &want)
+		})
+	}
+}
+
+func TestChunkCompactShrinks(t *testing.T) {
+	c := NewChunk(ChunkPos{})
+	for id := BlockID(1); id <= 20; id++ {
+		c.Set(int(id)%ChunkSize, int(id)/ChunkSize, 0, id)
+	}
+	if c.bits != 8 {
+		t.Fatalf("bits with 21 block types = %d, want 8", c.bits)
+	}
+
+	for id := BlockID(2); id <= 20; id++ {
+		c.Set(int(id)%ChunkSize, int(id)/ChunkSize, 0, Air)
+	}
+	c.Compact()
+	if len(c.palette) != 2 || c.bits != 1 {
+		t.Errorf("after compaction: %d palette entries of %d bits, want 2 of 1", len(c.palette), c.bits)
+	}
+	if got := c.Get(1, 0, 0); got != 1 {
+		t.Errorf("block kept = %d, want 1", got)
+	}
+
+	c.Set(1, 0, 0, Air)
+	c.Compact()
+	if len(c.palette) != 1 || c.bits != 0 || c.data != nil {
+		t.Errorf("all-air chunk keeps %d palette entries of %d bits", len(c.palette), c.bits)
+	}
+	if !c.IsEmpty() {
+		t.Errorf("all-air chunk is not empty")
+	}
+}
+
+func TestChunkReusesFreeEntries(t *testing.T) {
+	c := NewChunk(ChunkPos{})
+	c.Set(0, 0, 0, 1)
+	c.Set(1, 0, 0, 2)
+	bits := c.bits
+
+	// Block 1 is no longer used, so block 3 takes its entry
+	c.Set(0, 0, 0, Air)
+	c.Set(2, 0, 0, 3)
+	if len(c.palette) != 3 || c.bits != bits {
+		t.Errorf("palette = %v with %d bits, want 3 entries with %d bits", c.palette, c.bits, bits)
+	}
+	if c.Get(2, 0, 0) != 3 || c.Get(1, 0, 0) != 2 || c.Get(0, 0, 0) != Air {
+		t.Errorf("blocks changed when reusing a palette entry")
+	}
+}
+
+func TestChunkFill(t *testing.T) {
+	c := NewChunk(ChunkPos{})
+	c.Set(3, 4, 5, 7)
+	c.Fill(2)
+
+	var want [ChunkVolume]BlockID
+	for i := range want {
+		want[i] = 2
+	}
+	checkChunk(t, c, &want)
+
+	c.Set(3, 4, 5, Air)
+	want[chunkIndex(3, 4, 5)] = Air
+	checkChunk(t, c, &want)
+}
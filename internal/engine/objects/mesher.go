@@ -0,0 +1,529 @@
+package objects
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/mesh"
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// ChunkLayout is the vertex layout of chunk meshes: position relative to
+// the chunk origin, normal, UV in blocks (textures repeat across merged
+// faces) and a custom attribute holding the texture array layer and the
+// vertex brightness from face lighting and ambient occlusion
+var ChunkLayout = mesh.NewLayout(mesh.Position, mesh.Normal, mesh.UV, ChunkAttribute)
+
+// ChunkAttribute is the texture layer and brightness attribute of chunk
+// meshes
+var ChunkAttribute = mesh.Attribute{Semantic: mesh.SemanticCustom, Location: 5, Components: 2}
+
+// faceShade is the brightness of each face, so that shapes read well
+// before any dynamic lighting is applied
+var faceShade = [6]float32{0.8, 0.8, 1.0, 0.5, 0.65, 0.65}
+
+// aoCurve maps the number of unoccluded neighbors of a vertex (0-3) to a
+// brightness factor
+var aoCurve = [4]float32{0.45, 0.65, 0.85, 1.0}
+
+// ChunkMeshData is the geometry of a chunk built by the mesher. Opaque and
+// transparent faces are separate so transparent ones can be drawn after
+// and sorted. Either is nil when the chunk has no such faces
+type ChunkMeshData struct {
+	Pos         ChunkPos
+	Opaque      *mesh.Data
+	Transparent *mesh.Data
+
+	generation uint64
+}
+
+// blockInfo is what the mesher needs to know about a block type
+type blockInfo struct {
+	opaque      bool
+	transparent bool
+	layers      [6]float32
+	light       uint8
+}
+
+// meshTable holds the blockInfo of every registered block
+type meshTable []blockInfo
+
+// newMeshTable builds the table for the blocks currently registered
+func newMeshTable(registry *BlockRegistry) meshTable {
+	layers := registry.textureLayers()
+	table := make(meshTable, registry.Len())
+	for i := range table {
+		b := registry.Get(BlockID(i))
+		info := blockInfo{
+			opaque:      BlockID(i) != Air && !b.Transparent,
+			transparent: BlockID(i) != Air && b.Transparent,
+			light:       b.Light,
+		}
+		for face, layer := range layers[i] {
+			info.layers[face] = float32(layer)
+		}
+		table[i] = info
+	}
+	return table
+}
+
+// get returns the info of a block, treating unknown IDs as air
+func (t meshTable) get(id BlockID) blockInfo {
+	if int(id) >= len(t) {
+		return blockInfo{}
+	}
+	return t[id]
+}
+
+// paddedSize is the edge of a chunk plus a one block border
+const paddedSize = ChunkSize + 2
+
+// paddedBlocks holds a chunk and the border of blocks around it, which
+// decide face visibility and ambient occlusion at the chunk edges
+type paddedBlocks [paddedSize * paddedSize * paddedSize]BlockID
+
+// at returns the block at chunk-local coordinates in [-1, ChunkSize]
+func (p *paddedBlocks) at(x, y, z int) BlockID {
+	return p[((y+1)*paddedSize+z+1)*paddedSize+x+1]
+}
+
+// gatherBlocks copies a chunk and its border from the world. It reports
+// false when the chunk is not loaded
+func gatherBlocks(world *World, pos ChunkPos, dst *paddedBlocks) bool {
+	c := world.Chunk(pos)
+	if c == nil {
+		return false
+	}
+
+	var inner [ChunkVolume]BlockID
+	c.Blocks(&inner)
+
+	ox, oy, oz := pos.Origin()
+	for y := -1; y <= ChunkSize; y++ {
+		for z := -1; z <= ChunkSize; z++ {
+			for x := -1; x <= ChunkSize; x++ {
+				i := ((y+1)*paddedSize+z+1)*paddedSize + x + 1
+				if x >= 0 && x < ChunkSize && y >= 0 && y < ChunkSize && z >= 0 && z < ChunkSize {
+					dst[i] = inner[chunkIndex(x, y, z)]
+				} else {
+					dst[i] = world.Block(ox+x, oy+y, oz+z)
+				}
+			}
+		}
+	}
+	return true
+}
+
+// faceKey describes a visible block face. Adjacent faces with equal keys
+// are merged into one quad
+type faceKey struct {
+	visible     bool
+	transparent bool
+	layer       float32
+	light       uint8
+	ao          [4]uint8
+}
+
+// MeshChunk builds the geometry of the chunk at pos. Hidden faces are
+// culled and coplanar faces with the same texture and lighting are merged
+// (greedy meshing). It only reads the world and may run on any goroutine
+func MeshChunk(world *World, pos ChunkPos) *ChunkMeshData {
+	return meshChunk(world, pos, newMeshTable(world.Registry()))
+}
+
+// meshChunk is MeshChunk with a prebuilt block table
+func meshChunk(world *World, pos ChunkPos, table meshTable) *ChunkMeshData {
+	result := &ChunkMeshData{Pos: pos}
+
+	c := world.Chunk(pos)
+	if c == nil || c.IsEmpty() {
+		return result
+	}
+
+	var blocks paddedBlocks
+	if !gatherBlocks(world, pos, &blocks) {
+		return result
+	}
+
+	opaque := &mesh.Data{Layout: ChunkLayout, Primitive: mesh.Triangles}
+	transparent := &mesh.Data{Layout: ChunkLayout, Primitive: mesh.Triangles}
+
+	var mask [ChunkSize * ChunkSize]faceKey
+	for _, face := range Faces {
+		d := int(face) / 2
+		u, v := (d+1)%3, (d+2)%3
+		normal := face.Direction()
+
+		for slice := 0; slice < ChunkSize; slice++ {
+			// Collect the visible faces of this slice
+			for b := 0; b < ChunkSize; b++ {
+				for a := 0; a < ChunkSize; a++ {
+					var p [3]int
+					p[d], p[u], p[v] = slice, a, b
+					mask[b*ChunkSize+a] = faceAt(&blocks, table, p, face, u, v)
+				}
+			}
+
+			// Merge equal faces into rectangles
+			for b := 0; b < ChunkSize; b++ {
+				for a := 0; a < ChunkSize; {
+					key := mask[b*ChunkSize+a]
+					if !key.visible {
+						a++
+						continue
+					}
+
+					w := 1
+					for a+w < ChunkSize && mask[b*ChunkSize+a+w] == key {
+						w++
+					}
+
+					h := 1
+				grow:
+					for b+h < ChunkSize {
+						for k := 0; k < w; k++ {
+							if mask[(b+h)*ChunkSize+a+k] != key {
+								break grow
+							}
+						}
+						h++
+					}
+
+					target := opaque
+					if key.transparent {
+						target = transparent
+					}
+					plane := slice
+					if normal[d] > 0 {
+						plane++
+					}
+					emitQuad(target, key, face, d, u, v, plane, a, b, w, h)
+
+					for y := 0; y < h; y++ {
+						for x := 0; x < w; x++ {
+							mask[(b+y)*ChunkSize+a+x] = faceKey{}
+						}
+					}
+					a += w
+				}
+			}
+		}
+	}
+
+	if len(opaque.Indices) > 0 {
+		result.Opaque = opaque
+	}
+	if len(transparent.Indices) > 0 {
+		result.Transparent = transparent
+	}
+	return result
+}
+
+// faceAt returns the key of a block face, invisible when the face is
+// hidden by its neighbor
+func faceAt(blocks *paddedBlocks, table meshTable, p [3]int, face Face, u, v int) faceKey {
+	id := blocks.at(p[0], p[1], p[2])
+	if id == Air {
+		return faceKey{}
+	}
+	info := table.get(id)
+
+	n := face.Direction()
+	q := [3]int{p[0] + n[0], p[1] + n[1], p[2] + n[2]}
+	neighborID := blocks.at(q[0], q[1], q[2])
+	neighbor := table.get(neighborID)
+
+	// Opaque neighbors hide every face; transparent ones only hide faces
+	// of the same block, e.g. between two water blocks
+	if neighbor.opaque || (info.transparent && neighborID == id) {
+		return faceKey{}
+	}
+
+	key := faceKey{
+		visible:     true,
+		transparent: info.transparent,
+		layer:       info.layers[face],
+		light:       info.light,
+	}
+
+	// Ambient occlusion from the blocks around each corner, in the plane
+	// in front of the face
+	occludes := func(du, dv int) int {
+		r := q
+		r[u] += du
+		r[v] += dv
+		if table.get(blocks.at(r[0], r[1], r[2])).opaque {
+			return 1
+		}
+		return 0
+	}
+	for i, corner := range [4][2]int{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+		side1, side2 := occludes(corner[0], 0), occludes(0, corner[1])
+		if side1 == 1 && side2 == 1 {
+			key.ao[i] = 0
+		} else {
+			key.ao[i] = uint8(3 - side1 - side2 - occludes(corner[0], corner[1]))
+		}
+	}
+
+	return key
+}
+
+// emitQuad appends a merged face spanning w x h blocks from (a, b) on the
+// given plane
+func emitQuad(data *mesh.Data, key faceKey, face Face, d, u, v, plane, a, b, w, h int) {
+	n := face.Direction()
+	base := uint32(data.VertexCount())
+
+	corners := [4][2]int{{a, b}, {a + w, b}, {a + w, b + h}, {a, b + h}}
+	for i, corner := range corners {
+		var p [3]float32
+		p[d] = float32(plane)
+		p[u] = float32(corner[0])
+		p[v] = float32(corner[1])
+
+		shade := faceShade[face] * aoCurve[key.ao[i]]
+		if emitted := float32(key.light) / MaxLight; emitted > shade {
+			shade = emitted
+		}
+
+		uvX, uvY := faceUV(face, p)
+		data.Vertices = append(data.Vertices,
+			p[0], p[1], p[2],
+			float32(n[0]), float32(n[1]), float32(n[2]),
+			uvX, uvY,
+			key.layer, shade,
+		)
+	}
+
+	// Split the quad along the diagonal that keeps the ambient occlusion
+	// gradient symmetric
+	var order [6]uint32
+	if int(key.ao[0])+int(key.ao[2]) > int(key.ao[1])+int(key.ao[3]) {
+		order = [6]uint32{0, 1, 2, 0, 2, 3}
+	} else {
+		order = [6]uint32{1, 2, 3, 1, 3, 0}
+	}
+
+	// The corners wind counter-clockwise seen from +d; reverse them for
+	// faces pointing the other way
+	if n[d] < 0 {
+		order[1], order[2] = order[2], order[1]
+		order[4], order[5] = order[5], order[4]
+	}
+	for _, i := range order {
+		data.Indices = append(data.Indices, base+i)
+	}
+}
+
+// faceUV returns the texture coordinates of a face vertex so that side
+// textures stand upright
+func faceUV(face Face, p [3]float32) (float32, float32) {
+	switch face {
+	case FaceEast, FaceWest:
+		return p[2], -p[1]
+	case FaceUp, FaceDown:
+		return p[0], p[2]
+	default:
+		return p[0], -p[1]
+	}
+}
+
+// ChunkMesher builds chunk meshes on worker goroutines. Scheduled chunks
+// are meshed in the background, closest to the focus first, and the
+// results queued until the GL thread collects them with Results, since
+// only that thread may upload them
+type ChunkMesher struct {
+	world *World
+	table meshTable
+	focus focus
+	queue *workQueue[ChunkPos]
+
+	mu sync.Mutex
+	// generation holds a new number every time a chunk is scheduled, so
+	// that results of outdated meshing jobs can be dropped
+	generation map[ChunkPos]uint64
+	counter    uint64
+	results    []*ChunkMeshData
+	workers    sync.WaitGroup
+}
+
+// NewChunkMesher starts a mesher for world with the given number of
+// workers (one less than the number of CPUs when zero). Blocks registered
+// after this call are meshed as air
+func NewChunkMesher(world *World, workers int) *ChunkMesher {
+	if workers <= 0 {
+		workers = max(1, runtime.NumCPU()-1)
+	}
+
+	m := &ChunkMesher{
+		world:      world,
+		table:      newMeshTable(world.Registry()),
+		generation: make(map[ChunkPos]uint64),
+	}
+	m.queue = newWorkQueue(func(pos ChunkPos) float64 {
+		return m.focus.priority(chunkCenter(pos))
+	})
+
+	m.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go m.work()
+	}
+	return m
+}
+
+// Focus sets the point meshing is prioritized around, usually the camera
+// position and view direction
+func (m *ChunkMesher) Focus(position, forward vecmath.Vec3) {
+	m.focus.set(position, forward)
+}
+
+// Schedule queues a chunk for meshing. It never blocks
+func (m *ChunkMesher) Schedule(pos ChunkPos) {
+	m.mu.Lock()
+	m.counter++
+	m.generation[pos] = m.counter
+	m.mu.Unlock()
+
+	m.queue.push(pos)
+}
+
+// ScheduleDirty queues every dirty chunk of the world
+func (m *ChunkMesher) ScheduleDirty() {
+	for _, pos := range m.world.TakeDirty() {
+		m.Schedule(pos)
+	}
+}
+
+// Forget drops queued work and pending results for a chunk, e.g. when it
+// is unloaded
+func (m *ChunkMesher) Forget(pos ChunkPos) {
+	m.queue.remove(pos)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.generation, pos)
+}
+
+// Pending returns the number of chunks waiting to be meshed
+func (m *ChunkMesher) Pending() int {
+	return m.queue.len()
+}
+
+// Results returns up to max finished meshes (all when max <= 0), skipping
+// results made obsolete by a later Schedule or Forget of the same chunk
+func (m *ChunkMesher) Results(max int) []*ChunkMeshData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []*ChunkMeshData
+	for len(m.results) > 0 && (max <= 0 || len(out) < max) {
+		result := m.results[0]
+		m.results[0] = nil
+		m.results = m.results[1:]
+		if generation, ok := m.generation[result.Pos]; ok && result.generation == generation {
+			out = append(out, result)
+		}
+	}
+	return out
+}
+
+// Close stops the workers after their current job and drops queued work
+func (m *ChunkMesher) Close() {
+	m.queue.close()
+	m.workers.Wait()
+}
+
+// work meshes queued chunks until the mesher is closed
+func (m *ChunkMesher) work() {
+	defer m.workers.Done()
+
+	for {
+		pos, ok := m.queue.pop()
+		if !ok {
+			return
+		}
+
+		m.mu.Lock()
+		generation := m.generation[pos]
+		m.mu.Unlock()
+
+		result := meshChunk(m.world, pos, m.table)
+		result.generation = generation
+
+		m.mu.Lock()
+		m.results = append(m.results, result)
+		m.mu.Unlock()
+	}
+}
+
+// ChunkMeshes owns the GPU meshes of chunks. It must only be used on the
+// thread owning the GL context
+type ChunkMeshes struct {
+	meshes map[ChunkPos]*chunkMesh
+}
+
+// chunkMesh holds the uploaded meshes of one chunk
+type chunkMesh struct {
+	opaque      *mesh.Mesh
+	transparent *mesh.Mesh
+}
+
+// NewChunkMeshes creates an empty chunk mesh store
+func NewChunkMeshes() *ChunkMeshes {
+	return &ChunkMeshes{meshes: make(map[ChunkPos]*chunkMesh)}
+}
+
+// Upload replaces the meshes of a chunk with freshly built geometry
+func (c *ChunkMeshes) Upload(data *ChunkMeshData) {
+	c.Remove(data.Pos)
+	if data.Opaque == nil && data.Transparent == nil {
+		return
+	}
+
+	cm := &chunkMesh{}
+	if data.Opaque != nil {
+		cm.opaque = mesh.Upload(data.Opaque, mesh.Static)
+	}
+	if data.Transparent != nil {
+		cm.transparent = mesh.Upload(data.Transparent, mesh.Static)
+	}
+	c.meshes[data.Pos] = cm
+}
+
+// Remove releases the meshes of a chunk, e.g. when it is unloaded
+func (c *ChunkMeshes) Remove(pos ChunkPos) {
+	if cm, ok := c.meshes[pos]; ok {
+		cm.release()
+		delete(c.meshes, pos)
+	}
+}
+
+// Each calls fn for every chunk with meshes. Either mesh may be nil
+func (c *ChunkMeshes) Each(fn func(pos ChunkPos, opaque, transparent *mesh.Mesh)) {
+	for pos, cm := range c.meshes {
+		fn(pos, cm.opaque, cm.transparent)
+	}
+}
+
+// Len returns the number of chunks with meshes
+func (c *ChunkMeshes) Len() int {
+	return len(c.meshes)
+}
+
+// Release releases every mesh
+func (c *ChunkMeshes) Release() {
+	for pos := range c.meshes {
+		c.Remove(pos)
+	}
+}
+
+// release releases both meshes of a chunk
+func (cm *chunkMesh) release() {
+	if cm.opaque != nil {
+		cm.opaque.Release()
+	}
+	if cm.transparent != nil {
+		cm.transparent.Release()
+	}
+}
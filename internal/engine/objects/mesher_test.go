@@ -0,0 +1,329 @@
+package objects
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/mesh"
+)
+
+// mesherTimeout bounds the wait for background meshing
+const mesherTimeout = 5 * time.Second
+
+// meshBlocks are the block types of the mesher tests
+type meshBlocks struct {
+	stone, dirt, glass, water BlockID
+}
+
+// newMeshWorld creates an empty world with opaque and transparent blocks
+func newMeshWorld(t *testing.T) (*World, meshBlocks) {
+	t.Helper()
+	registry := NewBlockRegistry()
+	register := func(b Block) BlockID {
+		id, err := registry.Register(b)
+		if err != nil {
+			t.Fatalf("failed to register %s: %v", b.Name, err)
+		}
+		return id
+	}
+	blocks := meshBlocks{
+		stone: register(Block{Name: "stone", Solid: true, Textures: AllFaces("stone")}),
+		dirt:  register(Block{Name: "dirt", Solid: true, Textures: AllFaces("dirt")}),
+		glass: register(Block{Name: "glass", Solid: true, Transparent: true, Textures: AllFaces("glass")}),
+		water: register(Block{Name: "water", Transparent: true, Textures: AllFaces("water")}),
+	}
+	return NewWorld(registry), blocks
+}
+
+// fillBlocks sets every block of the box from min to max, inclusive
+func fillBlocks(w *World, min, max [3]int, id BlockID) {
+	for x := min[0]; x <= max[0]; x++ {
+		for y := min[1]; y <= max[1]; y++ {
+			for z := min[2]; z <= max[2]; z++ {
+				w.SetBlock(x, y, z, id)
+			}
+		}
+	}
+}
+
+// quadCount returns the number of quads in chunk geometry
+func quadCount(data *mesh.Data) int {
+	if data == nil {
+		return 0
+	}
+	return len(data.Indices) / 6
+}
+
+// meshVertex is a decoded vertex of chunk geometry
+type meshVertex struct {
+	pos    [3]float32
+	normal [3]float32
+	shade  float32
+}
+
+// vertex decodes vertex i of chunk geometry
+func vertex(data *mesh.Data, i uint32) meshVertex {
+	v := data.Vertices[int(i)*int(ChunkLayout.Stride()):]
+	return meshVertex{
+		pos:    [3]float32{v[0], v[1], v[2]},
+		normal: [3]float32{v[3], v[4], v[5]},
+		shade:  v[9],
+	}
+}
+
+func TestMeshChunkGreedy(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(w *World, b meshBlocks)
+		quads int
+	}{
+		{"single block", func(w *World, b meshBlocks) {
+			w.SetBlock(3, 3, 3, b.stone)
+		}, 6},
+		{"row", func(w *World, b meshBlocks) {
+			fillBlocks(w, [3]int{2, 3, 3}, [3]int{6, 3, 3}, b.stone)
+		}, 6},
+		{"slab", func(w *World, b meshBlocks) {
+			fillBlocks(w, [3]int{0, 0, 0}, [3]int{15, 0, 15}, b.stone)
+		}, 6},
+		{"full chunk", func(w *World, b meshBlocks) {
+			fillBlocks(w, [3]int{0, 0, 0}, [3]int{15, 15, 15}, b.stone)
+		}, 6},
+		{"different textures", func(w *World, b meshBlocks) {
+			w.SetBlock(3, 3, 3, b.stone)
+			w.SetBlock(4, 3, 3, b.dirt)
+		}, 10},
+		{"separate blocks", func(w *World, b meshBlocks) {
+			w.SetBlock(3, 3, 3, b.stone)
+			w.SetBlock(5, 3, 3, b.stone)
+		}, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, blocks := newMeshWorld(t)
+			tt.build(w, blocks)
+
+			result := MeshChunk(w, ChunkPos{0, 0, 0})
+			if got := quadCount(result.Opaque); got != tt.quads {
+				t.Errorf("opaque quads = %d, want %d", got, tt.quads)
+			}
+			if result.Transparent != nil {
+				t.Errorf("opaque blocks produced transparent geometry")
+			}
+			if got, want := result.Opaque.VertexCount(), tt.quads*4; got != want {
+				t.Errorf("vertices = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestMeshChunkHidesBorderFaces(t *testing.T) {
+	tests := []struct {
+		name      string
+		neighbors bool
+		quads     int
+	}{
+		{"neighbor loaded", true, 5},
+		{"neighbor missing", false, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, blocks := newMeshWorld(t)
+			fillBlocks(w, [3]int{0, 0, 0}, [3]int{15, 15, 15}, blocks.stone)
+			if tt.neighbors {
+				// The east neighbor chunk is full too
+				fillBlocks(w, [3]int{16, 0, 0}, [3]int{31, 15, 15}, blocks.stone)
+			}
+
+			result := MeshChunk(w, ChunkPos{0, 0, 0})
+			if got := quadCount(result.Opaque); got != tt.quads {
+				t.Errorf("quads = %d, want %d", got, tt.quads)
+			}
+		})
+	}
+}
+
+func TestMeshChunkTransparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		build       func(w *World, b meshBlocks)
+		opaque      int
+		transparent int
+	}{
+		{"glass", func(w *World, b meshBlocks) {
+			w.SetBlock(3, 3, 3, b.glass)
+		}, 0, 6},
+		{"water pool", func(w *World, b meshBlocks) {
+			fillBlocks(w, [3]int{2, 3, 3}, [3]int{4, 3, 3}, b.water)
+		}, 0, 6},
+		{"glass on stone", func(w *World, b meshBlocks) {
+			w.SetBlock(3, 3, 3, b.stone)
+			w.SetBlock(3, 4, 3, b.glass)
+		}, 6, 5},
+		{"glass next to water", func(w *World, b meshBlocks) {
+			w.SetBlock(3, 3, 3, b.glass)
+			w.SetBlock(4, 3, 3, b.water)
+		}, 0, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, blocks := newMeshWorld(t)
+			tt.build(w, blocks)
+
+			result := MeshChunk(w, ChunkPos{0, 0, 0})
+			if got := quadCount(result.Opaque); got != tt.opaque {
+				t.Errorf("opaque quads = %d, want %d", got, tt.opaque)
+			}
+			if got := quadCount(result.Transparent); got != tt.transparent {
+				t.Errorf("transparent quads = %d, want %d", got, tt.transparent)
+			}
+		})
+	}
+}
+
+func TestMeshChunkAmbientOcclusion(t *testing.T) {
+	up := faceShade[FaceUp]
+	tests := []struct {
+		name      string
+		occluders [][3]int
+		// shades of the top face corners at (x, z) = (1, 1), (2, 1),
+		// (2, 2) and (1, 2)
+		shades [4]float32
+	}{
+		{"open", nil, [4]float32{up, up, up, up}},
+		{"side", [][3]int{{2, 1, 1}}, [4]float32{
+			up, up * aoCurve[2], up * aoCurve[2], up,
+		}},
+		{"diagonal", [][3]int{{2, 1, 2}}, [4]float32{
+			up, up, up * aoCurve[2], up,
+		}},
+		{"other diagonal", [][3]int{{0, 1, 2}}, [4]float32{
+			up, up, up, up * aoCurve[2],
+		}},
+		{"two sides", [][3]int{{2, 1, 1}, {1, 1, 2}}, [4]float32{
+			up, up * aoCurve[2], up * aoCurve[0], up * aoCurve[2],
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, blocks := newMeshWorld(t)
+			w.SetBlock(1, 0, 1, blocks.stone)
+			for _, p := range tt.occluders {
+				w.SetBlock(p[0], p[1], p[2], blocks.stone)
+			}
+			data := MeshChunk(w, ChunkPos{0, 0, 0}).Opaque
+
+			// Find the top face of the block at (1, 0, 1)
+			var quad []uint32
+			for q := 0; q < quadCount(data); q++ {
+				indices := data.Indices[q*6 : q*6+6]
+				v := vertex(data, indices[0])
+				if v.normal == [3]float32{0, 1, 0} && v.pos[1] == 1 {
+					quad = indices
+				}
+			}
+			if quad == nil {
+				t.Fatalf("top face not found")
+			}
+
+			corners := [4][2]float32{{1, 1}, {2, 1}, {2, 2}, {1, 2}}
+			shades := make(map[[2]float32]float32)
+			for _, i := range quad {
+				v := vertex(data, i)
+				shades[[2]float32{v.pos[0], v.pos[2]}] = v.shade
+			}
+			for i, corner := range corners {
+				if got := shades[corner]; got != tt.shades[i] {
+					t.Errorf("corner %v shade = %v, want %v", corner, got, tt.shades[i])
+				}
+			}
+
+			// The triangles share the brighter diagonal so the gradient
+			// stays symmetric
+			first := make(map[uint32]bool)
+			for _, i := range quad[:3] {
+				first[i] = true
+			}
+			var shared, other float32
+			for _, i := range quad[3:] {
+				if first[i] {
+					shared += vertex(data, i).shade
+				} else {
+					other += vertex(data, i).shade
+				}
+			}
+			for i := range first {
+				if !contains(quad[3:], i) {
+					other += vertex(data, i).shade
+				}
+			}
+			if shared < other {
+				t.Errorf("quad split along the darker diagonal (%v < %v)", shared, other)
+			}
+		})
+	}
+}
+
+// contains reports whether indices contains i
+func contains(indices []uint32, i uint32) bool {
+	for _, index := range indices {
+		if index == i {
+			return true
+		}
+	}
+	return false
+}
+
+// waitResults waits until the workers of m have finished n meshes
+func waitResults(t *testing.T, m *ChunkMesher, n int) {
+	t.Helper()
+	deadline := time.Now().Add(mesherTimeout)
+	for {
+		m.mu.Lock()
+		done := len(m.results)
+		m.mu.Unlock()
+		if done >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d meshes finished after %v", done, n, mesherTimeout)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestChunkMesherDropsStaleResults(t *testing.T) {
+	w, blocks := newMeshWorld(t)
+	a, b, c := ChunkPos{0, 0, 0}, ChunkPos{1, 0, 0}, ChunkPos{2, 0, 0}
+	for _, pos := range []ChunkPos{a, b, c} {
+		x, y, z := pos.Origin()
+		w.SetBlock(x, y, z, blocks.stone)
+	}
+
+	// A single worker meshes the chunks in order
+	m := NewChunkMesher(w, 1)
+	m.Schedule(a)
+	m.Schedule(b)
+	m.Schedule(c)
+	waitResults(t, m, 3)
+
+	// Scheduling again makes the finished mesh of a stale. The mesher is
+	// closed first so that a is not meshed again
+	m.Close()
+	m.Schedule(a)
+
+	got := m.Results(1)
+	if len(got) != 1 || got[0].Pos != b {
+		t.Fatalf("Results(1) = %v, want the mesh of %v", got, b)
+	}
+	if quadCount(got[0].Opaque) != 6 {
+		t.Errorf("mesh of %v has %d quads, want 6", b, quadCount(got[0].Opaque))
+	}
+	got = m.Results(0)
+	if len(got) != 1 || got[0].Pos != c {
+		t.Fatalf("Results(0) = %v, want the mesh of %v", got, c)
+	}
+	if got := m.Results(0); len(got) != 0 {
+		t.Errorf("Results returned %d meshes after the queue was drained", len(got))
+	}
+}
@@ -0,0 +1,212 @@
+package objects
+
+import "math"
+
+// Noise generates gradient noise from a seed. The same seed always
+// produces the same values on every platform, since the permutation comes
+// from a fixed generator rather than math/rand
+type Noise struct {
+	perm [512]uint8
+}
+
+// Octaves configures fractal noise: Count layers of noise, each Lacunarity
+// times the frequency and Gain times the amplitude of the previous one
+type Octaves struct {
+	Count      int
+	Frequency  float64
+	Lacunarity float64
+	Gain       float64
+}
+
+// NewNoise creates a noise generator for seed
+func NewNoise(seed int64) *Noise {
+	n := &Noise{}
+	for i := 0; i < 256; i++ {
+		n.perm[i] = uint8(i)
+	}
+
+	rng := splitMix(seed)
+	for i := 255; i > 0; i-- {
+		j := int(rng.next() % uint64(i+1))
+		n.perm[i], n.perm[j] = n.perm[j], n.perm[i]
+	}
+	copy(n.perm[256:], n.perm[:256])
+	return n
+}
+
+// splitMix is the SplitMix64 generator, used to derive permutations, sub
+// seeds and per-column random numbers
+type splitMix uint64
+
+// next returns the next random value
+func (s *splitMix) next() uint64 {
+	*s += 0x9e3779b97f4a7c15
+	z := uint64(*s)
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// hashColumn returns a random value in [0, 1) for a world column, the same
+// for every call with the same arguments
+func hashColumn(seed int64, x, z int) float64 {
+	s := splitMix(uint64(seed) ^ uint64(x)*0x9e3779b97f4a7c15 ^ uint64(z)*0xc2b2ae3d27d4eb4f)
+	return float64(s.next()>>11) / (1 << 53)
+}
+
+// fade is the quintic interpolation curve of improved Perlin noise
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+// lerp interpolates linearly from a to b
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// grad3 returns the dot product of a pseudo-random gradient and (x, y, z)
+func grad3(hash uint8, x, y, z float64) float64 {
+	h := hash & 15
+	u, v := y, z
+	if h < 8 {
+		u = x
+	}
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+// Perlin3 returns improved Perlin noise at a point, roughly in [-1, 1]
+func (n *Noise) Perlin3(x, y, z float64) float64 {
+	fx, fy, fz := math.Floor(x), math.Floor(y), math.Floor(z)
+	X, Y, Z := int(fx)&255, int(fy)&255, int(fz)&255
+	x, y, z = x-fx, y-fy, z-fz
+	u, v, w := fade(x), fade(y), fade(z)
+
+	p := &n.perm
+	a := int(p[X]) + Y
+	aa, ab := int(p[a])+Z, int(p[a+1])+Z
+	b := int(p[X+1]) + Y
+	ba, bb := int(p[b])+Z, int(p[b+1])+Z
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad3(p[aa], x, y, z), grad3(p[ba], x-1, y, z)),
+			lerp(u, grad3(p[ab], x, y-1, z), grad3(p[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad3(p[aa+1], x, y, z-1), grad3(p[ba+1], x-1, y, z-1)),
+			lerp(u, grad3(p[ab+1], x, y-1, z-1), grad3(p[bb+1], x-1, y-1, z-1))))
+}
+
+// Skew factors of 2D simplex noise
+var (
+	simplexF2 = 0.5 * (math.Sqrt(3) - 1)
+	simplexG2 = (3 - math.Sqrt(3)) / 6
+)
+
+// simplexGradients are the gradient directions of 2D simplex noise
+var simplexGradients = [8][2]float64{
+	{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+	{math.Sqrt2 / 2, math.Sqrt2 / 2}, {-math.Sqrt2 / 2, math.Sqrt2 / 2},
+	{math.Sqrt2 / 2, -math.Sqrt2 / 2}, {-math.Sqrt2 / 2, -math.Sqrt2 / 2},
+}
+
+// Simplex2 returns 2D simplex noise at a point, roughly in [-1, 1]. It is
+// cheaper than Perlin noise and has no axis-aligned artifacts
+func (n *Noise) Simplex2(x, y float64) float64 {
+	s := (x + y) * simplexF2
+	i, j := math.Floor(x+s), math.Floor(y+s)
+	t := (i + j) * simplexG2
+	x0, y0 := x-(i-t), y-(j-t)
+
+	i1, j1 := 0, 1
+	if x0 > y0 {
+		i1, j1 = 1, 0
+	}
+
+	corners := [3][2]float64{
+		{x0, y0},
+		{x0 - float64(i1) + simplexG2, y0 - float64(j1) + simplexG2},
+		{x0 - 1 + 2*simplexG2, y0 - 1 + 2*simplexG2},
+	}
+	offsets := [3][2]int{{0, 0}, {i1, j1}, {1, 1}}
+
+	ii, jj := int(i)&255, int(j)&255
+	p := &n.perm
+	var sum float64
+	for k, c := range corners {
+		falloff := 0.5 - c[0]*c[0] - c[1]*c[1]
+		if falloff <= 0 {
+			continue
+		}
+		g := simplexGradients[p[ii+offsets[k][0]+int(p[jj+offsets[k][1]])]&7]
+		falloff *= falloff
+		sum += falloff * falloff * (g[0]*c[0] + g[1]*c[1])
+	}
+	return 70 * sum
+}
+
+// FBM2 returns fractal Brownian motion of 2D simplex noise, normalized to
+// roughly [-1, 1]
+func (n *Noise) FBM2(x, y float64, o Octaves) float64 {
+	var sum, norm float64
+	frequency, amplitude := o.Frequency, 1.0
+	for i := 0; i < o.Count; i++ {
+		sum += amplitude * n.Simplex2(x*frequency, y*frequency)
+		norm += amplitude
+		frequency *= o.Lacunarity
+		amplitude *= o.Gain
+	}
+	if norm == 0 {
+		return 0
+	}
+	return sum / norm
+}
+
+// FBM3 returns fractal Brownian motion of 3D Perlin noise, normalized to
+// roughly [-1, 1]
+func (n *Noise) FBM3(x, y, z float64, o Octaves) float64 {
+	var sum, norm float64
+	frequency, amplitude := o.Frequency, 1.0
+	for i := 0; i < o.Count; i++ {
+		sum += amplitude * n.Perlin3(x*frequency, y*frequency, z*frequency)
+		norm += amplitude
+		frequency *= o.Lacunarity
+		amplitude *= o.Gain
+	}
+	if norm == 0 {
+		return 0
+	}
+	return sum / norm
+}
+
+// Ridged2 returns ridged multifractal noise in [0, 1], with sharp crests
+// where the noise crosses zero, for mountain ranges. Each octave is
+// weighted by the previous one so detail gathers along the ridges
+func (n *Noise) Ridged2(x, y float64, o Octaves) float64 {
+	var sum, norm float64
+	frequency, amplitude, weight := o.Frequency, 1.0, 1.0
+	for i := 0; i < o.Count; i++ {
+		ridge := 1 - math.Abs(n.Simplex2(x*frequency, y*frequency))
+		ridge *= ridge * weight
+		weight = min(1, max(0, ridge*2))
+
+		sum += amplitude * ridge
+		norm += amplitude
+		frequency *= o.Lacunarity
+		amplitude *= o.Gain
+	}
+	if norm == 0 {
+		return 0
+	}
+	return sum / norm
+}
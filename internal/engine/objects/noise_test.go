@@ -0,0 +1,83 @@
+package objects
+
+import (
+	"math"
+	"testing"
+)
+
+// testOctaves are the fractal settings of the noise tests
+var testOctaves = Octaves{Count: 5, Frequency: 0.01, Lacunarity: 2, Gain: 0.5}
+
+// noiseFuncs samples every kind of noise at one point
+var noiseFuncs = []struct {
+	name   string
+	sample func(n *Noise) float64
+}{
+	{"Perlin3", func(n *Noise) float64 { return n.Perlin3(1.5, 2.25, -3.75) }},
+	{"Perlin3 far", func(n *Noise) float64 { return n.Perlin3(10.1, -0.3, 7.7) }},
+	{"Simplex2", func(n *Noise) float64 { return n.Simplex2(1.5, 2.25) }},
+	{"Simplex2 negative", func(n *Noise) float64 { return n.Simplex2(-10.1, 7.7) }},
+	{"FBM2", func(n *Noise) float64 { return n.FBM2(123.4, -56.7, testOctaves) }},
+	{"FBM3", func(n *Noise) float64 { return n.FBM3(12.5, 40.25, -7.5, testOctaves) }},
+	{"Ridged2", func(n *Noise) float64 { return n.Ridged2(123.4, -56.7, testOctaves) }},
+}
+
+// The golden values pin the noise of a seed, which must not change between
+// runs or platforms
+func TestNoiseGolden(t *testing.T) {
+	want := map[string]float64{
+		"Perlin3":           0.55636310577392578,
+		"Perlin3 far":       0.28457781302791707,
+		"Simplex2":          0.39109781056190446,
+		"Simplex2 negative": -0.19624101108257291,
+		"FBM2":              -0.061937509881911081,
+		"FBM3":              0.048349887305058736,
+		"Ridged2":           0.63757240439777341,
+	}
+	n := NewNoise(testSeed)
+	for _, f := range noiseFuncs {
+		t.Run(f.name, func(t *testing.T) {
+			if got := f.sample(n); math.Abs(got-want[f.name]) > 1e-12 {
+				t.Errorf("%s = %.17g, want %.17g", f.name, got, want[f.name])
+			}
+		})
+	}
+}
+
+func TestNoiseSeeds(t *testing.T) {
+	a, b, other := NewNoise(testSeed), NewNoise(testSeed), NewNoise(testSeed+1)
+	for _, f := range noiseFuncs {
+		t.Run(f.name, func(t *testing.T) {
+			if va, vb := f.sample(a), f.sample(b); va != vb {
+				t.Errorf("same seed gave %v and %v", va, vb)
+			}
+			if va, vo := f.sample(a), f.sample(other); va == vo {
+				t.Errorf("different seeds both gave %v", va)
+			}
+		})
+	}
+}
+
+func TestNoiseRange(t *testing.T) {
+	n := NewNoise(testSeed)
+	for i := 0; i < 1000; i++ {
+		x, y, z := float64(i)*0.37, float64(i)*-0.73, float64(i)*1.13
+		if v := n.Perlin3(x, y, z); v < -1 || v > 1 {
+			t.Fatalf("Perlin3(%v, %v, %v) = %v, want [-1, 1]", x, y, z, v)
+		}
+		if v := n.Simplex2(x, y); v < -1 || v > 1 {
+			t.Fatalf("Simplex2(%v, %v) = %v, want [-1, 1]", x, y, v)
+		}
+		if v := n.Ridged2(x, y, testOctaves); v < 0 || v > 1 {
+			t.Fatalf("Ridged2(%v, %v) = %v, want [0, 1]", x, y, v)
+		}
+	}
+
+	// Gradient noise is zero on the lattice
+	if v := n.Perlin3(3, -2, 5); v != 0 {
+		t.Errorf("Perlin3 on a lattice point = %v, want 0", v)
+	}
+	if v := n.FBM2(1, 2, Octaves{}); v != 0 {
+		t.Errorf("FBM2 without octaves = %v, want 0", v)
+	}
+}
@@ -0,0 +1,143 @@
+package objects
+
+import (
+	"math"
+	"sync"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// workQueue is a set of pending jobs shared by worker goroutines. Workers
+// always take the job with the lowest priority value, computed when the
+// job is taken so that it follows the player as they move and turn.
+// Pushing never blocks and pushing a queued job again has no effect
+type workQueue[K comparable] struct {
+	mu       sync.Mutex
+	wake     *sync.Cond
+	items    map[K]struct{}
+	priority func(K) float64
+	closed   bool
+}
+
+// newWorkQueue creates a queue ordered by priority (lower first)
+func newWorkQueue[K comparable](priority func(K) float64) *workQueue[K] {
+	q := &workQueue[K]{items: make(map[K]struct{}), priority: priority}
+	q.wake = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues a job and reports whether it was not already queued
+func (q *workQueue[K]) push(job K) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.items[job]; ok || q.closed {
+		return false
+	}
+	q.items[job] = struct{}{}
+	q.wake.Signal()
+	return true
+}
+
+// remove drops a queued job
+func (q *workQueue[K]) remove(job K) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.items, job)
+}
+
+// removeIf drops every queued job matching fn
+func (q *workQueue[K]) removeIf(fn func(K) bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for job := range q.items {
+		if fn(job) {
+			delete(q.items, job)
+		}
+	}
+}
+
+// pop waits for a job and returns it, or returns false once the queue is
+// closed
+func (q *workQueue[K]) pop() (K, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.items) == 0 && !q.closed {
+		q.wake.Wait()
+	}
+
+	var best K
+	if q.closed {
+		return best, false
+	}
+	bestPriority := math.Inf(1)
+	first := true
+	for job := range q.items {
+		if p := q.priority(job); first || p < bestPriority {
+			best, bestPriority, first = job, p, false
+		}
+	}
+	delete(q.items, best)
+	return best, true
+}
+
+// len returns the number of queued jobs
+func (q *workQueue[K]) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// close wakes every waiting worker and drops queued jobs
+func (q *workQueue[K]) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	clear(q.items)
+	q.wake.Broadcast()
+}
+
+// focus is the point chunk work is prioritized around, usually the camera
+type focus struct {
+	mu       sync.RWMutex
+	position vecmath.Vec3
+	forward  vecmath.Vec3
+}
+
+// set moves the focus
+func (f *focus) set(position, forward vecmath.Vec3) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.position = position
+	if forward.LenSqr() > 0 {
+		forward = forward.Normalize()
+	}
+	f.forward = forward
+}
+
+// priority returns the priority of work on a region centered on a world
+// point: its distance from the focus, halved for regions straight ahead
+// so that what the player looks at comes first
+func (f *focus) priority(center vecmath.Vec3) float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	offset := center.Sub(f.position)
+	distance := offset.Len()
+	if distance == 0 {
+		return 0
+	}
+	alignment := offset.Dot(f.forward) / distance
+	return float64(distance * (1 - 0.5*max(0, alignment)))
+}
+
+// chunkCenter returns the world coordinates of the center of a chunk
+func chunkCenter(pos ChunkPos) vecmath.Vec3 {
+	x, y, z := pos.Origin()
+	const half = ChunkSize / 2
+	return vecmath.Vec3{float32(x + half), float32(y + half), float32(z + half)}
+}
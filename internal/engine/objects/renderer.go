@@ -0,0 +1,288 @@
+package objects
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
+	"github.com/luidsonl/magic-and-blades/internal/engine/mesh"
+	"github.com/luidsonl/magic-and-blades/internal/engine/shader"
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// oceanSize is the edge in blocks of the area where the ocean is drawn
+// with waves; beyond it the flat water of heightmap tiles takes over
+const oceanSize = 512
+
+// oceanSnap is the spacing of the water surface vertices. The surface
+// follows the camera in steps of it so the waves do not swim
+const oceanSnap = oceanSize / waterSubdivisions
+
+// blockTextureSize is the edge in pixels of block textures
+const blockTextureSize = 16
+
+// placeholderColors are the colors of the generated block textures
+var placeholderColors = map[string][4]uint8{
+	"stone":       {125, 125, 125, 255},
+	"dirt":        {134, 96, 67, 255},
+	"grass_top":   {95, 159, 53, 255},
+	"grass_side":  {116, 120, 62, 255},
+	"sand":        {219, 207, 163, 255},
+	"gravel":      {136, 126, 126, 255},
+	"snow":        {240, 251, 251, 255},
+	"log_top":     {160, 130, 80, 255},
+	"log_side":    {102, 81, 51, 255},
+	"leaves":      {60, 120, 40, 255},
+	"planks":      {162, 131, 79, 255},
+	"cobblestone": {110, 110, 110, 255},
+	"glass":       {200, 230, 240, 60},
+	"water":       {40, 90, 200, 170},
+	"torch":       {255, 200, 90, 255},
+	"glowstone":   {250, 220, 140, 255},
+}
+
+// blockTextureDir holds the block textures in the asset files, one
+// <name>.png per texture name. Missing ones are replaced by placeholders
+const blockTextureDir = "textures/blocks"
+
+// placeholderTexture generates a speckled texture for a block texture
+// name, so the world can be drawn without texture files
+func placeholderTexture(name string) *image.RGBA {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	seed := h.Sum64()
+
+	base, ok := placeholderColors[name]
+	if !ok {
+		base = [4]uint8{uint8(seed), uint8(seed >> 8), uint8(seed >> 16), 255}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, blockTextureSize, blockTextureSize))
+	pixels := img.Pix
+	random := splitMix(seed)
+	for i := 0; i < blockTextureSize*blockTextureSize; i++ {
+		shade := 0.85 + 0.3*float64(random.next()%1000)/1000
+		for c := 0; c < 3; c++ {
+			pixels[i*4+c] = uint8(min(255, float64(base[c])*shade))
+		}
+		pixels[i*4+3] = base[3]
+	}
+	return img
+}
+
+// newBlockTextures creates a texture array with a layer for every name,
+// loaded from blockTextureDir in files
+func newBlockTextures(files fs.FS, names []string) (*graphics.TextureArray, error) {
+	layers := make([]image.Image, 0, max(1, len(names)))
+	for _, name := range names {
+		img, err := graphics.LoadImage(files, path.Join(blockTextureDir, name+".png"))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("Warning: failed to load block texture %s: %v", name, err)
+			}
+			img = placeholderTexture(name)
+		}
+		layers = append(layers, img)
+	}
+	if len(layers) == 0 {
+		layers = append(layers, placeholderTexture(""))
+	}
+
+	// Layers of other sizes are scaled to the first one
+	return graphics.NewTextureArray(layers, graphics.TextureOptions{
+		Filter:     graphics.FilterNearest,
+		Wrap:       graphics.WrapRepeat,
+		Mipmaps:    true,
+		Anisotropy: 8,
+	})
+}
+
+// WorldRenderer draws a streamed world through a graphics.Renderer3D:
+// chunk meshes, heightmap tiles and the ocean, which is drawn with waves
+// by a WaterRenderer. It must only be used on the thread owning the GL
+// context
+type WorldRenderer struct {
+	// SunDirection points towards the sun
+	SunDirection vecmath.Vec3
+	SunColor     vecmath.Vec3
+	// SkyColor clears the screen and colors the fog hiding the edge of the
+	// streamed area
+	SkyColor vecmath.Vec3
+	// Lights shine in addition to the sun, e.g. torches
+	Lights []graphics.Light
+
+	streamer   *Streamer
+	terrain    *Terrain
+	renderer   *graphics.Renderer3D
+	water      *WaterRenderer
+	shader     *shader.Shader
+	textures   *graphics.TextureArray
+	waterLayer float32
+	ocean      vecmath.Vec4
+
+	opaque      *graphics.Material
+	transparent *graphics.Material
+}
+
+// NewWorldRenderer creates a renderer for the world streamed by streamer
+// and generated by terrain, in a viewport of the given size. Shaders and
+// block textures are read from files
+func NewWorldRenderer(files fs.FS, streamer *Streamer, terrain *Terrain, registry *BlockRegistry, width, height int32) (*WorldRenderer, error) {
+	program, err := graphics.NewMaterialShader("chunk", shader.Files{
+		Vertex:   path.Join(graphics.ShaderDir, "chunk.vert"),
+		Fragment: path.Join(graphics.ShaderDir, "chunk.frag"),
+	}, shader.Options{FS: files})
+	if err != nil {
+		return nil, err
+	}
+
+	renderer, err := graphics.NewRenderer3D(files)
+	if err != nil {
+		program.Destroy()
+		return nil, err
+	}
+
+	water, err := NewWaterRenderer(files, width, height)
+	if err != nil {
+		program.Destroy()
+		renderer.Destroy()
+		return nil, err
+	}
+
+	textures, err := newBlockTextures(files, registry.TextureNames())
+	if err != nil {
+		program.Destroy()
+		renderer.Destroy()
+		water.Destroy()
+		return nil, fmt.Errorf("failed to create block textures: %v", err)
+	}
+
+	r := &WorldRenderer{
+		SunDirection: vecmath.Vec3{0.4, 0.8, 0.3}.Normalize(),
+		SunColor:     vecmath.Vec3{1, 0.95, 0.85},
+		SkyColor:     vecmath.Vec3{0.55, 0.7, 0.9},
+		streamer:     streamer,
+		terrain:      terrain,
+		renderer:     renderer,
+		water:        water,
+		shader:       program,
+		textures:     textures,
+		waterLayer:   float32(terrain.layers[terrain.blocks.water][FaceUp]),
+	}
+
+	r.opaque = &graphics.Material{
+		Shader: program,
+		Color:  vecmath.Vec4{1, 1, 1, 1},
+		Bind:   r.bindChunk,
+	}
+	r.transparent = &graphics.Material{
+		Shader:      program,
+		Color:       vecmath.Vec4{1, 1, 1, 1},
+		Transparent: true,
+		DoubleSided: true,
+		Bind:        r.bindChunk,
+	}
+
+	return r, nil
+}
+
+// bindChunk sets the uniforms of the chunk shader
+func (r *WorldRenderer) bindChunk(s *shader.Shader) {
+	s.SetSampler("uBlocks", 1)
+	s.SetVec4("uOcean", r.ocean)
+	s.SetFloat("uWaterLayer", r.waterLayer)
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, r.textures.ID())
+}
+
+// Renderer returns the 3D renderer, e.g. for its statistics
+func (r *WorldRenderer) Renderer() *graphics.Renderer3D {
+	return r.renderer
+}
+
+// Water returns the water renderer, e.g. to change the waves
+func (r *WorldRenderer) Water() *WaterRenderer {
+	return r.water
+}
+
+// Shaders returns the shaders of the renderer, e.g. to watch them for hot
+// reload
+func (r *WorldRenderer) Shaders() []*shader.Shader {
+	shaders := []*shader.Shader{r.shader, r.water.Shader()}
+	return append(shaders, r.renderer.Shaders()...)
+}
+
+// Resize adapts the renderer to a new viewport size
+func (r *WorldRenderer) Resize(width, height int32) error {
+	return r.water.Resize(width, height)
+}
+
+// Render draws the world from a view into the bound framebuffer
+func (r *WorldRenderer) Render(view graphics.View) {
+	level := float32(r.terrain.SeaLevel + 1)
+	oceanX := float32(math.Floor(float64(view.Position[0]/oceanSnap))) * oceanSnap
+	oceanZ := float32(math.Floor(float64(view.Position[2]/oceanSnap))) * oceanSnap
+	r.ocean = vecmath.Vec4{oceanX, oceanZ, oceanSize / 2, level}
+
+	r.renderer.Begin()
+	r.renderer.AddLight(graphics.Light{
+		Type:        graphics.LightDirectional,
+		Direction:   r.SunDirection.Neg(),
+		Color:       r.SunColor,
+		Intensity:   1,
+		CastShadows: true,
+	})
+	for _, light := range r.Lights {
+		r.renderer.AddLight(light)
+	}
+
+	r.streamer.Meshes().Each(func(pos ChunkPos, opaque, transparent *mesh.Mesh) {
+		x, y, z := pos.Origin()
+		transform := vecmath.Translate(vecmath.Vec3{float32(x), float32(y), float32(z)})
+		r.renderer.Submit(graphics.Item{Mesh: opaque, Material: r.opaque, Transform: transform})
+		r.renderer.Submit(graphics.Item{Mesh: transparent, Material: r.transparent, Transform: transform})
+	})
+	r.streamer.EachTile(func(origin vecmath.Vec3, m *mesh.Mesh) {
+		r.renderer.Submit(graphics.Item{Mesh: m, Material: r.opaque, Transform: vecmath.Translate(origin)})
+	})
+
+	// Fog hides where heightmap tiles end
+	far := float32(r.streamer.LODDistance * TileSize)
+	r.renderer.Fog = graphics.Fog{Mode: graphics.FogLinear, Color: r.SkyColor, Start: far * 0.5, End: far}
+	gl.ClearColor(r.SkyColor[0], r.SkyColor[1], r.SkyColor[2], 1)
+
+	r.renderer.RenderShadows(view)
+
+	r.water.SunDirection, r.water.SunColor = r.SunDirection, r.SunColor
+	water := WaterView{View: view.View, Projection: view.Projection, Eye: view.Position}
+	r.water.RenderReflection(water, level, func(m vecmath.Mat4, clip vecmath.Vec4) {
+		mirrored := view
+		mirrored.View, mirrored.Clip = m, clip
+		mirrored.Position[1] = 2*level - view.Position[1]
+		r.renderer.Render(mirrored)
+	})
+	r.water.RenderRefraction(water, level, func(m vecmath.Mat4, clip vecmath.Vec4) {
+		refracted := view
+		refracted.View, refracted.Clip = m, clip
+		r.renderer.Render(refracted)
+	})
+
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	r.renderer.Render(view)
+	r.water.Draw(water, level, oceanX, oceanZ, oceanSize, view.Time)
+}
+
+// Destroy releases the GL resources
+func (r *WorldRenderer) Destroy() {
+	r.textures.Destroy()
+	r.water.Destroy()
+	r.renderer.Destroy()
+	r.shader.Destroy()
+}
@@ -0,0 +1,204 @@
+package objects
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// chunkFileMagic starts every saved chunk file
+const chunkFileMagic = "MBCK"
+
+// chunkFileVersion is the current version of the chunk file format.
+// Version 2 added the levels of flowing water
+const chunkFileVersion = 2
+
+// ChunkStore saves chunks changed by the player to a directory, one
+// compressed file per chunk, with the levels of their flowing water.
+// Blocks are saved by name so that saves stay valid when block IDs change,
+// e.g. after adding a mod. It is safe for
+// concurrent use as long as a chunk is not saved and loaded at once
+type ChunkStore struct {
+	dir      string
+	registry *BlockRegistry
+}
+
+// NewChunkStore creates a store saving to dir, creating it if needed
+func NewChunkStore(dir string, registry *BlockRegistry) (*ChunkStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create chunk directory: %v", err)
+	}
+	return &ChunkStore{dir: dir, registry: registry}, nil
+}
+
+// path returns the file of a chunk
+func (s *ChunkStore) path(pos ChunkPos) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%d.%d.%d.chunk", pos.X, pos.Y, pos.Z))
+}
+
+// Save writes a chunk. The file is replaced atomically so a crash never
+// leaves a truncated chunk
+func (s *ChunkStore) Save(c *Chunk) error {
+	var blocks [ChunkVolume]BlockID
+	c.Blocks(&blocks)
+
+	// The palette of the file lists every block type used, by name
+	var names []string
+	index := make(map[BlockID]uint16)
+	indices := make([]uint16, ChunkVolume)
+	for i, id := range blocks {
+		entry, ok := index[id]
+		if !ok {
+			entry = uint16(len(names))
+			index[id] = entry
+			names = append(names, s.registry.Get(id).Name)
+		}
+		indices[i] = entry
+	}
+
+	path := s.path(c.Pos)
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save chunk: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	w := bufio.NewWriter(zw)
+	w.WriteString(chunkFileMagic)
+	w.WriteByte(chunkFileVersion)
+	binary.Write(w, binary.LittleEndian, uint16(len(names)))
+	for _, name := range names {
+		binary.Write(w, binary.LittleEndian, uint16(len(name)))
+		w.WriteString(name)
+	}
+	binary.Write(w, binary.LittleEndian, indices)
+
+	// Flowing water as index and level pairs, in index order
+	levels := c.waterLevels()
+	binary.Write(w, binary.LittleEndian, uint16(len(levels)))
+	for _, i := range slices.Sorted(maps.Keys(levels)) {
+		binary.Write(w, binary.LittleEndian, uint16(i))
+		w.WriteByte(levels[i])
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save chunk: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save chunk: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save chunk: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save chunk: %v", err)
+	}
+
+	return nil
+}
+
+// Load reads a saved chunk. It returns nil without error when the chunk
+// was never saved. Blocks that are no longer registered load as air
+func (s *ChunkStore) Load(pos ChunkPos) (*Chunk, error) {
+	f, err := os.Open(s.path(pos))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunk: %v", err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunk: %v", err)
+	}
+	r := bufio.NewReader(zr)
+
+	header := make([]byte, len(chunkFileMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to load chunk: %v", err)
+	}
+	if string(header[:len(chunkFileMagic)]) != chunkFileMagic {
+		return nil, fmt.Errorf("failed to load chunk: not a chunk file")
+	}
+	version := header[len(chunkFileMagic)]
+	if version < 1 || version > chunkFileVersion {
+		return nil, fmt.Errorf("failed to load chunk: unsupported version %d", version)
+	}
+
+	var count uint16
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("failed to load chunk: %v", err)
+	}
+	palette := make([]BlockID, count)
+	for i := range palette {
+		var length uint16
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, fmt.Errorf("failed to load chunk: %v", err)
+		}
+		name := make([]byte, length)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, fmt.Errorf("failed to load chunk: %v", err)
+		}
+		id, ok := s.registry.Lookup(string(name))
+		if !ok {
+			log.Printf("Warning: chunk %v uses unknown block %q, replacing it with air", pos, name)
+		}
+		palette[i] = id
+	}
+
+	indices := make([]uint16, ChunkVolume)
+	if err := binary.Read(r, binary.LittleEndian, indices); err != nil {
+		return nil, fmt.Errorf("failed to load chunk: %v", err)
+	}
+
+	c := NewChunk(pos)
+	for i, entry := range indices {
+		if int(entry) >= len(palette) {
+			return nil, fmt.Errorf("failed to load chunk: invalid palette index %d", entry)
+		}
+		if id := palette[entry]; id != Air {
+			x, z, y := i%ChunkSize, i/ChunkSize%ChunkSize, i/(ChunkSize*ChunkSize)
+			c.Set(x, y, z, id)
+		}
+	}
+
+	// Files from before version 2 hold no flowing water
+	if version >= 2 {
+		var count uint16
+		if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+			return nil, fmt.Errorf("failed to load chunk: %v", err)
+		}
+		for range count {
+			var entry struct {
+				Index uint16
+				Level uint8
+			}
+			if err := binary.Read(r, binary.LittleEndian, &entry); err != nil {
+				return nil, fmt.Errorf("failed to load chunk: %v", err)
+			}
+			if entry.Index >= ChunkVolume {
+				return nil, fmt.Errorf("failed to load chunk: invalid water index %d", entry.Index)
+			}
+			x, z, y := int(entry.Index)%ChunkSize, int(entry.Index)/ChunkSize%ChunkSize, int(entry.Index)/(ChunkSize*ChunkSize)
+			if c.Get(x, y, z) != Air {
+				c.SetWaterLevel(x, y, z, entry.Level)
+			}
+		}
+	}
+	c.Compact()
+	return c, nil
+}
@@ -0,0 +1,515 @@
+package objects
+
+import (
+	"log"
+	"math"
+	"math/bits"
+	"runtime"
+	"sync"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/mesh"
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// TileSize is the edge in blocks of a terrain tile. The world is streamed
+// by tiles: tiles near the player are loaded as voxel chunks and distant
+// ones are drawn as heightmaps whose detail drops with distance
+const TileSize = 2 * ChunkSize
+
+// Streaming defaults
+const (
+	DefaultViewDistance   = 3
+	DefaultLODDistance    = 24
+	DefaultVerticalRadius = 4
+	DefaultUploadBudget   = 4
+)
+
+// maxTileStep is the coarsest heightmap sample step
+const maxTileStep = TileSize / 2
+
+// recenterMargin is how far in blocks the player must walk past the
+// center tile before the streamed area follows, so that walking back and
+// forth over a tile border does not load and unload tiles
+const recenterMargin = 8
+
+// Tile is the position of a terrain tile in tile units
+type Tile struct {
+	X, Z int32
+}
+
+// TileOf returns the tile containing a world column
+func TileOf(x, z int) Tile {
+	return Tile{int32(floorDivBy(x, TileSize)), int32(floorDivBy(z, TileSize))}
+}
+
+// Origin returns the world coordinates of the tile's minimum corner at
+// height zero
+func (t Tile) Origin() vecmath.Vec3 {
+	return vecmath.Vec3{float32(int(t.X) * TileSize), 0, float32(int(t.Z) * TileSize)}
+}
+
+// floorDivBy divides rounding towards negative infinity
+func floorDivBy(v, d int) int {
+	q := v / d
+	if v%d < 0 {
+		q--
+	}
+	return q
+}
+
+// floorInt rounds a coordinate down to the block containing it
+func floorInt(v float32) int {
+	return int(math.Floor(float64(v)))
+}
+
+// tileDistance returns the Chebyshev distance between tiles
+func tileDistance(a, b Tile) int {
+	return max(abs(int(a.X-b.X)), abs(int(a.Z-b.Z)))
+}
+
+// streamJob is work for the streaming workers: loading or generating a
+// chunk, or building the heightmap of a tile with a given step and seams
+type streamJob struct {
+	chunk ChunkPos
+	tile  Tile
+	lod   bool
+	step  int
+	seams [4]int
+}
+
+// tileResult is a built heightmap waiting for upload
+type tileResult struct {
+	job  streamJob
+	data *mesh.Data
+}
+
+// tileState is a heightmap tile on the GL thread: the detail it should
+// have and the mesh currently drawn, which may be older
+type tileState struct {
+	step      int
+	seams     [4]int
+	queued    bool
+	mesh      *mesh.Mesh
+	meshStep  int
+	meshSeams [4]int
+}
+
+// Streamer keeps the world loaded around the player. Chunks within
+// ViewDistance tiles are loaded from the ChunkStore or generated, meshed
+// and uploaded; chunks leaving that area are unloaded, saving the ones
+// the player modified. Tiles up to LODDistance are drawn as heightmaps.
+// Work is done on worker goroutines in order of distance and view
+// direction. Update and Upload must be called on the GL thread, Update
+// every tick and Upload every frame
+type Streamer struct {
+	// ViewDistance is the radius in tiles of the area loaded as chunks
+	ViewDistance int
+	// LODDistance is the radius in tiles of the area drawn at all
+	LODDistance int
+	// VerticalRadius is the number of chunks loaded above and below the
+	// player
+	VerticalRadius int
+	// UploadBudget is the number of meshes uploaded per frame at most
+	UploadBudget int
+	// Headless loads chunks without meshing them or building heightmaps,
+	// for runs without a GL context. It must be set before the first Update
+	Headless bool
+
+	world   *World
+	terrain *Terrain
+	store   *ChunkStore
+	mesher  *ChunkMesher
+	meshes  *ChunkMeshes
+
+	focus   focus
+	queue   *workQueue[streamJob]
+	workers sync.WaitGroup
+	saves   sync.WaitGroup
+
+	mu     sync.Mutex
+	chunks []*Chunk
+	tiles  []tileResult
+	// saving holds the last save of unloaded chunks still running, so that
+	// loading them again meanwhile reuses them instead of reading an
+	// outdated file
+	saving map[ChunkPos]*chunkSave
+
+	// State owned by the GL thread
+	started   bool
+	center    Tile
+	centerY   int32
+	requested map[ChunkPos]bool
+	lodTiles  map[Tile]*tileState
+}
+
+// NewStreamer creates a streamer filling world from store, when not nil,
+// and terrain. It starts its own workers and a ChunkMesher, both with the
+// given number of workers (see NewChunkMesher)
+func NewStreamer(world *World, terrain *Terrain, store *ChunkStore, workers int) *Streamer {
+	if workers <= 0 {
+		workers = max(1, runtime.NumCPU()-1)
+	}
+
+	s := &Streamer{
+		ViewDistance:   DefaultViewDistance,
+		LODDistance:    DefaultLODDistance,
+		VerticalRadius: DefaultVerticalRadius,
+		UploadBudget:   DefaultUploadBudget,
+		world:          world,
+		terrain:        terrain,
+		store:          store,
+		mesher:         NewChunkMesher(world, workers),
+		meshes:         NewChunkMeshes(),
+		saving:         make(map[ChunkPos]*chunkSave),
+		requested:      make(map[ChunkPos]bool),
+		lodTiles:       make(map[Tile]*tileState),
+	}
+	s.queue = newWorkQueue(s.priority)
+
+	s.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+// priority orders streaming jobs by distance from the focus
+func (s *Streamer) priority(job streamJob) float64 {
+	if !job.lod {
+		return s.focus.priority(chunkCenter(job.chunk))
+	}
+	center := job.tile.Origin().Add(vecmath.Vec3{TileSize / 2, float32(s.terrain.SeaLevel), TileSize / 2})
+	return s.focus.priority(center)
+}
+
+// Meshes returns the meshes of loaded chunks
+func (s *Streamer) Meshes() *ChunkMeshes {
+	return s.meshes
+}
+
+// EachTile calls fn for every heightmap tile with its origin and mesh
+func (s *Streamer) EachTile(fn func(origin vecmath.Vec3, m *mesh.Mesh)) {
+	for tile, state := range s.lodTiles {
+		if state.mesh != nil {
+			fn(tile.Origin(), state.mesh)
+		}
+	}
+}
+
+// Pending returns the number of chunks and tiles waiting to be generated
+// or meshed
+func (s *Streamer) Pending() int {
+	return s.queue.len() + s.mesher.Pending()
+}
+
+// Update moves the streamed area to the player, queues the work this
+// requires and adds the chunks loaded since the last call to the world
+func (s *Streamer) Update(position, forward vecmath.Vec3) {
+	s.focus.set(position, forward)
+	s.mesher.Focus(position, forward)
+
+	if s.recenter(position) {
+		s.refresh()
+	}
+
+	s.mu.Lock()
+	chunks := s.chunks
+	s.chunks = nil
+	s.mu.Unlock()
+
+	for _, c := range chunks {
+		if s.requested[c.Pos] && s.world.Chunk(c.Pos) == nil {
+			s.world.AddChunk(c)
+		}
+	}
+	if s.Headless {
+		// Nothing is drawn, so changed chunks need no new meshes
+		s.world.TakeDirty()
+		return
+	}
+	s.mesher.ScheduleDirty()
+}
+
+// Upload uploads finished chunk meshes and heightmaps within the upload
+// budget. It must be called on the GL thread
+func (s *Streamer) Upload() {
+	s.mu.Lock()
+	tiles := s.tiles
+	s.tiles = nil
+	s.mu.Unlock()
+
+	budget := max(1, s.UploadBudget)
+	results := s.mesher.Results(budget)
+	for _, result := range results {
+		s.meshes.Upload(result)
+	}
+	budget -= len(results)
+
+	for i, result := range tiles {
+		if budget <= 0 {
+			// Keep the rest for the next frame
+			s.mu.Lock()
+			s.tiles = append(tiles[i:], s.tiles...)
+			s.mu.Unlock()
+			break
+		}
+		if s.uploadTile(result) {
+			budget--
+		}
+	}
+}
+
+// recenter moves the center of the streamed area to the player's tile
+// once they walk far enough from it, and reports whether it moved
+func (s *Streamer) recenter(position vecmath.Vec3) bool {
+	x, y, z := floorInt(position[0]), floorInt(position[1]), floorInt(position[2])
+	chunkY := int32(floorDivBy(y, ChunkSize))
+	if s.started && !s.outsideCenter(x, z) && abs(int(chunkY-s.centerY)) <= 1 {
+		return false
+	}
+	s.started = true
+	s.center, s.centerY = TileOf(x, z), chunkY
+	return true
+}
+
+// outsideCenter reports whether a world column is far enough from the
+// center tile to move the streamed area
+func (s *Streamer) outsideCenter(x, z int) bool {
+	origin := s.center.Origin()
+	minX, minZ := int(origin[0])-recenterMargin, int(origin[2])-recenterMargin
+	maxX, maxZ := int(origin[0])+TileSize+recenterMargin, int(origin[2])+TileSize+recenterMargin
+	return x < minX || x >= maxX || z < minZ || z >= maxZ
+}
+
+// isVoxelTile reports whether a tile is loaded as chunks
+func (s *Streamer) isVoxelTile(t Tile) bool {
+	return tileDistance(t, s.center) <= s.ViewDistance
+}
+
+// tileStep returns the heightmap step of a tile, doubling with the
+// distance from the voxel area, or zero for voxel tiles
+func (s *Streamer) tileStep(t Tile) int {
+	beyond := tileDistance(t, s.center) - s.ViewDistance
+	if beyond <= 0 {
+		return 0
+	}
+	return min(maxTileStep, 1<<bits.Len(uint(beyond)))
+}
+
+// refresh loads and unloads chunks and tiles around the current center
+func (s *Streamer) refresh() {
+	// Unload chunks outside the voxel area
+	for pos := range s.requested {
+		x, _, z := pos.Origin()
+		if s.isVoxelTile(TileOf(x, z)) && abs(int(pos.Y-s.centerY)) <= s.VerticalRadius {
+			continue
+		}
+		delete(s.requested, pos)
+		s.queue.remove(streamJob{chunk: pos})
+		s.unload(pos)
+	}
+
+	// Request the missing ones
+	for tz := -s.ViewDistance; tz <= s.ViewDistance; tz++ {
+		for tx := -s.ViewDistance; tx <= s.ViewDistance; tx++ {
+			tile := Tile{s.center.X + int32(tx), s.center.Z + int32(tz)}
+			for dz := int32(0); dz < TileSize/ChunkSize; dz++ {
+				for dx := int32(0); dx < TileSize/ChunkSize; dx++ {
+					for dy := -s.VerticalRadius; dy <= s.VerticalRadius; dy++ {
+						pos := ChunkPos{
+							X: tile.X*TileSize/ChunkSize + dx,
+							Y: s.centerY + int32(dy),
+							Z: tile.Z*TileSize/ChunkSize + dz,
+						}
+						if !s.requested[pos] {
+							s.requested[pos] = true
+							s.queue.push(streamJob{chunk: pos})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Drop heightmaps of tiles now loaded as chunks or too far away
+	for tile, state := range s.lodTiles {
+		if s.tileStep(tile) == 0 || tileDistance(tile, s.center) > s.LODDistance {
+			if state.mesh != nil {
+				state.mesh.Release()
+			}
+			delete(s.lodTiles, tile)
+			s.queue.removeIf(func(job streamJob) bool { return job.lod && job.tile == tile })
+		}
+	}
+
+	// Queue heightmaps whose detail or neighbors changed
+	if s.Headless {
+		return
+	}
+	neighbors := [4][2]int32{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+	for tz := -s.LODDistance; tz <= s.LODDistance; tz++ {
+		for tx := -s.LODDistance; tx <= s.LODDistance; tx++ {
+			tile := Tile{s.center.X + int32(tx), s.center.Z + int32(tz)}
+			step := s.tileStep(tile)
+			if step == 0 {
+				continue
+			}
+
+			var seams [4]int
+			for side, n := range neighbors {
+				seams[side] = s.tileStep(Tile{tile.X + n[0], tile.Z + n[1]})
+			}
+
+			state, ok := s.lodTiles[tile]
+			if !ok {
+				state = &tileState{}
+				s.lodTiles[tile] = state
+			}
+			if state.step == step && state.seams == seams && (state.queued || state.mesh != nil) {
+				continue
+			}
+			if state.queued {
+				s.queue.removeIf(func(job streamJob) bool { return job.lod && job.tile == tile })
+			}
+			state.step, state.seams = step, seams
+			state.queued = state.mesh == nil || state.meshStep != step || state.meshSeams != seams
+			if state.queued {
+				s.queue.push(streamJob{tile: tile, lod: true, step: step, seams: seams})
+			}
+		}
+	}
+}
+
+// chunkSave is a chunk being saved in the background. done is closed once
+// the file is written
+type chunkSave struct {
+	chunk *Chunk
+	done  chan struct{}
+}
+
+// unload removes a chunk from the world and releases its mesh. Modified
+// chunks are saved in the background. Saves of the same chunk run one
+// after the other, so the last one unloaded is the one left on disk
+func (s *Streamer) unload(pos ChunkPos) {
+	s.mesher.Forget(pos)
+	s.meshes.Remove(pos)
+
+	c := s.world.RemoveChunk(pos)
+	if c == nil || s.store == nil || !c.Modified() {
+		return
+	}
+
+	// The flag is cleared before taking the snapshot so that edits made
+	// after the chunk is loaded again mark it modified again
+	c.setModified(false)
+	save := &chunkSave{chunk: c, done: make(chan struct{})}
+	s.mu.Lock()
+	previous := s.saving[pos]
+	s.saving[pos] = save
+	s.mu.Unlock()
+
+	s.saves.Add(1)
+	go func() {
+		defer s.saves.Done()
+		defer close(save.done)
+		if previous != nil {
+			<-previous.done
+		}
+		if err := s.store.Save(c); err != nil {
+			log.Printf("Warning: %v", err)
+			c.setModified(true)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.saving[pos] == save {
+			delete(s.saving, pos)
+		}
+	}()
+}
+
+// uploadTile replaces the mesh of a tile if the result is still wanted
+func (s *Streamer) uploadTile(result tileResult) bool {
+	state, ok := s.lodTiles[result.job.tile]
+	if !ok || state.step != result.job.step || state.seams != result.job.seams {
+		return false
+	}
+
+	if state.mesh != nil {
+		state.mesh.Release()
+	}
+	state.mesh = mesh.Upload(result.data, mesh.Static)
+	state.meshStep, state.meshSeams = result.job.step, result.job.seams
+	state.queued = false
+	return true
+}
+
+// work runs streaming jobs until the streamer is closed
+func (s *Streamer) work() {
+	defer s.workers.Done()
+
+	for {
+		job, ok := s.queue.pop()
+		if !ok {
+			return
+		}
+
+		if job.lod {
+			origin := job.tile.Origin()
+			data := s.terrain.HeightmapMesh(int(origin[0]), int(origin[2]), TileSize, job.step, job.seams)
+
+			s.mu.Lock()
+			s.tiles = append(s.tiles, tileResult{job: job, data: data})
+			s.mu.Unlock()
+			continue
+		}
+
+		c := s.loadChunk(job.chunk)
+		s.mu.Lock()
+		s.chunks = append(s.chunks, c)
+		s.mu.Unlock()
+	}
+}
+
+// loadChunk returns a chunk being saved, the saved chunk or a newly
+// generated one
+func (s *Streamer) loadChunk(pos ChunkPos) *Chunk {
+	s.mu.Lock()
+	save := s.saving[pos]
+	s.mu.Unlock()
+	if save != nil {
+		return save.chunk
+	}
+
+	if s.store != nil {
+		c, err := s.store.Load(pos)
+		if err != nil {
+			log.Printf("Warning: regenerating chunk %v: %v", pos, err)
+		} else if c != nil {
+			return c
+		}
+	}
+	return s.terrain.GenerateChunk(pos)
+}
+
+// Close stops the workers, saves every modified chunk and releases all
+// meshes. It must be called on the GL thread
+func (s *Streamer) Close() {
+	s.queue.close()
+	s.workers.Wait()
+	s.mesher.Close()
+
+	for pos := range s.requested {
+		s.unload(pos)
+	}
+	s.saves.Wait()
+
+	s.meshes.Release()
+	for _, state := range s.lodTiles {
+		if state.mesh != nil {
+			state.mesh.Release()
+		}
+	}
+	clear(s.lodTiles)
+	clear(s.requested)
+}
@@ -0,0 +1,275 @@
+package objects
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// streamTimeout bounds the wait for streamed chunks
+const streamTimeout = 10 * time.Second
+
+// newTestStreamer creates a streamer over a terrain world that saves to a
+// temporary directory. It streams one tile around the player and a single
+// layer of chunks, without heightmaps
+func newTestStreamer(t *testing.T) *Streamer {
+	t.Helper()
+	registry := NewBlockRegistry()
+	if err := registry.RegisterDefaultBlocks(); err != nil {
+		t.Fatalf("failed to register blocks: %v", err)
+	}
+	terrain, err := NewTerrain(registry, testSeed, nil)
+	if err != nil {
+		t.Fatalf("failed to create terrain: %v", err)
+	}
+	store, err := NewChunkStore(t.TempDir(), registry)
+	if err != nil {
+		t.Fatalf("failed to create chunk store: %v", err)
+	}
+
+	s := NewStreamer(NewWorld(registry), terrain, store, 2)
+	s.ViewDistance = 1
+	s.LODDistance = 1
+	s.VerticalRadius = 0
+	t.Cleanup(s.Close)
+	return s
+}
+
+// streamTo moves the player to position and updates s until every chunk
+// it requested is in the world
+func streamTo(t *testing.T, s *Streamer, position vecmath.Vec3) {
+	t.Helper()
+	deadline := time.Now().Add(streamTimeout)
+	for {
+		s.Update(position, vecmath.Vec3{0, 0, -1})
+		loaded := len(s.world.Chunks()) == len(s.requested)
+		for pos := range s.requested {
+			loaded = loaded && s.world.Chunk(pos) != nil
+		}
+		if loaded {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d chunks loaded after %v", len(s.world.Chunks()), len(s.requested), streamTimeout)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// voxelArea returns the chunks of a single layer streamed around center
+// with a view distance of one tile
+func voxelArea(center Tile, y int32) map[ChunkPos]bool {
+	const perTile = TileSize / ChunkSize
+	area := make(map[ChunkPos]bool)
+	for tz := center.Z - 1; tz <= center.Z+1; tz++ {
+		for tx := center.X - 1; tx <= center.X+1; tx++ {
+			for dz := int32(0); dz < perTile; dz++ {
+				for dx := int32(0); dx < perTile; dx++ {
+					area[ChunkPos{tx*perTile + dx, y, tz*perTile + dz}] = true
+				}
+			}
+		}
+	}
+	return area
+}
+
+func TestStreamerLoadsAroundCenter(t *testing.T) {
+	s := newTestStreamer(t)
+
+	for _, center := range []Tile{{0, 0}, {2, 0}, {2, -3}} {
+		origin := center.Origin()
+		streamTo(t, s, origin.Add(vecmath.Vec3{TileSize / 2, 40, TileSize / 2}))
+
+		want := voxelArea(center, 2)
+		chunks := s.world.Chunks()
+		if len(chunks) != len(want) {
+			t.Errorf("center %v: %d chunks loaded, want %d", center, len(chunks), len(want))
+		}
+		for _, pos := range chunks {
+			if !want[pos] {
+				t.Errorf("center %v: chunk %v outside the view distance is loaded", center, pos)
+			}
+		}
+	}
+}
+
+func TestStreamerRecenterHysteresis(t *testing.T) {
+	s := &Streamer{}
+	steps := []struct {
+		name    string
+		x, y, z float32
+		moved   bool
+		center  Tile
+	}{
+		{"start", 10, 40, 10, true, Tile{0, 0}},
+		{"inside the tile", 31.5, 40, 0, false, Tile{0, 0}},
+		{"past the border", 33, 40, 0, false, Tile{0, 0}},
+		{"within the margin", 39.9, 40, 0, false, Tile{0, 0}},
+		{"past the margin", 40, 40, 10, true, Tile{1, 0}},
+		{"back over the border", 31, 40, 10, false, Tile{1, 0}},
+		{"back past the margin", 23.9, 40, 10, true, Tile{0, 0}},
+		{"negative side", 10, 40, -8, false, Tile{0, 0}},
+		{"negative past the margin", 10, 40, -8.1, true, Tile{0, -1}},
+		{"one chunk up", 10, 56, -10, false, Tile{0, -1}},
+		{"two chunks up", 10, 72, -10, true, Tile{0, -1}},
+	}
+	for _, step := range steps {
+		moved := s.recenter(vecmath.Vec3{step.x, step.y, step.z})
+		if moved != step.moved || s.center != step.center {
+			t.Errorf("%s: moved = %v center = %v, want %v %v", step.name, moved, s.center, step.moved, step.center)
+		}
+	}
+}
+
+func TestTileStep(t *testing.T) {
+	s := &Streamer{ViewDistance: 2}
+	tests := []struct {
+		tile Tile
+		want int
+	}{
+		{Tile{0, 0}, 0},
+		{Tile{2, -2}, 0},
+		{Tile{3, 0}, 2},
+		{Tile{-4, 1}, 4},
+		{Tile{0, 5}, 4},
+		{Tile{6, 6}, 8},
+		{Tile{-9, 0}, 8},
+		{Tile{10, 0}, maxTileStep},
+		{Tile{0, 100}, maxTileStep},
+	}
+	for _, tt := range tests {
+		if got := s.tileStep(tt.tile); got != tt.want {
+			t.Errorf("tileStep(%v) = %d, want %d", tt.tile, got, tt.want)
+		}
+	}
+}
+
+func TestStreamerHeightmapSeams(t *testing.T) {
+	s := newTestStreamer(t)
+	s.LODDistance = 6
+	s.Update(vecmath.Vec3{TileSize / 2, 40, TileSize / 2}, vecmath.Vec3{0, 0, -1})
+
+	// Every tile around the voxel area has a heightmap
+	side := 2*s.LODDistance + 1
+	voxel := 2*s.ViewDistance + 1
+	if got, want := len(s.lodTiles), side*side-voxel*voxel; got != want {
+		t.Fatalf("%d heightmap tiles, want %d", got, want)
+	}
+
+	neighbors := [4][2]int32{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+	for tile, state := range s.lodTiles {
+		if state.step != s.tileStep(tile) || state.step == 0 {
+			t.Errorf("tile %v step = %d, want %d", tile, state.step, s.tileStep(tile))
+		}
+		for i, n := range neighbors {
+			step := s.tileStep(Tile{tile.X + n[0], tile.Z + n[1]})
+			if state.seams[i] != step {
+				t.Errorf("tile %v seam %d = %d, want the neighbor step %d", tile, i, state.seams[i], step)
+			}
+			// Neighbors differ by one level of detail at most, so the
+			// finer side can be stitched to the coarser one
+			if step != 0 && (step > 2*state.step || state.step > 2*step) {
+				t.Errorf("tile %v step %d next to step %d", tile, state.step, step)
+			}
+			if step == 0 && state.step != 2 {
+				t.Errorf("tile %v next to the voxel area has step %d", tile, state.step)
+			}
+		}
+	}
+}
+
+func TestStreamerKeepsModifiedChunks(t *testing.T) {
+	s := newTestStreamer(t)
+	stone, _ := s.world.Registry().Lookup("stone")
+	home := vecmath.Vec3{TileSize / 2, 40, TileSize / 2}
+	away := home.Add(vecmath.Vec3{TileSize * 4, 0, 0})
+
+	streamTo(t, s, home)
+	x, y, z := 5, 35, 7
+	s.world.SetBlock(x, y, z, Air)
+	s.world.SetBlock(x, y+1, z, stone)
+
+	// Unloading saves the chunk in the background; coming back while the
+	// save may still run must not regenerate it
+	streamTo(t, s, away)
+	streamTo(t, s, home)
+	if got := s.world.Block(x, y, z); got != Air {
+		t.Errorf("removed block came back as %d", got)
+	}
+	if got := s.world.Block(x, y+1, z); got != stone {
+		t.Errorf("placed block = %d, want %d", got, stone)
+	}
+
+	// Once saved, the file has the changes too
+	streamTo(t, s, away)
+	s.saves.Wait()
+	pos, lx, ly, lz := ChunkPosOf(x, y+1, z)
+	c, err := s.store.Load(pos)
+	if err != nil || c == nil {
+		t.Fatalf("modified chunk was not saved: %v", err)
+	}
+	if got := c.Get(lx, ly, lz); got != stone {
+		t.Errorf("saved block = %d, want %d", got, stone)
+	}
+}
+
+func TestStreamerLoadsChunksBeingSaved(t *testing.T) {
+	s := newTestStreamer(t)
+	stone, _ := s.world.Registry().Lookup("stone")
+	dirt, _ := s.world.Registry().Lookup("dirt")
+	pos := ChunkPos{3, 1, -2}
+
+	// An older version of the chunk is on disk
+	saved := NewChunk(pos)
+	saved.Fill(dirt)
+	if err := s.store.Save(saved); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if got := s.loadChunk(pos).Get(0, 0, 0); got != dirt {
+		t.Fatalf("loaded block = %d, want the saved %d", got, dirt)
+	}
+
+	// A newer one is being saved
+	saving := NewChunk(pos)
+	saving.Fill(stone)
+	s.mu.Lock()
+	s.saving[pos] = &chunkSave{chunk: saving, done: make(chan struct{})}
+	s.mu.Unlock()
+	if got := s.loadChunk(pos); got != saving {
+		t.Errorf("loadChunk did not reuse the chunk being saved")
+	}
+}
+
+func TestStreamerSavesInOrder(t *testing.T) {
+	s := newTestStreamer(t)
+	stone, _ := s.world.Registry().Lookup("stone")
+	pos := ChunkPos{3, 1, -2}
+
+	// An earlier save of the chunk is still running
+	previous := &chunkSave{chunk: NewChunk(pos), done: make(chan struct{})}
+	s.mu.Lock()
+	s.saving[pos] = previous
+	s.mu.Unlock()
+
+	s.world.AddChunk(NewChunk(pos))
+	x, y, z := pos.Origin()
+	s.world.SetBlock(x, y, z, stone)
+	s.unload(pos)
+
+	// The new save waits for it
+	time.Sleep(10 * time.Millisecond)
+	if c, err := s.store.Load(pos); err != nil || c != nil {
+		t.Fatalf("chunk saved before the earlier save finished: %v", err)
+	}
+
+	close(previous.done)
+	s.saves.Wait()
+	c, err := s.store.Load(pos)
+	if err != nil || c == nil {
+		t.Fatalf("chunk was not saved: %v", err)
+	}
+	if got := c.Get(0, 0, 0); got != stone {
+		t.Errorf("saved block = %d, want %d", got, stone)
+	}
+}
@@ -1 +1,568 @@
 package objects
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/mesh"
+)
+
+// DefaultSeaLevel is the world height of the water surface
+const DefaultSeaLevel = 32
+
+// featureMargin is how far from a chunk features rooted outside it can
+// reach into it
+const featureMargin = 3
+
+// BiomeDef describes a biome by block names. Biomes are chosen by the
+// temperature and moisture closest to theirs
+type BiomeDef struct {
+	Name string
+	// Temperature and Moisture are the climate the biome is centered on,
+	// both in [0, 1]
+	Temperature, Moisture float64
+	// Surface is the top block and Subsurface the SubsurfaceDepth blocks
+	// below it, above stone
+	Surface, Subsurface string
+	SubsurfaceDepth     int
+	// TreeChance and RockChance are the probabilities that a column grows
+	// a tree or a rock
+	TreeChance, RockChance float64
+}
+
+// DefaultBiomes are the biomes of the base game
+var DefaultBiomes = []BiomeDef{
+	{Name: "desert", Temperature: 0.75, Moisture: 0.15, Surface: "sand", Subsurface: "sand", SubsurfaceDepth: 4, RockChance: 0.002},
+	{Name: "plains", Temperature: 0.55, Moisture: 0.4, Surface: "grass", Subsurface: "dirt", SubsurfaceDepth: 3, TreeChance: 0.002, RockChance: 0.001},
+	{Name: "forest", Temperature: 0.5, Moisture: 0.75, Surface: "grass", Subsurface: "dirt", SubsurfaceDepth: 3, TreeChance: 0.03, RockChance: 0.002},
+	{Name: "steppe", Temperature: 0.35, Moisture: 0.15, Surface: "gravel", Subsurface: "dirt", SubsurfaceDepth: 2, RockChance: 0.01},
+	{Name: "tundra", Temperature: 0.1, Moisture: 0.5, Surface: "snow", Subsurface: "dirt", SubsurfaceDepth: 2, TreeChance: 0.004, RockChance: 0.003},
+}
+
+// Biome is a BiomeDef with resolved block IDs
+type Biome struct {
+	Name                  string
+	Temperature, Moisture float64
+	Surface, Subsurface   BlockID
+	SubsurfaceDepth       int
+	TreeChance            float64
+	RockChance            float64
+}
+
+// Column describes the terrain of one world column
+type Column struct {
+	// Height is the world height of the topmost terrain block
+	Height int
+	Biome  *Biome
+	// Surface and Subsurface are the blocks actually used, which differ
+	// from the biome's on beaches, sea floors, cliffs and peaks
+	Surface, Subsurface   BlockID
+	SubsurfaceDepth       int
+	Temperature, Moisture float64
+}
+
+// Terrain generates the world from a seed. Generation only depends on the
+// seed and the position, so chunks can be generated in any order, on any
+// goroutine, and the same seed always produces the same world
+type Terrain struct {
+	Seed     int64
+	SeaLevel int
+	// SnowLine is the height above which peaks are covered in snow
+	SnowLine int
+
+	continent, hills, mountains, ridges *Noise
+	temperature, moisture               *Noise
+	cavesA, cavesB                      *Noise
+
+	biomes []Biome
+	layers [][6]int
+	blocks struct {
+		stone, water, sand, gravel, snow BlockID
+		log, leaves, cobblestone         BlockID
+	}
+}
+
+// SeedFromString turns a seed typed by the player into a numeric seed.
+// Numbers are used as is so that seeds can be shared either way
+func SeedFromString(s string) int64 {
+	var n int64
+	if _, err := fmt.Sscan(s, &n); err == nil {
+		return n
+	}
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return int64(h.Sum64())
+}
+
+// NewTerrain creates a terrain generator using blocks from registry. Nil
+// biomes use DefaultBiomes
+func NewTerrain(registry *BlockRegistry, seed int64, biomes []BiomeDef) (*Terrain, error) {
+	if biomes == nil {
+		biomes = DefaultBiomes
+	}
+	if len(biomes) == 0 {
+		return nil, fmt.Errorf("no biomes defined")
+	}
+
+	lookup := func(name string) (BlockID, error) {
+		id, ok := registry.Lookup(name)
+		if !ok {
+			return Air, fmt.Errorf("terrain block %q is not registered", name)
+		}
+		return id, nil
+	}
+
+	t := &Terrain{
+		Seed:     seed,
+		SeaLevel: DefaultSeaLevel,
+		SnowLine: DefaultSeaLevel + 56,
+		layers:   registry.textureLayers(),
+	}
+
+	var err error
+	for _, b := range []struct {
+		dst  *BlockID
+		name string
+	}{
+		{&t.blocks.stone, "stone"}, {&t.blocks.water, "water"}, {&t.blocks.sand, "sand"},
+		{&t.blocks.gravel, "gravel"}, {&t.blocks.snow, "snow"}, {&t.blocks.log, "log"},
+		{&t.blocks.leaves, "leaves"}, {&t.blocks.cobblestone, "cobblestone"},
+	} {
+		if *b.dst, err = lookup(b.name); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, def := range biomes {
+		biome := Biome{
+			Name:            def.Name,
+			Temperature:     def.Temperature,
+			Moisture:        def.Moisture,
+			SubsurfaceDepth: def.SubsurfaceDepth,
+			TreeChance:      def.TreeChance,
+			RockChance:      def.RockChance,
+		}
+		if biome.Surface, err = lookup(def.Surface); err != nil {
+			return nil, fmt.Errorf("failed to load biome %q: %v", def.Name, err)
+		}
+		if biome.Subsurface, err = lookup(def.Subsurface); err != nil {
+			return nil, fmt.Errorf("failed to load biome %q: %v", def.Name, err)
+		}
+		t.biomes = append(t.biomes, biome)
+	}
+
+	// Every noise layer gets its own seed so they are uncorrelated
+	rng := splitMix(seed)
+	for _, n := range []**Noise{
+		&t.continent, &t.hills, &t.mountains, &t.ridges,
+		&t.temperature, &t.moisture, &t.cavesA, &t.cavesB,
+	} {
+		*n = NewNoise(int64(rng.next()))
+	}
+
+	return t, nil
+}
+
+// Biomes returns the biomes of the terrain
+func (t *Terrain) Biomes() []Biome {
+	return t.biomes
+}
+
+// Noise layer settings, in blocks
+var (
+	continentOctaves = Octaves{Count: 4, Frequency: 1.0 / 600, Lacunarity: 2, Gain: 0.5}
+	hillOctaves      = Octaves{Count: 4, Frequency: 1.0 / 120, Lacunarity: 2, Gain: 0.5}
+	mountainOctaves  = Octaves{Count: 2, Frequency: 1.0 / 900, Lacunarity: 2, Gain: 0.5}
+	ridgeOctaves     = Octaves{Count: 5, Frequency: 1.0 / 300, Lacunarity: 2, Gain: 0.5}
+	climateOctaves   = Octaves{Count: 3, Frequency: 1.0 / 800, Lacunarity: 2, Gain: 0.5}
+	caveOctaves      = Octaves{Count: 2, Frequency: 1.0 / 48, Lacunarity: 2, Gain: 0.5}
+)
+
+// caveRadius is the squared width of cave tunnels in noise units
+const caveRadius = 0.004
+
+// Height returns the terrain height at world coordinates, before rounding
+// to blocks. Continents set the base level, hills add rolling detail and
+// ridged noise raises mountain ranges where the mountain mask allows
+func (t *Terrain) Height(x, z float64) float64 {
+	continent := t.continent.FBM2(x, z, continentOctaves)
+	hills := t.hills.FBM2(x, z, hillOctaves)
+	mask := smoothstep(0.05, 0.5, t.mountains.FBM2(x, z, mountainOctaves))
+	ridges := t.ridges.Ridged2(x, z, ridgeOctaves)
+
+	return float64(t.SeaLevel) + 4 + continent*28 + hills*6*(1+continent) + mask*ridges*72
+}
+
+// smoothstep maps v from [lo, hi] to [0, 1] with a smooth curve
+func smoothstep(lo, hi, v float64) float64 {
+	v = min(1, max(0, (v-lo)/(hi-lo)))
+	return v * v * (3 - 2*v)
+}
+
+// climate returns the temperature and moisture at world coordinates, in
+// [0, 1]. Temperature drops with altitude
+func (t *Terrain) climate(x, z, height float64) (temperature, moisture float64) {
+	temperature = 0.5 + 0.6*t.temperature.FBM2(x, z, climateOctaves)
+	temperature -= max(0, height-float64(t.SeaLevel)) / 150
+	moisture = 0.5 + 0.6*t.moisture.FBM2(x, z, climateOctaves)
+	return min(1, max(0, temperature)), min(1, max(0, moisture))
+}
+
+// biomeAt returns the biome closest to a climate
+func (t *Terrain) biomeAt(temperature, moisture float64) *Biome {
+	best, bestDist := &t.biomes[0], math.Inf(1)
+	for i := range t.biomes {
+		b := &t.biomes[i]
+		dt, dm := b.Temperature-temperature, b.Moisture-moisture
+		if d := dt*dt + dm*dm; d < bestDist {
+			best, bestDist = b, d
+		}
+	}
+	return best
+}
+
+// Column returns the terrain of the column at world coordinates
+func (t *Terrain) Column(x, z int) Column {
+	var heights [3][3]float64
+	for dz := -1; dz <= 1; dz++ {
+		for dx := -1; dx <= 1; dx++ {
+			heights[dz+1][dx+1] = t.Height(float64(x+dx), float64(z+dz))
+		}
+	}
+	return t.column(x, z, heights)
+}
+
+// column builds a Column from the heights of a column and its neighbors,
+// which give the slope
+func (t *Terrain) column(x, z int, heights [3][3]float64) Column {
+	h := heights[1][1]
+	temperature, moisture := t.climate(float64(x), float64(z), h)
+	biome := t.biomeAt(temperature, moisture)
+
+	col := Column{
+		Height:          int(math.Floor(h)),
+		Biome:           biome,
+		Surface:         biome.Surface,
+		Subsurface:      biome.Subsurface,
+		SubsurfaceDepth: biome.SubsurfaceDepth,
+		Temperature:     temperature,
+		Moisture:        moisture,
+	}
+
+	slope := max(math.Abs(heights[1][2]-heights[1][0]), math.Abs(heights[2][1]-heights[0][1])) / 2
+	switch {
+	case col.Height < t.SeaLevel-6:
+		col.Surface, col.Subsurface = t.blocks.gravel, t.blocks.gravel
+	case col.Height <= t.SeaLevel+1:
+		col.Surface, col.Subsurface = t.blocks.sand, t.blocks.sand
+	case slope > 1.6:
+		col.Surface, col.Subsurface = t.blocks.stone, t.blocks.stone
+	case col.Height > t.SnowLine:
+		col.Surface, col.Subsurface, col.SubsurfaceDepth = t.blocks.snow, t.blocks.stone, 1
+	}
+	return col
+}
+
+// isCave reports whether a block is carved out by a cave. Tunnels follow
+// the lines where two noise fields are both near zero
+func (t *Terrain) isCave(x, y, z int) bool {
+	fx, fy, fz := float64(x), float64(y)*1.5, float64(z)
+	a := t.cavesA.FBM3(fx, fy, fz, caveOctaves)
+	if a*a > caveRadius {
+		return false
+	}
+	b := t.cavesB.FBM3(fx, fy, fz, caveOctaves)
+	return a*a+b*b < caveRadius
+}
+
+// blockAt returns the terrain block at a height of a column
+func (t *Terrain) blockAt(col *Column, x, y, z int) BlockID {
+	if y > col.Height {
+		if y <= t.SeaLevel {
+			return t.blocks.water
+		}
+		return Air
+	}
+
+	depth := col.Height - y
+	// Caves stay below the surface so they never drain the sea or leave
+	// floating features
+	if depth > 3 && t.isCave(x, y, z) {
+		return Air
+	}
+	switch {
+	case depth == 0:
+		return col.Surface
+	case depth <= col.SubsurfaceDepth:
+		return col.Subsurface
+	default:
+		return t.blocks.stone
+	}
+}
+
+// GenerateChunk generates the chunk at pos, including the parts of trees
+// and rocks rooted in neighboring chunks
+func (t *Terrain) GenerateChunk(pos ChunkPos) *Chunk {
+	c := NewChunk(pos)
+	ox, oy, oz := pos.Origin()
+
+	// Heights of the chunk's columns, the feature margin and one more
+	// column for slopes
+	const border = featureMargin + 1
+	const size = ChunkSize + 2*border
+	var heights [size][size]float64
+	for z := 0; z < size; z++ {
+		for x := 0; x < size; x++ {
+			heights[z][x] = t.Height(float64(ox+x-border), float64(oz+z-border))
+		}
+	}
+
+	const area = ChunkSize + 2*featureMargin
+	var columns [area][area]Column
+	highest := t.SeaLevel
+	for z := 0; z < area; z++ {
+		for x := 0; x < area; x++ {
+			var around [3][3]float64
+			for dz := 0; dz < 3; dz++ {
+				for dx := 0; dx < 3; dx++ {
+					around[dz][dx] = heights[z+dz][x+dx]
+				}
+			}
+			columns[z][x] = t.column(ox+x-featureMargin, oz+z-featureMargin, around)
+			highest = max(highest, columns[z][x].Height)
+		}
+	}
+
+	// Nothing but air above the highest terrain and features
+	if oy > highest+maxFeatureHeight {
+		return c
+	}
+
+	for z := 0; z < ChunkSize; z++ {
+		for x := 0; x < ChunkSize; x++ {
+			col := &columns[z+featureMargin][x+featureMargin]
+			if oy > col.Height && oy > t.SeaLevel {
+				continue
+			}
+			for y := 0; y < ChunkSize; y++ {
+				if id := t.blockAt(col, ox+x, oy+y, oz+z); id != Air {
+					c.Set(x, y, z, id)
+				}
+			}
+		}
+	}
+
+	for z := 0; z < area; z++ {
+		for x := 0; x < area; x++ {
+			t.placeFeature(c, &columns[z][x], ox+x-featureMargin, oz+z-featureMargin)
+		}
+	}
+
+	c.Compact()
+	return c
+}
+
+// maxFeatureHeight is the height of the tallest feature above the ground
+const maxFeatureHeight = 8
+
+// placeFeature places the tree or rock rooted in a column, if any, writing
+// the blocks that fall inside c
+func (t *Terrain) placeFeature(c *Chunk, col *Column, x, z int) {
+	if col.Height <= t.SeaLevel || col.Surface == t.blocks.stone {
+		return
+	}
+
+	r := hashColumn(t.Seed, x, z)
+	y := col.Height + 1
+	switch {
+	case r < col.Biome.TreeChance && col.Surface != t.blocks.sand:
+		t.placeTree(c, x, y, z, 4+int(hashColumn(t.Seed+1, x, z)*3))
+	case r < col.Biome.TreeChance+col.Biome.RockChance:
+		t.placeRock(c, x, y-1, z, 1+int(hashColumn(t.Seed+2, x, z)*2))
+	}
+}
+
+// placeTree places a tree with a trunk of the given height
+func (t *Terrain) placeTree(c *Chunk, x, y, z, height int) {
+	top := y + height
+	for dy := -3; dy <= 1; dy++ {
+		radius := 2
+		if dy >= 0 {
+			radius = 1
+		}
+		for dz := -radius; dz <= radius; dz++ {
+			for dx := -radius; dx <= radius; dx++ {
+				// Trim the corners of the wide layers
+				if radius == 2 && abs(dx) == 2 && abs(dz) == 2 {
+					continue
+				}
+				t.setFeature(c, x+dx, top+dy, z+dz, t.blocks.leaves)
+			}
+		}
+	}
+	for dy := 0; dy < height; dy++ {
+		t.setFeature(c, x, y+dy, z, t.blocks.log)
+	}
+}
+
+// placeRock places a rough ball of cobblestone half buried in the ground
+func (t *Terrain) placeRock(c *Chunk, x, y, z, radius int) {
+	for dy := -radius; dy <= radius; dy++ {
+		for dz := -radius; dz <= radius; dz++ {
+			for dx := -radius; dx <= radius; dx++ {
+				if dx*dx+dy*dy+dz*dz <= radius*radius {
+					t.setFeature(c, x+dx, y+dy, z+dz, t.blocks.cobblestone)
+				}
+			}
+		}
+	}
+}
+
+// setFeature writes a feature block at world coordinates if it lies in c.
+// Features only replace air and lower priority feature blocks, so the
+// result does not depend on the order features are placed in, which
+// differs between neighboring chunks
+func (t *Terrain) setFeature(c *Chunk, x, y, z int, id BlockID) {
+	pos, lx, ly, lz := ChunkPosOf(x, y, z)
+	if pos != c.Pos {
+		return
+	}
+	existing := c.Get(lx, ly, lz)
+	if priority := t.featurePriority(existing); existing == Air || (priority > 0 && priority < t.featurePriority(id)) {
+		c.Set(lx, ly, lz, id)
+	}
+}
+
+// featurePriority orders the blocks features are made of. Terrain blocks
+// have priority 0 and are never replaced
+func (t *Terrain) featurePriority(id BlockID) int {
+	switch id {
+	case t.blocks.log:
+		return 3
+	case t.blocks.cobblestone:
+		return 2
+	case t.blocks.leaves:
+		return 1
+	}
+	return 0
+}
+
+// abs returns the absolute value of an int
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// skirtDepth is how far heightmap skirts hang below the surface
+const skirtDepth = 8
+
+// HeightmapMesh builds a mesh of the terrain surface over size x size
+// blocks from world column (x, z), sampling every step blocks, for distant
+// terrain that is not loaded as chunks. It uses ChunkLayout so it can be
+// drawn like chunk meshes; positions are relative to (x, 0, z).
+//
+// seams holds the sample step of the neighboring meshes on the -X, +X, -Z
+// and +Z sides. Edges next to coarser meshes follow the coarser edge so no
+// cracks open between them, and edges with a zero step, i.e. next to voxel
+// chunks, get a skirt hanging down to hide the gaps between the two
+func (t *Terrain) HeightmapMesh(x, z, size, step int, seams [4]int) *mesh.Data {
+	step = max(1, step)
+	n := size/step + 1
+
+	// Heights with a one sample border for normals and slopes
+	heights := make([]float64, (n+2)*(n+2))
+	at := func(i, j int) float64 { return heights[(j+1)*(n+2)+i+1] }
+	for j := -1; j <= n; j++ {
+		for i := -1; i <= n; i++ {
+			heights[(j+1)*(n+2)+i+1] = t.Height(float64(x+i*step), float64(z+j*step))
+		}
+	}
+
+	data := &mesh.Data{Layout: ChunkLayout, Primitive: mesh.Triangles}
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			h := at(i, j)
+			// Neighbor heights scaled to one block apart, as column expects
+			near := func(di, dj int) float64 { return h + (at(i+di, j+dj)-h)/float64(step) }
+			col := t.column(x+i*step, z+j*step, [3][3]float64{
+				{h, near(0, -1), h},
+				{near(-1, 0), h, near(1, 0)},
+				{h, near(0, 1), h},
+			})
+
+			surface, top := col.Surface, float64(col.Height+1)
+			if col.Height < t.SeaLevel {
+				surface, top = t.blocks.water, float64(t.SeaLevel+1)
+			}
+
+			nx := (at(i-1, j) - at(i+1, j)) / float64(2*step)
+			nz := (at(i, j-1) - at(i, j+1)) / float64(2*step)
+			l := math.Sqrt(nx*nx + 1 + nz*nz)
+
+			data.Vertices = append(data.Vertices,
+				float32(i*step), float32(top), float32(j*step),
+				float32(nx/l), float32(1/l), float32(nz/l),
+				float32(x+i*step), float32(z+j*step),
+				float32(t.layers[surface][FaceUp]), faceShade[FaceUp],
+			)
+		}
+	}
+
+	// edges maps the k-th vertex along each side to its vertex index
+	stride := int(ChunkLayout.Stride())
+	edges := [4]func(k int) int{
+		func(k int) int { return k * n },
+		func(k int) int { return k*n + n - 1 },
+		func(k int) int { return k },
+		func(k int) int { return (n-1)*n + k },
+	}
+
+	for side, neighbor := range seams {
+		if neighbor <= step {
+			continue
+		}
+		ratio := neighbor / step
+		vertex := edges[side]
+		for k := 0; k < n; k++ {
+			if k%ratio == 0 {
+				continue
+			}
+			k0 := k - k%ratio
+			k1 := min(k0+ratio, n-1)
+			f := float32(k-k0) / float32(ratio)
+			y0, y1 := data.Vertices[vertex(k0)*stride+1], data.Vertices[vertex(k1)*stride+1]
+			data.Vertices[vertex(k)*stride+1] = y0 + f*(y1-y0)
+		}
+	}
+
+	for j := 0; j < n-1; j++ {
+		for i := 0; i < n-1; i++ {
+			a := uint32(j*n + i)
+			b, c, d := a+1, a+uint32(n), a+uint32(n)+1
+			data.Indices = append(data.Indices, a, c, b, b, c, d)
+		}
+	}
+
+	for side, neighbor := range seams {
+		if neighbor != 0 {
+			continue
+		}
+		vertex := edges[side]
+		base := uint32(data.VertexCount())
+		for k := 0; k < n; k++ {
+			skirt := append([]float32(nil), data.Vertices[vertex(k)*stride:(vertex(k)+1)*stride]...)
+			skirt[1] -= skirtDepth
+			data.Vertices = append(data.Vertices, skirt...)
+		}
+		for k := 0; k < n-1; k++ {
+			a, b := uint32(vertex(k)), uint32(vertex(k+1))
+			c, d := base+uint32(k), base+uint32(k+1)
+			// Both windings so the skirt shows from either side
+			data.Indices = append(data.Indices, a, c, b, b, c, d, a, b, c, b, d, c)
+		}
+	}
+	return data
+}
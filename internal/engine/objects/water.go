@@ -1 +1,489 @@
 package objects
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
+	"github.com/luidsonl/magic-and-blades/internal/engine/mesh"
+	"github.com/luidsonl/magic-and-blades/internal/engine/shader"
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// MaxWaterLevel is the amount of water in a full block
+const MaxWaterLevel = 8
+
+// DefaultWaterUpdates is the number of water cells updated per tick
+const DefaultWaterUpdates = 4096
+
+// blockPos is the position of a block in world coordinates
+type blockPos struct {
+	X, Y, Z int
+}
+
+// add returns the position offset by d
+func (p blockPos) add(d [3]int) blockPos {
+	return blockPos{p.X + d[0], p.Y + d[1], p.Z + d[2]}
+}
+
+// horizontalFaces are the faces water spreads across
+var horizontalFaces = [4]Face{FaceEast, FaceSouth, FaceWest, FaceNorth}
+
+// WaterSim makes voxel water flow. Every tick, water falls into the space
+// below it and otherwise spreads sideways one level at a time until
+// neighboring levels differ by at most one, so pools level out over a few
+// ticks. Water blocks the simulation did not place, such as oceans from
+// terrain generation, are infinite sources; water it placed is finite and
+// conserved. Its levels are kept in the chunks, so flowing water stays
+// finite when chunks are saved and loaded again. Only cells woken by a
+// change are updated, so still water costs nothing. It is not safe for
+// concurrent use; call it from the game tick
+type WaterSim struct {
+	// MaxUpdates bounds the number of cells updated per tick; the rest are
+	// updated on the following ticks
+	MaxUpdates int
+
+	world  *World
+	water  BlockID
+	active map[blockPos]struct{}
+	ticks  uint64
+}
+
+// NewWaterSim creates a water simulation for world, whose registry must
+// contain a "water" block
+func NewWaterSim(world *World) (*WaterSim, error) {
+	water, ok := world.Registry().Lookup("water")
+	if !ok {
+		return nil, fmt.Errorf("water block is not registered")
+	}
+	return &WaterSim{
+		MaxUpdates: DefaultWaterUpdates,
+		world:      world,
+		water:      water,
+		active:     make(map[blockPos]struct{}),
+	}, nil
+}
+
+// Level returns the amount of water at world coordinates, from 0 to
+// MaxWaterLevel
+func (s *WaterSim) Level(x, y, z int) uint8 {
+	return s.level(blockPos{x, y, z})
+}
+
+// level returns the amount of water in a block
+func (s *WaterSim) level(p blockPos) uint8 {
+	if s.world.Block(p.X, p.Y, p.Z) != s.water {
+		return 0
+	}
+	if level, ok := s.world.WaterLevel(p.X, p.Y, p.Z); ok {
+		return level
+	}
+	return MaxWaterLevel
+}
+
+// isSource reports whether a block is an infinite water source
+func (s *WaterSim) isSource(p blockPos) bool {
+	_, flowing := s.world.WaterLevel(p.X, p.Y, p.Z)
+	return !flowing && s.world.Block(p.X, p.Y, p.Z) == s.water
+}
+
+// capacity returns how much water a block can still take
+func (s *WaterSim) capacity(p blockPos) uint8 {
+	switch s.world.Block(p.X, p.Y, p.Z) {
+	case Air:
+		return MaxWaterLevel
+	case s.water:
+		if s.isSource(p) {
+			return 0
+		}
+		return MaxWaterLevel - s.level(p)
+	}
+	return 0
+}
+
+// setLevel changes the amount of flowing water in a block, placing or
+// removing the water block as needed
+func (s *WaterSim) setLevel(p blockPos, level uint8) {
+	if level == 0 {
+		s.world.SetBlock(p.X, p.Y, p.Z, Air)
+		return
+	}
+	if s.world.Block(p.X, p.Y, p.Z) != s.water {
+		s.world.SetBlock(p.X, p.Y, p.Z, s.water)
+	}
+	s.world.SetWaterLevel(p.X, p.Y, p.Z, level)
+}
+
+// AddWater pours water into the block at world coordinates, e.g. from a
+// bucket. Water beyond what the block can take is lost
+func (s *WaterSim) AddWater(x, y, z int, amount uint8) {
+	p := blockPos{x, y, z}
+	amount = min(amount, s.capacity(p))
+	if amount == 0 {
+		return
+	}
+	s.setLevel(p, s.level(p)+amount)
+	s.wakeAround(p)
+}
+
+// AddSource places an infinite water source at world coordinates
+func (s *WaterSim) AddSource(x, y, z int) {
+	p := blockPos{x, y, z}
+	s.world.SetBlock(x, y, z, s.water)
+	s.world.SetWaterLevel(x, y, z, 0)
+	s.wakeAround(p)
+}
+
+// Wake makes the water at and around world coordinates flow again. Call it
+// whenever a block changes, e.g. when the player digs next to a lake
+func (s *WaterSim) Wake(x, y, z int) {
+	s.wakeAround(blockPos{x, y, z})
+}
+
+// wakeAround activates a block and its six neighbors
+func (s *WaterSim) wakeAround(p blockPos) {
+	s.active[p] = struct{}{}
+	for _, face := range Faces {
+		s.active[p.add(face.Direction())] = struct{}{}
+	}
+}
+
+// Active returns the number of cells waiting for an update
+func (s *WaterSim) Active() int {
+	return len(s.active)
+}
+
+// Tick advances the simulation by one step
+func (s *WaterSim) Tick() {
+	if len(s.active) == 0 {
+		return
+	}
+
+	// Cells are updated bottom-up in a fixed order so the result does not
+	// depend on map iteration order
+	cells := make([]blockPos, 0, len(s.active))
+	for p := range s.active {
+		cells = append(cells, p)
+	}
+	slices.SortFunc(cells, func(a, b blockPos) int {
+		return cmp.Or(cmp.Compare(a.Y, b.Y), cmp.Compare(a.Z, b.Z), cmp.Compare(a.X, b.X))
+	})
+	if s.MaxUpdates > 0 && len(cells) > s.MaxUpdates {
+		cells = cells[:s.MaxUpdates]
+	}
+	for _, p := range cells {
+		delete(s.active, p)
+	}
+
+	for _, p := range cells {
+		s.update(p)
+	}
+	s.ticks++
+}
+
+// update moves the water of one cell
+func (s *WaterSim) update(p blockPos) {
+	level := s.level(p)
+	if level == 0 {
+		return
+	}
+	remaining := level
+
+	below := p.add(FaceDown.Direction())
+	if room := s.capacity(below); room > 0 {
+		flow := min(remaining, room)
+		s.setLevel(below, s.level(below)+flow)
+		s.wakeAround(below)
+		remaining -= flow
+	}
+
+	// Spread only over a floor, giving one level to each lower neighbor.
+	// The starting direction rotates every tick to avoid a bias
+	if remaining > 1 && s.capacity(below) == 0 {
+		for i := range horizontalFaces {
+			face := horizontalFaces[(i+int(s.ticks))%len(horizontalFaces)]
+			n := p.add(face.Direction())
+			if s.capacity(n) == 0 {
+				continue
+			}
+			if neighbor := s.level(n); remaining > neighbor+1 {
+				s.setLevel(n, neighbor+1)
+				s.wakeAround(n)
+				remaining--
+			}
+		}
+	}
+
+	if remaining != level && !s.isSource(p) {
+		s.setLevel(p, remaining)
+		s.wakeAround(p)
+	} else if remaining != level {
+		// Sources refill and keep flowing
+		s.active[p] = struct{}{}
+	}
+}
+
+// GerstnerWave is one wave of a water surface. Gerstner waves move the
+// surface in circles rather than only up and down, which sharpens the
+// crests
+type GerstnerWave struct {
+	// Direction is the direction of travel on the XZ plane
+	Direction vecmath.Vec2
+	// Amplitude is the height of a crest in blocks
+	Amplitude float32
+	// Wavelength is the distance between crests in blocks
+	Wavelength float32
+	// Speed is the speed of the crests in blocks per second
+	Speed float32
+	// Steepness in [0, 1] sharpens crests; 1 makes them pointed
+	Steepness float32
+}
+
+// MaxWaves is the number of waves the water shader sums
+const MaxWaves = 4
+
+// DefaultWaves are gentle waves for lakes and seas
+var DefaultWaves = []GerstnerWave{
+	{Direction: vecmath.Vec2{1, 0.3}, Amplitude: 0.08, Wavelength: 9, Speed: 1.2, Steepness: 0.5},
+	{Direction: vecmath.Vec2{-0.4, 1}, Amplitude: 0.05, Wavelength: 5, Speed: 0.9, Steepness: 0.4},
+	{Direction: vecmath.Vec2{0.7, -0.8}, Amplitude: 0.03, Wavelength: 2.7, Speed: 0.7, Steepness: 0.3},
+}
+
+// GerstnerDisplacement returns how far the surface point at rest at (x, z)
+// is moved by waves at a time in seconds, and the surface normal there.
+// It matches the water shader, e.g. for floating objects
+func GerstnerDisplacement(waves []GerstnerWave, x, z, time float32) (offset, normal vecmath.Vec3) {
+	var count float32
+	for _, w := range waves {
+		if w.Amplitude > 0 && w.Wavelength > 0 {
+			count++
+		}
+	}
+
+	normal = vecmath.Vec3{0, 1, 0}
+	for _, w := range waves {
+		if w.Amplitude <= 0 || w.Wavelength <= 0 {
+			continue
+		}
+		d := w.Direction.Normalize()
+		k := 2 * math.Pi / w.Wavelength
+		q := w.Steepness / (k * w.Amplitude * count)
+		f := k*(d[0]*x+d[1]*z) - w.Speed*k*time
+		sin, cos := math.Sincos(float64(f))
+		s, c := float32(sin), float32(cos)
+
+		offset = offset.Add(vecmath.Vec3{q * w.Amplitude * d[0] * c, w.Amplitude * s, q * w.Amplitude * d[1] * c})
+		normal = normal.Sub(vecmath.Vec3{d[0] * k * w.Amplitude * c, q * k * w.Amplitude * s, d[1] * k * w.Amplitude * c})
+	}
+	return offset, normal.Normalize()
+}
+
+// waterClipOffset moves the clip planes of the reflection and refraction
+// passes past the surface so that waves do not show gaps at the edges
+const waterClipOffset = 0.25
+
+// WaterView is the camera water is rendered for
+type WaterView struct {
+	View       vecmath.Mat4
+	Projection vecmath.Mat4
+	Eye        vecmath.Vec3
+}
+
+// WaterRenderer draws water surfaces with Gerstner waves. The scene is
+// first rendered mirrored into a reflection target and below the surface
+// into a refraction target, which the water shader blends by view angle.
+// The refraction depth gives the water depth along each view ray, which
+// darkens deep water and puts foam on shores. It must only be used on the
+// thread owning the GL context
+type WaterRenderer struct {
+	Waves        []GerstnerWave
+	ShallowColor vecmath.Vec3
+	DeepColor    vecmath.Vec3
+	// ClarityDepth is the depth at which the bottom is no longer visible
+	ClarityDepth float32
+	// FoamDepth is the depth up to which foam appears along shores
+	FoamDepth float32
+	// SunDirection points towards the sun
+	SunDirection vecmath.Vec3
+	SunColor     vecmath.Vec3
+
+	shader     *shader.Shader
+	surface    *mesh.Mesh
+	reflection *graphics.RenderTarget
+	refraction *graphics.RenderTarget
+}
+
+// waterSubdivisions is the number of quads along each side of the surface
+// mesh, which must be fine enough for the shortest wave
+const waterSubdivisions = 128
+
+// NewWaterRenderer creates a water renderer for a viewport of the given
+// size, with the shader read from files
+func NewWaterRenderer(files fs.FS, width, height int32) (*WaterRenderer, error) {
+	program, err := shader.Load("water", shader.Files{
+		Vertex:   path.Join(graphics.ShaderDir, "water.vert"),
+		Fragment: path.Join(graphics.ShaderDir, "water.frag"),
+	}, shader.Options{
+		Defines: map[string]string{"MAX_WAVES": strconv.Itoa(MaxWaves)},
+		FS:      files,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WaterRenderer{
+		Waves:        DefaultWaves,
+		ShallowColor: vecmath.Vec3{0.75, 0.9, 0.95},
+		DeepColor:    vecmath.Vec3{0.02, 0.12, 0.22},
+		ClarityDepth: 8,
+		FoamDepth:    0.6,
+		SunDirection: vecmath.Vec3{0.4, 0.8, 0.3}.Normalize(),
+		SunColor:     vecmath.Vec3{1, 0.95, 0.85},
+		shader:       program,
+		surface:      mesh.Upload(mesh.Plane(1, 1, waterSubdivisions), mesh.Static),
+	}
+
+	// Reflections are blurred by ripples, so half resolution is enough
+	if w.reflection, err = graphics.NewRenderTarget(width/2, height/2, graphics.ColorRGBA8); err != nil {
+		w.Destroy()
+		return nil, err
+	}
+	if w.refraction, err = graphics.NewRenderTarget(width, height, graphics.ColorRGBA8); err != nil {
+		w.Destroy()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Shader returns the water shader, e.g. to watch it for hot reload
+func (w *WaterRenderer) Shader() *shader.Shader {
+	return w.shader
+}
+
+// Resize resizes the render targets for a new viewport size
+func (w *WaterRenderer) Resize(width, height int32) error {
+	if err := w.reflection.Resize(width/2, height/2); err != nil {
+		return err
+	}
+	return w.refraction.Resize(width, height)
+}
+
+// RenderReflection renders the scene mirrored across the water plane at
+// level. draw must render the scene with the given view matrix, clipping
+// with gl_ClipDistance[0] = dot(vec4(worldPosition, 1), clip)
+func (w *WaterRenderer) RenderReflection(view WaterView, level float32, draw func(view vecmath.Mat4, clip vecmath.Vec4)) {
+	mirror := vecmath.Translate(vecmath.Vec3{0, level, 0}).
+		Mul(vecmath.Scale(vecmath.Vec3{1, -1, 1})).
+		Mul(vecmath.Translate(vecmath.Vec3{0, -level, 0}))
+
+	// Mirroring turns counter-clockwise triangles clockwise
+	gl.FrontFace(gl.CW)
+	w.renderPass(w.reflection, view.View.Mul(mirror), vecmath.Vec4{0, 1, 0, -level + waterClipOffset}, draw)
+	gl.FrontFace(gl.CCW)
+}
+
+// RenderRefraction renders the scene below the water plane at level; see
+// RenderReflection for draw
+func (w *WaterRenderer) RenderRefraction(view WaterView, level float32, draw func(view vecmath.Mat4, clip vecmath.Vec4)) {
+	w.renderPass(w.refraction, view.View, vecmath.Vec4{0, -1, 0, level + waterClipOffset}, draw)
+}
+
+// renderPass renders into a target with a clip plane, restoring the
+// framebuffer and viewport afterwards
+func (w *WaterRenderer) renderPass(target *graphics.RenderTarget, view vecmath.Mat4, clip vecmath.Vec4, draw func(view vecmath.Mat4, clip vecmath.Vec4)) {
+	var framebuffer int32
+	var viewport [4]int32
+	gl.GetIntegerv(gl.FRAMEBUFFER_BINDING, &framebuffer)
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+
+	target.Bind()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	gl.Enable(gl.CLIP_DISTANCE0)
+	draw(view, clip)
+	gl.Disable(gl.CLIP_DISTANCE0)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(framebuffer))
+	gl.Viewport(viewport[0], viewport[1], viewport[2], viewport[3])
+}
+
+// Draw draws a square water surface of the given size centered on (x, z)
+// at level, after RenderReflection and RenderRefraction for the same view.
+// time is in seconds and drives the waves
+func (w *WaterRenderer) Draw(view WaterView, level, x, z, size, time float32) {
+	waves := w.Waves[:min(len(w.Waves), MaxWaves)]
+	params := make([][4]float32, 0, len(waves))
+	motion := make([]float32, 0, 2*len(waves))
+	var height float32
+	for _, wave := range waves {
+		if wave.Amplitude <= 0 || wave.Wavelength <= 0 {
+			continue
+		}
+		params = append(params, [4]float32{wave.Direction[0], wave.Direction[1], wave.Amplitude, wave.Wavelength})
+		motion = append(motion, wave.Speed, wave.Steepness)
+		height += wave.Amplitude
+	}
+
+	model := vecmath.Translate(vecmath.Vec3{x, level, z}).Mul(vecmath.Scale(vecmath.Vec3{size, 1, size}))
+
+	w.shader.Use()
+	w.shader.SetMat4("uModel", model)
+	w.shader.SetMat4("uViewProjection", view.Projection.Mul(view.View))
+	w.shader.SetFloat("uTime", time)
+	w.shader.SetInt("uWaveCount", int32(len(params)))
+	if len(params) > 0 {
+		w.shader.SetVec4Array("uWaves", params)
+		gl.Uniform2fv(w.shader.UniformLocation("uWaveMotion"), int32(len(params)), &motion[0])
+	}
+	w.shader.SetFloat("uWaveHeight", height)
+	w.shader.SetVec3("uEye", view.Eye)
+	w.shader.SetVec3("uShallowColor", w.ShallowColor)
+	w.shader.SetVec3("uDeepColor", w.DeepColor)
+	w.shader.SetVec3("uSunDirection", w.SunDirection.Normalize())
+	w.shader.SetVec3("uSunColor", w.SunColor)
+	w.shader.SetFloat("uClarityDepth", max(w.ClarityDepth, 0.01))
+	w.shader.SetFloat("uFoamDepth", max(w.FoamDepth, 0.01))
+	w.shader.SetVec2("uDepthParams", [2]float32{view.Projection[10], view.Projection[14]})
+
+	w.shader.SetSampler("uReflection", 0)
+	w.shader.SetSampler("uRefraction", 1)
+	w.shader.SetSampler("uRefractionDepth", 2)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, w.reflection.ColorTexture())
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_2D, w.refraction.ColorTexture())
+	gl.ActiveTexture(gl.TEXTURE2)
+	gl.BindTexture(gl.TEXTURE_2D, w.refraction.DepthTexture())
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	// The surface is seen from below too, and fades out in the shallows
+	cull := gl.IsEnabled(gl.CULL_FACE)
+	gl.Disable(gl.CULL_FACE)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	w.surface.Draw()
+	if cull {
+		gl.Enable(gl.CULL_FACE)
+	}
+}
+
+// Destroy releases the shader, mesh and render targets
+func (w *WaterRenderer) Destroy() {
+	if w.shader != nil {
+		w.shader.Destroy()
+	}
+	if w.surface != nil {
+		w.surface.Release()
+	}
+	if w.reflection != nil {
+		w.reflection.Destroy()
+	}
+	if w.refraction != nil {
+		w.refraction.Destroy()
+	}
+}
@@ -0,0 +1,242 @@
+package objects
+
+import (
+	"math"
+	"testing"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+)
+
+// floorSize is the edge of the test floor in blocks
+const floorSize = 9
+
+// newFlatWorld returns a world with a stone floor at y = 0 walled in by
+// stone, and a water simulation for it
+func newFlatWorld(t *testing.T) (*World, *WaterSim) {
+	t.Helper()
+	registry := NewBlockRegistry()
+	if err := registry.RegisterDefaultBlocks(); err != nil {
+		t.Fatalf("failed to register blocks: %v", err)
+	}
+	stone, _ := registry.Lookup("stone")
+
+	world := NewWorld(registry)
+	for z := -1; z <= floorSize; z++ {
+		for x := -1; x <= floorSize; x++ {
+			world.SetBlock(x, 0, z, stone)
+			if x < 0 || z < 0 || x == floorSize || z == floorSize {
+				for y := 1; y <= 3; y++ {
+					world.SetBlock(x, y, z, stone)
+				}
+			}
+		}
+	}
+
+	sim, err := NewWaterSim(world)
+	if err != nil {
+		t.Fatalf("failed to create water simulation: %v", err)
+	}
+	return world, sim
+}
+
+// totalWater sums the water above the test floor
+func totalWater(sim *WaterSim) int {
+	total := 0
+	for y := 1; y <= 3; y++ {
+		for z := 0; z < floorSize; z++ {
+			for x := 0; x < floorSize; x++ {
+				total += int(sim.Level(x, y, z))
+			}
+		}
+	}
+	return total
+}
+
+// settle ticks the simulation until no cell is active
+func settle(t *testing.T, sim *WaterSim) {
+	t.Helper()
+	for i := 0; sim.Active() > 0; i++ {
+		if i == 1000 {
+			t.Fatalf("water still flowing after %d ticks", i)
+		}
+		sim.Tick()
+	}
+}
+
+func TestWaterSpreadsAndLevels(t *testing.T) {
+	tests := []struct {
+		name   string
+		pours  [][2]int
+		amount uint8
+	}{
+		{"single pour", [][2]int{{4, 4}}, MaxWaterLevel},
+		{"corner", [][2]int{{0, 0}}, MaxWaterLevel},
+		{"two pours", [][2]int{{1, 1}, {7, 6}}, MaxWaterLevel},
+		{"shallow", [][2]int{{4, 4}}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, sim := newFlatWorld(t)
+			for _, p := range tt.pours {
+				sim.AddWater(p[0], 1, p[1], tt.amount)
+			}
+			poured := len(tt.pours) * int(tt.amount)
+
+			sim.Tick()
+			if got := totalWater(sim); got != poured {
+				t.Errorf("water after one tick = %d, want %d", got, poured)
+			}
+			settle(t, sim)
+			if got := totalWater(sim); got != poured {
+				t.Errorf("water after settling = %d, want %d", got, poured)
+			}
+
+			// Settled water covers more than the pours and no two
+			// neighbors differ by more than one level
+			wet := 0
+			for z := 0; z < floorSize; z++ {
+				for x := 0; x < floorSize; x++ {
+					level := sim.Level(x, 1, z)
+					if level > 0 {
+						wet++
+					}
+					for _, n := range [][2]int{{x + 1, z}, {x, z + 1}} {
+						if n[0] >= floorSize || n[1] >= floorSize {
+							continue
+						}
+						if d := int(level) - int(sim.Level(n[0], 1, n[1])); d > 1 || d < -1 {
+							t.Errorf("levels at (%d, %d) and %v differ by %d", x, z, n, d)
+						}
+					}
+				}
+			}
+			if tt.amount > 2 && wet <= len(tt.pours) {
+				t.Errorf("water covers %d blocks, want it to spread beyond %d", wet, len(tt.pours))
+			}
+		})
+	}
+}
+
+func TestWaterFalls(t *testing.T) {
+	world, sim := newFlatWorld(t)
+	sim.AddWater(4, 3, 4, 5)
+	settle(t, sim)
+
+	if got := sim.Level(4, 3, 4); got != 0 {
+		t.Errorf("level where water was poured = %d, want 0", got)
+	}
+	if got := totalWater(sim); got != 5 {
+		t.Errorf("water after falling = %d, want 5", got)
+	}
+	if world.Block(4, 2, 4) != Air {
+		t.Errorf("water left above the floor")
+	}
+}
+
+func TestWaterSourceRefills(t *testing.T) {
+	_, sim := newFlatWorld(t)
+	sim.AddSource(4, 1, 4)
+	for i := 0; i < 50; i++ {
+		sim.Tick()
+	}
+
+	if got := sim.Level(4, 1, 4); got != MaxWaterLevel {
+		t.Errorf("source level = %d, want %d", got, MaxWaterLevel)
+	}
+	if got := sim.Level(5, 1, 4); got == 0 {
+		t.Errorf("source did not flow to its neighbor")
+	}
+}
+
+func TestWaterLevelsSaved(t *testing.T) {
+	world, sim := newFlatWorld(t)
+	sim.AddWater(4, 1, 4, MaxWaterLevel)
+	settle(t, sim)
+	before := totalWater(sim)
+
+	store, err := NewChunkStore(t.TempDir(), world.Registry())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	pos, _, _, _ := ChunkPosOf(4, 1, 4)
+	if err := store.Save(world.RemoveChunk(pos)); err != nil {
+		t.Fatalf("failed to save chunk: %v", err)
+	}
+	loaded, err := store.Load(pos)
+	if err != nil {
+		t.Fatalf("failed to load chunk: %v", err)
+	}
+	world.AddChunk(loaded)
+
+	if got := totalWater(sim); got != before {
+		t.Errorf("water after reloading = %d, want %d", got, before)
+	}
+
+	// Reloaded water is still finite: more ticks neither add nor remove
+	// any
+	sim.Wake(4, 1, 4)
+	settle(t, sim)
+	if got := totalWater(sim); got != before {
+		t.Errorf("water after flowing again = %d, want %d", got, before)
+	}
+}
+
+// nearVec3 reports whether a and b are equal within a small tolerance
+func nearVec3(a, b vecmath.Vec3) bool {
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGerstnerDisplacement(t *testing.T) {
+	// One wave along X with a wavelength of 4, so the phase advances a
+	// quarter turn per block and per half second: k = π/2, Q·A = 1/π
+	wave := GerstnerWave{Direction: vecmath.Vec2{1, 0}, Amplitude: 0.5, Wavelength: 4, Speed: 2, Steepness: 0.5}
+	qa := float32(1 / math.Pi)
+	ka := float32(math.Pi / 4)
+	diagonal := wave
+	diagonal.Direction = vecmath.Vec2{3, 3}
+	flat := GerstnerWave{Direction: vecmath.Vec2{0, 1}, Wavelength: 4, Speed: 2}
+	up := vecmath.Vec3{0, 1, 0}
+
+	tests := []struct {
+		name       string
+		waves      []GerstnerWave
+		x, z, time float32
+		offset     vecmath.Vec3
+		normal     vecmath.Vec3
+	}{
+		{"no waves", nil, 3, 7, 1, vecmath.Vec3{}, up},
+		{"rest phase", []GerstnerWave{wave}, 0, 0, 0,
+			vecmath.Vec3{qa, 0, 0}, vecmath.Vec3{-ka, 1, 0}.Normalize()},
+		{"crest", []GerstnerWave{wave}, 1, 0, 0, vecmath.Vec3{0, 0.5, 0}, up},
+		{"half phase", []GerstnerWave{wave}, 2, 5, 0,
+			vecmath.Vec3{-qa, 0, 0}, vecmath.Vec3{ka, 1, 0}.Normalize()},
+		{"trough", []GerstnerWave{wave}, 3, 0, 0, vecmath.Vec3{0, -0.5, 0}, up},
+		{"moved by time", []GerstnerWave{wave}, 1, 0, 0.5,
+			vecmath.Vec3{qa, 0, 0}, vecmath.Vec3{-ka, 1, 0}.Normalize()},
+		{"normalized direction", []GerstnerWave{diagonal}, 0, 0, 0,
+			vecmath.Vec3{qa * math.Sqrt2 / 2, 0, qa * math.Sqrt2 / 2},
+			vecmath.Vec3{-ka * math.Sqrt2 / 2, 1, -ka * math.Sqrt2 / 2}.Normalize()},
+		// Waves without amplitude are skipped and do not count towards
+		// the steepness split
+		{"flat wave skipped", []GerstnerWave{wave, flat}, 0, 0, 0,
+			vecmath.Vec3{qa, 0, 0}, vecmath.Vec3{-ka, 1, 0}.Normalize()},
+		{"steepness split", []GerstnerWave{wave, wave}, 0, 0, 0,
+			vecmath.Vec3{qa, 0, 0}, vecmath.Vec3{-2 * ka, 1, 0}.Normalize()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, normal := GerstnerDisplacement(tt.waves, tt.x, tt.z, tt.time)
+			if !nearVec3(offset, tt.offset) {
+				t.Errorf("offset = %v, want %v", offset, tt.offset)
+			}
+			if !nearVec3(normal, tt.normal) {
+				t.Errorf("normal = %v, want %v", normal, tt.normal)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/font"
+	"github.com/luidsonl/magic-and-blades/internal/i18n"
+)
+
+// overlayMargin is the distance in pixels between overlays and the edges
+// of the window
+const overlayMargin = 10
+
+// drawShaderErrors draws the errors of shaders that failed to hot reload
+// over the frame, so they are noticed without watching the log
+func (e *Engine) drawShaderErrors() {
+	if e.shaders == nil || e.ui == nil {
+		return
+	}
+
+	errs := e.shaders.Errors()
+	if len(errs) == 0 {
+		return
+	}
+
+	width, _ := e.ui.Size()
+
+	opts := font.Options{
+		Size:         14,
+		MaxWidth:     float32(width - 2*overlayMargin),
+		Color:        color.RGBA{R: 255, G: 90, B: 90, A: 255},
+		Outline:      1,
+		OutlineColor: color.RGBA{A: 255},
+	}
+
+	y := float32(overlayMargin)
+	for _, err := range errs {
+		message := err.Error()
+		e.ui.DrawText(e.uiFont, message, overlayMargin, y, opts)
+
+		_, height := e.uiFont.Measure(message, opts)
+		y += height + e.uiFont.LineHeight(opts.Size)
+	}
+}
+
+// drawLoading shows the progress of assets loading in the background in
+// the bottom right corner
+func (e *Engine) drawLoading() {
+	if e.assets == nil || e.ui == nil {
+		return
+	}
+
+	progress := e.assets.Progress()
+	if progress.Done() {
+		return
+	}
+
+	opts := font.Options{
+		Size:         16,
+		Color:        color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		Outline:      1,
+		OutlineColor: color.RGBA{A: 255},
+	}
+	message := fmt.Sprintf("%s %d%%", e.translator.Translate(i18n.LabelLoading), int(progress.Fraction()*100))
+	textWidth, textHeight := e.uiFont.Measure(message, opts)
+
+	width, height := e.ui.Size()
+	e.ui.DrawText(e.uiFont, message, float32(width)-textWidth-overlayMargin, float32(height)-textHeight-overlayMargin, opts)
+}
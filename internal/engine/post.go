@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"image"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/assets"
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
+	"github.com/luidsonl/magic-and-blades/internal/game"
+)
+
+// gradingLUTPath is the color grading table loaded at startup, below the
+// asset root. Until it is loaded, or without it, a built-in grade is used
+const gradingLUTPath = "luts/grading.png"
+
+// defaultLUTSize is the size of the built-in color grading table
+const defaultLUTSize = 16
+
+// lutKind loads color grading tables
+var lutKind = &assets.Kind[*graphics.Texture]{
+	Name: "lut",
+	Decode: func(fsys fs.FS, name string) (any, error) {
+		return graphics.LoadImage(fsys, name)
+	},
+	Upload: func(decoded any) (*graphics.Texture, error) {
+		return graphics.NewLUT(decoded.(image.Image))
+	},
+	Free: (*graphics.Texture).Destroy,
+}
+
+// newPostProcess creates the post-processing chain with the built-in color
+// grading table
+func newPostProcess(files fs.FS, width, height int32) (*graphics.PostProcess, error) {
+	post, err := graphics.NewPostProcess(files, width, height)
+	if err != nil {
+		return nil, err
+	}
+	post.LUT, _ = graphics.NewLUT(graphics.GenerateLUT(defaultLUTSize, defaultGrade))
+	return post, nil
+}
+
+// updateLUT replaces the built-in color grading table by the loaded one
+// once it is ready
+func (e *Engine) updateLUT() {
+	if e.post == nil || e.lutApplied || e.lut.State() == assets.StateLoading {
+		return
+	}
+	e.lutApplied = true
+
+	lut, ok := e.lut.Get()
+	if !ok {
+		if err := e.lut.Err(); !os.IsNotExist(err) {
+			log.Printf("Warning: failed to load color grading LUT: %v", err)
+		}
+		return
+	}
+	if e.post.LUT != nil {
+		e.post.LUT.Destroy()
+	}
+	e.post.LUT = lut
+}
+
+// defaultGrade slightly raises contrast and warms the highlights
+func defaultGrade(r, g, b float64) (float64, float64, float64) {
+	contrast := func(v float64) float64 {
+		// Smoothstep S-curve blended with the identity
+		s := v * v * (3 - 2*v)
+		return v + (s-v)*0.25
+	}
+	r, g, b = contrast(r), contrast(g), contrast(b)
+
+	luma := 0.299*r + 0.587*g + 0.114*b
+	warmth := 0.04 * math.Max(luma-0.5, 0) * 2
+	return r + warmth, g + warmth*0.4, b - warmth
+}
+
+// postEffects converts the effect settings to the effects of the chain
+func postEffects(settings game.PostEffects) graphics.Effect {
+	var effects graphics.Effect
+	flags := []struct {
+		enabled bool
+		effect  graphics.Effect
+	}{
+		{settings.ToneMapping, graphics.EffectToneMapping},
+		{settings.Bloom, graphics.EffectBloom},
+		{settings.FXAA, graphics.EffectFXAA},
+		{settings.ColorGrading, graphics.EffectColorGrading},
+		{settings.Vignette, graphics.EffectVignette},
+		{settings.Damage, graphics.EffectDamage},
+	}
+	for _, f := range flags {
+		if f.enabled {
+			effects |= f.effect
+		}
+	}
+	return effects
+}
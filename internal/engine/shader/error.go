@@ -0,0 +1,77 @@
+package shader
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// logLocation matches the source location at the start of an info log line
+// in the formats used by the common drivers:
+//
+//	Mesa:       0:12(5): error: ...
+//	NVIDIA:     0(12) : error C1008: ...
+//	AMD/Apple:  ERROR: 0:12: ...
+var logLocation = regexp.MustCompile(`^((?:ERROR|WARNING):\s*)?\d+[:(](\d+)\)?(?:\(\d+\))?\s*:?\s*(.*)$`)
+
+// Error is a failed shader compilation or program link. Its message
+// annotates the GL info log with the offending source lines
+type Error struct {
+	// Shader is the name of the shader program
+	Shader string
+	// Stage is the stage that failed to compile, or 0 when linking failed
+	Stage Stage
+	// Log is the raw GL info log
+	Log string
+
+	lines []sourceLine
+}
+
+// Error returns the info log with file names, line numbers and source text
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.Stage == 0 {
+		fmt.Fprintf(&b, "failed to link shader %s", e.Shader)
+	} else {
+		fmt.Fprintf(&b, "failed to compile %s shader %s", e.Stage, e.Shader)
+	}
+
+	for _, entry := range strings.Split(strings.TrimSpace(e.Log), "\n") {
+		entry = strings.TrimRight(entry, "\x00\r ")
+		if entry == "" {
+			continue
+		}
+
+		b.WriteString("\n")
+		line, message, ok := e.locate(entry)
+		if !ok {
+			b.WriteString(entry)
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s:%d: %s\n", line.file, line.line, message)
+		fmt.Fprintf(&b, "%6d | %s", line.line, strings.TrimRight(line.text, " \t"))
+	}
+
+	return b.String()
+}
+
+// locate finds the source line an info log entry refers to
+func (e *Error) locate(entry string) (sourceLine, string, bool) {
+	match := logLocation.FindStringSubmatch(entry)
+	if match == nil {
+		return sourceLine{}, "", false
+	}
+
+	n, err := strconv.Atoi(match[2])
+	if err != nil || n < 1 || n > len(e.lines) {
+		return sourceLine{}, "", false
+	}
+
+	message := match[3]
+	if match[1] != "" {
+		message = strings.ToLower(strings.TrimSpace(match[1])) + " " + message
+	}
+	return e.lines[n-1], message, true
+}
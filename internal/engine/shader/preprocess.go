@@ -0,0 +1,168 @@
+package shader
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Options controls how shader sources are preprocessed
+type Options struct {
+	// Defines are inserted as #define directives right after #version
+	Defines map[string]string
+	// Include loads the source of an included file. When nil, included
+	// files are read from FS
+	Include func(path string) (string, error)
+	// FS holds the source files of shaders created with Load and their
+	// includes, with slash separated names. When nil, files are read from
+	// disk
+	FS fs.FS
+}
+
+// readFile reads a source file from opts.FS or the disk
+func readFile(opts Options, path string) ([]byte, error) {
+	if opts.FS != nil {
+		return fs.ReadFile(opts.FS, filepath.ToSlash(path))
+	}
+	return os.ReadFile(path)
+}
+
+// includePattern matches #include "file" and #include <file>
+var includePattern = regexp.MustCompile(`^\s*#\s*include\s+["<]([^">]+)[">]\s*$`)
+
+// versionPattern matches the #version directive
+var versionPattern = regexp.MustCompile(`^\s*#\s*version\b`)
+
+// sourceLine maps a line of the preprocessed source back to its origin
+type sourceLine struct {
+	file string
+	line int
+	text string
+}
+
+// preprocessor expands #include directives and injects defines
+type preprocessor struct {
+	opts Options
+	// included holds every file already expanded. Each file is included
+	// at most once per stage, which also makes include cycles harmless
+	included map[string]bool
+	// includes lists the included files in the order they were expanded
+	includes []string
+	lines    []sourceLine
+}
+
+// Preprocess expands the #include directives of source, read from file
+// name, and inserts opts.Defines after the #version directive. Included
+// paths are relative to the including file
+func Preprocess(name, source string, opts Options) (string, error) {
+	lines, _, err := preprocess(name, source, opts)
+	if err != nil {
+		return "", err
+	}
+	return joinLines(lines), nil
+}
+
+// preprocess expands source and returns its lines with their origin and
+// the files it included
+func preprocess(name, source string, opts Options) ([]sourceLine, []string, error) {
+	p := &preprocessor{
+		opts:     opts,
+		included: make(map[string]bool),
+	}
+	if err := p.expand(name, source); err != nil {
+		return nil, nil, err
+	}
+	p.insertDefines()
+	return p.lines, p.includes, nil
+}
+
+// expand appends the lines of a file, recursively expanding includes
+func (p *preprocessor) expand(name, source string) error {
+	p.included[name] = true
+
+	for i, text := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n") {
+		match := includePattern.FindStringSubmatch(text)
+		if match == nil {
+			p.lines = append(p.lines, sourceLine{file: name, line: i + 1, text: text})
+			continue
+		}
+
+		path := filepath.Join(filepath.Dir(name), match[1])
+		if p.included[path] {
+			continue
+		}
+
+		included, err := p.load(path)
+		if err != nil {
+			return fmt.Errorf("%s:%d: failed to include %s: %v", name, i+1, match[1], err)
+		}
+		p.includes = append(p.includes, path)
+		if err := p.expand(path, included); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// load reads an included file
+func (p *preprocessor) load(path string) (string, error) {
+	if p.opts.Include != nil {
+		return p.opts.Include(path)
+	}
+
+	data, err := readFile(p.opts, path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// insertDefines inserts the defines after #version, which must stay the
+// first directive, or at the top when there is none
+func (p *preprocessor) insertDefines() {
+	if len(p.opts.Defines) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(p.opts.Defines))
+	for name := range p.opts.Defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	defines := make([]sourceLine, 0, len(names))
+	for _, name := range names {
+		defines = append(defines, sourceLine{
+			file: "<defines>",
+			text: strings.TrimSpace("#define " + name + " " + p.opts.Defines[name]),
+		})
+	}
+
+	at := 0
+	for i, line := range p.lines {
+		if versionPattern.MatchString(line.text) {
+			at = i + 1
+			break
+		}
+	}
+
+	lines := make([]sourceLine, 0, len(p.lines)+len(defines))
+	lines = append(lines, p.lines[:at]...)
+	lines = append(lines, defines...)
+	p.lines = append(lines, p.lines[at:]...)
+}
+
+// joinLines returns the text of the preprocessed source
+func joinLines(lines []sourceLine) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line.text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
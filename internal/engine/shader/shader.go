@@ -1 +1,393 @@
 package shader
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// Stage is a programmable stage of the GL pipeline
+type Stage uint32
+
+const (
+	Vertex   Stage = gl.VERTEX_SHADER
+	Fragment Stage = gl.FRAGMENT_SHADER
+	Geometry Stage = gl.GEOMETRY_SHADER
+)
+
+// String returns the stage name used in error messages
+func (s Stage) String() string {
+	switch s {
+	case Vertex:
+		return "vertex"
+	case Fragment:
+		return "fragment"
+	case Geometry:
+		return "geometry"
+	default:
+		return fmt.Sprintf("Stage(%d)", uint32(s))
+	}
+}
+
+// Source holds the GLSL source of every stage. Geometry is optional
+type Source struct {
+	Vertex   string
+	Fragment string
+	Geometry string
+}
+
+// Files holds the paths of the GLSL source files of every stage. Geometry
+// is optional
+type Files struct {
+	Vertex   string
+	Fragment string
+	Geometry string
+}
+
+// Shader is a linked GL program. Uniform and attribute locations are
+// looked up once and cached. It must only be used on the thread owning the
+// GL context
+type Shader struct {
+	name       string
+	program    uint32
+	version    int
+	uniforms   map[string]int32
+	attributes map[string]int32
+
+	// Sources the program is rebuilt from by Reload: files when created
+	// with Load, src otherwise
+	src          Source
+	files        Files
+	opts         Options
+	dependencies []string
+}
+
+// New compiles and links a program from source strings, typically
+// embedded in the binary. Includes are resolved relative to the current
+// directory unless opts.Include or opts.FS is set
+func New(name string, src Source, opts Options) (*Shader, error) {
+	s := newShader(name, opts)
+	s.src = src
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load compiles and links a program from source files, read from opts.FS
+// when set
+func Load(name string, files Files, opts Options) (*Shader, error) {
+	if files.Vertex == "" || files.Fragment == "" {
+		return nil, fmt.Errorf("failed to load shader %s: vertex and fragment files are required", name)
+	}
+
+	s := newShader(name, opts)
+	s.files = files
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// newShader creates a shader without a program
+func newShader(name string, opts Options) *Shader {
+	return &Shader{
+		name:       name,
+		uniforms:   make(map[string]int32),
+		attributes: make(map[string]int32),
+		opts:       opts,
+	}
+}
+
+// Reload rebuilds the program, reading the source files again for shaders
+// created with Load. When the new program fails to build the previous one
+// is kept and the error is returned
+func (s *Shader) Reload() error {
+	stages, err := s.stages()
+	if err != nil {
+		return err
+	}
+
+	program, includes, err := build(s.name, stages, s.opts)
+	if err != nil {
+		return err
+	}
+
+	s.Destroy()
+	s.program = program
+	s.version++
+	clear(s.uniforms)
+	clear(s.attributes)
+
+	s.dependencies = s.dependencies[:0]
+	for _, path := range []string{s.files.Vertex, s.files.Fragment, s.files.Geometry} {
+		if path != "" {
+			s.dependencies = append(s.dependencies, path)
+		}
+	}
+	s.dependencies = append(s.dependencies, includes...)
+	return nil
+}
+
+// Dependencies returns the files the program was built from, including
+// included files, as of the last successful build
+func (s *Shader) Dependencies() []string {
+	return append([]string(nil), s.dependencies...)
+}
+
+// stages returns the unprocessed source of every stage
+func (s *Shader) stages() ([]stageSource, error) {
+	if s.files.Vertex == "" {
+		stages := []stageSource{
+			{stage: Vertex, name: s.name + ".vert", source: s.src.Vertex},
+			{stage: Fragment, name: s.name + ".frag", source: s.src.Fragment},
+		}
+		if s.src.Geometry != "" {
+			stages = append(stages, stageSource{stage: Geometry, name: s.name + ".geom", source: s.src.Geometry})
+		}
+		return stages, nil
+	}
+
+	paths := []struct {
+		stage Stage
+		path  string
+	}{
+		{Vertex, s.files.Vertex},
+		{Fragment, s.files.Fragment},
+		{Geometry, s.files.Geometry},
+	}
+
+	var stages []stageSource
+	for _, p := range paths {
+		if p.path == "" {
+			continue
+		}
+
+		data, err := readFile(s.opts, p.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load shader %s: %v", s.name, err)
+		}
+		stages = append(stages, stageSource{stage: p.stage, name: p.path, source: string(data)})
+	}
+	return stages, nil
+}
+
+// Name returns the name given when the shader was created
+func (s *Shader) Name() string {
+	return s.name
+}
+
+// Program returns the GL program object
+func (s *Shader) Program() uint32 {
+	return s.program
+}
+
+// Version counts the programs built for the shader. It changes with every
+// successful Reload, unlike the program id, which GL may reuse
+func (s *Shader) Version() int {
+	return s.version
+}
+
+// Use binds the program. Uniform setters apply to the bound program
+func (s *Shader) Use() {
+	gl.UseProgram(s.program)
+}
+
+// Destroy deletes the GL program
+func (s *Shader) Destroy() {
+	if s.program != 0 {
+		gl.DeleteProgram(s.program)
+		s.program = 0
+	}
+}
+
+// UniformLocation returns the location of a uniform, or -1 when the
+// program has no active uniform with that name
+func (s *Shader) UniformLocation(name string) int32 {
+	location, ok := s.uniforms[name]
+	if !ok {
+		location = gl.GetUniformLocation(s.program, gl.Str(name+"\x00"))
+		s.uniforms[name] = location
+	}
+	return location
+}
+
+// AttribLocation returns the location of a vertex attribute, or -1 when
+// the program has no active attribute with that name
+func (s *Shader) AttribLocation(name string) int32 {
+	location, ok := s.attributes[name]
+	if !ok {
+		location = gl.GetAttribLocation(s.program, gl.Str(name+"\x00"))
+		s.attributes[name] = location
+	}
+	return location
+}
+
+// SetBool sets a bool uniform
+func (s *Shader) SetBool(name string, v bool) {
+	var i int32
+	if v {
+		i = 1
+	}
+	gl.Uniform1i(s.UniformLocation(name), i)
+}
+
+// SetInt sets an int uniform
+func (s *Shader) SetInt(name string, v int32) {
+	gl.Uniform1i(s.UniformLocation(name), v)
+}
+
+// SetSampler binds a sampler uniform to a texture unit
+func (s *Shader) SetSampler(name string, unit int32) {
+	gl.Uniform1i(s.UniformLocation(name), unit)
+}
+
+// SetFloat sets a float uniform
+func (s *Shader) SetFloat(name string, v float32) {
+	gl.Uniform1f(s.UniformLocation(name), v)
+}
+
+// SetVec2 sets a vec2 uniform
+func (s *Shader) SetVec2(name string, v [2]float32) {
+	gl.Uniform2f(s.UniformLocation(name), v[0], v[1])
+}
+
+// SetVec3 sets a vec3 uniform
+func (s *Shader) SetVec3(name string, v [3]float32) {
+	gl.Uniform3f(s.UniformLocation(name), v[0], v[1], v[2])
+}
+
+// SetVec4 sets a vec4 uniform
+func (s *Shader) SetVec4(name string, v [4]float32) {
+	gl.Uniform4f(s.UniformLocation(name), v[0], v[1], v[2], v[3])
+}
+
+// SetMat3 sets a mat3 uniform from a column-major matrix
+func (s *Shader) SetMat3(name string, m [9]float32) {
+	gl.UniformMatrix3fv(s.UniformLocation(name), 1, false, &m[0])
+}
+
+// SetMat4 sets a mat4 uniform from a column-major matrix
+func (s *Shader) SetMat4(name string, m [16]float32) {
+	gl.UniformMatrix4fv(s.UniformLocation(name), 1, false, &m[0])
+}
+
+// SetIntArray sets an int[] uniform
+func (s *Shader) SetIntArray(name string, v []int32) {
+	if len(v) > 0 {
+		gl.Uniform1iv(s.UniformLocation(name), int32(len(v)), &v[0])
+	}
+}
+
+// SetFloatArray sets a float[] uniform
+func (s *Shader) SetFloatArray(name string, v []float32) {
+	if len(v) > 0 {
+		gl.Uniform1fv(s.UniformLocation(name), int32(len(v)), &v[0])
+	}
+}
+
+// SetVec3Array sets a vec3[] uniform
+func (s *Shader) SetVec3Array(name string, v [][3]float32) {
+	if len(v) > 0 {
+		gl.Uniform3fv(s.UniformLocation(name), int32(len(v)), &v[0][0])
+	}
+}
+
+// SetVec4Array sets a vec4[] uniform
+func (s *Shader) SetVec4Array(name string, v [][4]float32) {
+	if len(v) > 0 {
+		gl.Uniform4fv(s.UniformLocation(name), int32(len(v)), &v[0][0])
+	}
+}
+
+// SetMat4Array sets a mat4[] uniform from column-major matrices
+func (s *Shader) SetMat4Array(name string, m [][16]float32) {
+	if len(m) > 0 {
+		gl.UniformMatrix4fv(s.UniformLocation(name), int32(len(m)), false, &m[0][0])
+	}
+}
+
+// stageSource is the unprocessed source of one stage
+type stageSource struct {
+	stage  Stage
+	name   string
+	source string
+}
+
+// build preprocesses and compiles every stage and links the program. It
+// also returns the files included by the stages
+func build(name string, stages []stageSource, opts Options) (uint32, []string, error) {
+	var shaders []uint32
+	defer func() {
+		for _, shader := range shaders {
+			gl.DeleteShader(shader)
+		}
+	}()
+
+	var includes []string
+	for _, stage := range stages {
+		lines, included, err := preprocess(stage.name, stage.source, opts)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to preprocess %s shader %s: %v", stage.stage, name, err)
+		}
+		for _, path := range included {
+			if !slices.Contains(includes, path) {
+				includes = append(includes, path)
+			}
+		}
+
+		shader, compileErr := compile(stage.stage, lines)
+		if compileErr != nil {
+			compileErr.Shader = name
+			return 0, nil, compileErr
+		}
+		shaders = append(shaders, shader)
+	}
+
+	program := gl.CreateProgram()
+	for _, shader := range shaders {
+		gl.AttachShader(program, shader)
+	}
+	gl.LinkProgram(program)
+	for _, shader := range shaders {
+		gl.DetachShader(program, shader)
+	}
+
+	var status int32
+	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
+		infoLog := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(infoLog))
+		gl.DeleteProgram(program)
+		return 0, nil, &Error{Shader: name, Log: infoLog}
+	}
+
+	return program, includes, nil
+}
+
+// compile compiles a single preprocessed stage
+func compile(stage Stage, lines []sourceLine) (uint32, *Error) {
+	shader := gl.CreateShader(uint32(stage))
+
+	csources, free := gl.Strs(joinLines(lines) + "\x00")
+	gl.ShaderSource(shader, 1, csources, nil)
+	free()
+	gl.CompileShader(shader)
+
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		infoLog := strings.Repeat("\x00", int(logLength+1))
+		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(infoLog))
+		gl.DeleteShader(shader)
+		return 0, &Error{Stage: stage, Log: infoLog, lines: lines}
+	}
+
+	return shader, nil
+}
@@ -0,0 +1,135 @@
+package shader
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultPollInterval is how often a Watcher checks source files for
+// changes by default
+const DefaultPollInterval = 500 * time.Millisecond
+
+// Watcher rebuilds shaders when their source files change, on disk or in
+// their Options.FS. It polls file modification times, so it works the same
+// on every platform and with editors that save by replacing the file. Poll
+// must be called on the thread owning the GL context
+type Watcher struct {
+	interval time.Duration
+	lastPoll time.Time
+	shaders  map[*Shader]*watchedShader
+}
+
+// watchedShader is the state of one watched shader
+type watchedShader struct {
+	modTimes map[string]time.Time
+	// err is the error of the last failed rebuild, cleared on success
+	err error
+}
+
+// NewWatcher creates a watcher checking files every interval
+// (DefaultPollInterval when zero)
+func NewWatcher(interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	return &Watcher{
+		interval: interval,
+		shaders:  make(map[*Shader]*watchedShader),
+	}
+}
+
+// Add starts watching the source files of s
+func (w *Watcher) Add(s *Shader) {
+	w.shaders[s] = &watchedShader{modTimes: modTimes(s.opts.FS, s.Dependencies())}
+}
+
+// Remove stops watching s
+func (w *Watcher) Remove(s *Shader) {
+	delete(w.shaders, s)
+}
+
+// Poll rebuilds the shaders whose files changed since the last poll. A
+// shader that fails to rebuild keeps its previous program and the error is
+// logged and reported by Errors until a later rebuild succeeds
+func (w *Watcher) Poll(now time.Time) {
+	if now.Sub(w.lastPoll) < w.interval {
+		return
+	}
+	w.lastPoll = now
+
+	for s, watched := range w.shaders {
+		current := modTimes(s.opts.FS, s.Dependencies())
+		if !changed(watched.modTimes, current) {
+			continue
+		}
+
+		if err := s.Reload(); err != nil {
+			log.Printf("Warning: keeping previous shader %s: %v", s.Name(), err)
+			watched.err = err
+			// Retry once the files change again
+			watched.modTimes = current
+			continue
+		}
+
+		log.Printf("Reloaded shader %s", s.Name())
+		watched.err = nil
+		// Includes may have changed
+		watched.modTimes = modTimes(s.opts.FS, s.Dependencies())
+	}
+}
+
+// Errors returns the errors of the shaders that currently fail to build,
+// ordered by shader name
+func (w *Watcher) Errors() []error {
+	var names []string
+	failed := make(map[string]error)
+	for s, watched := range w.shaders {
+		if watched.err != nil {
+			names = append(names, s.Name())
+			failed[s.Name()] = watched.err
+		}
+	}
+	sort.Strings(names)
+
+	errs := make([]error, 0, len(names))
+	for _, name := range names {
+		errs = append(errs, failed[name])
+	}
+	return errs
+}
+
+// modTimes returns the modification time of every file, in fsys or on
+// disk when nil. Missing files get the zero time, so they count as changed
+// when they reappear
+func modTimes(fsys fs.FS, paths []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		var info fs.FileInfo
+		var err error
+		if fsys != nil {
+			info, err = fs.Stat(fsys, filepath.ToSlash(path))
+		} else {
+			info, err = os.Stat(path)
+		}
+		if err == nil {
+			times[path] = info.ModTime()
+		} else {
+			times[path] = time.Time{}
+		}
+	}
+	return times
+}
+
+// changed reports whether any file was modified between two snapshots
+func changed(before, after map[string]time.Time) bool {
+	for path, t := range after {
+		if !before[path].Equal(t) {
+			return true
+		}
+	}
+	return false
+}
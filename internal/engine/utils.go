@@ -1,7 +1,32 @@
 package engine
 
+import "time"
+
 // OpenGL imports
 
 func init() {
 	// É importante que a thread principal do OpenGL chame init
 }
+
+// fpsCounter measures the number of frames rendered per real second
+type fpsCounter struct {
+	windowStart time.Time
+	frames      int
+	fps         float64
+}
+
+// frame registers a rendered frame and returns the latest FPS measurement
+func (c *fpsCounter) frame(now time.Time) float64 {
+	if c.windowStart.IsZero() {
+		c.windowStart = now
+	}
+
+	c.frames++
+	if elapsed := now.Sub(c.windowStart); elapsed >= time.Second {
+		c.fps = float64(c.frames) / elapsed.Seconds()
+		c.frames = 0
+		c.windowStart = now
+	}
+
+	return c.fps
+}
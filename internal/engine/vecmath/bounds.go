@@ -0,0 +1,61 @@
+package vecmath
+
+import "math"
+
+// AABB is an axis-aligned bounding box
+type AABB struct {
+	Min, Max Vec3
+}
+
+// EmptyAABB returns a box containing nothing, which grows to fit the first
+// point or box added to it
+func EmptyAABB() AABB {
+	inf := float32(math.Inf(1))
+	return AABB{
+		Min: Vec3{inf, inf, inf},
+		Max: Vec3{-inf, -inf, -inf},
+	}
+}
+
+// IsEmpty reports whether the box contains no point
+func (b AABB) IsEmpty() bool {
+	return b.Min[0] > b.Max[0] || b.Min[1] > b.Max[1] || b.Min[2] > b.Max[2]
+}
+
+// Extend returns the box grown to contain p
+func (b AABB) Extend(p Vec3) AABB {
+	return AABB{Min: b.Min.Min(p), Max: b.Max.Max(p)}
+}
+
+// Union returns the smallest box containing both boxes
+func (b AABB) Union(o AABB) AABB {
+	return AABB{Min: b.Min.Min(o.Min), Max: b.Max.Max(o.Max)}
+}
+
+// Center returns the center of the box
+func (b AABB) Center() Vec3 {
+	return b.Min.Add(b.Max).Mul(0.5)
+}
+
+// Size returns the width, height and depth of the box
+func (b AABB) Size() Vec3 {
+	return b.Max.Sub(b.Min)
+}
+
+// Contains reports whether p is inside the box or on its surface
+func (b AABB) Contains(p Vec3) bool {
+	return p[0] >= b.Min[0] && p[0] <= b.Max[0] &&
+		p[1] >= b.Min[1] && p[1] <= b.Max[1] &&
+		p[2] >= b.Min[2] && p[2] <= b.Max[2]
+}
+
+// Sphere is a bounding sphere
+type Sphere struct {
+	Center Vec3
+	Radius float32
+}
+
+// Contains reports whether p is inside the sphere or on its surface
+func (s Sphere) Contains(p Vec3) bool {
+	return p.Sub(s.Center).LenSqr() <= s.Radius*s.Radius
+}
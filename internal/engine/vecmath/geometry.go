@@ -0,0 +1,118 @@
+package vecmath
+
+// Plane is the set of points p with Normal.Dot(p) + D == 0. Points with a
+// positive distance are in front of the plane
+type Plane struct {
+	Normal Vec3
+	D      float32
+}
+
+// PlaneFromPoint returns the plane through p with the given normal
+func PlaneFromPoint(normal, p Vec3) Plane {
+	normal = normal.Normalize()
+	return Plane{Normal: normal, D: -normal.Dot(p)}
+}
+
+// Normalize returns the plane with a unit normal
+func (p Plane) Normalize() Plane {
+	l := p.Normal.Len()
+	if l == 0 {
+		return p
+	}
+	return Plane{Normal: p.Normal.Mul(1 / l), D: p.D / l}
+}
+
+// Distance returns the signed distance from the plane to point, in units
+// of the normal's length
+func (p Plane) Distance(point Vec3) float32 {
+	return p.Normal.Dot(point) + p.D
+}
+
+// Ray is a half-line starting at Origin
+type Ray struct {
+	Origin    Vec3
+	Direction Vec3
+}
+
+// At returns the point at distance t along the ray, in units of the
+// direction's length
+func (r Ray) At(t float32) Vec3 {
+	return r.Origin.Add(r.Direction.Mul(t))
+}
+
+// Frustum planes, with normals pointing inside
+const (
+	FrustumLeft = iota
+	FrustumRight
+	FrustumBottom
+	FrustumTop
+	FrustumNear
+	FrustumFar
+)
+
+// Frustum is the volume visible through a projection, bounded by six
+// planes whose normals point inside
+type Frustum struct {
+	Planes [6]Plane
+}
+
+// FrustumFromMatrix extracts the frustum of a view-projection matrix
+func FrustumFromMatrix(m Mat4) Frustum {
+	r0, r1, r2, r3 := m.Row(0), m.Row(1), m.Row(2), m.Row(3)
+	rows := [6]Vec4{
+		r3.Add(r0),
+		r3.Sub(r0),
+		r3.Add(r1),
+		r3.Sub(r1),
+		r3.Add(r2),
+		r3.Sub(r2),
+	}
+
+	var f Frustum
+	for i, row := range rows {
+		f.Planes[i] = Plane{Normal: row.Vec3(), D: row[3]}.Normalize()
+	}
+	return f
+}
+
+// ContainsPoint reports whether p is inside the frustum
+func (f Frustum) ContainsPoint(p Vec3) bool {
+	for _, plane := range f.Planes {
+		if plane.Distance(p) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsSphere reports whether any part of s may be inside the
+// frustum. It is conservative: spheres near a corner can be reported
+// visible while just outside
+func (f Frustum) IntersectsSphere(s Sphere) bool {
+	for _, plane := range f.Planes {
+		if plane.Distance(s.Center) < -s.Radius {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsAABB reports whether any part of b may be inside the frustum,
+// with the same conservativeness as IntersectsSphere
+func (f Frustum) IntersectsAABB(b AABB) bool {
+	for _, plane := range f.Planes {
+		// The corner furthest along the plane normal
+		var p Vec3
+		for axis := 0; axis < 3; axis++ {
+			if plane.Normal[axis] >= 0 {
+				p[axis] = b.Max[axis]
+			} else {
+				p[axis] = b.Min[axis]
+			}
+		}
+		if plane.Distance(p) < 0 {
+			return false
+		}
+	}
+	return true
+}
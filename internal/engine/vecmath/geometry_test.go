@@ -0,0 +1,100 @@
+package vecmath
+
+import (
+	"math"
+	"testing"
+)
+
+// testFrustum looks down -Z from the origin with a 90 degree field of view
+// and a square aspect, between 1 and 100
+func testFrustum() Frustum {
+	return FrustumFromMatrix(Perspective(math.Pi/2, 1, 1, 100))
+}
+
+func TestFrustumFromMatrix(t *testing.T) {
+	f := testFrustum()
+	tests := []struct {
+		plane  int
+		normal Vec3
+		d      float32
+	}{
+		{FrustumLeft, Vec3{1, 0, -1}.Normalize(), 0},
+		{FrustumRight, Vec3{-1, 0, -1}.Normalize(), 0},
+		{FrustumBottom, Vec3{0, 1, -1}.Normalize(), 0},
+		{FrustumTop, Vec3{0, -1, -1}.Normalize(), 0},
+		{FrustumNear, Vec3{0, 0, -1}, -1},
+		{FrustumFar, Vec3{0, 0, 1}, 100},
+	}
+	for _, tt := range tests {
+		p := f.Planes[tt.plane]
+		assertVec3(t, "normal", p.Normal, tt.normal)
+		if math.Abs(float64(p.D-tt.d)) > 1e-2 {
+			t.Errorf("plane %d D = %v, want %v", tt.plane, p.D, tt.d)
+		}
+	}
+}
+
+func TestFrustumContainsPoint(t *testing.T) {
+	f := testFrustum()
+	tests := []struct {
+		name string
+		p    Vec3
+		want bool
+	}{
+		{"center", Vec3{0, 0, -10}, true},
+		{"near corner", Vec3{0.9, 0.9, -1.5}, true},
+		{"behind", Vec3{0, 0, 10}, false},
+		{"before near", Vec3{0, 0, -0.5}, false},
+		{"beyond far", Vec3{0, 0, -101}, false},
+		{"left", Vec3{-11, 0, -10}, false},
+		{"above", Vec3{0, 11, -10}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.ContainsPoint(tt.p); got != tt.want {
+				t.Errorf("ContainsPoint(%v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrustumCulling(t *testing.T) {
+	f := testFrustum()
+	tests := []struct {
+		name string
+		box  AABB
+		want bool
+	}{
+		{"inside", AABB{Min: Vec3{-1, -1, -11}, Max: Vec3{1, 1, -9}}, true},
+		{"crossing left", AABB{Min: Vec3{-15, -1, -11}, Max: Vec3{-9, 1, -9}}, true},
+		{"around camera", AABB{Min: Vec3{-5, -5, -5}, Max: Vec3{5, 5, 5}}, true},
+		{"behind", AABB{Min: Vec3{-1, -1, 5}, Max: Vec3{1, 1, 7}}, false},
+		{"left", AABB{Min: Vec3{-30, -1, -11}, Max: Vec3{-20, 1, -9}}, false},
+		{"below", AABB{Min: Vec3{-1, -30, -11}, Max: Vec3{1, -20, -9}}, false},
+		{"beyond far", AABB{Min: Vec3{-1, -1, -300}, Max: Vec3{1, 1, -200}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.IntersectsAABB(tt.box); got != tt.want {
+				t.Errorf("IntersectsAABB = %v, want %v", got, tt.want)
+			}
+			// The bounding sphere of the box is culled the same way here
+			sphere := Sphere{Center: tt.box.Center(), Radius: tt.box.Size().Len() / 2}
+			if got := f.IntersectsSphere(sphere); got != tt.want {
+				t.Errorf("IntersectsSphere = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrustumTransformed(t *testing.T) {
+	// A camera at x = 50 looking down +X
+	view := LookAt(Vec3{50, 0, 0}, Vec3{60, 0, 0}, Vec3{0, 1, 0})
+	f := FrustumFromMatrix(Perspective(math.Pi/2, 1, 1, 100).Mul(view))
+	if !f.ContainsPoint(Vec3{70, 0, 0}) {
+		t.Error("point ahead of the camera is outside")
+	}
+	if f.ContainsPoint(Vec3{30, 0, 0}) {
+		t.Error("point behind the camera is inside")
+	}
+}
@@ -0,0 +1,163 @@
+package vecmath
+
+import "math"
+
+// epsilon is the tolerance used for parallel and degenerate cases
+const epsilon = 1e-6
+
+// IntersectPlane returns the distance along r at which it crosses p. ok is
+// false when the ray is parallel to the plane or points away from it
+func (r Ray) IntersectPlane(p Plane) (t float32, ok bool) {
+	denom := p.Normal.Dot(r.Direction)
+	if abs(denom) < epsilon {
+		return 0, false
+	}
+
+	t = -p.Distance(r.Origin) / denom
+	return t, t >= 0
+}
+
+// IntersectSphere returns the distance along r to the first point of s in
+// front of the origin, 0 when the origin is inside s
+func (r Ray) IntersectSphere(s Sphere) (t float32, ok bool) {
+	oc := r.Origin.Sub(s.Center)
+	a := r.Direction.Dot(r.Direction)
+	b := oc.Dot(r.Direction)
+	c := oc.Dot(oc) - s.Radius*s.Radius
+	if c <= 0 {
+		return 0, true
+	}
+
+	disc := b*b - a*c
+	if disc < 0 || b > 0 || a == 0 {
+		return 0, false
+	}
+	return (-b - float32(math.Sqrt(float64(disc)))) / a, true
+}
+
+// IntersectAABB returns the distance along r to the first point of b in
+// front of the origin, 0 when the origin is inside b
+func (r Ray) IntersectAABB(b AABB) (t float32, ok bool) {
+	tMin := float32(0)
+	tMax := float32(math.Inf(1))
+
+	// Slab method: intersect the intervals where the ray is between each
+	// pair of parallel faces
+	for axis := 0; axis < 3; axis++ {
+		if abs(r.Direction[axis]) < epsilon {
+			if r.Origin[axis] < b.Min[axis] || r.Origin[axis] > b.Max[axis] {
+				return 0, false
+			}
+			continue
+		}
+
+		inv := 1 / r.Direction[axis]
+		t0 := (b.Min[axis] - r.Origin[axis]) * inv
+		t1 := (b.Max[axis] - r.Origin[axis]) * inv
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		tMin = max(tMin, t0)
+		tMax = min(tMax, t1)
+		if tMin > tMax {
+			return 0, false
+		}
+	}
+
+	return tMin, true
+}
+
+// IntersectTriangle returns the distance along r to the triangle a, b, c,
+// which is hit from both sides
+func (r Ray) IntersectTriangle(a, b, c Vec3) (t float32, ok bool) {
+	// Möller-Trumbore
+	e1, e2 := b.Sub(a), c.Sub(a)
+	p := r.Direction.Cross(e2)
+	det := e1.Dot(p)
+	if abs(det) < epsilon {
+		return 0, false
+	}
+
+	inv := 1 / det
+	s := r.Origin.Sub(a)
+	u := s.Dot(p) * inv
+	if u < 0 || u > 1 {
+		return 0, false
+	}
+
+	q := s.Cross(e1)
+	v := r.Direction.Dot(q) * inv
+	if v < 0 || u+v > 1 {
+		return 0, false
+	}
+
+	t = e2.Dot(q) * inv
+	return t, t >= 0
+}
+
+// Intersects reports whether the boxes overlap or touch
+func (b AABB) Intersects(o AABB) bool {
+	return b.Min[0] <= o.Max[0] && b.Max[0] >= o.Min[0] &&
+		b.Min[1] <= o.Max[1] && b.Max[1] >= o.Min[1] &&
+		b.Min[2] <= o.Max[2] && b.Max[2] >= o.Min[2]
+}
+
+// ClosestPoint returns the point of the box closest to p
+func (b AABB) ClosestPoint(p Vec3) Vec3 {
+	return p.Max(b.Min).Min(b.Max)
+}
+
+// IntersectsSphere reports whether the box and the sphere overlap or touch
+func (b AABB) IntersectsSphere(s Sphere) bool {
+	return b.ClosestPoint(s.Center).Sub(s.Center).LenSqr() <= s.Radius*s.Radius
+}
+
+// Transform returns the box containing b transformed by m
+func (b AABB) Transform(m Mat4) AABB {
+	if b.IsEmpty() {
+		return b
+	}
+
+	// Arvo's method: each output axis is the translation plus the extremes
+	// of every matrix element times the input interval
+	r := AABB{
+		Min: Vec3{m[12], m[13], m[14]},
+		Max: Vec3{m[12], m[13], m[14]},
+	}
+	for col := 0; col < 3; col++ {
+		for row := 0; row < 3; row++ {
+			e := m[col*4+row]
+			lo, hi := e*b.Min[col], e*b.Max[col]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			r.Min[row] += lo
+			r.Max[row] += hi
+		}
+	}
+	return r
+}
+
+// Intersects reports whether the spheres overlap or touch
+func (s Sphere) Intersects(o Sphere) bool {
+	r := s.Radius + o.Radius
+	return s.Center.Sub(o.Center).LenSqr() <= r*r
+}
+
+// SideOfAABB returns 1 when b is entirely in front of the plane, -1 when
+// it is entirely behind and 0 when the plane crosses it
+func (p Plane) SideOfAABB(b AABB) int {
+	center := b.Center()
+	extents := b.Max.Sub(center)
+	radius := extents[0]*abs(p.Normal[0]) + extents[1]*abs(p.Normal[1]) + extents[2]*abs(p.Normal[2])
+
+	d := p.Distance(center)
+	switch {
+	case d > radius:
+		return 1
+	case d < -radius:
+		return -1
+	default:
+		return 0
+	}
+}
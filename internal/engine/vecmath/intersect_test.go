@@ -0,0 +1,165 @@
+package vecmath
+
+import "testing"
+
+func TestRayIntersectSphere(t *testing.T) {
+	sphere := Sphere{Center: Vec3{0, 0, -10}, Radius: 2}
+	tests := []struct {
+		name   string
+		ray    Ray
+		wantOK bool
+		wantT  float32
+	}{
+		{"hit", Ray{Origin: Vec3{}, Direction: Vec3{0, 0, -1}}, true, 8},
+		{"hit scaled direction", Ray{Origin: Vec3{}, Direction: Vec3{0, 0, -2}}, true, 4},
+		{"graze", Ray{Origin: Vec3{2, 0, 0}, Direction: Vec3{0, 0, -1}}, true, 10},
+		{"inside", Ray{Origin: Vec3{0, 0, -10}, Direction: Vec3{1, 0, 0}}, true, 0},
+		{"miss", Ray{Origin: Vec3{3, 0, 0}, Direction: Vec3{0, 0, -1}}, false, 0},
+		{"behind", Ray{Origin: Vec3{}, Direction: Vec3{0, 0, 1}}, false, 0},
+		{"zero direction", Ray{Origin: Vec3{}, Direction: Vec3{}}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.ray.IntersectSphere(sphere)
+			if ok != tt.wantOK || (ok && !near(got, tt.wantT)) {
+				t.Errorf("IntersectSphere = %v, %v, want %v, %v", got, ok, tt.wantT, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRayIntersectAABB(t *testing.T) {
+	box := AABB{Min: Vec3{-1, -1, -6}, Max: Vec3{1, 1, -4}}
+	tests := []struct {
+		name   string
+		ray    Ray
+		wantOK bool
+		wantT  float32
+	}{
+		{"hit front", Ray{Origin: Vec3{}, Direction: Vec3{0, 0, -1}}, true, 4},
+		{"hit diagonal", Ray{Origin: Vec3{-5, 0, -5}, Direction: Vec3{1, 0, 0}}, true, 4},
+		{"hit edge", Ray{Origin: Vec3{1, 1, 0}, Direction: Vec3{0, 0, -1}}, true, 4},
+		{"inside", Ray{Origin: Vec3{0, 0, -5}, Direction: Vec3{0, 1, 0}}, true, 0},
+		{"miss beside", Ray{Origin: Vec3{2, 0, 0}, Direction: Vec3{0, 0, -1}}, false, 0},
+		{"miss parallel", Ray{Origin: Vec3{0, 2, 0}, Direction: Vec3{0, 0, -1}}, false, 0},
+		{"behind", Ray{Origin: Vec3{}, Direction: Vec3{0, 0, 1}}, false, 0},
+		{"miss skew", Ray{Origin: Vec3{}, Direction: Vec3{1, 0, -1}}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.ray.IntersectAABB(box)
+			if ok != tt.wantOK || (ok && !near(got, tt.wantT)) {
+				t.Errorf("IntersectAABB = %v, %v, want %v, %v", got, ok, tt.wantT, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRayIntersectPlane(t *testing.T) {
+	ground := PlaneFromPoint(Vec3{0, 1, 0}, Vec3{0, 2, 0})
+	tests := []struct {
+		name   string
+		ray    Ray
+		wantOK bool
+		wantT  float32
+	}{
+		{"hit from above", Ray{Origin: Vec3{0, 10, 0}, Direction: Vec3{0, -1, 0}}, true, 8},
+		{"hit from below", Ray{Origin: Vec3{0, 0, 0}, Direction: Vec3{0, 1, 0}}, true, 2},
+		{"hit slanted", Ray{Origin: Vec3{0, 6, 0}, Direction: Vec3{1, -1, 0}}, true, 4},
+		{"on plane", Ray{Origin: Vec3{5, 2, 5}, Direction: Vec3{0, -1, 0}}, true, 0},
+		{"parallel", Ray{Origin: Vec3{0, 10, 0}, Direction: Vec3{1, 0, 0}}, false, 0},
+		{"away", Ray{Origin: Vec3{0, 10, 0}, Direction: Vec3{0, 1, 0}}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.ray.IntersectPlane(ground)
+			if ok != tt.wantOK || (ok && !near(got, tt.wantT)) {
+				t.Errorf("IntersectPlane = %v, %v, want %v, %v", got, ok, tt.wantT, tt.wantOK)
+			}
+			if ok {
+				if d := ground.Distance(tt.ray.At(got)); !near(d, 0) {
+					t.Errorf("hit point is %v away from the plane", d)
+				}
+			}
+		})
+	}
+}
+
+func TestRayIntersectTriangle(t *testing.T) {
+	a, b, c := Vec3{-1, -1, -5}, Vec3{1, -1, -5}, Vec3{0, 1, -5}
+	tests := []struct {
+		name   string
+		ray    Ray
+		wantOK bool
+		wantT  float32
+	}{
+		{"hit", Ray{Origin: Vec3{}, Direction: Vec3{0, 0, -1}}, true, 5},
+		{"hit back face", Ray{Origin: Vec3{0, 0, -10}, Direction: Vec3{0, 0, 1}}, true, 5},
+		{"miss", Ray{Origin: Vec3{2, 0, 0}, Direction: Vec3{0, 0, -1}}, false, 0},
+		{"parallel", Ray{Origin: Vec3{}, Direction: Vec3{1, 0, 0}}, false, 0},
+		{"behind", Ray{Origin: Vec3{}, Direction: Vec3{0, 0, 1}}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.ray.IntersectTriangle(a, b, c)
+			if ok != tt.wantOK || (ok && !near(got, tt.wantT)) {
+				t.Errorf("IntersectTriangle = %v, %v, want %v, %v", got, ok, tt.wantT, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAABBOverlap(t *testing.T) {
+	box := AABB{Min: Vec3{0, 0, 0}, Max: Vec3{2, 2, 2}}
+	tests := []struct {
+		name   string
+		other  AABB
+		sphere Sphere
+		want   bool
+	}{
+		{"overlap", AABB{Min: Vec3{1, 1, 1}, Max: Vec3{3, 3, 3}}, Sphere{Center: Vec3{3, 1, 1}, Radius: 1.5}, true},
+		{"touch", AABB{Min: Vec3{2, 0, 0}, Max: Vec3{3, 1, 1}}, Sphere{Center: Vec3{3, 1, 1}, Radius: 1}, true},
+		{"apart", AABB{Min: Vec3{3, 3, 3}, Max: Vec3{4, 4, 4}}, Sphere{Center: Vec3{4, 4, 4}, Radius: 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := box.Intersects(tt.other); got != tt.want {
+				t.Errorf("Intersects = %v, want %v", got, tt.want)
+			}
+			if got := box.IntersectsSphere(tt.sphere); got != tt.want {
+				t.Errorf("IntersectsSphere = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAABBTransform(t *testing.T) {
+	box := AABB{Min: Vec3{-1, -1, -1}, Max: Vec3{1, 1, 1}}
+	got := box.Transform(Translate(Vec3{5, 0, 0}).Mul(Scale(Vec3{2, 1, 1})))
+	assertVec3(t, "Min", got.Min, Vec3{3, -1, -1})
+	assertVec3(t, "Max", got.Max, Vec3{7, 1, 1})
+
+	if got := EmptyAABB().Transform(Translate(Vec3{1, 2, 3})); !got.IsEmpty() {
+		t.Errorf("transformed empty box = %v, want empty", got)
+	}
+}
+
+func TestPlaneSideOfAABB(t *testing.T) {
+	plane := PlaneFromPoint(Vec3{1, 0, 0}, Vec3{})
+	tests := []struct {
+		name string
+		box  AABB
+		want int
+	}{
+		{"front", AABB{Min: Vec3{1, -1, -1}, Max: Vec3{2, 1, 1}}, 1},
+		{"back", AABB{Min: Vec3{-2, -1, -1}, Max: Vec3{-1, 1, 1}}, -1},
+		{"crossing", AABB{Min: Vec3{-1, -1, -1}, Max: Vec3{1, 1, 1}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plane.SideOfAABB(tt.box); got != tt.want {
+				t.Errorf("SideOfAABB = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
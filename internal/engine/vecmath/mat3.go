@@ -0,0 +1,102 @@
+package vecmath
+
+// Mat3 is a 3x3 matrix stored in column-major order like Mat4, mostly used
+// for rotations and normal matrices
+type Mat3 [9]float32
+
+// Ident3 returns the identity matrix
+func Ident3() Mat3 {
+	return Mat3{
+		1, 0, 0,
+		0, 1, 0,
+		0, 0, 1,
+	}
+}
+
+// At returns the element at row, col
+func (m Mat3) At(row, col int) float32 {
+	return m[col*3+row]
+}
+
+// Mul returns m * o, which applies o first and then m
+func (m Mat3) Mul(o Mat3) Mat3 {
+	var r Mat3
+	for col := 0; col < 3; col++ {
+		for row := 0; row < 3; row++ {
+			r[col*3+row] = m[row]*o[col*3] + m[3+row]*o[col*3+1] + m[6+row]*o[col*3+2]
+		}
+	}
+	return r
+}
+
+// MulVec3 returns m * v
+func (m Mat3) MulVec3(v Vec3) Vec3 {
+	return Vec3{
+		m[0]*v[0] + m[3]*v[1] + m[6]*v[2],
+		m[1]*v[0] + m[4]*v[1] + m[7]*v[2],
+		m[2]*v[0] + m[5]*v[1] + m[8]*v[2],
+	}
+}
+
+// Transpose returns the transposed matrix
+func (m Mat3) Transpose() Mat3 {
+	return Mat3{
+		m[0], m[3], m[6],
+		m[1], m[4], m[7],
+		m[2], m[5], m[8],
+	}
+}
+
+// Det returns the determinant
+func (m Mat3) Det() float32 {
+	return m[0]*(m[4]*m[8]-m[7]*m[5]) -
+		m[3]*(m[1]*m[8]-m[7]*m[2]) +
+		m[6]*(m[1]*m[5]-m[4]*m[2])
+}
+
+// Inverse returns the inverse matrix, or the zero matrix when m is
+// singular
+func (m Mat3) Inverse() Mat3 {
+	det := m.Det()
+	if det == 0 {
+		return Mat3{}
+	}
+
+	inv := 1 / det
+	return Mat3{
+		(m[4]*m[8] - m[5]*m[7]) * inv,
+		(m[2]*m[7] - m[1]*m[8]) * inv,
+		(m[1]*m[5] - m[2]*m[4]) * inv,
+		(m[5]*m[6] - m[3]*m[8]) * inv,
+		(m[0]*m[8] - m[2]*m[6]) * inv,
+		(m[2]*m[3] - m[0]*m[5]) * inv,
+		(m[3]*m[7] - m[4]*m[6]) * inv,
+		(m[1]*m[6] - m[0]*m[7]) * inv,
+		(m[0]*m[4] - m[1]*m[3]) * inv,
+	}
+}
+
+// Mat4 returns m extended to a 4x4 matrix without translation
+func (m Mat3) Mat4() Mat4 {
+	return Mat4{
+		m[0], m[1], m[2], 0,
+		m[3], m[4], m[5], 0,
+		m[6], m[7], m[8], 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Mat3 returns the upper-left 3x3 part of m
+func (m Mat4) Mat3() Mat3 {
+	return Mat3{
+		m[0], m[1], m[2],
+		m[4], m[5], m[6],
+		m[8], m[9], m[10],
+	}
+}
+
+// NormalMatrix returns the matrix transforming normals of a model
+// transformed by m: the inverse transpose of its 3x3 part
+func (m Mat4) NormalMatrix() Mat3 {
+	return m.Mat3().Inverse().Transpose()
+}
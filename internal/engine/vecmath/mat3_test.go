@@ -0,0 +1,63 @@
+package vecmath
+
+import "testing"
+
+func TestMat3Mul(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Mat3
+		want Mat3
+	}{
+		{"identity", Ident3(), Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9}, Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"scales", Mat3{2, 0, 0, 0, 3, 0, 0, 0, 4}, Mat3{1, 1, 1, 1, 1, 1, 1, 1, 1}, Mat3{2, 3, 4, 2, 3, 4, 2, 3, 4}},
+		// Column-major: b's columns are transformed by a
+		{"general", Mat3{1, 2, 3, 4, 5, 6, 7, 8, 10}, Mat3{1, 0, 0, 0, 0, 1, 0, 1, 0}, Mat3{1, 2, 3, 7, 8, 10, 4, 5, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertMat3(t, "Mul", tt.a.Mul(tt.b), tt.want)
+		})
+	}
+}
+
+func TestMat3MulVec3(t *testing.T) {
+	m := Mat3{0, 1, 0, -1, 0, 0, 0, 0, 1} // 90 degrees around Z
+	assertVec3(t, "MulVec3", m.MulVec3(Vec3{1, 0, 0}), Vec3{0, 1, 0})
+	assertVec3(t, "MulVec3", m.MulVec3(Vec3{0, 1, 2}), Vec3{-1, 0, 2})
+}
+
+func TestMat3Inverse(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Mat3
+	}{
+		{"identity", Ident3()},
+		{"scale", Mat3{2, 0, 0, 0, 4, 0, 0, 0, 0.5}},
+		{"rotation", QuatAxisAngle(Vec3{1, 2, 3}, 0.7).Mat4().Mat3()},
+		{"general", Mat3{1, 2, 3, 0, 1, 4, 5, 6, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := tt.m.Inverse()
+			assertMat3(t, "m * inverse", tt.m.Mul(inv), Ident3())
+			assertMat3(t, "inverse * m", inv.Mul(tt.m), Ident3())
+		})
+	}
+
+	t.Run("singular", func(t *testing.T) {
+		m := Mat3{1, 2, 3, 2, 4, 6, 0, 0, 1}
+		if got := m.Inverse(); got != (Mat3{}) {
+			t.Errorf("Inverse of a singular matrix = %v, want zero", got)
+		}
+	})
+}
+
+func TestNormalMatrix(t *testing.T) {
+	// Normals of a surface scaled along X must stay perpendicular to it
+	m := Scale(Vec3{4, 1, 1}).Mul(QuatAxisAngle(Vec3{0, 0, 1}, 0.5).Mat4())
+	tangent := m.MulDir(Vec3{1, 1, 0})
+	normal := m.NormalMatrix().MulVec3(Vec3{1, -1, 0})
+	if d := tangent.Dot(normal); !near(d, 0) {
+		t.Errorf("transformed normal is not perpendicular: dot = %v", d)
+	}
+}
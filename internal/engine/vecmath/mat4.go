@@ -0,0 +1,187 @@
+package vecmath
+
+import "math"
+
+// Mat4 is a 4x4 matrix stored in column-major order, the layout GL
+// expects, so element (row, col) is m[col*4+row]. Transforming a vector
+// multiplies it on the right: m.MulVec4(v) is M * v
+type Mat4 [16]float32
+
+// Ident4 returns the identity matrix
+func Ident4() Mat4 {
+	return Mat4{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// At returns the element at row, col
+func (m Mat4) At(row, col int) float32 {
+	return m[col*4+row]
+}
+
+// Row returns a row of the matrix
+func (m Mat4) Row(row int) Vec4 {
+	return Vec4{m[row], m[4+row], m[8+row], m[12+row]}
+}
+
+// Col returns a column of the matrix
+func (m Mat4) Col(col int) Vec4 {
+	return Vec4{m[col*4], m[col*4+1], m[col*4+2], m[col*4+3]}
+}
+
+// Mul returns m * o, which applies o first and then m
+func (m Mat4) Mul(o Mat4) Mat4 {
+	var r Mat4
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			r[col*4+row] = m[row]*o[col*4] + m[4+row]*o[col*4+1] + m[8+row]*o[col*4+2] + m[12+row]*o[col*4+3]
+		}
+	}
+	return r
+}
+
+// MulVec4 returns m * v
+func (m Mat4) MulVec4(v Vec4) Vec4 {
+	return Vec4{
+		m[0]*v[0] + m[4]*v[1] + m[8]*v[2] + m[12]*v[3],
+		m[1]*v[0] + m[5]*v[1] + m[9]*v[2] + m[13]*v[3],
+		m[2]*v[0] + m[6]*v[1] + m[10]*v[2] + m[14]*v[3],
+		m[3]*v[0] + m[7]*v[1] + m[11]*v[2] + m[15]*v[3],
+	}
+}
+
+// MulPoint transforms a point, dividing by w for projective matrices
+func (m Mat4) MulPoint(p Vec3) Vec3 {
+	r := m.MulVec4(Vec4{p[0], p[1], p[2], 1})
+	if r[3] != 0 && r[3] != 1 {
+		return r.Vec3().Mul(1 / r[3])
+	}
+	return r.Vec3()
+}
+
+// MulDir transforms a direction, ignoring the translation
+func (m Mat4) MulDir(d Vec3) Vec3 {
+	return m.MulVec4(Vec4{d[0], d[1], d[2], 0}).Vec3()
+}
+
+// Transpose returns the transposed matrix
+func (m Mat4) Transpose() Mat4 {
+	var r Mat4
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			r[row*4+col] = m[col*4+row]
+		}
+	}
+	return r
+}
+
+// Det returns the determinant
+func (m Mat4) Det() float32 {
+	return m[0]*m.cofactor(0, 0) + m[4]*m.cofactor(0, 1) + m[8]*m.cofactor(0, 2) + m[12]*m.cofactor(0, 3)
+}
+
+// Inverse returns the inverse matrix, or the zero matrix when m is
+// singular
+func (m Mat4) Inverse() Mat4 {
+	var cofactors Mat4
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			cofactors[col*4+row] = m.cofactor(row, col)
+		}
+	}
+
+	det := m[0]*cofactors[0] + m[4]*cofactors[4] + m[8]*cofactors[8] + m[12]*cofactors[12]
+	if det == 0 {
+		return Mat4{}
+	}
+
+	// The inverse is the transposed cofactor matrix divided by det
+	inv := 1 / det
+	var r Mat4
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			r[col*4+row] = cofactors[row*4+col] * inv
+		}
+	}
+	return r
+}
+
+// cofactor returns the signed minor of the element at row, col
+func (m Mat4) cofactor(row, col int) float32 {
+	var minor [9]float32
+	i := 0
+	for c := 0; c < 4; c++ {
+		if c == col {
+			continue
+		}
+		for r := 0; r < 4; r++ {
+			if r == row {
+				continue
+			}
+			minor[i] = m[c*4+r]
+			i++
+		}
+	}
+
+	// minor is column-major 3x3
+	det := minor[0]*(minor[4]*minor[8]-minor[7]*minor[5]) -
+		minor[3]*(minor[1]*minor[8]-minor[7]*minor[2]) +
+		minor[6]*(minor[1]*minor[5]-minor[4]*minor[2])
+	if (row+col)%2 == 1 {
+		return -det
+	}
+	return det
+}
+
+// Translate returns a translation matrix
+func Translate(v Vec3) Mat4 {
+	m := Ident4()
+	m[12], m[13], m[14] = v[0], v[1], v[2]
+	return m
+}
+
+// Scale returns a scaling matrix
+func Scale(v Vec3) Mat4 {
+	m := Ident4()
+	m[0], m[5], m[10] = v[0], v[1], v[2]
+	return m
+}
+
+// Perspective returns a GL perspective projection with a vertical field
+// of view in radians, mapping depth from near to far into [-1, 1]
+func Perspective(fovy, aspect, near, far float32) Mat4 {
+	f := float32(1 / math.Tan(float64(fovy)/2))
+	return Mat4{
+		f / aspect, 0, 0, 0,
+		0, f, 0, 0,
+		0, 0, (far + near) / (near - far), -1,
+		0, 0, 2 * far * near / (near - far), 0,
+	}
+}
+
+// Ortho returns a GL orthographic projection
+func Ortho(left, right, bottom, top, near, far float32) Mat4 {
+	return Mat4{
+		2 / (right - left), 0, 0, 0,
+		0, 2 / (top - bottom), 0, 0,
+		0, 0, -2 / (far - near), 0,
+		-(right + left) / (right - left), -(top + bottom) / (top - bottom), -(far + near) / (far - near), 1,
+	}
+}
+
+// LookAt returns a view matrix for an eye looking at center
+func LookAt(eye, center, up Vec3) Mat4 {
+	f := center.Sub(eye).Normalize()
+	s := f.Cross(up).Normalize()
+	u := s.Cross(f)
+
+	return Mat4{
+		s[0], u[0], -f[0], 0,
+		s[1], u[1], -f[1], 0,
+		s[2], u[2], -f[2], 0,
+		-s.Dot(eye), -u.Dot(eye), f.Dot(eye), 1,
+	}
+}
@@ -0,0 +1,91 @@
+package vecmath
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMat4Mul(t *testing.T) {
+	tests := []struct {
+		name  string
+		m     Mat4
+		point Vec3
+		want  Vec3
+	}{
+		{"identity", Ident4().Mul(Ident4()), Vec3{1, 2, 3}, Vec3{1, 2, 3}},
+		// Translate after scaling: the scale does not affect the offset
+		{"translate scale", Translate(Vec3{1, 2, 3}).Mul(Scale(Vec3{2, 2, 2})), Vec3{1, 1, 1}, Vec3{3, 4, 5}},
+		{"scale translate", Scale(Vec3{2, 2, 2}).Mul(Translate(Vec3{1, 2, 3})), Vec3{1, 1, 1}, Vec3{4, 6, 8}},
+		{"rotate translate", QuatAxisAngle(Vec3{0, 1, 0}, math.Pi/2).Mat4().Mul(Translate(Vec3{1, 0, 0})), Vec3{}, Vec3{0, 0, -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertVec3(t, "MulPoint", tt.m.MulPoint(tt.point), tt.want)
+		})
+	}
+}
+
+func TestMat4MulAssociative(t *testing.T) {
+	a := Translate(Vec3{1, -2, 3})
+	b := QuatAxisAngle(Vec3{1, 1, 0}, 1.2).Mat4()
+	c := Scale(Vec3{2, 3, 0.5})
+	assertMat4(t, "(ab)c", a.Mul(b).Mul(c), a.Mul(b.Mul(c)))
+}
+
+func TestMat4Inverse(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Mat4
+	}{
+		{"identity", Ident4()},
+		{"translate", Translate(Vec3{4, -5, 6})},
+		{"scale", Scale(Vec3{2, 0.5, 8})},
+		{"rotation", QuatAxisAngle(Vec3{1, 2, 3}, 2.1).Mat4()},
+		{"look at", LookAt(Vec3{3, 4, 5}, Vec3{0, 1, 0}, Vec3{0, 1, 0})},
+		{"perspective", Perspective(1.2, 16.0/9, 0.1, 100)},
+		{"ortho", Ortho(-4, 4, -3, 3, 0.5, 50)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := tt.m.Inverse()
+			assertMat4(t, "m * inverse", tt.m.Mul(inv), Ident4())
+			assertMat4(t, "inverse * m", inv.Mul(tt.m), Ident4())
+		})
+	}
+
+	t.Run("singular", func(t *testing.T) {
+		m := Scale(Vec3{1, 0, 1})
+		if got := m.Inverse(); got != (Mat4{}) {
+			t.Errorf("Inverse of a singular matrix = %v, want zero", got)
+		}
+	})
+}
+
+func TestMat4Det(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Mat4
+		want float32
+	}{
+		{"identity", Ident4(), 1},
+		{"scale", Scale(Vec3{2, 3, 4}), 24},
+		{"rotation", QuatAxisAngle(Vec3{0, 0, 1}, 1).Mat4(), 1},
+		{"translate", Translate(Vec3{7, 8, 9}), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Det(); !near(got, tt.want) {
+				t.Errorf("Det = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookAt(t *testing.T) {
+	eye := Vec3{0, 0, 5}
+	view := LookAt(eye, Vec3{}, Vec3{0, 1, 0})
+	assertVec3(t, "eye", view.MulPoint(eye), Vec3{})
+	// The target ends up straight ahead, along -Z
+	assertVec3(t, "target", view.MulPoint(Vec3{}), Vec3{0, 0, -5})
+	assertVec3(t, "up", view.MulDir(Vec3{0, 1, 0}), Vec3{0, 1, 0})
+}
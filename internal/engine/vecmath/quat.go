@@ -0,0 +1,168 @@
+package vecmath
+
+import "math"
+
+// Quat is a rotation quaternion with vector part (X, Y, Z) and scalar W
+type Quat struct {
+	X, Y, Z, W float32
+}
+
+// QuatIdent returns the identity rotation
+func QuatIdent() Quat {
+	return Quat{W: 1}
+}
+
+// QuatAxisAngle returns a rotation of angle radians around axis
+func QuatAxisAngle(axis Vec3, angle float32) Quat {
+	axis = axis.Normalize()
+	s := float32(math.Sin(float64(angle) / 2))
+	return Quat{axis[0] * s, axis[1] * s, axis[2] * s, float32(math.Cos(float64(angle) / 2))}
+}
+
+// QuatLookAt returns the rotation turning the -Z axis towards forward with
+// +Y as close to up as possible, the orientation of a camera looking along
+// forward
+func QuatLookAt(forward, up Vec3) Quat {
+	back := forward.Normalize().Neg()
+	right := up.Cross(back).Normalize()
+	if right.LenSqr() == 0 {
+		// forward is parallel to up; any right vector will do
+		right = Vec3{1, 0, 0}
+		if abs(back[0]) > 0.9 {
+			right = Vec3{0, 0, 1}
+		}
+		right = right.Sub(back.Mul(back.Dot(right))).Normalize()
+	}
+	return quatFromBasis(right, back.Cross(right), back)
+}
+
+// quatFromBasis returns the rotation mapping the X, Y and Z axes to the
+// orthonormal vectors x, y and z
+func quatFromBasis(x, y, z Vec3) Quat {
+	trace := x[0] + y[1] + z[2]
+	switch {
+	case trace > 0:
+		s := float32(math.Sqrt(float64(trace)+1)) * 2
+		return Quat{(y[2] - z[1]) / s, (z[0] - x[2]) / s, (x[1] - y[0]) / s, s / 4}
+	case x[0] > y[1] && x[0] > z[2]:
+		s := float32(math.Sqrt(float64(1+x[0]-y[1]-z[2]))) * 2
+		return Quat{s / 4, (y[0] + x[1]) / s, (z[0] + x[2]) / s, (y[2] - z[1]) / s}
+	case y[1] > z[2]:
+		s := float32(math.Sqrt(float64(1+y[1]-x[0]-z[2]))) * 2
+		return Quat{(y[0] + x[1]) / s, s / 4, (z[1] + y[2]) / s, (z[0] - x[2]) / s}
+	default:
+		s := float32(math.Sqrt(float64(1+z[2]-x[0]-y[1]))) * 2
+		return Quat{(z[0] + x[2]) / s, (z[1] + y[2]) / s, s / 4, (x[1] - y[0]) / s}
+	}
+}
+
+// Mul returns the rotation q * o, which applies o first and then q
+func (q Quat) Mul(o Quat) Quat {
+	return Quat{
+		q.W*o.X + q.X*o.W + q.Y*o.Z - q.Z*o.Y,
+		q.W*o.Y - q.X*o.Z + q.Y*o.W + q.Z*o.X,
+		q.W*o.Z + q.X*o.Y - q.Y*o.X + q.Z*o.W,
+		q.W*o.W - q.X*o.X - q.Y*o.Y - q.Z*o.Z,
+	}
+}
+
+// Conjugate returns the inverse rotation of a unit quaternion
+func (q Quat) Conjugate() Quat {
+	return Quat{-q.X, -q.Y, -q.Z, q.W}
+}
+
+// Len returns the norm of q
+func (q Quat) Len() float32 {
+	return float32(math.Sqrt(float64(q.X*q.X + q.Y*q.Y + q.Z*q.Z + q.W*q.W)))
+}
+
+// Normalize returns q scaled to unit length, or the identity when q is
+// zero
+func (q Quat) Normalize() Quat {
+	l := q.Len()
+	if l == 0 {
+		return QuatIdent()
+	}
+	return Quat{q.X / l, q.Y / l, q.Z / l, q.W / l}
+}
+
+// Rotate rotates v by q
+func (q Quat) Rotate(v Vec3) Vec3 {
+	// v + 2w(u x v) + 2u x (u x v), with u the vector part
+	u := Vec3{q.X, q.Y, q.Z}
+	t := u.Cross(v).Mul(2)
+	return v.Add(t.Mul(q.W)).Add(u.Cross(t))
+}
+
+// Mat4 returns the rotation matrix of a unit quaternion
+func (q Quat) Mat4() Mat4 {
+	x, y, z, w := q.X, q.Y, q.Z, q.W
+	return Mat4{
+		1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+		2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+		2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+		0, 0, 0, 1,
+	}
+}
+
+// abs returns the absolute value of f
+func abs(f float32) float32 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+// Dot returns the dot product of q and o
+func (q Quat) Dot(o Quat) float32 {
+	return q.X*o.X + q.Y*o.Y + q.Z*o.Z + q.W*o.W
+}
+
+// Inverse returns the inverse rotation, also for non-unit quaternions
+func (q Quat) Inverse() Quat {
+	l := q.Dot(q)
+	if l == 0 {
+		return QuatIdent()
+	}
+	return Quat{-q.X / l, -q.Y / l, -q.Z / l, q.W / l}
+}
+
+// Slerp interpolates along the shortest arc between q (t = 0) and o
+// (t = 1) at constant angular speed
+func (q Quat) Slerp(o Quat, t float32) Quat {
+	cos := q.Dot(o)
+	if cos < 0 {
+		// Take the short way around
+		o = Quat{-o.X, -o.Y, -o.Z, -o.W}
+		cos = -cos
+	}
+
+	// Nearly parallel: fall back to normalized linear interpolation
+	if cos > 0.9995 {
+		return Quat{
+			q.X + (o.X-q.X)*t,
+			q.Y + (o.Y-q.Y)*t,
+			q.Z + (o.Z-q.Z)*t,
+			q.W + (o.W-q.W)*t,
+		}.Normalize()
+	}
+
+	angle := math.Acos(float64(cos))
+	sin := math.Sin(angle)
+	a := float32(math.Sin((1-float64(t))*angle) / sin)
+	b := float32(math.Sin(float64(t)*angle) / sin)
+	return Quat{
+		q.X*a + o.X*b,
+		q.Y*a + o.Y*b,
+		q.Z*a + o.Z*b,
+		q.W*a + o.W*b,
+	}
+}
+
+// QuatEuler returns the rotation for a yaw around Y, then a pitch around
+// X, then a roll around Z, all in radians and in the rotated frame
+func QuatEuler(yaw, pitch, roll float32) Quat {
+	return QuatAxisAngle(Vec3{0, 1, 0}, yaw).
+		Mul(QuatAxisAngle(Vec3{1, 0, 0}, pitch)).
+		Mul(QuatAxisAngle(Vec3{0, 0, 1}, roll))
+}
@@ -0,0 +1,110 @@
+package vecmath
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuatRotate(t *testing.T) {
+	tests := []struct {
+		name string
+		q    Quat
+		v    Vec3
+		want Vec3
+	}{
+		{"identity", QuatIdent(), Vec3{1, 2, 3}, Vec3{1, 2, 3}},
+		{"y quarter", QuatAxisAngle(Vec3{0, 1, 0}, math.Pi/2), Vec3{1, 0, 0}, Vec3{0, 0, -1}},
+		{"x quarter", QuatAxisAngle(Vec3{1, 0, 0}, math.Pi/2), Vec3{0, 1, 0}, Vec3{0, 0, 1}},
+		{"z half", QuatAxisAngle(Vec3{0, 0, 1}, math.Pi), Vec3{1, 1, 0}, Vec3{-1, -1, 0}},
+		{"unnormalized axis", QuatAxisAngle(Vec3{0, 0, 5}, math.Pi/2), Vec3{1, 0, 0}, Vec3{0, 1, 0}},
+		{"around itself", QuatAxisAngle(Vec3{1, 1, 1}, 1.3), Vec3{2, 2, 2}, Vec3{2, 2, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertVec3(t, "Rotate", tt.q.Rotate(tt.v), tt.want)
+			// The matrix form rotates the same way
+			assertVec3(t, "Mat4", tt.q.Mat4().MulDir(tt.v), tt.want)
+		})
+	}
+}
+
+func TestQuatMul(t *testing.T) {
+	a := QuatAxisAngle(Vec3{0, 1, 0}, 0.8)
+	b := QuatAxisAngle(Vec3{1, 0, 0}, -0.4)
+	v := Vec3{1, 2, 3}
+	// a * b applies b first
+	assertVec3(t, "Mul", a.Mul(b).Rotate(v), a.Rotate(b.Rotate(v)))
+	assertQuat(t, "q * inverse", a.Mul(a.Inverse()), QuatIdent())
+	assertQuat(t, "conjugate", a.Conjugate(), a.Inverse())
+}
+
+func TestQuatSlerp(t *testing.T) {
+	from := QuatIdent()
+	to := QuatAxisAngle(Vec3{0, 1, 0}, math.Pi/2)
+	tests := []struct {
+		name string
+		from Quat
+		to   Quat
+		t    float32
+		want Quat
+	}{
+		{"start", from, to, 0, from},
+		{"end", from, to, 1, to},
+		{"half", from, to, 0.5, QuatAxisAngle(Vec3{0, 1, 0}, math.Pi/4)},
+		{"quarter", from, to, 0.25, QuatAxisAngle(Vec3{0, 1, 0}, math.Pi/8)},
+		// -to is the same rotation; the short arc must still be taken
+		{"negated", from, Quat{-to.X, -to.Y, -to.Z, -to.W}, 0.5, QuatAxisAngle(Vec3{0, 1, 0}, math.Pi/4)},
+		{"nearly equal", from, QuatAxisAngle(Vec3{1, 0, 0}, 0.001), 0.5, QuatAxisAngle(Vec3{1, 0, 0}, 0.0005)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.from.Slerp(tt.to, tt.t)
+			assertQuat(t, "Slerp", got, tt.want)
+			if !near(got.Len(), 1) {
+				t.Errorf("Slerp length = %v, want 1", got.Len())
+			}
+		})
+	}
+}
+
+func TestQuatLookAt(t *testing.T) {
+	tests := []struct {
+		name        string
+		forward, up Vec3
+	}{
+		{"ahead", Vec3{0, 0, -1}, Vec3{0, 1, 0}},
+		{"right", Vec3{1, 0, 0}, Vec3{0, 1, 0}},
+		{"diagonal", Vec3{1, -1, 2}, Vec3{0, 1, 0}},
+		{"unnormalized", Vec3{0, 0, 10}, Vec3{0, 3, 0}},
+		// forward parallel to up leaves the roll undefined
+		{"straight up", Vec3{0, 1, 0}, Vec3{0, 1, 0}},
+		{"straight down", Vec3{0, -1, 0}, Vec3{0, 1, 0}},
+		{"along x up", Vec3{1, 0, 0}, Vec3{1, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := QuatLookAt(tt.forward, tt.up)
+			if !near(q.Len(), 1) {
+				t.Fatalf("QuatLookAt length = %v, want 1", q.Len())
+			}
+			assertVec3(t, "forward", q.Rotate(Vec3{0, 0, -1}), tt.forward.Normalize())
+
+			// The rotated up vector leans towards up unless they are
+			// parallel, and always stays perpendicular to forward
+			up := q.Rotate(Vec3{0, 1, 0})
+			if d := up.Dot(tt.forward.Normalize()); !near(d, 0) {
+				t.Errorf("up is not perpendicular to forward: dot = %v", d)
+			}
+			if parallel := tt.forward.Normalize().Cross(tt.up.Normalize()).LenSqr() < 1e-6; !parallel && up.Dot(tt.up) <= 0 {
+				t.Errorf("up = %v points away from %v", up, tt.up)
+			}
+		})
+	}
+}
+
+func TestQuatEuler(t *testing.T) {
+	q := QuatEuler(math.Pi/2, 0, 0)
+	assertVec3(t, "yaw", q.Rotate(Vec3{0, 0, -1}), Vec3{-1, 0, 0})
+	q = QuatEuler(0, math.Pi/2, 0)
+	assertVec3(t, "pitch", q.Rotate(Vec3{0, 0, -1}), Vec3{0, 1, 0})
+}
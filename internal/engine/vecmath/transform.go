@@ -0,0 +1,76 @@
+package vecmath
+
+// Transform is a position, rotation and scale, applied to a point in the
+// order scale, rotate, translate
+type Transform struct {
+	Position Vec3
+	Rotation Quat
+	Scale    Vec3
+}
+
+// IdentTransform returns the transform that changes nothing
+func IdentTransform() Transform {
+	return Transform{Rotation: QuatIdent(), Scale: Vec3{1, 1, 1}}
+}
+
+// Matrix returns the transform as a matrix
+func (t Transform) Matrix() Mat4 {
+	m := t.Rotation.Mat4()
+	for col := 0; col < 3; col++ {
+		for row := 0; row < 3; row++ {
+			m[col*4+row] *= t.Scale[col]
+		}
+	}
+	m[12], m[13], m[14] = t.Position[0], t.Position[1], t.Position[2]
+	return m
+}
+
+// TransformPoint applies the transform to a point
+func (t Transform) TransformPoint(p Vec3) Vec3 {
+	return t.Rotation.Rotate(p.MulVec(t.Scale)).Add(t.Position)
+}
+
+// TransformDir applies the rotation and scale to a direction
+func (t Transform) TransformDir(d Vec3) Vec3 {
+	return t.Rotation.Rotate(d.MulVec(t.Scale))
+}
+
+// Mul returns the transform applying child first and then t, as for a
+// child object attached to a parent. Like in most engines the result is
+// only exact when t has a uniform scale or child has no rotation
+func (t Transform) Mul(child Transform) Transform {
+	return Transform{
+		Position: t.TransformPoint(child.Position),
+		Rotation: t.Rotation.Mul(child.Rotation),
+		Scale:    t.Scale.MulVec(child.Scale),
+	}
+}
+
+// Inverse returns the transform undoing t, exact for uniform scales
+func (t Transform) Inverse() Transform {
+	scale := Vec3{safeInverse(t.Scale[0]), safeInverse(t.Scale[1]), safeInverse(t.Scale[2])}
+	rotation := t.Rotation.Inverse()
+	return Transform{
+		Position: rotation.Rotate(t.Position.Neg()).MulVec(scale),
+		Rotation: rotation,
+		Scale:    scale,
+	}
+}
+
+// Lerp interpolates between t (f = 0) and o (f = 1), e.g. to render
+// between two simulation ticks
+func (t Transform) Lerp(o Transform, f float32) Transform {
+	return Transform{
+		Position: t.Position.Lerp(o.Position, f),
+		Rotation: t.Rotation.Slerp(o.Rotation, f),
+		Scale:    t.Scale.Lerp(o.Scale, f),
+	}
+}
+
+// safeInverse returns 1/f, or 0 for 0
+func safeInverse(f float32) float32 {
+	if f == 0 {
+		return 0
+	}
+	return 1 / f
+}
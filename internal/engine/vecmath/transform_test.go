@@ -0,0 +1,69 @@
+package vecmath
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTransformMatrix(t *testing.T) {
+	tr := Transform{
+		Position: Vec3{1, 2, 3},
+		Rotation: QuatAxisAngle(Vec3{0, 1, 0}, 0.9),
+		Scale:    Vec3{2, 0.5, 3},
+	}
+	for _, p := range []Vec3{{}, {1, 0, 0}, {-2, 3, 1.5}} {
+		assertVec3(t, "Matrix", tr.Matrix().MulPoint(p), tr.TransformPoint(p))
+	}
+}
+
+func TestTransformMul(t *testing.T) {
+	tests := []struct {
+		name          string
+		parent, child Transform
+	}{
+		{"identity", IdentTransform(), Transform{Position: Vec3{1, 2, 3}, Rotation: QuatAxisAngle(Vec3{1, 0, 0}, 1), Scale: Vec3{1, 1, 1}}},
+		{"translations", Transform{Position: Vec3{5, 0, 0}, Rotation: QuatIdent(), Scale: Vec3{1, 1, 1}}, Transform{Position: Vec3{0, 2, 0}, Rotation: QuatIdent(), Scale: Vec3{1, 1, 1}}},
+		{"rotated parent", Transform{Position: Vec3{1, 0, 0}, Rotation: QuatAxisAngle(Vec3{0, 1, 0}, math.Pi/2), Scale: Vec3{2, 2, 2}}, Transform{Position: Vec3{1, 0, 0}, Rotation: QuatAxisAngle(Vec3{0, 0, 1}, 0.3), Scale: Vec3{1, 3, 1}}},
+		{"unrotated child", Transform{Position: Vec3{0, 1, 0}, Rotation: QuatIdent(), Scale: Vec3{1, 2, 3}}, Transform{Position: Vec3{1, 1, 1}, Rotation: QuatIdent(), Scale: Vec3{2, 2, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			combined := tt.parent.Mul(tt.child)
+			for _, p := range []Vec3{{}, {1, 2, 3}, {-1, 0, 4}} {
+				assertVec3(t, "Mul", combined.TransformPoint(p), tt.parent.TransformPoint(tt.child.TransformPoint(p)))
+			}
+			assertMat4(t, "Matrix", combined.Matrix(), tt.parent.Matrix().Mul(tt.child.Matrix()))
+		})
+	}
+}
+
+func TestTransformInverse(t *testing.T) {
+	tests := []struct {
+		name string
+		tr   Transform
+	}{
+		{"identity", IdentTransform()},
+		{"translate", Transform{Position: Vec3{3, -4, 5}, Rotation: QuatIdent(), Scale: Vec3{1, 1, 1}}},
+		{"rotate", Transform{Rotation: QuatAxisAngle(Vec3{1, 2, 3}, 1.1), Scale: Vec3{1, 1, 1}}},
+		{"uniform scale", Transform{Position: Vec3{1, 2, 3}, Rotation: QuatAxisAngle(Vec3{0, 1, 0}, -0.6), Scale: Vec3{4, 4, 4}}},
+		{"axis scale", Transform{Position: Vec3{1, 2, 3}, Scale: Vec3{2, 4, 0.5}, Rotation: QuatIdent()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := tt.tr.Inverse()
+			for _, p := range []Vec3{{}, {1, 2, 3}, {-5, 0.5, 2}} {
+				assertVec3(t, "inverse", inv.TransformPoint(tt.tr.TransformPoint(p)), p)
+			}
+			assertMat4(t, "Matrix", inv.Matrix(), tt.tr.Matrix().Inverse())
+		})
+	}
+}
+
+func TestTransformLerp(t *testing.T) {
+	a := IdentTransform()
+	b := Transform{Position: Vec3{2, 4, 6}, Rotation: QuatAxisAngle(Vec3{0, 1, 0}, math.Pi/2), Scale: Vec3{3, 3, 3}}
+	mid := a.Lerp(b, 0.5)
+	assertVec3(t, "Position", mid.Position, Vec3{1, 2, 3})
+	assertVec3(t, "Scale", mid.Scale, Vec3{2, 2, 2})
+	assertQuat(t, "Rotation", mid.Rotation, QuatAxisAngle(Vec3{0, 1, 0}, math.Pi/4))
+}
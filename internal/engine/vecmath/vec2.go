@@ -0,0 +1,75 @@
+package vecmath
+
+import "math"
+
+// Vec2 is a 2D vector
+type Vec2 [2]float32
+
+// V2 creates a Vec2
+func V2(x, y float32) Vec2 {
+	return Vec2{x, y}
+}
+
+// X returns the first component
+func (v Vec2) X() float32 { return v[0] }
+
+// Y returns the second component
+func (v Vec2) Y() float32 { return v[1] }
+
+// Add returns v + o
+func (v Vec2) Add(o Vec2) Vec2 {
+	return Vec2{v[0] + o[0], v[1] + o[1]}
+}
+
+// Sub returns v - o
+func (v Vec2) Sub(o Vec2) Vec2 {
+	return Vec2{v[0] - o[0], v[1] - o[1]}
+}
+
+// Mul returns v scaled by s
+func (v Vec2) Mul(s float32) Vec2 {
+	return Vec2{v[0] * s, v[1] * s}
+}
+
+// MulVec returns the component-wise product of v and o
+func (v Vec2) MulVec(o Vec2) Vec2 {
+	return Vec2{v[0] * o[0], v[1] * o[1]}
+}
+
+// Dot returns the dot product of v and o
+func (v Vec2) Dot(o Vec2) float32 {
+	return v[0]*o[0] + v[1]*o[1]
+}
+
+// Cross returns the z component of the cross product of v and o, positive
+// when o is counter-clockwise from v
+func (v Vec2) Cross(o Vec2) float32 {
+	return v[0]*o[1] - v[1]*o[0]
+}
+
+// Len returns the length of v
+func (v Vec2) Len() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// Normalize returns v scaled to unit length, or the zero vector when v has
+// no length
+func (v Vec2) Normalize() Vec2 {
+	l := v.Len()
+	if l == 0 {
+		return Vec2{}
+	}
+	return v.Mul(1 / l)
+}
+
+// Rotate returns v rotated counter-clockwise by angle radians
+func (v Vec2) Rotate(angle float32) Vec2 {
+	s, c := math.Sincos(float64(angle))
+	sin, cos := float32(s), float32(c)
+	return Vec2{v[0]*cos - v[1]*sin, v[0]*sin + v[1]*cos}
+}
+
+// Lerp interpolates linearly between v (t = 0) and o (t = 1)
+func (v Vec2) Lerp(o Vec2, t float32) Vec2 {
+	return v.Add(o.Sub(v).Mul(t))
+}
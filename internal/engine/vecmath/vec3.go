@@ -0,0 +1,100 @@
+package vecmath
+
+import "math"
+
+// Vec3 is a 3D vector. Being an array it can be passed directly to GL
+// calls and to shader uniform setters taking [3]float32
+type Vec3 [3]float32
+
+// V3 creates a Vec3
+func V3(x, y, z float32) Vec3 {
+	return Vec3{x, y, z}
+}
+
+// X returns the first component
+func (v Vec3) X() float32 { return v[0] }
+
+// Y returns the second component
+func (v Vec3) Y() float32 { return v[1] }
+
+// Z returns the third component
+func (v Vec3) Z() float32 { return v[2] }
+
+// Add returns v + o
+func (v Vec3) Add(o Vec3) Vec3 {
+	return Vec3{v[0] + o[0], v[1] + o[1], v[2] + o[2]}
+}
+
+// Sub returns v - o
+func (v Vec3) Sub(o Vec3) Vec3 {
+	return Vec3{v[0] - o[0], v[1] - o[1], v[2] - o[2]}
+}
+
+// Mul returns v scaled by s
+func (v Vec3) Mul(s float32) Vec3 {
+	return Vec3{v[0] * s, v[1] * s, v[2] * s}
+}
+
+// MulVec returns the component-wise product of v and o
+func (v Vec3) MulVec(o Vec3) Vec3 {
+	return Vec3{v[0] * o[0], v[1] * o[1], v[2] * o[2]}
+}
+
+// Neg returns -v
+func (v Vec3) Neg() Vec3 {
+	return Vec3{-v[0], -v[1], -v[2]}
+}
+
+// Dot returns the dot product of v and o
+func (v Vec3) Dot(o Vec3) float32 {
+	return v[0]*o[0] + v[1]*o[1] + v[2]*o[2]
+}
+
+// Cross returns the cross product v x o
+func (v Vec3) Cross(o Vec3) Vec3 {
+	return Vec3{
+		v[1]*o[2] - v[2]*o[1],
+		v[2]*o[0] - v[0]*o[2],
+		v[0]*o[1] - v[1]*o[0],
+	}
+}
+
+// LenSqr returns the squared length of v
+func (v Vec3) LenSqr() float32 {
+	return v.Dot(v)
+}
+
+// Len returns the length of v
+func (v Vec3) Len() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// Normalize returns v scaled to unit length, or the zero vector when v has
+// no length
+func (v Vec3) Normalize() Vec3 {
+	l := v.Len()
+	if l == 0 {
+		return Vec3{}
+	}
+	return v.Mul(1 / l)
+}
+
+// Min returns the component-wise minimum of v and o
+func (v Vec3) Min(o Vec3) Vec3 {
+	return Vec3{min(v[0], o[0]), min(v[1], o[1]), min(v[2], o[2])}
+}
+
+// Max returns the component-wise maximum of v and o
+func (v Vec3) Max(o Vec3) Vec3 {
+	return Vec3{max(v[0], o[0]), max(v[1], o[1]), max(v[2], o[2])}
+}
+
+// Lerp interpolates linearly between v (t = 0) and o (t = 1)
+func (v Vec3) Lerp(o Vec3, t float32) Vec3 {
+	return v.Add(o.Sub(v).Mul(t))
+}
+
+// Distance returns the distance between v and o
+func (v Vec3) Distance(o Vec3) float32 {
+	return v.Sub(o).Len()
+}
@@ -0,0 +1,56 @@
+package vecmath
+
+import "math"
+
+// Vec4 is a 4D vector, mostly used for homogeneous coordinates
+type Vec4 [4]float32
+
+// V4 creates a Vec4
+func V4(x, y, z, w float32) Vec4 {
+	return Vec4{x, y, z, w}
+}
+
+// Vec3 returns the first three components
+func (v Vec4) Vec3() Vec3 {
+	return Vec3{v[0], v[1], v[2]}
+}
+
+// Dot returns the dot product of v and o
+func (v Vec4) Dot(o Vec4) float32 {
+	return v[0]*o[0] + v[1]*o[1] + v[2]*o[2] + v[3]*o[3]
+}
+
+// Add returns v + o
+func (v Vec4) Add(o Vec4) Vec4 {
+	return Vec4{v[0] + o[0], v[1] + o[1], v[2] + o[2], v[3] + o[3]}
+}
+
+// Sub returns v - o
+func (v Vec4) Sub(o Vec4) Vec4 {
+	return Vec4{v[0] - o[0], v[1] - o[1], v[2] - o[2], v[3] - o[3]}
+}
+
+// Mul returns v scaled by s
+func (v Vec4) Mul(s float32) Vec4 {
+	return Vec4{v[0] * s, v[1] * s, v[2] * s, v[3] * s}
+}
+
+// Len returns the length of v
+func (v Vec4) Len() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// Normalize returns v scaled to unit length, or the zero vector when v has
+// no length
+func (v Vec4) Normalize() Vec4 {
+	l := v.Len()
+	if l == 0 {
+		return Vec4{}
+	}
+	return v.Mul(1 / l)
+}
+
+// Lerp interpolates linearly between v (t = 0) and o (t = 1)
+func (v Vec4) Lerp(o Vec4, t float32) Vec4 {
+	return v.Add(o.Sub(v).Mul(t))
+}
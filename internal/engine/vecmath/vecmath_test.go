@@ -0,0 +1,51 @@
+package vecmath
+
+import (
+	"math"
+	"testing"
+)
+
+// tolerance is the largest difference accepted between float32 results
+const tolerance = 1e-4
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) <= tolerance
+}
+
+func assertVec3(t *testing.T, name string, got, want Vec3) {
+	t.Helper()
+	for i := range got {
+		if !near(got[i], want[i]) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+	}
+}
+
+func assertMat4(t *testing.T, name string, got, want Mat4) {
+	t.Helper()
+	for i := range got {
+		if !near(got[i], want[i]) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+	}
+}
+
+func assertMat3(t *testing.T, name string, got, want Mat3) {
+	t.Helper()
+	for i := range got {
+		if !near(got[i], want[i]) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+	}
+}
+
+// assertQuat compares rotations, so q and -q are equal
+func assertQuat(t *testing.T, name string, got, want Quat) {
+	t.Helper()
+	if !near(abs(got.Dot(want)), 1) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
@@ -1,10 +1,96 @@
 package game
 
+// WindowMode selects how the game window is presented
+type WindowMode string
+
+const (
+	// WindowModeWindowed shows a regular decorated window
+	WindowModeWindowed WindowMode = "windowed"
+	// WindowModeFullscreen switches the display to the configured resolution
+	WindowModeFullscreen WindowMode = "fullscreen"
+	// WindowModeBorderless covers the display at the desktop resolution
+	WindowModeBorderless WindowMode = "borderless"
+)
+
+// ShadowQuality selects the resolution and softness of shadows
+type ShadowQuality string
+
+const (
+	// ShadowQualityOff disables shadows
+	ShadowQualityOff ShadowQuality = "off"
+	// ShadowQualityLow uses small hard-edged shadow maps
+	ShadowQualityLow ShadowQuality = "low"
+	// ShadowQualityMedium softens shadow edges and adds sun cascades
+	ShadowQualityMedium ShadowQuality = "medium"
+	// ShadowQualityHigh uses large maps with wide filtering up to a long
+	// distance
+	ShadowQualityHigh ShadowQuality = "high"
+)
+
+// PostEffects selects the post-processing effects applied to frames
+type PostEffects struct {
+	ToneMapping  bool
+	Bloom        bool
+	FXAA         bool
+	ColorGrading bool
+	Vignette     bool
+	// Damage flashes the screen when hit and at low health
+	Damage bool
+}
+
+// ModSetting is the state of an installed mod chosen by the player
+type ModSetting struct {
+	// ID is the id of the mod manifest
+	ID      string
+	Enabled bool
+}
+
 // Config contains game configuration settings
 type Config struct {
 	WindowTitle  string
 	WindowWidth  int32
 	WindowHeight int32
-	Fullscreen   bool
+	WindowMode   WindowMode
+	// Display is the index of the monitor the window is placed on
+	Display  int
+	VSync    bool
+	Language string
+	// ShadowQuality selects the shadow map resolution and filtering
+	ShadowQuality ShadowQuality
+	PostEffects   PostEffects
+	// Mods lists the installed mods in the load order chosen by the
+	// player; later mods override earlier ones
+	Mods []ModSetting
+	// TickRate is the number of fixed simulation updates per second
+	// (DefaultTickRate when zero)
+	TickRate int
+	// Headless runs the engine without a window or OpenGL context
+	Headless bool
+	// DummyVideo selects the SDL "dummy" video driver so that SDL video
+	// calls succeed without a display (headless only)
+	DummyVideo bool
+	// HotReload rebuilds shaders when their source files change (development)
+	HotReload bool
+	// MaxTicks stops the game loop after this many ticks (0 = no limit)
+	MaxTicks uint64
+	// SettingsPath is the file the config was loaded from and is saved to
+	// (empty when settings are not persisted)
+	SettingsPath string
+
+	// overrides are the settings given for this run only, and saved the
+	// persistent settings they replaced
+	overrides Overrides
+	saved     settingsFile
+	// readOnly stops Save from replacing a settings file that could not be
+	// read nor moved aside
+	readOnly bool
+}
+
+// Overrides are settings chosen for a single run, e.g. on the command
+// line. Zero fields keep the saved setting
+type Overrides struct {
+	WindowWidth  int32
+	WindowHeight int32
+	WindowMode   WindowMode
 	Language     string
 }
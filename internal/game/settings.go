@@ -0,0 +1,408 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// SettingsVersion is the current version of the settings file format
+const SettingsVersion = 5
+
+// errInvalidSettings reports a settings file that cannot be decoded
+var errInvalidSettings = errors.New("invalid settings file")
+
+// errNewerSettings reports a settings file written by a newer version of the
+// game
+var errNewerSettings = errors.New("settings file is newer than supported")
+
+// Window size limits accepted from the settings file
+const (
+	minWindowSize = 320
+	maxWindowSize = 16384
+)
+
+// settingsFile is the on-disk representation of the player settings
+type settingsFile struct {
+	Version       int             `json:"version"`
+	WindowWidth   int32           `json:"window_width"`
+	WindowHeight  int32           `json:"window_height"`
+	WindowMode    string          `json:"window_mode"`
+	Display       int             `json:"display"`
+	VSync         bool            `json:"vsync"`
+	Language      string          `json:"language"`
+	ShadowQuality string          `json:"shadow_quality"`
+	PostEffects   postEffectsFile `json:"post_effects"`
+	Mods          []modFile       `json:"mods"`
+}
+
+// postEffectsFile is the on-disk representation of PostEffects
+type postEffectsFile struct {
+	ToneMapping  bool `json:"tone_mapping"`
+	Bloom        bool `json:"bloom"`
+	FXAA         bool `json:"fxaa"`
+	ColorGrading bool `json:"color_grading"`
+	Vignette     bool `json:"vignette"`
+	Damage       bool `json:"damage"`
+}
+
+// modFile is the on-disk representation of a ModSetting
+type modFile struct {
+	ID      string `json:"id"`
+	Enabled bool   `json:"enabled"`
+}
+
+// migrations upgrade raw settings from the version used as key to the next
+// one. Every format change must bump SettingsVersion and add an entry here
+var migrations = map[int]func(raw map[string]interface{}){
+	// Files written before versioning was introduced share the version 1
+	// layout and only lack the version field
+	0: func(raw map[string]interface{}) {},
+	// Version 2 replaces the fullscreen flag with a window mode and adds
+	// display selection and vsync
+	1: func(raw map[string]interface{}) {
+		mode := WindowModeWindowed
+		if fullscreen, _ := raw["fullscreen"].(bool); fullscreen {
+			mode = WindowModeFullscreen
+		}
+		delete(raw, "fullscreen")
+		raw["window_mode"] = string(mode)
+		raw["display"] = 0
+		raw["vsync"] = true
+	},
+	// Version 3 adds the shadow quality
+	2: func(raw map[string]interface{}) {
+		raw["shadow_quality"] = string(ShadowQualityMedium)
+	},
+	// Version 4 adds the post-processing effects, all enabled
+	3: func(raw map[string]interface{}) {
+		raw["post_effects"] = map[string]interface{}{
+			"tone_mapping":  true,
+			"bloom":         true,
+			"fxaa":          true,
+			"color_grading": true,
+			"vignette":      true,
+			"damage":        true,
+		}
+	},
+	// Version 5 adds the mod load order, empty until mods are found
+	4: func(raw map[string]interface{}) {
+		raw["mods"] = []interface{}{}
+	},
+}
+
+// DefaultConfig returns the configuration used when no settings exist
+func DefaultConfig() Config {
+	return Config{
+		WindowTitle:   "Magic and Blades",
+		WindowWidth:   800,
+		WindowHeight:  600,
+		WindowMode:    WindowModeWindowed,
+		Display:       0,
+		VSync:         true,
+		Language:      "", // Auto-detect language
+		ShadowQuality: ShadowQualityMedium,
+		PostEffects: PostEffects{
+			ToneMapping:  true,
+			Bloom:        true,
+			FXAA:         true,
+			ColorGrading: true,
+			Vignette:     true,
+			Damage:       true,
+		},
+		TickRate: DefaultTickRate,
+	}
+}
+
+// SettingsPath returns the default location of the settings file inside
+// the per-user configuration directory ($XDG_CONFIG_HOME on Linux)
+func SettingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %v", err)
+	}
+	return filepath.Join(dir, "magic-and-blades", "settings.json"), nil
+}
+
+// LoadConfig reads the settings file at path on top of the defaults.
+// A missing file is not an error. Invalid values are replaced by defaults.
+// A file that cannot be decoded is moved to path.bak so that saving the
+// returned defaults does not destroy it. Defaults loaded in place of a file
+// written by a newer version, or of a file that cannot be read, are never
+// saved
+func LoadConfig(path string) (Config, error) {
+	config, err := loadConfig(path)
+	if err == nil {
+		return config, nil
+	}
+
+	config = DefaultConfig()
+	config.SettingsPath = path
+	if errors.Is(err, errNewerSettings) || !errors.Is(err, errInvalidSettings) {
+		config.readOnly = true
+		return config, fmt.Errorf("%v; settings will not be saved", err)
+	}
+	return config, backupSettings(&config, err)
+}
+
+// loadConfig reads the settings file at path on top of the defaults
+func loadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+	config.SettingsPath = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+
+	// Decode loosely first so older formats can be migrated
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return config, fmt.Errorf("%w %s: %v", errInvalidSettings, path, err)
+	}
+
+	if err := migrateSettings(raw); err != nil {
+		return config, fmt.Errorf("%w %s: %w", errInvalidSettings, path, err)
+	}
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return config, err
+	}
+
+	// Fields missing from the file keep their default values
+	settings := newSettingsFile(config)
+	if err := json.Unmarshal(migrated, &settings); err != nil {
+		// A field has the wrong type; keep what decoded and validate the rest
+		log.Printf("Warning: some settings could not be read: %v", err)
+	}
+
+	settings.applyTo(&config)
+	return config, nil
+}
+
+// backupSettings moves aside the settings file of config that failed to
+// load with err. When that fails, config is never saved
+func backupSettings(config *Config, err error) error {
+	backup := config.SettingsPath + ".bak"
+	if renameErr := os.Rename(config.SettingsPath, backup); renameErr != nil {
+		config.readOnly = true
+		return fmt.Errorf("%v; settings will not be saved: %v", err, renameErr)
+	}
+	return fmt.Errorf("%v; moved it to %s", err, backup)
+}
+
+// migrateSettings upgrades raw settings to SettingsVersion
+func migrateSettings(raw map[string]interface{}) error {
+	version := 0
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	}
+
+	if version > SettingsVersion {
+		return fmt.Errorf("%w: version %d, supported version %d", errNewerSettings, version, SettingsVersion)
+	}
+
+	for ; version < SettingsVersion; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return fmt.Errorf("no migration from settings version %d", version)
+		}
+		migrate(raw)
+		log.Printf("Migrated settings from version %d to %d", version, version+1)
+	}
+
+	raw["version"] = SettingsVersion
+	return nil
+}
+
+// newSettingsFile returns the persistent part of config
+func newSettingsFile(config Config) settingsFile {
+	return settingsFile{
+		Version:       SettingsVersion,
+		WindowWidth:   config.WindowWidth,
+		WindowHeight:  config.WindowHeight,
+		WindowMode:    string(config.WindowMode),
+		Display:       config.Display,
+		VSync:         config.VSync,
+		Language:      config.Language,
+		ShadowQuality: string(config.ShadowQuality),
+		PostEffects:   postEffectsFile(config.PostEffects),
+		Mods:          modFiles(config.Mods),
+	}
+}
+
+// modFiles converts mod settings to their on-disk representation
+func modFiles(mods []ModSetting) []modFile {
+	files := make([]modFile, len(mods))
+	for i, mod := range mods {
+		files[i] = modFile(mod)
+	}
+	return files
+}
+
+// applyTo copies valid settings into config, keeping defaults otherwise
+func (s settingsFile) applyTo(config *Config) {
+	if validWindowSize(s.WindowWidth) && validWindowSize(s.WindowHeight) {
+		config.WindowWidth = s.WindowWidth
+		config.WindowHeight = s.WindowHeight
+	} else {
+		log.Printf("Warning: ignoring invalid window size %dx%d", s.WindowWidth, s.WindowHeight)
+	}
+
+	if mode := WindowMode(s.WindowMode); validWindowMode(mode) {
+		config.WindowMode = mode
+	} else {
+		log.Printf("Warning: ignoring invalid window mode %q", s.WindowMode)
+	}
+
+	if s.Display >= 0 {
+		config.Display = s.Display
+	}
+
+	config.VSync = s.VSync
+
+	if validLanguage(s.Language) {
+		config.Language = s.Language
+	} else {
+		log.Printf("Warning: ignoring invalid language %q", s.Language)
+	}
+
+	switch quality := ShadowQuality(s.ShadowQuality); quality {
+	case ShadowQualityOff, ShadowQualityLow, ShadowQualityMedium, ShadowQualityHigh:
+		config.ShadowQuality = quality
+	default:
+		log.Printf("Warning: ignoring invalid shadow quality %q", s.ShadowQuality)
+	}
+
+	config.PostEffects = PostEffects(s.PostEffects)
+
+	config.Mods = nil
+	seen := make(map[string]bool)
+	for _, mod := range s.Mods {
+		if mod.ID == "" || seen[mod.ID] {
+			log.Printf("Warning: ignoring invalid or repeated mod %q", mod.ID)
+			continue
+		}
+		seen[mod.ID] = true
+		config.Mods = append(config.Mods, ModSetting(mod))
+	}
+}
+
+// Override applies settings for this run only. Values are checked like
+// those of the settings file, and nothing is applied when one is invalid.
+// Save keeps writing the saved value of an overridden setting until it is
+// changed again
+func (c *Config) Override(o Overrides) error {
+	if o.WindowWidth != 0 && !validWindowSize(o.WindowWidth) {
+		return fmt.Errorf("invalid window width %d: must be between %d and %d", o.WindowWidth, minWindowSize, maxWindowSize)
+	}
+	if o.WindowHeight != 0 && !validWindowSize(o.WindowHeight) {
+		return fmt.Errorf("invalid window height %d: must be between %d and %d", o.WindowHeight, minWindowSize, maxWindowSize)
+	}
+	if o.WindowMode != "" && !validWindowMode(o.WindowMode) {
+		return fmt.Errorf("invalid window mode %q", o.WindowMode)
+	}
+	if !validLanguage(o.Language) {
+		return fmt.Errorf("invalid language %q: must be a lowercase language code such as en", o.Language)
+	}
+
+	c.saved = c.persistentSettings()
+	c.overrides = o
+	if o.WindowWidth != 0 {
+		c.WindowWidth = o.WindowWidth
+	}
+	if o.WindowHeight != 0 {
+		c.WindowHeight = o.WindowHeight
+	}
+	if o.WindowMode != "" {
+		c.WindowMode = o.WindowMode
+	}
+	if o.Language != "" {
+		c.Language = o.Language
+	}
+	return nil
+}
+
+// persistentSettings returns the settings to save: those of the config,
+// except that settings still holding their override keep the saved value
+func (c *Config) persistentSettings() settingsFile {
+	settings := newSettingsFile(*c)
+	o := c.overrides
+	if o.WindowWidth != 0 && c.WindowWidth == o.WindowWidth {
+		settings.WindowWidth = c.saved.WindowWidth
+	}
+	if o.WindowHeight != 0 && c.WindowHeight == o.WindowHeight {
+		settings.WindowHeight = c.saved.WindowHeight
+	}
+	if o.WindowMode != "" && c.WindowMode == o.WindowMode {
+		settings.WindowMode = c.saved.WindowMode
+	}
+	if o.Language != "" && c.Language == o.Language {
+		settings.Language = c.saved.Language
+	}
+	return settings
+}
+
+// Save writes the persistent settings to config.SettingsPath. It does
+// nothing when the config was not loaded from a settings file, or when that
+// file could not be read nor backed up
+func (c *Config) Save() error {
+	if c.SettingsPath == "" || c.readOnly {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c.persistentSettings(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.SettingsPath), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated
+	// settings file behind
+	tmp := c.SettingsPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.SettingsPath)
+}
+
+// validWindowSize reports whether a window dimension is acceptable
+func validWindowSize(size int32) bool {
+	return size >= minWindowSize && size <= maxWindowSize
+}
+
+// validWindowMode reports whether a window mode is known
+func validWindowMode(mode WindowMode) bool {
+	switch mode {
+	case WindowModeWindowed, WindowModeFullscreen, WindowModeBorderless:
+		return true
+	}
+	return false
+}
+
+// validLanguage accepts an empty language (auto-detect) or a lowercase
+// language code such as "en" or "pt"
+func validLanguage(lang string) bool {
+	if lang == "" {
+		return true
+	}
+	if len(lang) < 2 || len(lang) > 3 {
+		return false
+	}
+	for _, r := range lang {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,332 @@
+package game
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeSettings writes a settings file with the given contents in a
+// temporary directory and returns its path
+func writeSettings(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+	return path
+}
+
+// readSettings decodes the settings file at path
+func readSettings(t *testing.T, path string) settingsFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read settings: %v", err)
+	}
+	var settings settingsFile
+	if err := json.Unmarshal(data, &settings); err != nil {
+		t.Fatalf("failed to decode settings: %v", err)
+	}
+	return settings
+}
+
+func TestMigrations(t *testing.T) {
+	tests := []struct {
+		name string
+		from int
+		raw  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{"0 unversioned", 0,
+			map[string]interface{}{"window_width": 1024.0},
+			map[string]interface{}{"window_width": 1024.0}},
+		{"1 fullscreen", 1,
+			map[string]interface{}{"fullscreen": true},
+			map[string]interface{}{"window_mode": "fullscreen", "display": 0, "vsync": true}},
+		{"1 windowed", 1,
+			map[string]interface{}{"fullscreen": false},
+			map[string]interface{}{"window_mode": "windowed", "display": 0, "vsync": true}},
+		{"1 no flag", 1,
+			map[string]interface{}{},
+			map[string]interface{}{"window_mode": "windowed", "display": 0, "vsync": true}},
+		{"2 shadows", 2,
+			map[string]interface{}{},
+			map[string]interface{}{"shadow_quality": "medium"}},
+		{"3 post effects", 3,
+			map[string]interface{}{},
+			map[string]interface{}{"post_effects": map[string]interface{}{
+				"tone_mapping":  true,
+				"bloom":         true,
+				"fxaa":          true,
+				"color_grading": true,
+				"vignette":      true,
+				"damage":        true,
+			}}},
+		{"4 mods", 4,
+			map[string]interface{}{},
+			map[string]interface{}{"mods": []interface{}{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrate, ok := migrations[tt.from]
+			if !ok {
+				t.Fatalf("no migration from version %d", tt.from)
+			}
+			migrate(tt.raw)
+			if !reflect.DeepEqual(tt.raw, tt.want) {
+				t.Errorf("migrated = %v, want %v", tt.raw, tt.want)
+			}
+		})
+	}
+
+	for version := 0; version < SettingsVersion; version++ {
+		if _, ok := migrations[version]; !ok {
+			t.Errorf("no migration from version %d", version)
+		}
+	}
+}
+
+func TestLoadConfigMigratesOldFiles(t *testing.T) {
+	path := writeSettings(t, `{"window_width": 1024, "window_height": 768, "fullscreen": true, "language": "pt"}`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	want := DefaultConfig()
+	want.SettingsPath = path
+	want.WindowWidth = 1024
+	want.WindowHeight = 768
+	want.WindowMode = WindowModeFullscreen
+	want.Language = "pt"
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("config = %+v, want %+v", config, want)
+	}
+}
+
+func TestLoadConfigFallsBackToDefaults(t *testing.T) {
+	defaults := DefaultConfig()
+	tests := []struct {
+		name     string
+		contents string
+		check    func(t *testing.T, config Config)
+	}{
+		{"missing fields", `{"version": 5, "window_width": 1280, "window_height": 720}`, func(t *testing.T, c Config) {
+			if c.WindowWidth != 1280 || c.WindowHeight != 720 {
+				t.Errorf("window size = %dx%d, want 1280x720", c.WindowWidth, c.WindowHeight)
+			}
+			if c.ShadowQuality != defaults.ShadowQuality || c.PostEffects != defaults.PostEffects {
+				t.Errorf("missing settings did not keep their defaults")
+			}
+		}},
+		{"unknown fields", `{"version": 5, "language": "pt", "difficulty": "hard", "extra": {"a": 1}}`, func(t *testing.T, c Config) {
+			if c.Language != "pt" {
+				t.Errorf("language = %q, want pt", c.Language)
+			}
+		}},
+		{"window too small", `{"version": 5, "window_width": 100, "window_height": 720}`, func(t *testing.T, c Config) {
+			if c.WindowWidth != defaults.WindowWidth || c.WindowHeight != defaults.WindowHeight {
+				t.Errorf("window size = %dx%d, want the default", c.WindowWidth, c.WindowHeight)
+			}
+		}},
+		{"window too large", `{"version": 5, "window_width": 1280, "window_height": 100000}`, func(t *testing.T, c Config) {
+			if c.WindowWidth != defaults.WindowWidth || c.WindowHeight != defaults.WindowHeight {
+				t.Errorf("window size = %dx%d, want the default", c.WindowWidth, c.WindowHeight)
+			}
+		}},
+		{"wrong type", `{"version": 5, "window_width": "wide", "language": "pt"}`, func(t *testing.T, c Config) {
+			if c.WindowWidth != defaults.WindowWidth {
+				t.Errorf("window width = %d, want the default", c.WindowWidth)
+			}
+			if c.Language != "pt" {
+				t.Errorf("language = %q, want pt", c.Language)
+			}
+		}},
+		{"window mode", `{"version": 5, "window_mode": "maximized"}`, func(t *testing.T, c Config) {
+			if c.WindowMode != defaults.WindowMode {
+				t.Errorf("window mode = %q, want the default", c.WindowMode)
+			}
+		}},
+		{"display", `{"version": 5, "display": -2}`, func(t *testing.T, c Config) {
+			if c.Display != defaults.Display {
+				t.Errorf("display = %d, want the default", c.Display)
+			}
+		}},
+		{"language", `{"version": 5, "language": "Portuguese"}`, func(t *testing.T, c Config) {
+			if c.Language != defaults.Language {
+				t.Errorf("language = %q, want the default", c.Language)
+			}
+		}},
+		{"shadow quality", `{"version": 5, "shadow_quality": "ultra"}`, func(t *testing.T, c Config) {
+			if c.ShadowQuality != defaults.ShadowQuality {
+				t.Errorf("shadow quality = %q, want the default", c.ShadowQuality)
+			}
+		}},
+		{"mods", `{"version": 5, "mods": [{"id": "a", "enabled": true}, {"id": ""}, {"id": "a"}, {"id": "b"}]}`, func(t *testing.T, c Config) {
+			want := []ModSetting{{ID: "a", Enabled: true}, {ID: "b"}}
+			if !reflect.DeepEqual(c.Mods, want) {
+				t.Errorf("mods = %v, want %v", c.Mods, want)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSettings(t, tt.contents)
+			config, err := LoadConfig(path)
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			tt.check(t, config)
+		})
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if got := readSettings(t, path); got.Version != SettingsVersion {
+		t.Errorf("saved version = %d, want %d", got.Version, SettingsVersion)
+	}
+}
+
+func TestLoadConfigRejectsNewerVersion(t *testing.T) {
+	contents := `{"version": 99, "window_width": 1280, "window_height": 720}`
+	path := writeSettings(t, contents)
+
+	config, err := LoadConfig(path)
+	if err == nil {
+		t.Fatalf("LoadConfig accepted a newer settings version")
+	}
+	if config.WindowWidth != DefaultConfig().WindowWidth {
+		t.Errorf("window width = %d, want the default", config.WindowWidth)
+	}
+
+	// The newer file is kept for the version that wrote it
+	config.VSync = false
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read settings: %v", err)
+	}
+	if string(data) != contents {
+		t.Errorf("newer settings file was overwritten with %s", data)
+	}
+}
+
+func TestLoadConfigBacksUpInvalidFile(t *testing.T) {
+	contents := `{"version": 5, "window_width": 1280`
+	path := writeSettings(t, contents)
+
+	config, err := LoadConfig(path)
+	if err == nil {
+		t.Fatalf("LoadConfig accepted an invalid settings file")
+	}
+
+	data, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatalf("invalid settings file was not backed up: %v", err)
+	}
+	if string(data) != contents {
+		t.Errorf("backup = %s, want %s", data, contents)
+	}
+
+	// Saving the defaults is allowed once the file is backed up
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if got := readSettings(t, path); got.WindowWidth != DefaultConfig().WindowWidth {
+		t.Errorf("saved window width = %d, want the default", got.WindowWidth)
+	}
+}
+
+func TestOverridesNotPersisted(t *testing.T) {
+	saved := `{"version": 5, "window_width": 1280, "window_height": 720, "window_mode": "windowed", "language": "pt"}`
+	overrides := Overrides{
+		WindowWidth:  1920,
+		WindowHeight: 1080,
+		WindowMode:   WindowModeFullscreen,
+		Language:     "en",
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   settingsFile
+	}{
+		{"unchanged", func(c *Config) {}, settingsFile{
+			WindowWidth: 1280, WindowHeight: 720, WindowMode: "windowed", Language: "pt",
+		}},
+		{"other setting changed", func(c *Config) { c.VSync = false }, settingsFile{
+			WindowWidth: 1280, WindowHeight: 720, WindowMode: "windowed", Language: "pt",
+		}},
+		{"overridden settings changed", func(c *Config) {
+			c.WindowWidth, c.WindowHeight = 1024, 768
+			c.WindowMode = WindowModeBorderless
+			c.Language = "es"
+		}, settingsFile{
+			WindowWidth: 1024, WindowHeight: 768, WindowMode: "borderless", Language: "es",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSettings(t, saved)
+			config, err := LoadConfig(path)
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if err := config.Override(overrides); err != nil {
+				t.Fatalf("Override failed: %v", err)
+			}
+			if config.WindowWidth != 1920 || config.Language != "en" {
+				t.Fatalf("overrides were not applied: %+v", config)
+			}
+
+			tt.change(&config)
+			if err := config.Save(); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			got := readSettings(t, path)
+			if got.WindowWidth != tt.want.WindowWidth || got.WindowHeight != tt.want.WindowHeight ||
+				got.WindowMode != tt.want.WindowMode || got.Language != tt.want.Language {
+				t.Errorf("saved %dx%d %s %q, want %dx%d %s %q",
+					got.WindowWidth, got.WindowHeight, got.WindowMode, got.Language,
+					tt.want.WindowWidth, tt.want.WindowHeight, tt.want.WindowMode, tt.want.Language)
+			}
+		})
+	}
+}
+
+func TestOverrideRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides Overrides
+	}{
+		{"width", Overrides{WindowWidth: 10}},
+		{"height", Overrides{WindowHeight: 100000}},
+		{"window mode", Overrides{WindowMode: "maximized"}},
+		{"language", Overrides{Language: "EN"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			if err := config.Override(tt.overrides); err == nil {
+				t.Errorf("Override accepted %+v", tt.overrides)
+			}
+			if !reflect.DeepEqual(config, DefaultConfig()) {
+				t.Errorf("rejected override changed the config")
+			}
+		})
+	}
+}
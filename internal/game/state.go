@@ -2,15 +2,15 @@ package game
 
 // GameState represents the overall game state
 type GameState struct {
-	Running      bool
-	CurrentScene string
+	Running bool
+	// Time is updated by the engine loop once per simulation tick
+	Time Time
 	// Add other game state variables as needed
 }
 
 // NewState creates a new game state
 func NewState() *GameState {
 	return &GameState{
-		Running:      true,
-		CurrentScene: "menu",
+		Running: true,
 	}
 }
@@ -0,0 +1,37 @@
+package game
+
+import "time"
+
+// DefaultTickRate is the number of simulation ticks per second used when
+// Config.TickRate is not set
+const DefaultTickRate = 60
+
+// Time holds timing information for the current simulation tick
+type Time struct {
+	// Tick is the number of fixed simulation steps executed so far
+	Tick uint64
+	// DT is the fixed duration of a single simulation step
+	DT time.Duration
+	// Elapsed is the total simulated time (Tick * DT)
+	Elapsed time.Duration
+	// FPS is the number of frames actually rendered during the last second
+	FPS float64
+}
+
+// DeltaSeconds returns the fixed step duration in seconds
+func (t Time) DeltaSeconds() float64 {
+	return t.DT.Seconds()
+}
+
+// ElapsedSeconds returns the total simulated time in seconds
+func (t Time) ElapsedSeconds() float64 {
+	return t.Elapsed.Seconds()
+}
+
+// TickDuration returns the duration of a single tick for the given rate
+func TickDuration(tickRate int) time.Duration {
+	if tickRate <= 0 {
+		tickRate = DefaultTickRate
+	}
+	return time.Second / time.Duration(tickRate)
+}
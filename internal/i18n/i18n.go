@@ -2,10 +2,11 @@ package i18n
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 
@@ -26,13 +27,18 @@ type i18n struct {
 	mu           sync.RWMutex
 	translations map[string]map[string]string
 	currentLang  string
+	// layers hold the <lang>.json translation files, from the lowest
+	// priority to the highest
+	layers []fs.FS
 }
 
-// New creates a new instance of the internationalization system
+// New creates a new instance of the internationalization system loading
+// translations from layers; keys of later layers override earlier ones
 // It automatically detects the system language and falls back to English
-func New() (Translator, error) {
+func New(layers ...fs.FS) (Translator, error) {
 	i := &i18n{
 		translations: make(map[string]map[string]string),
+		layers:       layers,
 	}
 
 	// Detect system language
@@ -54,10 +60,12 @@ func New() (Translator, error) {
 	return i, nil
 }
 
-// NewWithLanguage creates a new instance with a specific language
-func NewWithLanguage(lang string) (Translator, error) {
+// NewWithLanguage creates a new instance with a specific language, loading
+// translations from layers
+func NewWithLanguage(lang string, layers ...fs.FS) (Translator, error) {
 	i := &i18n{
 		translations: make(map[string]map[string]string),
+		layers:       layers,
 	}
 
 	if err := i.loadLanguage(lang); err != nil {
@@ -158,51 +166,79 @@ func detectSystemLanguage() string {
 	return "en"
 }
 
-// loadLanguage loads translations from a JSON file
+// loadLanguage loads translations and stores them. The caller must not hold
+// the lock
 func (i *i18n) loadLanguage(lang string) error {
+	translations, err := i.readLanguage(lang)
+	if err != nil {
+		return err
+	}
+
 	i.mu.Lock()
 	defer i.mu.Unlock()
+	i.translations[lang] = translations
+	return nil
+}
 
+// readLanguage reads translations from the JSON file of every layer,
+// merging their keys. Layers never change, so no lock is needed
+func (i *i18n) readLanguage(lang string) (map[string]string, error) {
 	// Path to the translation file
-	path := filepath.Join("assets", "i18n", lang+".json")
+	path := lang + ".json"
 
-	// Check if file exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return fmt.Errorf("language file not found: %s", path)
-	}
+	var translations map[string]string
+	for _, layer := range i.layers {
+		// Read the file
+		data, err := fs.ReadFile(layer, path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: failed to read %s: %v", path, err)
+			continue
+		}
 
-	// Read the file
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
+		// Decode JSON
+		var layerTranslations map[string]string
+		if err := json.Unmarshal(data, &layerTranslations); err != nil {
+			log.Printf("Warning: invalid language file %s: %v", path, err)
+			continue
+		}
 
-	// Decode JSON
-	var translations map[string]string
-	if err := json.Unmarshal(data, &translations); err != nil {
-		return err
+		if translations == nil {
+			translations = make(map[string]string, len(layerTranslations))
+		}
+		for key, text := range layerTranslations {
+			translations[key] = text
+		}
 	}
 
-	i.translations[lang] = translations
-	return nil
+	if translations == nil {
+		return nil, fmt.Errorf("language file not found: %s", path)
+	}
+	return translations, nil
 }
 
 // SetLanguage sets the current language
 func (i *i18n) SetLanguage(lang string) error {
-	i.mu.Lock()
-	defer i.mu.Unlock()
-
 	// If language is already loaded, just set as current
+	i.mu.Lock()
 	if _, exists := i.translations[lang]; exists {
 		i.currentLang = lang
+		i.mu.Unlock()
 		return nil
 	}
+	i.mu.Unlock()
 
-	// Try to load the new language
-	if err := i.loadLanguage(lang); err != nil {
+	// Load the new language without holding the lock
+	translations, err := i.readLanguage(lang)
+	if err != nil {
 		return err
 	}
 
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.translations[lang] = translations
 	i.currentLang = lang
 	return nil
 }
@@ -0,0 +1,91 @@
+package i18n
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// setLanguageTimeout bounds SetLanguage so that a deadlock fails the test
+const setLanguageTimeout = time.Second
+
+// setLanguage calls SetLanguage, failing the test if it does not return
+func setLanguage(t *testing.T, translator Translator, lang string) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- translator.SetLanguage(lang) }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(setLanguageTimeout):
+		t.Fatalf("SetLanguage(%q) did not return", lang)
+		return nil
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	files := fstest.MapFS{
+		"en.json": {Data: []byte(`{"title": "Title"}`)},
+		"pt.json": {Data: []byte(`{"title": "Título"}`)},
+	}
+	translator, err := NewWithLanguage("en", files)
+	if err != nil {
+		t.Fatalf("NewWithLanguage failed: %v", err)
+	}
+
+	tests := []struct {
+		lang    string
+		wantErr bool
+		want    string
+		title   string
+	}{
+		{"pt", false, "pt", "Título"}, // loaded on demand
+		{"en", false, "en", "Title"},  // already loaded
+		{"xx", true, "en", "Title"},   // missing, current language kept
+	}
+	for _, tt := range tests {
+		err := setLanguage(t, translator, tt.lang)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SetLanguage(%q) error = %v, want error %v", tt.lang, err, tt.wantErr)
+		}
+		if got := translator.GetLanguage(); got != tt.want {
+			t.Errorf("after SetLanguage(%q) language = %q, want %q", tt.lang, got, tt.want)
+		}
+		if got := translator.Translate("title"); got != tt.title {
+			t.Errorf("after SetLanguage(%q) title = %q, want %q", tt.lang, got, tt.title)
+		}
+	}
+}
+
+func TestLayersOverride(t *testing.T) {
+	base := fstest.MapFS{
+		"en.json": {Data: []byte(`{"title": "Title", "play": "Play"}`)},
+	}
+	mod := fstest.MapFS{
+		"en.json": {Data: []byte(`{"title": "Modded"}`)},
+		"pt.json": {Data: []byte(`{"title": "Título"}`)},
+	}
+	broken := fstest.MapFS{
+		"en.json": {Data: []byte(`{"title": `)},
+	}
+	translator, err := NewWithLanguage("en", base, mod, broken)
+	if err != nil {
+		t.Fatalf("NewWithLanguage failed: %v", err)
+	}
+
+	// Later layers win; invalid files are skipped
+	if got := translator.Translate("title"); got != "Modded" {
+		t.Errorf("title = %q, want Modded", got)
+	}
+	if got := translator.Translate("play"); got != "Play" {
+		t.Errorf("play = %q, want Play", got)
+	}
+
+	// Languages only some layers provide load too
+	if err := setLanguage(t, translator, "pt"); err != nil {
+		t.Fatalf("SetLanguage(pt) failed: %v", err)
+	}
+	if got := translator.Translate("title"); got != "Título" {
+		t.Errorf("title = %q, want Título", got)
+	}
+}
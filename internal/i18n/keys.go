@@ -3,14 +3,51 @@ package i18n
 // Translation keys for code reference
 const (
 	// User interface
-	TitleWelcome  = "title.welcome"
-	TitleMainMenu = "title.main_menu"
-	ButtonPlay    = "button.play"
-	ButtonOptions = "button.options"
-	ButtonQuit    = "button.quit"
-	LabelLoading  = "label.loading"
-	LabelScore    = "label.score"
-	LabelLevel    = "label.level"
+	TitleWelcome   = "title.welcome"
+	TitleMainMenu  = "title.main_menu"
+	ButtonPlay     = "button.play"
+	ButtonOptions  = "button.options"
+	ButtonQuit     = "button.quit"
+	ButtonResume   = "button.resume"
+	ButtonMainMenu = "button.main_menu"
+	ButtonMods     = "button.mods"
+	LabelLoading   = "label.loading"
+	LabelScore     = "label.score"
+	LabelLevel     = "label.level"
+
+	// Settings menu
+	SettingsLanguage   = "settings.language"
+	SettingsResolution = "settings.resolution"
+	SettingsWindowMode = "settings.window_mode"
+	SettingsDisplay    = "settings.display"
+	SettingsVSync      = "settings.vsync"
+	SettingsShadows    = "settings.shadows"
+	SettingsEffects    = "settings.effects"
+	SettingsBack       = "settings.back"
+
+	WindowModeWindowed   = "window_mode.windowed"
+	WindowModeFullscreen = "window_mode.fullscreen"
+	WindowModeBorderless = "window_mode.borderless"
+
+	ShadowQualityOff    = "shadow_quality.off"
+	ShadowQualityLow    = "shadow_quality.low"
+	ShadowQualityMedium = "shadow_quality.medium"
+	ShadowQualityHigh   = "shadow_quality.high"
+
+	EffectToneMapping  = "effect.tone_mapping"
+	EffectBloom        = "effect.bloom"
+	EffectFXAA         = "effect.fxaa"
+	EffectColorGrading = "effect.color_grading"
+	EffectVignette     = "effect.vignette"
+	EffectDamage       = "effect.damage"
+
+	OptionOn  = "option.on"
+	OptionOff = "option.off"
+
+	// Mods menu
+	ModsHint    = "mods.hint"
+	ModsRestart = "mods.restart"
+	ModsNone    = "mods.none"
 
 	// Game messages
 	MessageGameStart = "message.game_start"
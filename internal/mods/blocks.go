@@ -0,0 +1,84 @@
+package mods
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/objects"
+)
+
+// BlocksFile lists the block types a mod adds, at the root of the mod
+const BlocksFile = "blocks.json"
+
+// blockDefinition is a block type of a BlocksFile
+type blockDefinition struct {
+	Name string `json:"name"`
+	// Solid defaults to true
+	Solid       *bool `json:"solid"`
+	Transparent bool  `json:"transparent"`
+	Light       uint8 `json:"light"`
+	// Texture is used on every face; it defaults to the block name
+	Texture string `json:"texture"`
+	// Textures overrides the texture of some faces: "top", "bottom" and
+	// "side", or a single face by name
+	Textures map[string]string `json:"textures"`
+}
+
+// faceNames maps the keys of blockDefinition.Textures to the faces they
+// cover
+var faceNames = map[string][]objects.Face{
+	"top":    {objects.FaceUp},
+	"bottom": {objects.FaceDown},
+	"side":   {objects.FaceEast, objects.FaceWest, objects.FaceSouth, objects.FaceNorth},
+	"east":   {objects.FaceEast},
+	"west":   {objects.FaceWest},
+	"south":  {objects.FaceSouth},
+	"north":  {objects.FaceNorth},
+}
+
+// parseBlocks decodes the block types of a BlocksFile. Textures of a mod
+// are read from textures/blocks/<texture>.png like those of the base game
+func parseBlocks(data []byte) ([]objects.Block, error) {
+	var definitions []blockDefinition
+	if err := json.Unmarshal(data, &definitions); err != nil {
+		return nil, err
+	}
+
+	blocks := make([]objects.Block, 0, len(definitions))
+	for _, def := range definitions {
+		if def.Name == "" {
+			return nil, fmt.Errorf("block has no name")
+		}
+		if def.Light > objects.MaxLight {
+			return nil, fmt.Errorf("block %q: light %d exceeds %d", def.Name, def.Light, objects.MaxLight)
+		}
+
+		texture := def.Texture
+		if texture == "" {
+			texture = def.Name
+		}
+		b := objects.Block{
+			Name:        def.Name,
+			Solid:       def.Solid == nil || *def.Solid,
+			Transparent: def.Transparent,
+			Textures:    objects.AllFaces(texture),
+			Light:       def.Light,
+		}
+
+		// Groups first so that single faces win over them
+		for _, key := range []string{"side", "top", "bottom", "east", "west", "south", "north"} {
+			if name, ok := def.Textures[key]; ok {
+				for _, face := range faceNames[key] {
+					b.Textures[face] = name
+				}
+			}
+		}
+		for key := range def.Textures {
+			if _, ok := faceNames[key]; !ok {
+				return nil, fmt.Errorf("block %q: unknown face %q", def.Name, key)
+			}
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, nil
+}
@@ -0,0 +1,100 @@
+package mods
+
+import (
+	"testing"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/objects"
+)
+
+func TestParseBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []objects.Block
+	}{
+		{
+			name: "defaults",
+			data: `[{"name": "marble"}]`,
+			want: []objects.Block{{Name: "marble", Solid: true, Textures: objects.AllFaces("marble")}},
+		},
+		{
+			name: "properties",
+			data: `[{"name": "glass_lamp", "solid": false, "transparent": true, "light": 12, "texture": "lamp"}]`,
+			want: []objects.Block{{Name: "glass_lamp", Transparent: true, Light: 12, Textures: objects.AllFaces("lamp")}},
+		},
+		{
+			name: "face groups",
+			data: `[{"name": "log", "textures": {"top": "log_top", "bottom": "log_top", "side": "bark"}}]`,
+			want: []objects.Block{{Name: "log", Solid: true, Textures: [6]string{
+				objects.FaceEast:  "bark",
+				objects.FaceWest:  "bark",
+				objects.FaceUp:    "log_top",
+				objects.FaceDown:  "log_top",
+				objects.FaceSouth: "bark",
+				objects.FaceNorth: "bark",
+			}}},
+		},
+		{
+			name: "single face wins over its group",
+			data: `[{"name": "furnace", "texture": "stone", "textures": {"north": "furnace_front", "side": "furnace_side"}}]`,
+			want: []objects.Block{{Name: "furnace", Solid: true, Textures: [6]string{
+				objects.FaceEast:  "furnace_side",
+				objects.FaceWest:  "furnace_side",
+				objects.FaceUp:    "stone",
+				objects.FaceDown:  "stone",
+				objects.FaceSouth: "furnace_side",
+				objects.FaceNorth: "furnace_front",
+			}}},
+		},
+		{
+			name: "several",
+			data: `[{"name": "a"}, {"name": "b", "solid": true}]`,
+			want: []objects.Block{
+				{Name: "a", Solid: true, Textures: objects.AllFaces("a")},
+				{Name: "b", Solid: true, Textures: objects.AllFaces("b")},
+			},
+		},
+		{
+			name: "empty",
+			data: `[]`,
+			want: []objects.Block{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, err := parseBlocks([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseBlocks failed: %v", err)
+			}
+			if len(blocks) != len(tt.want) {
+				t.Fatalf("got %d blocks, want %d", len(blocks), len(tt.want))
+			}
+			for i := range blocks {
+				if blocks[i] != tt.want[i] {
+					t.Errorf("block %d = %+v, want %+v", i, blocks[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseBlocksErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"invalid json", `[{"name": "a"`},
+		{"not a list", `{"name": "a"}`},
+		{"no name", `[{"texture": "a"}]`},
+		{"too bright", `[{"name": "sun", "light": 200}]`},
+		{"unknown face", `[{"name": "a", "textures": {"front": "b"}}]`},
+		{"wrong type", `[{"name": "a", "solid": "yes"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseBlocks([]byte(tt.data)); err == nil {
+				t.Errorf("parseBlocks accepted %s", tt.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package mods
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/assets/pack"
+)
+
+// ManifestFile describes a mod. It sits at the root of the mod directory
+// or archive
+const ManifestFile = "mod.json"
+
+// Manifest is the description of a mod read from its ManifestFile
+type Manifest struct {
+	// ID identifies the mod in dependencies and settings: lowercase
+	// letters, digits, dashes and underscores
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	// Dependencies must be installed and enabled for the mod to load, and
+	// load before it
+	Dependencies []Dependency `json:"dependencies"`
+	// LoadAfter and LoadBefore order the mod relative to other mods when
+	// those are enabled, without requiring them
+	LoadAfter  []string `json:"load_after"`
+	LoadBefore []string `json:"load_before"`
+}
+
+// Dependency is a mod required by another
+type Dependency struct {
+	ID string `json:"id"`
+	// Version is the lowest accepted version; any version when empty
+	Version string `json:"version"`
+}
+
+// Mod is an installed mod
+type Mod struct {
+	Manifest
+	// Path is the mod directory or archive
+	Path string
+	// Files holds the assets of the mod, laid out like the base assets
+	Files fs.FS
+}
+
+// Close closes the archive of a mod installed as a pack. Its files cannot
+// be read afterwards
+func (m *Mod) Close() error {
+	if closer, ok := m.Files.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Title returns the name of the mod with its version
+func (m *Mod) Title() string {
+	name := m.Name
+	if name == "" {
+		name = m.ID
+	}
+	if m.Version != "" {
+		name += " " + m.Version
+	}
+	return name
+}
+
+// idPattern matches valid mod IDs
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// DefaultDir returns the directory mods are installed in, next to the
+// settings file at settingsPath (empty when settings are not persisted)
+func DefaultDir(settingsPath string) string {
+	if settingsPath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(settingsPath), "mods")
+}
+
+// Discover reads the manifest of every mod in dir, each a subdirectory or
+// a pack archive, sorted by ID. Mods that cannot be read are reported
+// instead. A missing directory holds no mods
+func Discover(dir string) ([]*Mod, []Problem) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []Problem{{Message: fmt.Sprintf("failed to list mods in %s: %v", dir, err)}}
+	}
+
+	var found []*Mod
+	var problems []Problem
+	byID := make(map[string]*Mod)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() && !strings.EqualFold(filepath.Ext(entry.Name()), pack.Extension) {
+			continue
+		}
+
+		mod, err := open(path, entry.IsDir())
+		if err != nil {
+			problems = append(problems, Problem{Mod: entry.Name(), Message: err.Error()})
+			continue
+		}
+		if other, ok := byID[mod.ID]; ok {
+			problems = append(problems, Problem{Mod: mod.ID, Message: fmt.Sprintf("installed twice, in %s and %s; ignoring the second", other.Path, mod.Path)})
+			mod.Close()
+			continue
+		}
+		byID[mod.ID] = mod
+		found = append(found, mod)
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found, problems
+}
+
+// open reads the manifest of the mod at path
+func open(path string, dir bool) (*Mod, error) {
+	if dir {
+		return readManifest(path, os.DirFS(path))
+	}
+
+	archive, err := pack.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	mod, err := readManifest(path, archive)
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+	return mod, nil
+}
+
+// readManifest reads the manifest of the mod at path from its files
+func readManifest(path string, files fs.FS) (*Mod, error) {
+	data, err := fs.ReadFile(files, ManifestFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if !idPattern.MatchString(manifest.ID) {
+		return nil, fmt.Errorf("invalid mod id %q", manifest.ID)
+	}
+	for _, dep := range manifest.Dependencies {
+		if !idPattern.MatchString(dep.ID) {
+			return nil, fmt.Errorf("invalid dependency id %q", dep.ID)
+		}
+	}
+	return &Mod{Manifest: manifest, Path: path, Files: files}, nil
+}
+
+// compareVersions compares dotted versions such as "1.10.2" part by part,
+// numerically, returning -1, 0 or 1. Missing parts count as zero and a
+// suffix such as "-beta" is ignored
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the numeric parts of a version
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
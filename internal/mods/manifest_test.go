@@ -0,0 +1,32 @@
+package mods
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.0.0", 0},
+		{"", "0", 0},
+		{"1.2", "1.10", -1},
+		{"1.10.2", "1.9.9", 1},
+		{"2", "1.99", 1},
+		{"v1.2.0", "1.2", 0},
+		{"1.2.0-beta", "1.2", 0},
+		{"1.2+build.5", "1.2.1", -1},
+		{"1.x", "1", 0},
+		{"0.9", "1", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := compareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := compareVersions(tt.b, tt.a); got != -tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
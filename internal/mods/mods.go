@@ -0,0 +1,244 @@
+package mods
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/assets"
+	"github.com/luidsonl/magic-and-blades/internal/engine/objects"
+	"github.com/luidsonl/magic-and-blades/internal/game"
+)
+
+// baseGame names the base game as the owner of its blocks in problems
+const baseGame = "base game"
+
+// Set is the result of loading the installed mods: the enabled ones in
+// load order, their assets layered over the base assets and the problems
+// found. The methods of a nil Set describe the base game alone
+type Set struct {
+	dir       string
+	installed []*Mod
+	loaded    []*Mod
+	problems  []Problem
+	files     *assets.Overlay
+	blocks    []objects.Block
+}
+
+// Load discovers the mods installed in dir and layers the files of the
+// enabled ones over base, following the order and states of settings
+func Load(dir string, base fs.FS, settings []game.ModSetting) *Set {
+	s := &Set{dir: dir}
+	var problems []Problem
+	s.installed, problems = Discover(dir)
+	s.problems = append(s.problems, problems...)
+
+	s.loaded, problems = Resolve(s.installed, settings)
+	s.problems = append(s.problems, problems...)
+
+	layers := []fs.FS{base}
+	for _, mod := range s.loaded {
+		layers = append(layers, mod.Files)
+	}
+	s.files = assets.NewOverlay(layers...)
+
+	s.problems = append(s.problems, s.fileConflicts()...)
+	s.problems = append(s.problems, s.loadBlocks()...)
+
+	for _, p := range s.problems {
+		log.Printf("Warning: mod problem: %s", p)
+	}
+	if len(s.loaded) > 0 {
+		titles := make([]string, len(s.loaded))
+		for i, mod := range s.loaded {
+			titles[i] = mod.Title()
+		}
+		log.Printf("Loaded mods: %s", strings.Join(titles, ", "))
+	}
+	return s
+}
+
+// mergedFile reports whether the file of a mod is merged with the same
+// file of other layers instead of overriding it
+func mergedFile(name string) bool {
+	return name == ManifestFile || name == BlocksFile || path.Dir(name) == "i18n"
+}
+
+// fileConflicts reports the files provided by several mods. The last mod
+// in load order wins
+func (s *Set) fileConflicts() []Problem {
+	providers := make(map[string][]string)
+	for _, mod := range s.loaded {
+		fs.WalkDir(mod.Files, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || mergedFile(name) {
+				return nil
+			}
+			providers[name] = append(providers[name], mod.ID)
+			return nil
+		})
+	}
+
+	var names []string
+	for name, ids := range providers {
+		if len(ids) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	problems := make([]Problem, 0, len(names))
+	for _, name := range names {
+		ids := providers[name]
+		winner := ids[len(ids)-1]
+		problems = append(problems, Problem{
+			Mod:     winner,
+			Message: fmt.Sprintf("overrides %s, also provided by %s", name, strings.Join(ids[:len(ids)-1], ", ")),
+		})
+	}
+	return problems
+}
+
+// loadBlocks reads the block types of the loaded mods. A block named like
+// one of the base game or of an earlier mod is skipped
+func (s *Set) loadBlocks() []Problem {
+	owners := map[string]string{"air": baseGame}
+	for _, b := range objects.DefaultBlocks {
+		owners[b.Name] = baseGame
+	}
+
+	var problems []Problem
+	for _, mod := range s.loaded {
+		data, err := fs.ReadFile(mod.Files, BlocksFile)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			var blocks []objects.Block
+			if blocks, err = parseBlocks(data); err == nil {
+				for _, b := range blocks {
+					if owner, ok := owners[b.Name]; ok {
+						problems = append(problems, Problem{Mod: mod.ID, Message: fmt.Sprintf("block %q is already defined by %s; skipped", b.Name, owner)})
+						continue
+					}
+					owners[b.Name] = mod.ID
+					s.blocks = append(s.blocks, b)
+				}
+				continue
+			}
+		}
+		problems = append(problems, Problem{Mod: mod.ID, Message: fmt.Sprintf("invalid %s: %v", BlocksFile, err)})
+	}
+	return problems
+}
+
+// Dir returns the directory mods are installed in
+func (s *Set) Dir() string {
+	if s == nil {
+		return ""
+	}
+	return s.dir
+}
+
+// Installed returns every installed mod, enabled or not, by ID
+func (s *Set) Installed() []*Mod {
+	if s == nil {
+		return nil
+	}
+	return s.installed
+}
+
+// Loaded returns the enabled mods in load order, from the lowest priority
+// to the highest
+func (s *Set) Loaded() []*Mod {
+	if s == nil {
+		return nil
+	}
+	return s.loaded
+}
+
+// Mod returns the installed mod with an ID, or nil
+func (s *Set) Mod(id string) *Mod {
+	for _, mod := range s.Installed() {
+		if mod.ID == id {
+			return mod
+		}
+	}
+	return nil
+}
+
+// Pending reports whether settings would load other mods, or load them in
+// another order, than the mods loaded now. Changes apply on restart
+func (s *Set) Pending(settings []game.ModSetting) bool {
+	loaded, _ := Resolve(s.Installed(), settings)
+	current := s.Loaded()
+	if len(loaded) != len(current) {
+		return true
+	}
+	for i := range loaded {
+		if loaded[i] != current[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Problems returns the problems found while loading
+func (s *Set) Problems() []Problem {
+	if s == nil {
+		return nil
+	}
+	return s.problems
+}
+
+// Files returns the base assets with the files of the loaded mods layered
+// over them, or nil for a nil Set
+func (s *Set) Files() fs.FS {
+	if s == nil {
+		return nil
+	}
+	return s.files
+}
+
+// Layers returns the base assets followed by the files of every loaded mod,
+// for files that are merged rather than overridden, such as translations
+func (s *Set) Layers() []fs.FS {
+	if s == nil {
+		return nil
+	}
+	return s.files.Layers()
+}
+
+// Blocks returns the block types added by the loaded mods, in load order
+func (s *Set) Blocks() []objects.Block {
+	if s == nil {
+		return nil
+	}
+	return s.blocks
+}
+
+// Close closes the archives of the installed mods. The files of the set
+// cannot be read afterwards
+func (s *Set) Close() error {
+	var first error
+	for _, mod := range s.Installed() {
+		if err := mod.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// RegisterBlocks registers the blocks added by mods in a registry that
+// already holds the default blocks
+func (s *Set) RegisterBlocks(registry *objects.BlockRegistry) error {
+	for _, b := range s.Blocks() {
+		if _, err := registry.Register(b); err != nil {
+			return fmt.Errorf("failed to register mod block %q: %v", b.Name, err)
+		}
+	}
+	return nil
+}
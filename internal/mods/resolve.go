@@ -0,0 +1,155 @@
+package mods
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/luidsonl/magic-and-blades/internal/game"
+)
+
+// Problem is an issue found while loading mods, such as a missing
+// dependency or two mods overriding the same file
+type Problem struct {
+	// Mod is the ID of the mod concerned, or the file name of a mod whose
+	// manifest could not be read (empty for general problems)
+	Mod     string
+	Message string
+}
+
+// String returns the problem as a single line
+func (p Problem) String() string {
+	if p.Mod == "" {
+		return p.Message
+	}
+	return p.Mod + ": " + p.Message
+}
+
+// Sync returns settings listing every installed mod: settings in their
+// order, then newly installed mods, enabled, by ID. Settings of mods that
+// are no longer installed are kept so that their state survives a
+// reinstall
+func Sync(settings []game.ModSetting, installed []*Mod) []game.ModSetting {
+	synced := append([]game.ModSetting(nil), settings...)
+	known := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		known[s.ID] = true
+	}
+	for _, mod := range installed {
+		if !known[mod.ID] {
+			synced = append(synced, game.ModSetting{ID: mod.ID, Enabled: true})
+		}
+	}
+	return synced
+}
+
+// Resolve returns the enabled mods of installed in load order, from the
+// lowest priority to the highest. Dependencies load before the mods
+// requiring them and load_after and load_before hints are followed; the
+// player order of settings decides everything else. Enabled mods whose
+// dependencies are missing, disabled or too old are left out and reported
+func Resolve(installed []*Mod, settings []game.ModSetting) ([]*Mod, []Problem) {
+	byID := make(map[string]*Mod, len(installed))
+	for _, mod := range installed {
+		byID[mod.ID] = mod
+	}
+
+	// Player order of the installed mods
+	var order []*Mod
+	enabled := make(map[string]bool)
+	for _, s := range Sync(settings, installed) {
+		if mod, ok := byID[s.ID]; ok {
+			order = append(order, mod)
+			enabled[s.ID] = s.Enabled
+		}
+	}
+
+	// Disabling a mod can break the mods depending on it, so check until
+	// nothing changes
+	var problems []Problem
+	for changed := true; changed; {
+		changed = false
+		for _, mod := range order {
+			if !enabled[mod.ID] {
+				continue
+			}
+			if reason := missingDependency(mod, byID, enabled); reason != "" {
+				problems = append(problems, Problem{Mod: mod.ID, Message: reason + "; not loaded"})
+				enabled[mod.ID] = false
+				changed = true
+			}
+		}
+	}
+
+	// Edges point from a mod to the mods that must load after it
+	after := make(map[string][]string)
+	pending := make(map[string]int)
+	addEdge := func(first, then string) {
+		if enabled[first] && enabled[then] && first != then {
+			after[first] = append(after[first], then)
+			pending[then]++
+		}
+	}
+	for _, mod := range order {
+		for _, dep := range mod.Dependencies {
+			addEdge(dep.ID, mod.ID)
+		}
+		for _, id := range mod.LoadAfter {
+			addEdge(id, mod.ID)
+		}
+		for _, id := range mod.LoadBefore {
+			addEdge(mod.ID, id)
+		}
+	}
+
+	// Topological sort always picking the ready mod the player ranked first
+	var loaded []*Mod
+	done := make(map[string]bool)
+	for {
+		var next *Mod
+		for _, mod := range order {
+			if enabled[mod.ID] && !done[mod.ID] && pending[mod.ID] == 0 {
+				next = mod
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		done[next.ID] = true
+		loaded = append(loaded, next)
+		for _, id := range after[next.ID] {
+			pending[id]--
+		}
+	}
+
+	// What is left waits on itself: load it in player order
+	var cycle []string
+	for _, mod := range order {
+		if enabled[mod.ID] && !done[mod.ID] {
+			cycle = append(cycle, mod.ID)
+			loaded = append(loaded, mod)
+		}
+	}
+	if len(cycle) > 0 {
+		problems = append(problems, Problem{Message: fmt.Sprintf("load order cycle between %s; using the player order", strings.Join(cycle, ", "))})
+	}
+
+	return loaded, problems
+}
+
+// missingDependency returns why a dependency of mod is not satisfied, or
+// an empty string
+func missingDependency(mod *Mod, installed map[string]*Mod, enabled map[string]bool) string {
+	for _, dep := range mod.Dependencies {
+		other, ok := installed[dep.ID]
+		switch {
+		case !ok:
+			return fmt.Sprintf("requires %s, which is not installed", dep.ID)
+		case !enabled[dep.ID]:
+			return fmt.Sprintf("requires %s, which is disabled or not loaded", dep.ID)
+		case dep.Version != "" && compareVersions(other.Version, dep.Version) < 0:
+			return fmt.Sprintf("requires %s %s or newer, found %s", dep.ID, dep.Version, other.Version)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package mods
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/luidsonl/magic-and-blades/internal/game"
+)
+
+// testMod returns an installed mod with the given manifest
+func testMod(manifest Manifest) *Mod {
+	return &Mod{Manifest: manifest}
+}
+
+// enabledMods returns settings enabling the given mods in that order
+func enabledMods(ids ...string) []game.ModSetting {
+	settings := make([]game.ModSetting, len(ids))
+	for i, id := range ids {
+		settings[i] = game.ModSetting{ID: id, Enabled: true}
+	}
+	return settings
+}
+
+// modIDs returns the IDs of mods in order
+func modIDs(mods []*Mod) []string {
+	ids := make([]string, 0, len(mods))
+	for _, mod := range mods {
+		ids = append(ids, mod.ID)
+	}
+	return ids
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []Manifest
+		settings  []game.ModSetting
+		want      []string
+		// problems lists the mod of every problem reported, "" for
+		// general ones
+		problems []string
+	}{
+		{
+			name:      "player order",
+			installed: []Manifest{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+			settings:  enabledMods("c", "a", "b"),
+			want:      []string{"c", "a", "b"},
+		},
+		{
+			name:      "new mods after known ones",
+			installed: []Manifest{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+			settings:  enabledMods("c"),
+			want:      []string{"c", "a", "b"},
+		},
+		{
+			name:      "uninstalled mods ignored",
+			installed: []Manifest{{ID: "a"}},
+			settings:  enabledMods("gone", "a"),
+			want:      []string{"a"},
+		},
+		{
+			name:      "disabled",
+			installed: []Manifest{{ID: "a"}, {ID: "b"}},
+			settings:  []game.ModSetting{{ID: "a"}, {ID: "b", Enabled: true}},
+			want:      []string{"b"},
+		},
+		{
+			name:      "dependency first",
+			installed: []Manifest{{ID: "a"}, {ID: "b", Dependencies: []Dependency{{ID: "a"}}}},
+			settings:  enabledMods("b", "a"),
+			want:      []string{"a", "b"},
+		},
+		{
+			name: "dependency chain",
+			installed: []Manifest{
+				{ID: "a", Dependencies: []Dependency{{ID: "b"}}},
+				{ID: "b", Dependencies: []Dependency{{ID: "c"}}},
+				{ID: "c"},
+			},
+			settings: enabledMods("a", "b", "c"),
+			want:     []string{"c", "b", "a"},
+		},
+		{
+			name:      "missing dependency",
+			installed: []Manifest{{ID: "a"}, {ID: "b", Dependencies: []Dependency{{ID: "x"}}}},
+			settings:  enabledMods("a", "b"),
+			want:      []string{"a"},
+			problems:  []string{"b"},
+		},
+		{
+			name: "disabled dependency",
+			installed: []Manifest{
+				{ID: "a"},
+				{ID: "b", Dependencies: []Dependency{{ID: "a"}}},
+				{ID: "c", Dependencies: []Dependency{{ID: "b"}}},
+				{ID: "d"},
+			},
+			settings: []game.ModSetting{{ID: "a"}, {ID: "b", Enabled: true}, {ID: "c", Enabled: true}, {ID: "d", Enabled: true}},
+			want:     []string{"d"},
+			problems: []string{"b", "c"},
+		},
+		{
+			name: "dependency too old",
+			installed: []Manifest{
+				{ID: "a", Version: "1.5"},
+				{ID: "b", Dependencies: []Dependency{{ID: "a", Version: "2.0"}}},
+			},
+			settings: enabledMods("a", "b"),
+			want:     []string{"a"},
+			problems: []string{"b"},
+		},
+		{
+			name: "dependency new enough",
+			installed: []Manifest{
+				{ID: "a", Version: "2.0.1"},
+				{ID: "b", Dependencies: []Dependency{{ID: "a", Version: "2.0"}}},
+			},
+			settings: enabledMods("b", "a"),
+			want:     []string{"a", "b"},
+		},
+		{
+			name:      "load after",
+			installed: []Manifest{{ID: "a", LoadAfter: []string{"b"}}, {ID: "b"}, {ID: "c"}},
+			settings:  enabledMods("a", "c", "b"),
+			want:      []string{"c", "b", "a"},
+		},
+		{
+			name:      "load before",
+			installed: []Manifest{{ID: "a"}, {ID: "b", LoadBefore: []string{"a"}}},
+			settings:  enabledMods("a", "b"),
+			want:      []string{"b", "a"},
+		},
+		{
+			name:      "hints on missing or disabled mods",
+			installed: []Manifest{{ID: "a", LoadAfter: []string{"x", "b"}}, {ID: "b"}},
+			settings:  []game.ModSetting{{ID: "a", Enabled: true}, {ID: "b"}},
+			want:      []string{"a"},
+		},
+		{
+			name: "cycle",
+			installed: []Manifest{
+				{ID: "a", LoadAfter: []string{"b"}},
+				{ID: "b", LoadAfter: []string{"a"}},
+				{ID: "c"},
+			},
+			settings: enabledMods("a", "b", "c"),
+			want:     []string{"c", "a", "b"},
+			problems: []string{""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installed := make([]*Mod, len(tt.installed))
+			for i, manifest := range tt.installed {
+				installed[i] = testMod(manifest)
+			}
+
+			loaded, problems := Resolve(installed, tt.settings)
+			if got := modIDs(loaded); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("load order = %v, want %v", got, tt.want)
+			}
+
+			var got []string
+			for _, p := range problems {
+				got = append(got, p.Mod)
+			}
+			if !reflect.DeepEqual(got, tt.problems) {
+				t.Errorf("problems = %v, want problems for %q", problems, tt.problems)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	installed := []*Mod{testMod(Manifest{ID: "a"}), testMod(Manifest{ID: "b"}), testMod(Manifest{ID: "c"})}
+	settings := []game.ModSetting{{ID: "c"}, {ID: "gone", Enabled: true}}
+
+	got := Sync(settings, installed)
+	want := []game.ModSetting{{ID: "c"}, {ID: "gone", Enabled: true}, {ID: "a", Enabled: true}, {ID: "b", Enabled: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sync = %v, want %v", got, want)
+	}
+}
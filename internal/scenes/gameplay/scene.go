@@ -0,0 +1,287 @@
+package gameplay
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"path/filepath"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/luidsonl/magic-and-blades/internal/engine"
+	"github.com/luidsonl/magic-and-blades/internal/engine/font"
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
+	"github.com/luidsonl/magic-and-blades/internal/engine/objects"
+	"github.com/luidsonl/magic-and-blades/internal/engine/vecmath"
+	"github.com/luidsonl/magic-and-blades/internal/game"
+	"github.com/luidsonl/magic-and-blades/internal/i18n"
+	"github.com/luidsonl/magic-and-blades/internal/mods"
+	"github.com/luidsonl/magic-and-blades/internal/scenes/menu"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// World and camera settings
+const (
+	worldSeed        = "magic-and-blades"
+	flySpeed         = 12.0 // blocks per second
+	sprintMultiplier = 4.0
+)
+
+// shadowSettings returns the shadow maps used for a quality setting
+func shadowSettings(quality game.ShadowQuality) graphics.ShadowSettings {
+	switch quality {
+	case game.ShadowQualityLow:
+		return graphics.ShadowSettings{Resolution: 1024, Cascades: 2, Distance: 64, PointResolution: 256, PointLights: 1}
+	case game.ShadowQualityMedium:
+		return graphics.ShadowSettings{Resolution: 2048, Cascades: 3, Distance: 128, Filter: 1, PointResolution: 512, PointLights: 2}
+	case game.ShadowQualityHigh:
+		return graphics.ShadowSettings{Resolution: 2048, Cascades: 4, Distance: 256, Filter: 2, PointResolution: 1024, PointLights: graphics.MaxPointShadows}
+	}
+	return graphics.ShadowSettings{}
+}
+
+// Scene represents the in-game scene
+type Scene struct {
+	engine     *engine.Engine
+	translator i18n.Translator
+	config     *game.Config
+	renderer   *graphics.Renderer2D
+	font       *font.Font
+	commands   menu.CommandSink
+	// mods add block types to the world
+	mods *mods.Set
+
+	world         *objects.World
+	streamer      *objects.Streamer
+	water         *objects.WaterSim
+	terrain       *objects.Terrain
+	registry      *objects.BlockRegistry
+	worldRenderer *objects.WorldRenderer
+	width, height int32
+	shadowQuality game.ShadowQuality
+
+	// The camera flies freely. previous is its position at the last tick,
+	// used to interpolate between ticks while rendering; look is the mouse
+	// motion since then
+	camera   *engine.Camera
+	control  *engine.FreeFlyController
+	previous vecmath.Vec3
+	look     [2]float32
+	time     float64
+}
+
+// NewScene creates a new gameplay scene. The world is simulated in any case
+// and only drawn when a renderer is available
+func NewScene(eng *engine.Engine, translator i18n.Translator, config *game.Config, renderer *graphics.Renderer2D, uiFont *font.Font, commands menu.CommandSink, modSet *mods.Set) *Scene {
+	s := &Scene{
+		engine:     eng,
+		translator: translator,
+		config:     config,
+		renderer:   renderer,
+		font:       uiFont,
+		commands:   commands,
+		mods:       modSet,
+		camera:     engine.NewCamera(eng.DrawableSize()),
+		control:    engine.NewFreeFlyController(),
+	}
+	s.control.Speed = flySpeed
+	if err := s.loadWorld(); err != nil {
+		log.Printf("Warning: %v", err)
+		return s
+	}
+	if renderer != nil {
+		if err := s.loadRenderer(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	return s
+}
+
+// Factory returns the factory building gameplay scenes for the scene
+// manager of e
+func Factory(e *engine.Engine) menu.SceneFactory {
+	return func(ctx menu.SceneContext, payload interface{}) menu.Scene {
+		return NewScene(e, ctx.Translator, ctx.Config, ctx.Renderer, ctx.Font, ctx.Commands, ctx.Mods)
+	}
+}
+
+// loadWorld generates the terrain, starts streaming chunks around the
+// spawn point and creates the water simulation
+func (s *Scene) loadWorld() error {
+	registry := objects.NewBlockRegistry()
+	if err := registry.RegisterDefaultBlocks(); err != nil {
+		return fmt.Errorf("failed to register blocks: %v", err)
+	}
+	if err := s.mods.RegisterBlocks(registry); err != nil {
+		return err
+	}
+	terrain, err := objects.NewTerrain(registry, objects.SeedFromString(worldSeed), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create terrain: %v", err)
+	}
+
+	// Edited chunks are saved next to the settings, unless those are not
+	// persisted either
+	var store *objects.ChunkStore
+	if s.config.SettingsPath != "" {
+		dir := filepath.Join(filepath.Dir(s.config.SettingsPath), "worlds", "default")
+		if store, err = objects.NewChunkStore(dir, registry); err != nil {
+			log.Printf("Warning: world changes will not be saved: %v", err)
+			store = nil
+		}
+	}
+
+	world := objects.NewWorld(registry)
+	water, err := objects.NewWaterSim(world)
+	if err != nil {
+		return fmt.Errorf("failed to create water simulation: %v", err)
+	}
+	streamer := objects.NewStreamer(world, terrain, store, 0)
+	// Without a renderer nothing is drawn, so nothing is meshed either
+	streamer.Headless = s.renderer == nil
+	s.world, s.streamer, s.water = world, streamer, water
+	s.terrain, s.registry = terrain, registry
+
+	s.camera.Position = vecmath.Vec3{0, float32(terrain.Height(0, 0)) + 16, 0}
+	s.previous = s.camera.Position
+	return nil
+}
+
+// loadRenderer creates the world renderer for the loaded world
+func (s *Scene) loadRenderer() error {
+	s.width, s.height = s.renderer.Size()
+	s.camera.SetViewport(s.width, s.height)
+	worldRenderer, err := objects.NewWorldRenderer(s.engine.Files(), s.streamer, s.terrain, s.registry, s.width, s.height)
+	if err != nil {
+		return fmt.Errorf("failed to create world renderer: %v", err)
+	}
+	s.worldRenderer = worldRenderer
+	for _, program := range worldRenderer.Shaders() {
+		s.engine.WatchShader(program)
+	}
+	return nil
+}
+
+// Enter is called when gameplay becomes the active scene
+func (s *Scene) Enter() {
+	if s.renderer != nil {
+		sdl.SetRelativeMouseMode(true)
+	}
+}
+
+// Exit is called when gameplay stops being the active scene
+func (s *Scene) Exit() {
+	if s.renderer != nil {
+		sdl.SetRelativeMouseMode(false)
+	}
+}
+
+// Update advances the game simulation by one tick
+func (s *Scene) Update(t game.Time) {
+	s.time += t.DeltaSeconds()
+	s.previous = s.camera.Position
+	if s.streamer == nil {
+		return
+	}
+
+	// Fly along the view direction with WASD, up with space and down with
+	// shift. Control sprints
+	keys := sdl.GetKeyboardState()
+	axis := func(positive, negative sdl.Scancode) float32 {
+		return float32(keys[positive]) - float32(keys[negative])
+	}
+	input := engine.CameraInput{
+		Move:  vecmath.Vec3{axis(sdl.SCANCODE_D, sdl.SCANCODE_A), axis(sdl.SCANCODE_SPACE, sdl.SCANCODE_LSHIFT), axis(sdl.SCANCODE_W, sdl.SCANCODE_S)},
+		LookX: s.look[0],
+		LookY: s.look[1],
+	}
+	s.look = [2]float32{}
+
+	s.control.Speed = flySpeed
+	if keys[sdl.SCANCODE_LCTRL] != 0 {
+		s.control.Speed *= sprintMultiplier
+	}
+	s.control.Update(s.camera, input, t)
+
+	s.water.Tick()
+	s.streamer.Update(s.camera.Position, s.camera.Forward())
+}
+
+// Render renders the gameplay scene
+func (s *Scene) Render(alpha float64) {
+	if s.worldRenderer == nil {
+		gl.ClearColor(0.3, 0.5, 0.3, 1.0) // Green background without a world
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		return
+	}
+
+	if width, height := s.renderer.Size(); width != s.width || height != s.height {
+		s.width, s.height = width, height
+		s.camera.SetViewport(width, height)
+		if err := s.worldRenderer.Resize(width, height); err != nil {
+			log.Printf("Warning: failed to resize world renderer: %v", err)
+		}
+	}
+
+	if s.config.ShadowQuality != s.shadowQuality {
+		s.shadowQuality = s.config.ShadowQuality
+		if err := s.worldRenderer.Renderer().SetShadows(shadowSettings(s.shadowQuality)); err != nil {
+			log.Printf("Warning: failed to create shadow maps: %v", err)
+		}
+	}
+
+	s.streamer.Upload()
+
+	// Draw from between the last two ticks
+	view := *s.camera
+	view.Position = s.previous.Lerp(s.camera.Position, float32(alpha))
+	view.Far = float32(s.streamer.LODDistance * objects.TileSize)
+
+	s.worldRenderer.Render(graphics.View{
+		View:       view.ViewMatrix(),
+		Projection: view.ProjectionMatrix(),
+		Position:   view.Position,
+		Time:       float32(s.time),
+	})
+
+	s.drawHUD()
+}
+
+// drawHUD draws the crosshair
+func (s *Scene) drawHUD() {
+	cx, cy := float32(s.width)/2, float32(s.height)/2
+	crosshair := color.RGBA{R: 255, G: 255, B: 255, A: 200}
+	s.renderer.FillRect(graphics.Rect{X: cx - 8, Y: cy - 1, W: 16, H: 2}, crosshair)
+	s.renderer.FillRect(graphics.Rect{X: cx - 1, Y: cy - 8, W: 2, H: 16}, crosshair)
+}
+
+// ProcessEvent handles input events
+func (s *Scene) ProcessEvent(event sdl.Event) bool {
+	switch e := event.(type) {
+	case *sdl.KeyboardEvent:
+		if e.State == sdl.PRESSED && e.Keysym.Sym == sdl.K_ESCAPE {
+			s.commands.Send(menu.PushScene(menu.ScenePause, nil))
+			return true
+		}
+	case *sdl.MouseMotionEvent:
+		// Applied by the camera controller on the next tick
+		s.look[0] += float32(e.XRel)
+		s.look[1] += float32(e.YRel)
+		return true
+	}
+	return false
+}
+
+// Cleanup saves the edited chunks and releases resources
+func (s *Scene) Cleanup() {
+	if s.streamer != nil {
+		s.streamer.Close()
+		s.streamer = nil
+	}
+	if s.worldRenderer != nil {
+		for _, program := range s.worldRenderer.Shaders() {
+			s.engine.UnwatchShader(program)
+		}
+		s.worldRenderer.Destroy()
+		s.worldRenderer = nil
+	}
+}
@@ -0,0 +1,101 @@
+package gameplay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine"
+	"github.com/luidsonl/magic-and-blades/internal/game"
+	"github.com/luidsonl/magic-and-blades/internal/i18n"
+	"github.com/luidsonl/magic-and-blades/internal/scenes/menu"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// streamTimeout bounds the wait for the first chunks of the world
+const streamTimeout = 10 * time.Second
+
+// newHeadlessEngine creates a headless engine in English with the gameplay
+// scene registered. Settings are not saved
+func newHeadlessEngine(t *testing.T) *engine.Engine {
+	t.Helper()
+	config := game.DefaultConfig()
+	config.Headless = true
+	config.Language = "en"
+
+	e, err := engine.NewEngine(config)
+	if err != nil {
+		t.Fatalf("failed to create headless engine: %v", err)
+	}
+	t.Cleanup(e.Destroy)
+	e.GetSceneManager().Register(menu.SceneGameplay, Factory(e))
+	return e
+}
+
+// press sends a key press to the active scene and runs a tick so that the
+// commands it queues are applied
+func press(e *engine.Engine, keys ...sdl.Keycode) {
+	for _, key := range keys {
+		e.GetSceneManager().ProcessEvent(&sdl.KeyboardEvent{
+			Type:   sdl.KEYDOWN,
+			State:  sdl.PRESSED,
+			Keysym: sdl.Keysym{Sym: key},
+		})
+		e.RunTicks(1)
+	}
+}
+
+func TestHeadlessMenuToGameplay(t *testing.T) {
+	e := newHeadlessEngine(t)
+	translator := e.GetTranslator()
+
+	if got := e.GetSceneManager().GetSceneType(); got != menu.SceneMainMenu {
+		t.Fatalf("initial scene = %s, want %s", got, menu.SceneMainMenu)
+	}
+	title := translator.Translate(i18n.TitleMainMenu)
+
+	// Options > Language > Português
+	press(e, sdl.K_DOWN, sdl.K_RETURN, sdl.K_RETURN, sdl.K_DOWN, sdl.K_RETURN)
+	if got := translator.GetLanguage(); got != "pt" {
+		t.Fatalf("language = %q, want pt", got)
+	}
+	if got := e.GetConfig().Language; got != "pt" {
+		t.Errorf("configured language = %q, want pt", got)
+	}
+	if got := translator.Translate(i18n.TitleMainMenu); got == title {
+		t.Errorf("title still translated as %q after switching language", got)
+	}
+
+	// Back to the main menu and Play, through a fade
+	press(e, sdl.K_ESCAPE, sdl.K_RETURN)
+	e.RunTicks(game.DefaultTickRate)
+	if got := e.GetSceneManager().GetSceneType(); got != menu.SceneGameplay {
+		t.Fatalf("scene after Play = %s, want %s", got, menu.SceneGameplay)
+	}
+
+	scene, ok := e.GetSceneManager().GetCurrentScene().(*Scene)
+	if !ok {
+		t.Fatalf("current scene is %T, want *Scene", e.GetSceneManager().GetCurrentScene())
+	}
+	if scene.world == nil || scene.streamer == nil || scene.water == nil {
+		t.Fatalf("headless gameplay has no world")
+	}
+	if scene.worldRenderer != nil {
+		t.Errorf("headless gameplay created a world renderer")
+	}
+
+	// Chunks are generated in the background and added by the ticks
+	deadline := time.Now().Add(streamTimeout)
+	for len(scene.world.Chunks()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no chunks streamed after %v", streamTimeout)
+		}
+		e.RunTicks(1)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	before := e.GetTime().Tick
+	e.RunTicks(10)
+	if got := e.GetTime().Tick - before; got != 10 {
+		t.Errorf("ran %d ticks, want 10", got)
+	}
+}
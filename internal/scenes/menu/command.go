@@ -0,0 +1,75 @@
+package menu
+
+import "time"
+
+// CommandType identifies a request sent from a scene to the scene manager
+type CommandType int
+
+const (
+	// CommandChangeScene replaces the whole stack with a new scene
+	CommandChangeScene CommandType = iota
+	// CommandPushScene opens a scene as an overlay over the current one
+	CommandPushScene
+	// CommandPopScene closes the top scene
+	CommandPopScene
+	// CommandQuit asks the engine to stop the game loop
+	CommandQuit
+	// CommandApplyDisplay asks the engine to apply the window size, mode,
+	// display and vsync currently stored in the config
+	CommandApplyDisplay
+)
+
+// Command is a request from a scene to change the scene stack or quit.
+// Commands are queued and applied between ticks, never in the middle of a
+// scene update
+type Command struct {
+	Type       CommandType
+	Scene      SceneType
+	Payload    interface{}
+	Transition Transition
+}
+
+// CommandSink receives commands from scenes
+type CommandSink interface {
+	Send(cmd Command)
+}
+
+// ChangeScene returns a command that replaces the stack with a new scene
+func ChangeScene(sceneType SceneType, payload interface{}) Command {
+	return Command{Type: CommandChangeScene, Scene: sceneType, Payload: payload}
+}
+
+// ChangeSceneWithFade returns a scene change that fades out and back in
+func ChangeSceneWithFade(sceneType SceneType, payload interface{}, duration time.Duration) Command {
+	cmd := ChangeScene(sceneType, payload)
+	cmd.Transition = Transition{Kind: TransitionFade, Duration: duration}
+	return cmd
+}
+
+// ChangeSceneWithCrossfade returns a scene change that blends the old scene
+// into the new one
+func ChangeSceneWithCrossfade(sceneType SceneType, payload interface{}, duration time.Duration) Command {
+	cmd := ChangeScene(sceneType, payload)
+	cmd.Transition = Transition{Kind: TransitionCrossfade, Duration: duration}
+	return cmd
+}
+
+// PushScene returns a command that opens a scene over the current one
+func PushScene(sceneType SceneType, payload interface{}) Command {
+	return Command{Type: CommandPushScene, Scene: sceneType, Payload: payload}
+}
+
+// PopScene returns a command that closes the top scene
+func PopScene() Command {
+	return Command{Type: CommandPopScene}
+}
+
+// Quit returns a command that stops the game
+func Quit() Command {
+	return Command{Type: CommandQuit}
+}
+
+// ApplyDisplay returns a command that applies the display settings
+func ApplyDisplay() Command {
+	return Command{Type: CommandApplyDisplay}
+}
@@ -0,0 +1,77 @@
+package menu
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// resolution is a window size offered in the resolution menu
+type resolution struct {
+	Width  int32
+	Height int32
+}
+
+// String returns the resolution as shown in the menu
+func (r resolution) String() string {
+	return fmt.Sprintf("%dx%d", r.Width, r.Height)
+}
+
+// fallbackResolutions is offered when SDL cannot report display modes,
+// e.g. when running headless
+var fallbackResolutions = []resolution{
+	{800, 600},
+	{1024, 768},
+	{1280, 720},
+	{1366, 768},
+	{1920, 1080},
+}
+
+// availableResolutions returns the distinct resolutions reported by SDL for
+// the display, largest first
+func availableResolutions(display int) []resolution {
+	count, err := sdl.GetNumDisplayModes(display)
+	if err != nil || count <= 0 {
+		return fallbackResolutions
+	}
+
+	seen := make(map[resolution]bool)
+	var resolutions []resolution
+	for i := 0; i < count; i++ {
+		mode, err := sdl.GetDisplayMode(display, i)
+		if err != nil {
+			continue
+		}
+
+		// Modes differing only in refresh rate or format share a size
+		res := resolution{Width: mode.W, Height: mode.H}
+		if !seen[res] {
+			seen[res] = true
+			resolutions = append(resolutions, res)
+		}
+	}
+
+	if len(resolutions) == 0 {
+		return fallbackResolutions
+	}
+	return resolutions
+}
+
+// availableDisplays returns a label for every connected display
+func availableDisplays() []string {
+	count, err := sdl.GetNumVideoDisplays()
+	if err != nil || count <= 0 {
+		return []string{"1"}
+	}
+
+	displays := make([]string, count)
+	for i := range displays {
+		name, err := sdl.GetDisplayName(i)
+		if err != nil || name == "" {
+			displays[i] = fmt.Sprintf("%d", i+1)
+		} else {
+			displays[i] = fmt.Sprintf("%d: %s", i+1, name)
+		}
+	}
+	return displays
+}
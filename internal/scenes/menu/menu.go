@@ -1,33 +1,59 @@
 package menu
 
 import (
+	"image/color"
 	"log"
+	"time"
 
+	"github.com/luidsonl/magic-and-blades/internal/engine/font"
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
 	"github.com/luidsonl/magic-and-blades/internal/game"
 	"github.com/luidsonl/magic-and-blades/internal/i18n"
+	"github.com/luidsonl/magic-and-blades/internal/mods"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+// sceneFadeDuration is the length of fades between menus and gameplay
+const sceneFadeDuration = 600 * time.Millisecond
+
 // MenuScene represents the main menu scene
 type MenuScene struct {
 	translator     i18n.Translator
 	config         *game.Config
-	renderer       *sdl.Renderer
-	font           *sdl.Texture
+	renderer       *graphics.Renderer2D
+	font           *font.Font
+	commands       CommandSink
 	menuItems      []string
 	settingsItems  []string
 	currentMenu    string
 	selectedIndex  int
+	resolutions    []resolution
 	resolutionOpts []string
+	windowModes    []game.WindowMode
+	windowModeOpts []string
+	displayOpts    []string
 	languageOpts   []string
+	shadowLevels   []game.ShadowQuality
+	shadowOpts     []string
+	effectOpts     []string
+	// mods are the installed mods; modItems lists their IDs in load order
+	// followed by Back
+	mods        *mods.Set
+	modItems    []string
+	modProblems []mods.Problem
+	// modsPending is set when the mod settings differ from the mods loaded
+	modsPending bool
 }
 
 // NewMenuScene creates a new menu scene
-func NewMenuScene(translator i18n.Translator, config *game.Config, renderer *sdl.Renderer) *MenuScene {
+func NewMenuScene(translator i18n.Translator, config *game.Config, renderer *graphics.Renderer2D, uiFont *font.Font, commands CommandSink, modSet *mods.Set) *MenuScene {
 	menu := &MenuScene{
 		translator:    translator,
 		config:        config,
 		renderer:      renderer,
+		font:          uiFont,
+		commands:      commands,
+		mods:          modSet,
 		currentMenu:   "main",
 		selectedIndex: 0,
 	}
@@ -36,22 +62,56 @@ func NewMenuScene(translator i18n.Translator, config *game.Config, renderer *sdl
 	menu.menuItems = []string{
 		"button.play",
 		"button.options",
+		"button.mods",
 		"button.quit",
 	}
 
 	menu.settingsItems = []string{
-		"settings.language",
-		"settings.resolution",
-		"settings.back",
+		i18n.SettingsLanguage,
+		i18n.SettingsResolution,
+		i18n.SettingsWindowMode,
+		i18n.SettingsDisplay,
+		i18n.SettingsVSync,
+		i18n.SettingsShadows,
+		i18n.SettingsEffects,
+		i18n.SettingsBack,
 	}
 
 	// Available options
-	menu.resolutionOpts = []string{
-		"800x600",
-		"1024x768",
-		"1280x720",
-		"1366x768",
-		"1920x1080",
+	menu.refreshDisplayOptions()
+
+	menu.windowModes = []game.WindowMode{
+		game.WindowModeWindowed,
+		game.WindowModeFullscreen,
+		game.WindowModeBorderless,
+	}
+	menu.windowModeOpts = []string{
+		i18n.WindowModeWindowed,
+		i18n.WindowModeFullscreen,
+		i18n.WindowModeBorderless,
+	}
+
+	menu.shadowLevels = []game.ShadowQuality{
+		game.ShadowQualityOff,
+		game.ShadowQualityLow,
+		game.ShadowQualityMedium,
+		game.ShadowQualityHigh,
+	}
+	menu.shadowOpts = []string{
+		i18n.ShadowQualityOff,
+		i18n.ShadowQualityLow,
+		i18n.ShadowQualityMedium,
+		i18n.ShadowQualityHigh,
+	}
+
+	menu.effectOpts = []string{
+		i18n.EffectToneMapping,
+		i18n.EffectBloom,
+		i18n.EffectFXAA,
+		i18n.EffectColorGrading,
+		i18n.EffectVignette,
+		i18n.EffectDamage,
+		i18n.SettingsBack,
 	}
 
 	menu.languageOpts = []string{
@@ -64,55 +124,76 @@ func NewMenuScene(translator i18n.Translator, config *game.Config, renderer *sdl
 	return menu
 }
 
+// Enter is called when the menu becomes the active scene
+func (m *MenuScene) Enter() {
+	m.selectedIndex = 0
+}
+
+// Exit is called when the menu stops being the active scene
+func (m *MenuScene) Exit() {}
+
 // Update processes menu logic
-func (m *MenuScene) Update() {
+func (m *MenuScene) Update(t game.Time) {
 	// Menu logic will be handled in ProcessEvent
 }
 
 // Render draws the menu
-func (m *MenuScene) Render() {
-	m.renderer.SetDrawColor(0, 0, 0, 255)
-	m.renderer.Clear()
+func (m *MenuScene) Render(alpha float64) {
+	width, height := m.renderer.Size()
 
 	// Draw background
-	m.renderer.SetDrawColor(30, 30, 50, 255)
-	m.renderer.FillRect(&sdl.Rect{X: 0, Y: 0, W: m.config.WindowWidth, H: m.config.WindowHeight})
+	m.renderer.FillRect(graphics.Rect{W: float32(width), H: float32(height)}, backgroundColor)
 
 	// Draw title
 	title := m.translator.Translate(i18n.TitleMainMenu)
-	m.drawText(title, m.config.WindowWidth/2, 100, 48, true)
-
-	// Draw menu items
-	var items []string
-	if m.currentMenu == "main" {
-		items = m.menuItems
-	} else if m.currentMenu == "settings" {
-		items = m.settingsItems
-	} else if m.currentMenu == "language" {
-		items = m.languageOpts
-	} else if m.currentMenu == "resolution" {
-		items = m.resolutionOpts
-	}
-
-	for i, itemKey := range items {
-		var text string
-		if m.currentMenu == "main" || m.currentMenu == "settings" {
-			text = m.translator.Translate(itemKey)
-		} else {
-			text = itemKey
-		}
+	m.drawText(title, width/2, 100, 48, true, textColor)
 
-		yPos := int32(200 + i*60) // Convert to int32
+	// Draw the menu items that fit on screen
+	items := m.currentItems()
+	first, last, spacing := itemLayout(len(items), m.selectedIndex, height)
+	for i := first; i < last; i++ {
+		text := m.itemText(items[i])
+
+		yPos := itemsTop + int32(i-first)*spacing
 
 		if i == m.selectedIndex {
 			// Gold for selected item
-			m.drawText("> "+text, m.config.WindowWidth/2-20, yPos, 32, false)
+			m.drawText("> "+text+" <", width/2, yPos, 32, true, selectedColor)
 		} else {
-			m.drawText(text, m.config.WindowWidth/2, yPos, 32, false)
+			m.drawText(text, width/2, yPos, 32, true, textColor)
 		}
 	}
 
-	m.renderer.Present()
+	if m.currentMenu == "mods" {
+		m.renderModsInfo(width, height)
+	}
+}
+
+// Menu item layout
+const (
+	itemsTop          = 200
+	itemSpacing       = 60
+	minItemSpacing    = 40
+	itemsBottomMargin = 60
+)
+
+// itemLayout returns the range of the count items to show on a screen of the
+// given height and the spacing between them. Items are squeezed together down
+// to minItemSpacing; past that the list scrolls to keep selected visible
+func itemLayout(count, selected int, height int32) (first, last int, spacing int32) {
+	if count == 0 {
+		return 0, 0, itemSpacing
+	}
+
+	available := height - itemsTop - itemsBottomMargin
+	spacing = min(itemSpacing, max(available/int32(count), minItemSpacing))
+
+	visible := max(int(available/spacing), 1)
+	if visible >= count {
+		return 0, count, spacing
+	}
+	first = min(max(selected-visible+1, 0), count-visible)
+	return first, first + visible, spacing
 }
 
 // ProcessEvent handles input events
@@ -122,10 +203,18 @@ func (m *MenuScene) ProcessEvent(event sdl.Event) bool {
 		if e.State == sdl.PRESSED {
 			switch e.Keysym.Sym {
 			case sdl.K_UP:
-				m.moveSelection(-1)
+				if m.currentMenu == "mods" && e.Keysym.Mod&sdl.KMOD_SHIFT != 0 {
+					m.moveMod(-1)
+				} else {
+					m.moveSelection(-1)
+				}
 				return true
 			case sdl.K_DOWN:
-				m.moveSelection(1)
+				if m.currentMenu == "mods" && e.Keysym.Mod&sdl.KMOD_SHIFT != 0 {
+					m.moveMod(1)
+				} else {
+					m.moveSelection(1)
+				}
 				return true
 			case sdl.K_RETURN, sdl.K_SPACE:
 				m.selectItem()
@@ -136,29 +225,121 @@ func (m *MenuScene) ProcessEvent(event sdl.Event) bool {
 					m.selectedIndex = 0
 					return true
 				}
+				log.Printf("Escape key pressed, exiting game")
+				m.commands.Send(Quit())
+				return true
 			}
 		}
 	}
 	return false
 }
 
-// moveSelection changes the selected menu item
-func (m *MenuScene) moveSelection(direction int) {
-	var maxItems int
+// currentItems returns the items of the menu being shown
+func (m *MenuScene) currentItems() []string {
 	switch m.currentMenu {
 	case "main":
-		maxItems = len(m.menuItems)
+		return m.menuItems
 	case "settings":
-		maxItems = len(m.settingsItems)
+		return m.settingsItems
 	case "language":
-		maxItems = len(m.languageOpts)
+		return m.languageOpts
 	case "resolution":
-		maxItems = len(m.resolutionOpts)
+		return m.resolutionOpts
+	case "window_mode":
+		return m.windowModeOpts
+	case "display":
+		return m.displayOpts
+	case "shadows":
+		return m.shadowOpts
+	case "effects":
+		return m.effectOpts
+	case "mods":
+		return m.modItems
+	}
+	return nil
+}
+
+// itemText returns the text displayed for a menu item
+func (m *MenuScene) itemText(itemKey string) string {
+	switch m.currentMenu {
+	case "main", "window_mode", "shadows":
+		return m.translator.Translate(itemKey)
+	case "settings":
+		text := m.translator.Translate(itemKey)
+		switch itemKey {
+		case i18n.SettingsVSync:
+			state := i18n.OptionOff
+			if m.config.VSync {
+				state = i18n.OptionOn
+			}
+			text += ": " + m.translator.Translate(state)
+		case i18n.SettingsShadows:
+			for i, level := range m.shadowLevels {
+				if level == m.config.ShadowQuality {
+					text += ": " + m.translator.Translate(m.shadowOpts[i])
+				}
+			}
+		}
+		return text
+	case "effects":
+		text := m.translator.Translate(itemKey)
+		if enabled := m.effectSetting(itemKey); enabled != nil {
+			state := i18n.OptionOff
+			if *enabled {
+				state = i18n.OptionOn
+			}
+			text += ": " + m.translator.Translate(state)
+		}
+		return text
+	case "mods":
+		return m.modText(itemKey)
+	}
+	return itemKey
+}
+
+// effectSetting returns the config flag toggled by an item of the effects
+// menu, or nil for other items
+func (m *MenuScene) effectSetting(itemKey string) *bool {
+	effects := &m.config.PostEffects
+	switch itemKey {
+	case i18n.EffectToneMapping:
+		return &effects.ToneMapping
+	case i18n.EffectBloom:
+		return &effects.Bloom
+	case i18n.EffectFXAA:
+		return &effects.FXAA
+	case i18n.EffectColorGrading:
+		return &effects.ColorGrading
+	case i18n.EffectVignette:
+		return &effects.Vignette
+	case i18n.EffectDamage:
+		return &effects.Damage
+	}
+	return nil
+}
+
+// moveSelection changes the selected menu item
+func (m *MenuScene) moveSelection(direction int) {
+	maxItems := len(m.currentItems())
+	if maxItems == 0 {
+		return
 	}
 
 	m.selectedIndex = (m.selectedIndex + direction + maxItems) % maxItems
 }
 
+// refreshDisplayOptions queries the displays and the resolutions supported
+// by the configured display
+func (m *MenuScene) refreshDisplayOptions() {
+	m.displayOpts = availableDisplays()
+
+	m.resolutions = availableResolutions(m.config.Display)
+	m.resolutionOpts = make([]string, len(m.resolutions))
+	for i, res := range m.resolutions {
+		m.resolutionOpts[i] = res.String()
+	}
+}
+
 // selectItem handles menu item selection
 func (m *MenuScene) selectItem() {
 	switch m.currentMenu {
@@ -166,13 +347,17 @@ func (m *MenuScene) selectItem() {
 		switch m.selectedIndex {
 		case 0: // Play
 			log.Println("Starting game...")
-			// Transition to gameplay scene
+			m.commands.Send(ChangeSceneWithFade(SceneGameplay, nil, sceneFadeDuration))
 		case 1: // Options
 			m.currentMenu = "settings"
 			m.selectedIndex = 0
-		case 2: // Quit
+		case 2: // Mods
+			m.refreshMods()
+			m.currentMenu = "mods"
+			m.selectedIndex = 0
+		case 3: // Quit
 			log.Println("Quitting game...")
-			// Signal to quit the game
+			m.commands.Send(Quit())
 		}
 	case "settings":
 		switch m.selectedIndex {
@@ -180,20 +365,35 @@ func (m *MenuScene) selectItem() {
 			m.currentMenu = "language"
 			m.selectedIndex = 0
 		case 1: // Resolution
+			m.refreshDisplayOptions()
 			m.currentMenu = "resolution"
 			m.selectedIndex = 0
-		case 2: // Back
+		case 2: // Window mode
+			m.currentMenu = "window_mode"
+			m.selectedIndex = 0
+		case 3: // Display
+			m.refreshDisplayOptions()
+			m.currentMenu = "display"
+			m.selectedIndex = 0
+		case 4: // VSync
+			m.config.VSync = !m.config.VSync
+			m.applyDisplay()
+		case 5: // Shadows
+			m.currentMenu = "shadows"
+			m.selectedIndex = 0
+		case 6: // Effects
+			m.currentMenu = "effects"
+			m.selectedIndex = 0
+		case 7: // Back
 			m.currentMenu = "main"
 			m.selectedIndex = 0
 		}
 	case "language":
 		switch m.selectedIndex {
 		case 0: // English
-			m.translator.SetLanguage("en")
-			m.config.Language = "en"
+			m.setLanguage("en")
 		case 1: // Portuguese
-			m.translator.SetLanguage("pt")
-			m.config.Language = "pt"
+			m.setLanguage("pt")
 		case 2: // Spanish
 			// Add support for more languages as needed
 		case 3: // French
@@ -201,44 +401,84 @@ func (m *MenuScene) selectItem() {
 		}
 		m.currentMenu = "settings"
 	case "resolution":
-		switch m.selectedIndex {
-		case 0: // 800x600
-			m.config.WindowWidth = 800
-			m.config.WindowHeight = 600
-		case 1: // 1024x768
-			m.config.WindowWidth = 1024
-			m.config.WindowHeight = 768
-		case 2: // 1280x720
-			m.config.WindowWidth = 1280
-			m.config.WindowHeight = 720
-		case 3: // 1366x768
-			m.config.WindowWidth = 1366
-			m.config.WindowHeight = 768
-		case 4: // 1920x1080
-			m.config.WindowWidth = 1920
-			m.config.WindowHeight = 1080
+		if m.selectedIndex < len(m.resolutions) {
+			res := m.resolutions[m.selectedIndex]
+			m.config.WindowWidth = res.Width
+			m.config.WindowHeight = res.Height
+			log.Printf("Resolution changed to: %dx%d", m.config.WindowWidth, m.config.WindowHeight)
+			m.applyDisplay()
 		}
-		// In a real implementation, you would recreate the window here
-		log.Printf("Resolution changed to: %dx%d", m.config.WindowWidth, m.config.WindowHeight)
 		m.currentMenu = "settings"
+		m.selectedIndex = 0
+	case "window_mode":
+		if m.selectedIndex < len(m.windowModes) {
+			m.config.WindowMode = m.windowModes[m.selectedIndex]
+			log.Printf("Window mode changed to: %s", m.config.WindowMode)
+			m.applyDisplay()
+		}
+		m.currentMenu = "settings"
+		m.selectedIndex = 0
+	case "display":
+		if m.selectedIndex < len(m.displayOpts) {
+			m.config.Display = m.selectedIndex
+			log.Printf("Display changed to: %d", m.config.Display)
+			m.applyDisplay()
+		}
+		m.currentMenu = "settings"
+		m.selectedIndex = 0
+	case "shadows":
+		if m.selectedIndex < len(m.shadowLevels) {
+			m.config.ShadowQuality = m.shadowLevels[m.selectedIndex]
+			log.Printf("Shadow quality changed to: %s", m.config.ShadowQuality)
+			m.saveSettings()
+		}
+		m.currentMenu = "settings"
+		m.selectedIndex = 0
+	case "effects":
+		// Effects apply from the next frame; the menu stays open so that
+		// several can be toggled
+		itemKey := m.effectOpts[m.selectedIndex]
+		if enabled := m.effectSetting(itemKey); enabled != nil {
+			*enabled = !*enabled
+			log.Printf("Toggled %s: %v", itemKey, *enabled)
+			m.saveSettings()
+		} else {
+			m.currentMenu = "settings"
+			m.selectedIndex = 0
+		}
+	case "mods":
+		m.toggleMod()
 	}
 }
 
-// drawText draws text on the screen (simplified implementation)
-func (m *MenuScene) drawText(text string, x, y int32, size int32, centered bool) {
-	// This is a simplified text rendering function
-	// In a real implementation, you would use a proper font rendering library
-
-	// For now, we'll just log the text that would be displayed
-	log.Printf("Would draw text: %s at (%d, %d)", text, x, y)
+// setLanguage switches the translator to lang and saves it once the
+// translations are loaded
+func (m *MenuScene) setLanguage(lang string) {
+	if err := m.translator.SetLanguage(lang); err != nil {
+		log.Printf("Warning: failed to change language to %s: %v", lang, err)
+		return
+	}
+	m.config.Language = lang
+	m.saveSettings()
+}
 
-	// Placeholder: actual SDL text rendering would go here
-	// You would typically use SDL_ttf for proper text rendering
+// applyDisplay asks the engine to apply the display settings and saves them
+func (m *MenuScene) applyDisplay() {
+	m.commands.Send(ApplyDisplay())
+	m.saveSettings()
 }
 
-// Cleanup releases resources
-func (m *MenuScene) Cleanup() {
-	if m.font != nil {
-		m.font.Destroy()
+// saveSettings persists the current configuration
+func (m *MenuScene) saveSettings() {
+	if err := m.config.Save(); err != nil {
+		log.Printf("Warning: failed to save settings: %v", err)
 	}
 }
+
+// drawText draws text on the screen
+func (m *MenuScene) drawText(text string, x, y int32, size int32, centered bool, c color.RGBA) {
+	drawText(m.renderer, m.font, text, x, y, size, centered, c)
+}
+
+// Cleanup releases resources
+func (m *MenuScene) Cleanup() {}
@@ -0,0 +1,121 @@
+package menu
+
+import (
+	"image/color"
+	"log"
+
+	"github.com/luidsonl/magic-and-blades/internal/game"
+	"github.com/luidsonl/magic-and-blades/internal/i18n"
+	"github.com/luidsonl/magic-and-blades/internal/mods"
+)
+
+// problemColor draws mod problems in the mods menu
+var problemColor = color.RGBA{R: 255, G: 110, B: 90, A: 255}
+
+// maxShownProblems limits the problems listed under the mods menu
+const maxShownProblems = 5
+
+// refreshMods lists the installed mods in the player order, adding newly
+// installed ones to the settings, and checks the order for problems
+func (m *MenuScene) refreshMods() {
+	installed := m.mods.Installed()
+	m.config.Mods = mods.Sync(m.config.Mods, installed)
+
+	m.modItems = m.modItems[:0]
+	for _, setting := range m.config.Mods {
+		if m.mods.Mod(setting.ID) != nil {
+			m.modItems = append(m.modItems, setting.ID)
+		}
+	}
+	m.modItems = append(m.modItems, i18n.SettingsBack)
+
+	// Conflicts between files are only known for the mods loaded at
+	// startup; after a change only the order itself can be checked
+	m.modsPending = m.mods.Pending(m.config.Mods)
+	if m.modsPending {
+		_, m.modProblems = mods.Resolve(installed, m.config.Mods)
+	} else {
+		m.modProblems = m.mods.Problems()
+	}
+}
+
+// modSetting returns the settings entry of an installed mod
+func (m *MenuScene) modSetting(id string) *game.ModSetting {
+	for i := range m.config.Mods {
+		if m.config.Mods[i].ID == id {
+			return &m.config.Mods[i]
+		}
+	}
+	return nil
+}
+
+// modText returns the text displayed for an item of the mods menu
+func (m *MenuScene) modText(itemKey string) string {
+	mod := m.mods.Mod(itemKey)
+	if mod == nil {
+		return m.translator.Translate(itemKey)
+	}
+	state := i18n.OptionOff
+	if setting := m.modSetting(itemKey); setting != nil && setting.Enabled {
+		state = i18n.OptionOn
+	}
+	return mod.Title() + ": " + m.translator.Translate(state)
+}
+
+// toggleMod enables or disables the selected mod, or leaves the menu when
+// Back is selected
+func (m *MenuScene) toggleMod() {
+	if m.selectedIndex >= len(m.modItems)-1 {
+		m.currentMenu = "main"
+		m.selectedIndex = 0
+		return
+	}
+
+	setting := m.modSetting(m.modItems[m.selectedIndex])
+	setting.Enabled = !setting.Enabled
+	log.Printf("Mod %s enabled: %v", setting.ID, setting.Enabled)
+	m.saveSettings()
+	m.refreshMods()
+}
+
+// moveMod moves the selected mod up or down the load order. Mods later in
+// the list override earlier ones
+func (m *MenuScene) moveMod(direction int) {
+	target := m.selectedIndex + direction
+	if m.selectedIndex >= len(m.modItems)-1 || target < 0 || target >= len(m.modItems)-1 {
+		return
+	}
+
+	// Swap the entries in the settings, leaving those of mods that are no
+	// longer installed in place
+	a, b := m.modSetting(m.modItems[m.selectedIndex]), m.modSetting(m.modItems[target])
+	*a, *b = *b, *a
+	m.selectedIndex = target
+	m.saveSettings()
+	m.refreshMods()
+}
+
+// renderModsInfo draws the key hint, the restart note and the problems
+// found below the mods menu
+func (m *MenuScene) renderModsInfo(width, height int32) {
+	y := height - 40
+	m.drawText(m.translator.Translate(i18n.ModsHint), width/2, y, 18, true, textColor)
+	if m.modsPending {
+		y -= 28
+		m.drawText(m.translator.Translate(i18n.ModsRestart), width/2, y, 18, true, selectedColor)
+	}
+
+	problems := m.modProblems
+	if len(problems) > maxShownProblems {
+		problems = problems[:maxShownProblems]
+	}
+	for i := len(problems) - 1; i >= 0; i-- {
+		y -= 24
+		m.drawText(problems[i].String(), width/2, y, 16, true, problemColor)
+	}
+
+	if len(m.modItems) == 1 && m.mods.Dir() != "" {
+		y -= 32
+		m.drawText(m.translator.Translatef(i18n.ModsNone, m.mods.Dir()), width/2, y, 18, true, textColor)
+	}
+}
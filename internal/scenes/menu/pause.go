@@ -0,0 +1,107 @@
+package menu
+
+import (
+	"image/color"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/font"
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
+	"github.com/luidsonl/magic-and-blades/internal/game"
+	"github.com/luidsonl/magic-and-blades/internal/i18n"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// PauseScene is drawn as an overlay over the gameplay scene
+type PauseScene struct {
+	translator    i18n.Translator
+	config        *game.Config
+	renderer      *graphics.Renderer2D
+	font          *font.Font
+	commands      CommandSink
+	items         []string
+	selectedIndex int
+}
+
+// NewPauseScene creates a new pause scene
+func NewPauseScene(translator i18n.Translator, config *game.Config, renderer *graphics.Renderer2D, uiFont *font.Font, commands CommandSink) *PauseScene {
+	return &PauseScene{
+		translator: translator,
+		config:     config,
+		renderer:   renderer,
+		font:       uiFont,
+		commands:   commands,
+		items: []string{
+			i18n.ButtonResume,
+			i18n.ButtonMainMenu,
+		},
+	}
+}
+
+// Enter is called when the pause menu is opened
+func (p *PauseScene) Enter() {
+	p.selectedIndex = 0
+}
+
+// Exit is called when the pause menu is closed
+func (p *PauseScene) Exit() {}
+
+// Update processes pause menu logic
+func (p *PauseScene) Update(t game.Time) {}
+
+// Render dims the scenes below and draws the pause menu
+func (p *PauseScene) Render(alpha float64) {
+	width, height := p.renderer.Size()
+
+	// Semi-transparent dark background over the game
+	p.renderer.FillRect(graphics.Rect{W: float32(width), H: float32(height)}, color.RGBA{R: 20, G: 20, B: 20, A: 200})
+
+	title := p.translator.Translate(i18n.MessagePaused)
+	drawText(p.renderer, p.font, title, width/2, 150, 48, true, textColor)
+
+	for i, itemKey := range p.items {
+		text := p.translator.Translate(itemKey)
+		yPos := int32(250 + i*60)
+
+		if i == p.selectedIndex {
+			drawText(p.renderer, p.font, "> "+text+" <", width/2, yPos, 32, true, selectedColor)
+		} else {
+			drawText(p.renderer, p.font, text, width/2, yPos, 32, true, textColor)
+		}
+	}
+}
+
+// ProcessEvent handles input events
+func (p *PauseScene) ProcessEvent(event sdl.Event) bool {
+	switch e := event.(type) {
+	case *sdl.KeyboardEvent:
+		if e.State == sdl.PRESSED {
+			switch e.Keysym.Sym {
+			case sdl.K_UP:
+				p.selectedIndex = (p.selectedIndex - 1 + len(p.items)) % len(p.items)
+				return true
+			case sdl.K_DOWN:
+				p.selectedIndex = (p.selectedIndex + 1) % len(p.items)
+				return true
+			case sdl.K_RETURN, sdl.K_SPACE:
+				p.selectItem()
+				return true
+			case sdl.K_ESCAPE:
+				p.commands.Send(PopScene())
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectItem handles pause menu item selection
+func (p *PauseScene) selectItem() {
+	switch p.selectedIndex {
+	case 0: // Resume
+		p.commands.Send(PopScene())
+	case 1: // Main menu
+		p.commands.Send(ChangeSceneWithFade(SceneMainMenu, nil, sceneFadeDuration))
+	}
+}
+
+// Cleanup releases resources
+func (p *PauseScene) Cleanup() {}
@@ -1,15 +1,29 @@
 package menu
 
 import (
+	"image/color"
+	"log"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/luidsonl/magic-and-blades/internal/engine/font"
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
 	"github.com/luidsonl/magic-and-blades/internal/game"
 	"github.com/luidsonl/magic-and-blades/internal/i18n"
+	"github.com/luidsonl/magic-and-blades/internal/mods"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 // Scene interface for menu scenes
 type Scene interface {
-	Update()
-	Render()
+	// Enter is called when the scene becomes the top of the stack
+	Enter()
+	// Exit is called when the scene stops being the top of the stack,
+	// either because another scene was pushed over it or it was removed
+	Exit()
+	// Update advances the scene by one fixed simulation tick
+	Update(t game.Time)
+	// Render draws the scene; alpha interpolates between the last two ticks
+	Render(alpha float64)
 	ProcessEvent(event sdl.Event) bool
 	Cleanup()
 }
@@ -24,74 +38,446 @@ const (
 	ScenePause
 )
 
-// SceneManager manages scene transitions
+// String returns a readable name for the scene type
+func (t SceneType) String() string {
+	switch t {
+	case SceneMainMenu:
+		return "main menu"
+	case SceneSettings:
+		return "settings"
+	case SceneGameplay:
+		return "gameplay"
+	case ScenePause:
+		return "pause"
+	}
+	return "unknown"
+}
+
+// SceneContext holds the dependencies handed to scene factories
+type SceneContext struct {
+	Translator i18n.Translator
+	Config     *game.Config
+	// Renderer draws 2D graphics and text (nil in headless mode)
+	Renderer *graphics.Renderer2D
+	// Font is the UI font
+	Font     *font.Font
+	Commands CommandSink
+	// Mods are the mods installed and loaded at startup
+	Mods *mods.Set
+}
+
+// SceneFactory builds a scene; payload is the value sent with the command
+// that requested the scene, or nil
+type SceneFactory func(ctx SceneContext, payload interface{}) Scene
+
+// sceneEntry is a scene on the stack together with its type
+type sceneEntry struct {
+	scene     Scene
+	sceneType SceneType
+}
+
+// SceneManager manages a stack of scenes
+//
+// Only the top scene receives updates and events. Every scene on the stack
+// is rendered from the bottom up, so scenes pushed over others (such as the
+// pause menu over gameplay) are drawn as overlays.
 type SceneManager struct {
-	currentScene Scene
-	sceneType    SceneType
-	translator   i18n.Translator
-	config       *game.Config
-	renderer     *sdl.Renderer
+	stack      []sceneEntry
+	factories  map[SceneType]SceneFactory
+	commands   []Command
+	transition *activeTransition
+	quit       bool
+	handler    func(cmd Command)
+	translator i18n.Translator
+	config     *game.Config
+	renderer   *graphics.Renderer2D
+	font       *font.Font
+	mods       *mods.Set
 }
 
-// NewSceneManager creates a new scene manager
-func NewSceneManager(translator i18n.Translator, config *game.Config, renderer *sdl.Renderer) *SceneManager {
-	return &SceneManager{
+// NewSceneManager creates a new scene manager with the menu scenes
+// registered
+func NewSceneManager(translator i18n.Translator, config *game.Config, renderer *graphics.Renderer2D, uiFont *font.Font, modSet *mods.Set) *SceneManager {
+	sm := &SceneManager{
+		factories:  make(map[SceneType]SceneFactory),
 		translator: translator,
 		config:     config,
 		renderer:   renderer,
+		font:       uiFont,
+		mods:       modSet,
 	}
+
+	sm.Register(SceneMainMenu, func(ctx SceneContext, payload interface{}) Scene {
+		return NewMenuScene(ctx.Translator, ctx.Config, ctx.Renderer, ctx.Font, ctx.Commands, ctx.Mods)
+	})
+	sm.Register(SceneSettings, func(ctx SceneContext, payload interface{}) Scene {
+		menu := NewMenuScene(ctx.Translator, ctx.Config, ctx.Renderer, ctx.Font, ctx.Commands, ctx.Mods)
+		menu.currentMenu = "settings"
+		return menu
+	})
+	sm.Register(ScenePause, func(ctx SceneContext, payload interface{}) Scene {
+		return NewPauseScene(ctx.Translator, ctx.Config, ctx.Renderer, ctx.Font, ctx.Commands)
+	})
+
+	return sm
 }
 
-// SwitchTo changes the current scene
+// Register sets the factory used to build scenes of the given type,
+// replacing any previous registration
+func (sm *SceneManager) Register(sceneType SceneType, factory SceneFactory) {
+	sm.factories[sceneType] = factory
+}
+
+// newScene creates a scene of the given type using its registered factory
+func (sm *SceneManager) newScene(sceneType SceneType, payload interface{}) Scene {
+	factory, ok := sm.factories[sceneType]
+	if !ok {
+		log.Printf("Warning: no scene registered for %s", sceneType)
+		return nil
+	}
+
+	return factory(SceneContext{
+		Translator: sm.translator,
+		Config:     sm.config,
+		Renderer:   sm.renderer,
+		Font:       sm.font,
+		Commands:   sm,
+		Mods:       sm.mods,
+	}, payload)
+}
+
+// Send queues a command to be applied after the current tick
+func (sm *SceneManager) Send(cmd Command) {
+	sm.commands = append(sm.commands, cmd)
+}
+
+// SetCommandHandler sets the function receiving commands the scene manager
+// does not handle itself, such as CommandApplyDisplay
+func (sm *SceneManager) SetCommandHandler(handler func(cmd Command)) {
+	sm.handler = handler
+}
+
+// QuitRequested reports whether a scene asked the game to stop
+func (sm *SceneManager) QuitRequested() bool {
+	return sm.quit
+}
+
+// SwitchTo clears the stack and makes a new scene of the given type current
 func (sm *SceneManager) SwitchTo(sceneType SceneType) {
-	if sm.currentScene != nil {
-		sm.currentScene.Cleanup()
+	sm.changeScene(sceneType, nil)
+}
+
+// changeScene clears the stack and pushes a new scene
+func (sm *SceneManager) changeScene(sceneType SceneType, payload interface{}) {
+	scene := sm.newScene(sceneType, payload)
+	if scene == nil {
+		return
 	}
 
-	switch sceneType {
-	case SceneMainMenu:
-		sm.currentScene = NewMenuScene(sm.translator, sm.config, sm.renderer)
-		sm.sceneType = SceneMainMenu
-	case SceneSettings:
-		// You can create a dedicated settings scene if needed
-		sm.sceneType = SceneSettings
-	case SceneGameplay:
-		// Initialize gameplay scene
-		sm.sceneType = SceneGameplay
-	case ScenePause:
-		// Initialize pause menu
-		sm.sceneType = ScenePause
+	sm.Clear()
+	sm.pushScene(scene, sceneType)
+}
+
+// Push creates a scene of the given type and places it on top of the stack
+func (sm *SceneManager) Push(sceneType SceneType) {
+	if scene := sm.newScene(sceneType, nil); scene != nil {
+		sm.pushScene(scene, sceneType)
 	}
 }
 
+// pushScene places an already built scene on top of the stack
+func (sm *SceneManager) pushScene(scene Scene, sceneType SceneType) {
+	if top := sm.top(); top != nil {
+		top.scene.Exit()
+	}
+
+	sm.stack = append(sm.stack, sceneEntry{scene: scene, sceneType: sceneType})
+	scene.Enter()
+}
+
+// Pop removes the top scene and resumes the one below it
+func (sm *SceneManager) Pop() {
+	top := sm.top()
+	if top == nil {
+		return
+	}
+
+	top.scene.Exit()
+	top.scene.Cleanup()
+	sm.stack = sm.stack[:len(sm.stack)-1]
+
+	if next := sm.top(); next != nil {
+		next.scene.Enter()
+	}
+}
+
+// Replace swaps the top scene for a new scene of the given type
+func (sm *SceneManager) Replace(sceneType SceneType) {
+	scene := sm.newScene(sceneType, nil)
+	if scene == nil {
+		return
+	}
+
+	if top := sm.top(); top != nil {
+		top.scene.Exit()
+		top.scene.Cleanup()
+		sm.stack = sm.stack[:len(sm.stack)-1]
+	}
+
+	sm.stack = append(sm.stack, sceneEntry{scene: scene, sceneType: sceneType})
+	scene.Enter()
+}
+
+// Clear removes every scene from the stack
+func (sm *SceneManager) Clear() {
+	for len(sm.stack) > 0 {
+		top := sm.stack[len(sm.stack)-1]
+		top.scene.Exit()
+		top.scene.Cleanup()
+		sm.stack = sm.stack[:len(sm.stack)-1]
+	}
+}
+
+// Destroy removes every scene and finishes any running transition
+func (sm *SceneManager) Destroy() {
+	if sm.transition != nil {
+		// Release the outgoing scenes without building the pending one
+		sm.transition.switched = true
+		sm.finishTransition()
+	}
+	sm.Clear()
+	sm.commands = nil
+}
+
+// Len returns the number of scenes on the stack
+func (sm *SceneManager) Len() int {
+	return len(sm.stack)
+}
+
+// top returns the top stack entry or nil when the stack is empty
+func (sm *SceneManager) top() *sceneEntry {
+	if len(sm.stack) == 0 {
+		return nil
+	}
+	return &sm.stack[len(sm.stack)-1]
+}
+
 // GetCurrentScene returns the current scene
 func (sm *SceneManager) GetCurrentScene() Scene {
-	return sm.currentScene
+	if top := sm.top(); top != nil {
+		return top.scene
+	}
+	return nil
 }
 
 // GetSceneType returns the current scene type
 func (sm *SceneManager) GetSceneType() SceneType {
-	return sm.sceneType
+	if top := sm.top(); top != nil {
+		return top.sceneType
+	}
+	return SceneMainMenu
+}
+
+// InTransition reports whether a scene transition is running
+func (sm *SceneManager) InTransition() bool {
+	return sm.transition != nil
 }
 
-// Update updates the current scene
-func (sm *SceneManager) Update() {
-	if sm.currentScene != nil {
-		sm.currentScene.Update()
+// Update updates the current scene, advances any running transition and
+// applies the commands queued during the tick
+func (sm *SceneManager) Update(t game.Time) {
+	if top := sm.top(); top != nil {
+		top.scene.Update(t)
 	}
+
+	if sm.transition != nil {
+		sm.advanceTransition(t)
+	}
+
+	sm.applyCommands()
 }
 
-// Render renders the current scene
-func (sm *SceneManager) Render() {
-	if sm.currentScene != nil {
-		sm.currentScene.Render()
+// applyCommands applies queued commands in order. Commands sent while a
+// transition is running wait until it has finished
+func (sm *SceneManager) applyCommands() {
+	for len(sm.commands) > 0 && sm.transition == nil {
+		cmd := sm.commands[0]
+		sm.commands = sm.commands[1:]
+		sm.apply(cmd)
 	}
 }
 
-// ProcessEvent processes events for the current scene
+// apply executes a single command
+func (sm *SceneManager) apply(cmd Command) {
+	switch cmd.Type {
+	case CommandChangeScene:
+		if cmd.Transition.Kind != TransitionNone && cmd.Transition.Duration > 0 {
+			sm.startTransition(cmd)
+			return
+		}
+		sm.changeScene(cmd.Scene, cmd.Payload)
+		log.Printf("Scene changed to: %s", cmd.Scene)
+	case CommandPushScene:
+		if scene := sm.newScene(cmd.Scene, cmd.Payload); scene != nil {
+			sm.pushScene(scene, cmd.Scene)
+		}
+	case CommandPopScene:
+		sm.Pop()
+	case CommandQuit:
+		sm.quit = true
+	default:
+		if sm.handler != nil {
+			sm.handler(cmd)
+		}
+	}
+}
+
+// startTransition begins a timed scene change
+func (sm *SceneManager) startTransition(cmd Command) {
+	sm.transition = &activeTransition{Transition: cmd.Transition, cmd: cmd}
+
+	if cmd.Transition.Kind == TransitionCrossfade {
+		// The new scene starts right away while the old stack keeps
+		// rendering underneath until the blend completes
+		scene := sm.newScene(cmd.Scene, cmd.Payload)
+		if scene == nil {
+			sm.transition = nil
+			return
+		}
+		if top := sm.top(); top != nil {
+			top.scene.Exit()
+		}
+		sm.transition.outgoing = sm.stack
+		sm.transition.switched = true
+		sm.stack = nil
+		sm.pushScene(scene, cmd.Scene)
+	}
+}
+
+// advanceTransition moves the running transition forward by one tick
+func (sm *SceneManager) advanceTransition(t game.Time) {
+	tr := sm.transition
+	tr.elapsed += t.DT
+
+	// Fades switch scenes at the darkest point
+	if tr.Kind == TransitionFade && !tr.switched && tr.progress() >= 0.5 {
+		sm.changeScene(tr.cmd.Scene, tr.cmd.Payload)
+		tr.switched = true
+		log.Printf("Scene changed to: %s", tr.cmd.Scene)
+	}
+
+	if tr.done() {
+		sm.finishTransition()
+	}
+}
+
+// finishTransition completes the running transition and releases the
+// outgoing scenes
+func (sm *SceneManager) finishTransition() {
+	tr := sm.transition
+	sm.transition = nil
+
+	if !tr.switched {
+		sm.changeScene(tr.cmd.Scene, tr.cmd.Payload)
+	}
+
+	for i := len(tr.outgoing) - 1; i >= 0; i-- {
+		tr.outgoing[i].scene.Cleanup()
+	}
+
+	if tr.snapshot != nil {
+		tr.snapshot.Destroy()
+	}
+}
+
+// Render renders every scene on the stack from the bottom up, followed by
+// any running transition
+func (sm *SceneManager) Render(alpha float64) {
+	if sm.transition != nil && len(sm.transition.outgoing) > 0 {
+		sm.renderCrossfade(alpha)
+		return
+	}
+
+	sm.renderStack(sm.stack, alpha)
+
+	if sm.transition != nil && sm.transition.Kind == TransitionFade {
+		sm.fillOverlay(uint8(sm.transition.fadeAlpha() * 255))
+	}
+}
+
+// renderStack renders the given scenes from the bottom up. 2D drawing is
+// flushed after every scene so that scenes drawing directly with OpenGL
+// are layered correctly
+func (sm *SceneManager) renderStack(stack []sceneEntry, alpha float64) {
+	for _, entry := range stack {
+		entry.scene.Render(alpha)
+		if sm.renderer != nil {
+			sm.renderer.Flush()
+		}
+	}
+}
+
+// renderCrossfade draws the incoming scenes and blends a snapshot of the
+// outgoing ones over them
+func (sm *SceneManager) renderCrossfade(alpha float64) {
+	tr := sm.transition
+
+	if sm.renderer == nil {
+		sm.renderStack(sm.stack, alpha)
+		return
+	}
+
+	width, height := sm.renderer.Size()
+	if tr.snapshot == nil {
+		snapshot, err := graphics.NewRenderTarget(width, height, graphics.ColorRGBA8)
+		if err != nil {
+			log.Printf("Warning: failed to create crossfade target: %v", err)
+			sm.renderStack(sm.stack, alpha)
+			return
+		}
+		tr.snapshot = snapshot
+	}
+	if err := tr.snapshot.Resize(width, height); err != nil {
+		log.Printf("Warning: failed to resize crossfade target: %v", err)
+	}
+
+	// Capture the outgoing scenes, then go back to the frame being drawn
+	// (the window or the post-processing target)
+	framebuffer := graphics.CurrentFramebuffer()
+	tr.snapshot.Bind()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	sm.renderStack(tr.outgoing, alpha)
+	graphics.BindFramebuffer(framebuffer, width, height)
+
+	sm.renderStack(sm.stack, alpha)
+
+	opacity := uint8((1 - tr.progress()) * 255)
+	sm.renderer.DrawRegion(tr.snapshot.Texture().Region(),
+		graphics.Rect{W: float32(width), H: float32(height)},
+		color.RGBA{R: 255, G: 255, B: 255, A: opacity})
+	sm.renderer.Flush()
+}
+
+// fillOverlay covers the screen with black at the given opacity
+func (sm *SceneManager) fillOverlay(opacity uint8) {
+	if sm.renderer == nil || opacity == 0 {
+		return
+	}
+
+	width, height := sm.renderer.Size()
+	sm.renderer.FillRect(graphics.Rect{W: float32(width), H: float32(height)}, color.RGBA{A: opacity})
+	sm.renderer.Flush()
+}
+
+// ProcessEvent processes events for the current scene. Input is ignored
+// while a transition is running
 func (sm *SceneManager) ProcessEvent(event sdl.Event) bool {
-	if sm.currentScene != nil {
-		return sm.currentScene.ProcessEvent(event)
+	if sm.transition != nil {
+		return true
+	}
+
+	if top := sm.top(); top != nil {
+		return top.scene.ProcessEvent(event)
 	}
 	return false
 }
@@ -0,0 +1,37 @@
+package menu
+
+import (
+	"image/color"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/font"
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
+)
+
+// Menu colors
+var (
+	backgroundColor = color.RGBA{R: 30, G: 30, B: 50, A: 255}
+	textColor       = color.RGBA{R: 230, G: 230, B: 230, A: 255}
+	selectedColor   = color.RGBA{R: 255, G: 215, B: 0, A: 255} // Gold
+	outlineColor    = color.RGBA{R: 0, G: 0, B: 0, A: 255}
+)
+
+// drawText draws text with its top edge at y. x is the left edge, or the
+// center when centered is set
+func drawText(renderer *graphics.Renderer2D, f *font.Font, s string, x, y int32, size int32, centered bool, c color.RGBA) {
+	// Nothing is drawn in headless mode
+	if renderer == nil || f == nil {
+		return
+	}
+
+	opts := font.Options{
+		Size:         int(size),
+		Color:        c,
+		Outline:      1,
+		OutlineColor: outlineColor,
+	}
+	if centered {
+		opts.Align = font.AlignCenter
+	}
+
+	renderer.DrawText(f, s, float32(x), float32(y), opts)
+}
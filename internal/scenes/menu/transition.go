@@ -0,0 +1,65 @@
+package menu
+
+import (
+	"time"
+
+	"github.com/luidsonl/magic-and-blades/internal/engine/graphics"
+)
+
+// TransitionKind selects how a scene change is presented
+type TransitionKind int
+
+const (
+	// TransitionNone switches scenes immediately
+	TransitionNone TransitionKind = iota
+	// TransitionFade fades to black, switches scenes and fades back in
+	TransitionFade
+	// TransitionCrossfade blends the outgoing scene into the incoming one
+	TransitionCrossfade
+)
+
+// Transition describes a timed scene change
+type Transition struct {
+	Kind     TransitionKind
+	Duration time.Duration
+}
+
+// activeTransition tracks the progress of a running transition
+type activeTransition struct {
+	Transition
+	cmd     Command
+	elapsed time.Duration
+	// switched reports whether the scene change has been applied yet
+	switched bool
+	// outgoing holds the previous stack while a crossfade is running
+	outgoing []sceneEntry
+	// snapshot captures the outgoing scenes for crossfading
+	snapshot *graphics.RenderTarget
+}
+
+// progress returns how far the transition is, from 0 to 1
+func (t *activeTransition) progress() float64 {
+	if t.Duration <= 0 {
+		return 1
+	}
+	p := float64(t.elapsed) / float64(t.Duration)
+	if p > 1 {
+		return 1
+	}
+	return p
+}
+
+// done reports whether the transition has finished
+func (t *activeTransition) done() bool {
+	return t.elapsed >= t.Duration
+}
+
+// fadeAlpha returns the opacity of the black overlay for fade transitions:
+// rising to 1 during the first half and falling back to 0 in the second
+func (t *activeTransition) fadeAlpha() float64 {
+	p := t.progress()
+	if p < 0.5 {
+		return p * 2
+	}
+	return (1 - p) * 2
+}